@@ -0,0 +1,165 @@
+// Command shortener-migrate переносит все сокращённые ссылки из одного хранилища в другое.
+//
+// Поддерживаются движки memory, file, postgres и bolt. Короткие ключи, владельцы,
+// correlation_id, признак и момент удаления, момент создания, история изменений
+// и переходы по ссылкам сохраняются. Перенос идёт пачками; после каждой
+// пачки курсор источника сохраняется в файл контрольной точки, поэтому прерванный
+// перенос продолжается с места остановки при повторном запуске с тем же -checkpoint.
+//
+// Пример:
+//
+//	shortener-migrate -from file -from-file storage.json -to postgres -to-dsn postgres://... -checkpoint migrate.json
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/issafronov/shortener/internal/app/config"
+	"github.com/issafronov/shortener/internal/app/storage"
+	_ "github.com/jackc/pgx/stdlib"
+)
+
+// endpoint описывает хранилище-источник или приёмник
+type endpoint struct {
+	engine string
+	dsn    string
+	file   string
+	bolt   string
+}
+
+// config преобразует описание хранилища в конфигурацию для storage.New
+func (e endpoint) config() *config.Config {
+	return &config.Config{
+		StorageEngine:   e.engine,
+		DatabaseDSN:     e.dsn,
+		FileStoragePath: e.file,
+		BoltPath:        e.bolt,
+		AutoMigrate:     true,
+	}
+}
+
+func main() {
+	var from, to endpoint
+	var batchSize int
+	var checkpoint string
+
+	flag.StringVar(&from.engine, "from", "", "source storage engine: memory, file, postgres or bolt")
+	flag.StringVar(&from.dsn, "from-dsn", "", "source database DSN")
+	flag.StringVar(&from.file, "from-file", "", "source file storage path")
+	flag.StringVar(&from.bolt, "from-bolt", "", "source bolt storage path")
+	flag.StringVar(&to.engine, "to", "", "destination storage engine: memory, file, postgres or bolt")
+	flag.StringVar(&to.dsn, "to-dsn", "", "destination database DSN")
+	flag.StringVar(&to.file, "to-file", "", "destination file storage path")
+	flag.StringVar(&to.bolt, "to-bolt", "", "destination bolt storage path")
+	flag.IntVar(&batchSize, "batch", storage.DefaultCopyBatchSize, "records per batch")
+	flag.StringVar(&checkpoint, "checkpoint", "", "checkpoint file to resume an interrupted migration")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, from, to, batchSize, checkpoint); err != nil {
+		log.Fatalf("migration failed: %v", err)
+	}
+}
+
+func run(ctx context.Context, from, to endpoint, batchSize int, checkpoint string) error {
+	if from.engine == "" || to.engine == "" {
+		return errors.New("both -from and -to must be set")
+	}
+
+	src, err := storage.New(ctx, from.config())
+	if err != nil {
+		return fmt.Errorf("open source: %w", err)
+	}
+	if closer, ok := src.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	dst, err := storage.New(ctx, to.config())
+	if err != nil {
+		return fmt.Errorf("open destination: %w", err)
+	}
+	if closer, ok := dst.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	start, err := loadCheckpoint(checkpoint)
+	if err != nil {
+		return err
+	}
+	if start.Done {
+		fmt.Println("Migration already completed according to", checkpoint)
+		return nil
+	}
+	if start.Cursor != "" {
+		fmt.Println("Resuming migration from cursor", start.Cursor)
+	}
+
+	progress, err := storage.Copy(ctx, src, dst, storage.CopyOptions{
+		BatchSize: batchSize,
+		Cursor:    start.Cursor,
+		OnBatch: func(p storage.CopyProgress) error {
+			p.Read += start.Read
+			p.Written += start.Written
+			p.Versions += start.Versions
+			p.Clicks += start.Clicks
+			fmt.Printf("Copied %d records, written %d, history versions %d, clicks %d\n", p.Read, p.Written, p.Versions, p.Clicks)
+			return saveCheckpoint(checkpoint, p)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Migration completed: read %d, written %d, skipped %d, history versions %d, clicks %d\n",
+		start.Read+progress.Read, start.Written+progress.Written, progress.Read-progress.Written,
+		start.Versions+progress.Versions, start.Clicks+progress.Clicks)
+	return nil
+}
+
+// loadCheckpoint читает сохранённое состояние переноса, если файл существует
+func loadCheckpoint(path string) (storage.CopyProgress, error) {
+	var progress storage.CopyProgress
+	if path == "" {
+		return progress, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return progress, nil
+	}
+	if err != nil {
+		return progress, err
+	}
+	if err := json.Unmarshal(data, &progress); err != nil {
+		return progress, fmt.Errorf("invalid checkpoint %s: %w", path, err)
+	}
+	return progress, nil
+}
+
+// saveCheckpoint атомарно сохраняет состояние переноса
+func saveCheckpoint(path string, progress storage.CopyProgress) error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
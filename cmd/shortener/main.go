@@ -1,10 +1,11 @@
 package main
 
 import (
-	"bufio"
 	"context"
-	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
+	"log"
 	"net"
 	"net/http"
 	"os"
@@ -17,11 +18,16 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/issafronov/shortener/internal/app/analytics"
 	"github.com/issafronov/shortener/internal/app/config"
+	"github.com/issafronov/shortener/internal/app/deleter"
 	"github.com/issafronov/shortener/internal/app/grpcserver"
 	"github.com/issafronov/shortener/internal/app/handlers"
+	"github.com/issafronov/shortener/internal/app/metrics"
 	"github.com/issafronov/shortener/internal/app/service"
 	"github.com/issafronov/shortener/internal/app/storage"
+	"github.com/issafronov/shortener/internal/app/tracing"
+	"github.com/issafronov/shortener/internal/app/utils"
 	"github.com/issafronov/shortener/internal/middleware/auth"
 	"github.com/issafronov/shortener/internal/middleware/compress"
 	"github.com/issafronov/shortener/internal/middleware/logger"
@@ -29,6 +35,7 @@ import (
 	"github.com/issafronov/shortener/internal/pprof"
 	"github.com/issafronov/shortener/proto"
 	_ "github.com/jackc/pgx/stdlib"
+	"go.opentelemetry.io/otel"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 )
@@ -41,17 +48,22 @@ var (
 
 func main() {
 	printBuildInfo()
-	pprof.Start()
-
-	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
-	defer stop()
 
 	conf := config.LoadConfig()
 
-	if err := restoreStorage(conf); err != nil {
-		panic(err)
+	// подкоманда migrate обновляет схему базы данных и не запускает сервер
+	if args := flag.Args(); len(args) > 0 && args[0] == "migrate" {
+		if err := runMigrate(conf, args[1:], os.Stdout); err != nil {
+			log.Fatalf("Migration error: %v", err)
+		}
+		return
 	}
 
+	pprof.Start()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	defer stop()
+
 	var wg sync.WaitGroup
 
 	if err := runServer(conf, ctx, &wg); err != nil {
@@ -63,8 +75,10 @@ func main() {
 	fmt.Println("Server shutdown completed")
 }
 
-// Router возвращает настроенный маршрутизатор chi с подключёнными middleware и обработчиками
-func Router(config *config.Config, s service.Service) chi.Router {
+// Router возвращает настроенный маршрутизатор chi с подключёнными middleware и обработчиками.
+// Если m не nil, запросы учитываются в метриках, а при пустом MetricsAddress
+// маршрут /metrics доступен доверенной подсети. Если tr не nil, запросы оборачиваются спанами.
+func Router(config *config.Config, s service.Service, m *metrics.Metrics, tr *tracing.Tracing) chi.Router {
 	router := chi.NewRouter()
 
 	if err := logger.Initialize(config.LoggerLevel); err != nil {
@@ -77,22 +91,42 @@ func Router(config *config.Config, s service.Service) chi.Router {
 		logger.Log.Info("Failed to initialize handler")
 	}
 
+	if m != nil {
+		router.Use(m.HTTPMiddleware)
+	}
+	// трассировка подключается до логирования, чтобы строки журнала содержали идентификатор трассы
+	if tr != nil {
+		router.Use(tr.HTTPMiddleware)
+	}
 	router.Use(logger.RequestLogger)
 	router.Use(compress.GzipMiddleware)
-	router.Use(middleware.Timeout(60 * time.Second))
 	router.Use(auth.AuthorizationMiddleware)
-	router.Get("/{key}", handler.GetLinkHandle)
-	router.Post("/", handler.CreateLinkHandle)
-	router.Post("/api/shorten", handler.CreateJSONLinkHandle)
-	router.Post("/api/shorten/batch", handler.CreateBatchJSONLinkHandle)
-	router.Get("/ping", handler.Ping)
-	router.Get("/api/user/urls", handler.GetUserLinksHandle)
-	router.Delete("/api/user/urls", handler.DeleteLinksHandle)
-
-	router.Group(func(r chi.Router) {
-		subnet := parseSubnet(config.TrustedSubnet)
-		r.Use(trustedsubnet.TrustedSubnetMiddleware(subnet))
-		r.Get("/api/internal/stats", handler.InternalStats)
+	// потоковый импорт может длиться дольше общего таймаута запросов
+	router.Post("/api/shorten/import", handler.ImportLinksHandle)
+
+	router.Group(func(router chi.Router) {
+		router.Use(middleware.Timeout(60 * time.Second))
+		router.Get("/{key}", handler.GetLinkHandle)
+		router.Post("/", handler.CreateLinkHandle)
+		router.Post("/api/shorten", handler.CreateJSONLinkHandle)
+		router.Post("/api/shorten/batch", handler.CreateBatchJSONLinkHandle)
+		router.Get("/ping", handler.Ping)
+		router.Get("/api/user/urls", handler.GetUserLinksHandle)
+		router.Get("/api/user/urls/{key}/stats", handler.GetLinkStatsHandle)
+		router.Patch("/api/user/urls/{key}", handler.UpdateLinkHandle)
+		router.Get("/api/user/urls/{key}/history", handler.GetLinkHistoryHandle)
+		router.Post("/api/user/urls/{key}/rollback", handler.RollbackLinkHandle)
+		router.Delete("/api/user/urls", handler.DeleteLinksHandle)
+		router.Get("/api/user/jobs/{id}", handler.GetJobHandle)
+
+		router.Group(func(r chi.Router) {
+			subnet := parseSubnet(config.TrustedSubnet)
+			r.Use(trustedsubnet.TrustedSubnetMiddleware(subnet))
+			r.Get("/api/internal/stats", handler.InternalStats)
+			if m != nil && config.MetricsAddress == "" {
+				r.Method(http.MethodGet, "/metrics", m.Handler())
+			}
+		})
 	})
 
 	return router
@@ -104,24 +138,112 @@ func runServer(cfg *config.Config, parentCtx context.Context, wg *sync.WaitGroup
 	serverCtx, stop := context.WithCancel(parentCtx)
 	defer stop()
 
-	var st storage.Storage
 	var srv service.Service
-	var err error
 
-	if cfg.DatabaseDSN != "" {
-		pgStorage, err := storage.NewPostgresStorage(serverCtx, cfg.DatabaseDSN)
-		if err != nil {
-			return fmt.Errorf("failed to connect to database: %w", err)
-		}
-		st = pgStorage
-	} else {
-		st, err = storage.NewFileStorage(cfg)
-		if err != nil {
-			return fmt.Errorf("failed to initialize file storage: %w", err)
+	engine := storage.ResolveEngine(cfg)
+	st, err := storage.New(serverCtx, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize %s storage: %w", engine, err)
+	}
+	if closer, ok := st.(io.Closer); ok {
+		defer closer.Close()
+	}
+	fmt.Println("Using storage engine", engine)
+
+	appMetrics := metrics.New()
+
+	exporter, err := tracing.NewExporter(cfg.TraceExporter, cfg.TraceFile)
+	if err != nil {
+		return fmt.Errorf("failed to initialize trace exporter: %w", err)
+	}
+	appTracing := tracing.New(exporter)
+	otel.SetTracerProvider(appTracing.TracerProvider())
+	otel.SetTextMapPropagator(appTracing.Propagator())
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := appTracing.Shutdown(shutdownCtx); err != nil {
+			fmt.Printf("Trace exporter shutdown error: %v\n", err)
 		}
+	}()
+
+	// фоновые обработчики хранилища должны завершиться до его закрытия
+	var workers sync.WaitGroup
+	// очереди переходов и удаления останавливаются после HTTP- и gRPC-серверов, чтобы принять
+	// переходы и запросы, обработанные при остановке; wg завершается, когда серверы обслужили
+	// активные запросы
+	queuesCtx, stopQueues := context.WithCancel(context.WithoutCancel(serverCtx))
+	defer func() {
+		stop()
+		wg.Wait()
+		stopQueues()
+		workers.Wait()
+	}()
+
+	if fileStorage, ok := st.(*storage.FileStorage); ok {
+		recovery := fileStorage.Recovery()
+		fmt.Printf("Restored %d records from %s, skipped %d, truncated %d bytes\n",
+			recovery.Recovered, cfg.FileStoragePath, recovery.Skipped, recovery.TruncatedBytes)
+
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			fileStorage.Run(serverCtx)
+		}()
+	}
+
+	st = metrics.NewStorage(st, engine, appMetrics)
+	st = tracing.NewStorage(st, engine, appTracing)
+	// кэш оборачивает остальные декораторы, чтобы метрики и спаны хранилища отражали только промахи
+	if cfg.CacheSize > 0 {
+		st = storage.NewCachedStorage(st, cfg.CacheSize, cfg.CacheTTL, cfg.CacheNegativeTTL)
+	}
+
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		storage.RunJanitor(serverCtx, st, cfg.PurgeInterval)
+	}()
+
+	keys, err := newKeyGenerator(serverCtx, cfg, st)
+	if err != nil {
+		return fmt.Errorf("failed to initialize key generator: %w", err)
 	}
-	srv = service.NewService(st)
-	router := Router(cfg, srv)
+
+	recorder := analytics.NewRecorder(st, analytics.Options{
+		BufferSize:    cfg.ClickBufferSize,
+		FlushInterval: cfg.ClickFlushInterval,
+		Salt:          cfg.ClickSalt,
+	})
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		recorder.Run(queuesCtx)
+	}()
+
+	deletes := deleter.New(st, deleter.Options{
+		QueueSize:     cfg.DeleteQueueSize,
+		BatchSize:     cfg.DeleteBatchSize,
+		FlushInterval: cfg.DeleteFlushInterval,
+	})
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		deletes.Run(queuesCtx)
+	}()
+	appMetrics.RegisterGauge("delete_queue_depth", "Количество запросов на удаление, ожидающих обработки.", func() float64 {
+		return float64(deletes.Stats().Queued)
+	})
+
+	srv = service.NewService(st,
+		service.WithKeyGenerator(keys),
+		service.WithClickRecorder(recorder),
+		service.WithDeletionQueue(deletes),
+		service.WithObserver(appMetrics),
+		service.WithURLUniqueness(cfg.URLUniqueness),
+	)
+	srv = tracing.NewService(srv, appTracing)
+	router := Router(cfg, srv, appMetrics, appTracing)
 	server := &http.Server{
 		Addr:    cfg.ServerAddress,
 		Handler: router,
@@ -139,12 +261,23 @@ func runServer(cfg *config.Config, parentCtx context.Context, wg *sync.WaitGroup
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		if err := runGRPCServer(cfg, srv, serverCtx); err != nil {
+		if err := runGRPCServer(cfg, srv, appMetrics, appTracing, serverCtx); err != nil {
 			fmt.Printf("gRPC server error: %v\n", err)
 			stop()
 		}
 	}()
 
+	if cfg.MetricsAddress != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := runMetricsServer(cfg, appMetrics, serverCtx); err != nil {
+				fmt.Printf("Metrics server error: %v\n", err)
+				stop()
+			}
+		}()
+	}
+
 	<-serverCtx.Done()
 
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -157,25 +290,30 @@ func runServer(cfg *config.Config, parentCtx context.Context, wg *sync.WaitGroup
 	return nil
 }
 
-func restoreStorage(config *config.Config) error {
-	fmt.Println("Restoring storage")
-
-	file, err := os.OpenFile(config.FileStoragePath, os.O_RDONLY|os.O_CREATE, 0666)
-	if err != nil {
-		return err
-	}
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		data := scanner.Bytes()
-		shortenerURL := storage.ShortenerURL{}
-		err = json.Unmarshal(data, &shortenerURL)
-		if err != nil {
-			return err
+// newKeyGenerator создаёт генератор коротких ключей по конфигурации.
+// Счётчик генераторов sequence и hashids хранится в хранилище, если оно это поддерживает:
+// значения резервируются блоками и не выдаются повторно после перезапуска.
+// Иначе счётчик начинается с числа ссылок в хранилище, а совпавшие ключи генерируются заново.
+func newKeyGenerator(ctx context.Context, cfg *config.Config, st storage.Storage) (utils.KeyGenerator, error) {
+	opts := utils.KeyGeneratorOptions{Length: cfg.KeyLength, Salt: cfg.KeySalt}
+
+	if cfg.KeyGenerator == utils.KeyGeneratorSequence || cfg.KeyGenerator == utils.KeyGeneratorHashids {
+		if counter, ok := storage.Find[storage.KeyCounter](st); ok {
+			// ключи резервируются и для запросов, которые серверы дообрабатывают при остановке
+			reserveCtx := context.WithoutCancel(ctx)
+			opts.Reserve = func(floor, n uint64) (uint64, error) {
+				return counter.ReserveKeys(reserveCtx, cfg.KeyGenerator, floor, n)
+			}
+		} else {
+			count, err := st.CountURLs(ctx)
+			if err != nil {
+				return nil, err
+			}
+			opts.Start = uint64(count)
 		}
-		storage.Urls[shortenerURL.ShortURL] = shortenerURL
 	}
-	return file.Close()
+
+	return utils.NewKeyGenerator(cfg.KeyGenerator, opts)
 }
 
 func printBuildInfo() {
@@ -212,13 +350,24 @@ func startHTTPServer(cfg *config.Config, server *http.Server) error {
 }
 
 // runGRPCServer запускает grpc
-func runGRPCServer(cfg *config.Config, srv service.Service, ctx context.Context) error {
+func runGRPCServer(cfg *config.Config, srv service.Service, m *metrics.Metrics, tr *tracing.Tracing, ctx context.Context) error {
 	lis, err := net.Listen("tcp", cfg.GRPCServerAddress)
 	if err != nil {
 		return fmt.Errorf("failed to listen on gRPC: %w", err)
 	}
 
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			m.UnaryServerInterceptor(),
+			tr.UnaryServerInterceptor(),
+			auth.UnaryServerInterceptor(),
+		),
+		grpc.ChainStreamInterceptor(
+			m.StreamServerInterceptor(),
+			tr.StreamServerInterceptor(),
+			auth.StreamServerInterceptor(),
+		),
+	)
 	proto.RegisterShortenerServer(grpcServer, grpcserver.NewGRPCHandler(srv, cfg))
 	reflection.Register(grpcServer)
 
@@ -231,6 +380,26 @@ func runGRPCServer(cfg *config.Config, srv service.Service, ctx context.Context)
 	return grpcServer.Serve(lis)
 }
 
+// runMetricsServer запускает отдельный HTTP-сервер метрик Prometheus
+func runMetricsServer(cfg *config.Config, m *metrics.Metrics, ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+	server := &http.Server{Addr: cfg.MetricsAddress, Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Println("Starting metrics server on", cfg.MetricsAddress)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
 func parseSubnet(cidr string) *net.IPNet {
 	if cidr == "" {
 		return nil
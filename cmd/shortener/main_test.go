@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
-	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -17,8 +16,10 @@ import (
 	"github.com/go-resty/resty/v2"
 	"github.com/issafronov/shortener/internal/app/config"
 	"github.com/issafronov/shortener/internal/app/handlers"
+	"github.com/issafronov/shortener/internal/app/metrics"
 	"github.com/issafronov/shortener/internal/app/service"
 	"github.com/issafronov/shortener/internal/app/storage"
+	"github.com/issafronov/shortener/internal/app/tracing"
 	"github.com/issafronov/shortener/internal/app/utils"
 	"github.com/issafronov/shortener/internal/middleware/compress"
 	"github.com/issafronov/shortener/internal/testutils"
@@ -26,10 +27,6 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-func init() {
-	storage.Urls = map[string]storage.ShortenerURL{}
-}
-
 func createTempFile(t *testing.T, data []byte) string {
 	tmpFile, err := os.CreateTemp("", "test_storage_*.json")
 	require.NoError(t, err)
@@ -43,12 +40,12 @@ func createTempFile(t *testing.T, data []byte) string {
 
 	err = tmpFile.Close()
 	require.NoError(t, err)
+	t.Cleanup(func() { os.Remove(tmpFile.Name() + ".clicks") })
 
 	return tmpFile.Name()
 }
 
 func TestCreateLinkHandle(t *testing.T) {
-	storage.Urls = make(map[string]storage.ShortenerURL)
 	conf := &config.Config{}
 	tmpFile := createTempFile(t, nil)
 	defer os.Remove(tmpFile)
@@ -101,7 +98,6 @@ func TestCreateLinkHandle(t *testing.T) {
 }
 
 func TestCreateJSONLinkHandle(t *testing.T) {
-	storage.Urls = make(map[string]storage.ShortenerURL)
 	conf := &config.Config{}
 	tmpFile := createTempFile(t, nil)
 	defer os.Remove(tmpFile)
@@ -133,7 +129,6 @@ func TestCreateJSONLinkHandle(t *testing.T) {
 }
 
 func TestGzipCompression(t *testing.T) {
-	storage.Urls = make(map[string]storage.ShortenerURL)
 	conf := &config.Config{}
 	tmpFile := createTempFile(t, nil)
 	defer os.Remove(tmpFile)
@@ -175,7 +170,7 @@ func TestGzipCompression(t *testing.T) {
 	})
 
 	t.Run("accepts_gzip", func(t *testing.T) {
-		buf := bytes.NewBufferString(requestBody)
+		buf := bytes.NewBufferString(`{"url": "https://www.google.com/search"}`)
 
 		req, err := http.NewRequest("POST", srv.URL, buf)
 		require.NoError(t, err)
@@ -196,34 +191,6 @@ func TestGzipCompression(t *testing.T) {
 	})
 }
 
-func createTestStorageFile(t *testing.T, data []storage.ShortenerURL) string {
-	tmpFile, err := os.CreateTemp("", "test_storage_*.json")
-	require.NoError(t, err)
-	for _, d := range data {
-		line, _ := json.Marshal(d)
-		tmpFile.Write(append(line, '\n'))
-	}
-	tmpFile.Close()
-	return tmpFile.Name()
-}
-
-func Test_restoreStorage(t *testing.T) {
-	testData := []storage.ShortenerURL{
-		{ShortURL: "abc123", OriginalURL: "https://example.com"},
-		{ShortURL: "xyz789", OriginalURL: "https://test.com"},
-	}
-	path := createTestStorageFile(t, testData)
-	defer os.Remove(path)
-
-	cfg := &config.Config{FileStoragePath: path}
-	storage.Urls = make(map[string]storage.ShortenerURL)
-
-	err := restoreStorage(cfg)
-	require.NoError(t, err)
-	assert.Len(t, storage.Urls, 2)
-	assert.Equal(t, "https://example.com", storage.Urls["abc123"].OriginalURL)
-}
-
 func Test_getOrNA(t *testing.T) {
 	assert.Equal(t, "N/A", getOrNA(""))
 	assert.Equal(t, "value", getOrNA("value"))
@@ -241,7 +208,6 @@ func Test_Router(t *testing.T) {
 	tmpFile := createTempFile(t, nil)
 	defer os.Remove(tmpFile)
 
-	storage.Urls = make(map[string]storage.ShortenerURL)
 	cfg := &config.Config{
 		LoggerLevel:     "info",
 		FileStoragePath: tmpFile,
@@ -251,7 +217,7 @@ func Test_Router(t *testing.T) {
 	require.NoError(t, err)
 	svc := service.NewService(s)
 
-	router := Router(cfg, svc)
+	router := Router(cfg, svc, metrics.New(), tracing.New(nil))
 	assert.NotNil(t, router)
 
 	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
@@ -265,7 +231,6 @@ func Test_runServer_FileStorage(t *testing.T) {
 	tmpFile := createTempFile(t, nil)
 	defer os.Remove(tmpFile)
 
-	storage.Urls = make(map[string]storage.ShortenerURL)
 	cfg := &config.Config{
 		FileStoragePath: tmpFile,
 		ServerAddress:   "127.0.0.1:8085",
@@ -292,6 +257,32 @@ func Test_runServer_FileStorage(t *testing.T) {
 	wg.Wait()
 }
 
+func Test_runServer_MemoryStorage(t *testing.T) {
+	cfg := &config.Config{
+		ServerAddress: "127.0.0.1:8086",
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var wg sync.WaitGroup
+
+	go func() {
+		err := runServer(cfg, ctx, &wg)
+		assert.NoError(t, err)
+	}()
+
+	time.Sleep(500 * time.Millisecond)
+
+	resp, err := http.Get("http://" + cfg.ServerAddress + "/ping")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	cancel()
+	wg.Wait()
+}
+
 func Test_runServer_InvalidDB(t *testing.T) {
 	cfg := &config.Config{
 		DatabaseDSN:   "invalid-dsn",
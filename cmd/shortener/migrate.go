@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/issafronov/shortener/internal/app/config"
+	"github.com/issafronov/shortener/internal/scripts"
+)
+
+// migrateUsage описывает подкоманду migrate
+const migrateUsage = "usage: shortener [flags] migrate up [N] | down [N] | version | force VERSION"
+
+// runMigrate выполняет подкоманду migrate над базой данных из конфигурации:
+//
+//	up [N]         применяет все или N следующих миграций
+//	down [N]       откатывает N последних миграций, по умолчанию одну
+//	version        выводит текущую версию схемы
+//	force VERSION  помечает схему версией VERSION без выполнения миграций,
+//	               чтобы снять признак dirty после неудачной миграции
+func runMigrate(conf *config.Config, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	if conf.DatabaseDSN == "" {
+		return errors.New("database DSN is not set")
+	}
+
+	command, n, err := parseMigrateArgs(args)
+	if err != nil {
+		return err
+	}
+
+	m, err := scripts.NewMigrate(conf.DatabaseDSN)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	switch command {
+	case "up":
+		if n > 0 {
+			err = m.Steps(n)
+		} else {
+			err = m.Up()
+		}
+	case "down":
+		err = m.Steps(-n)
+	case "force":
+		err = m.Force(n)
+	}
+	if errors.Is(err, migrate.ErrNoChange) {
+		err = nil
+	}
+	if err != nil {
+		return fmt.Errorf("migrate %s: %w", command, err)
+	}
+
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		fmt.Fprintln(out, "No migrations applied")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if dirty {
+		fmt.Fprintf(out, "Schema version: %d (dirty)\n", version)
+	} else {
+		fmt.Fprintf(out, "Schema version: %d\n", version)
+	}
+	return nil
+}
+
+// parseMigrateArgs проверяет аргументы подкоманды migrate и возвращает команду и её числовой аргумент
+func parseMigrateArgs(args []string) (string, int, error) {
+	command := args[0]
+	switch command {
+	case "version":
+		if len(args) != 1 {
+			return "", 0, errors.New(migrateUsage)
+		}
+		return command, 0, nil
+	case "up", "down":
+		if len(args) == 1 {
+			if command == "down" {
+				return command, 1, nil
+			}
+			return command, 0, nil
+		}
+	case "force":
+		if len(args) == 1 {
+			return "", 0, errors.New(migrateUsage)
+		}
+	default:
+		return "", 0, fmt.Errorf("unknown migrate command %q\n%s", command, migrateUsage)
+	}
+
+	if len(args) != 2 {
+		return "", 0, errors.New(migrateUsage)
+	}
+	n, err := strconv.Atoi(args[1])
+	if err != nil || n < 0 || (n == 0 && command != "force") {
+		return "", 0, fmt.Errorf("invalid %s argument %q\n%s", command, args[1], migrateUsage)
+	}
+	return command, n, nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"io"
+	"testing"
+
+	"github.com/issafronov/shortener/internal/app/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMigrateArgs(t *testing.T) {
+	tests := []struct {
+		args    []string
+		command string
+		n       int
+	}{
+		{args: []string{"up"}, command: "up", n: 0},
+		{args: []string{"up", "2"}, command: "up", n: 2},
+		{args: []string{"down"}, command: "down", n: 1},
+		{args: []string{"down", "3"}, command: "down", n: 3},
+		{args: []string{"version"}, command: "version", n: 0},
+		{args: []string{"force", "5"}, command: "force", n: 5},
+	}
+	for _, tt := range tests {
+		command, n, err := parseMigrateArgs(tt.args)
+		require.NoError(t, err, tt.args)
+		assert.Equal(t, tt.command, command)
+		assert.Equal(t, tt.n, n)
+	}
+
+	for _, args := range [][]string{
+		{"sideways"},
+		{"up", "zero"},
+		{"down", "0"},
+		{"down", "-1"},
+		{"force"},
+		{"version", "1"},
+		{"up", "1", "2"},
+	} {
+		_, _, err := parseMigrateArgs(args)
+		assert.Error(t, err, args)
+	}
+}
+
+func TestRunMigrate_RequiresDSN(t *testing.T) {
+	err := runMigrate(&config.Config{}, []string{"up"}, io.Discard)
+	assert.ErrorContains(t, err, "DSN")
+
+	err = runMigrate(&config.Config{DatabaseDSN: "postgres://localhost/db"}, nil, io.Discard)
+	assert.ErrorContains(t, err, "usage")
+}
@@ -0,0 +1,148 @@
+// Package analytics содержит асинхронный конвейер записи переходов по сокращённым ссылкам
+package analytics
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync/atomic"
+	"time"
+
+	"github.com/issafronov/shortener/internal/app/models"
+	"github.com/issafronov/shortener/internal/middleware/logger"
+	"go.uber.org/zap"
+)
+
+// Значения по умолчанию для Recorder
+const (
+	DefaultBufferSize    = 4096
+	DefaultBatchSize     = 256
+	DefaultFlushInterval = time.Second
+)
+
+// flushTimeout ограничивает запись последней пачки при остановке
+const flushTimeout = 5 * time.Second
+
+// Sink сохраняет пачки переходов
+type Sink interface {
+	SaveClicks(ctx context.Context, clicks []models.Click) error
+}
+
+// Options настраивает Recorder
+type Options struct {
+	// BufferSize — ёмкость очереди событий; при переполнении события отбрасываются
+	BufferSize int
+	// BatchSize — максимальный размер пачки, записываемой за один раз
+	BatchSize int
+	// FlushInterval — максимальное время ожидания перед записью неполной пачки
+	FlushInterval time.Duration
+	// Salt — соль для хэширования IP-адресов
+	Salt string
+}
+
+// Recorder принимает переходы без блокировки вызывающего и пачками записывает их в Sink
+type Recorder struct {
+	sink    Sink
+	events  chan models.Click
+	opts    Options
+	dropped atomic.Int64
+}
+
+// NewRecorder создаёт Recorder. Для записи событий нужно запустить Run.
+func NewRecorder(sink Sink, opts Options) *Recorder {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = DefaultBufferSize
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = DefaultFlushInterval
+	}
+	return &Recorder{
+		sink:   sink,
+		events: make(chan models.Click, opts.BufferSize),
+		opts:   opts,
+	}
+}
+
+// Record ставит переход в очередь, заменяя IP-адрес клиента его хэшем.
+// Если очередь заполнена, событие отбрасывается, чтобы не задерживать редирект.
+func (r *Recorder) Record(click models.Click, clientIP string) {
+	click.IPHash = r.hashIP(clientIP)
+	select {
+	case r.events <- click:
+	default:
+		r.dropped.Add(1)
+	}
+}
+
+// Dropped возвращает количество событий, отброшенных из-за переполнения очереди
+func (r *Recorder) Dropped() int64 {
+	return r.dropped.Load()
+}
+
+// hashIP возвращает хэш IP-адреса с солью
+func (r *Recorder) hashIP(ip string) string {
+	if ip == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(r.opts.Salt + ip))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Run читает очередь и записывает пачки при заполнении или по таймеру.
+// После отмены контекста записывает оставшиеся в очереди события и возвращает управление.
+func (r *Recorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.opts.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]models.Click, 0, r.opts.BatchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			r.drain(batch)
+			return
+		case click := <-r.events:
+			batch = append(batch, click)
+			if len(batch) >= r.opts.BatchSize {
+				r.flush(ctx, batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				r.flush(ctx, batch)
+				batch = batch[:0]
+			}
+		}
+	}
+}
+
+// drain записывает события, оставшиеся в очереди на момент остановки
+func (r *Recorder) drain(batch []models.Click) {
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+
+	for {
+		select {
+		case click := <-r.events:
+			batch = append(batch, click)
+			if len(batch) >= r.opts.BatchSize {
+				r.flush(ctx, batch)
+				batch = batch[:0]
+			}
+		default:
+			if len(batch) > 0 {
+				r.flush(ctx, batch)
+			}
+			return
+		}
+	}
+}
+
+// flush записывает пачку; ошибка записи логируется, события теряются
+func (r *Recorder) flush(ctx context.Context, batch []models.Click) {
+	if err := r.sink.SaveClicks(ctx, batch); err != nil {
+		logger.Log.Error("failed to save clicks", zap.Int("count", len(batch)), zap.Error(err))
+	}
+}
@@ -0,0 +1,73 @@
+package analytics_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/issafronov/shortener/internal/app/analytics"
+	"github.com/issafronov/shortener/internal/app/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memorySink запоминает записанные пачки
+type memorySink struct {
+	mu      sync.Mutex
+	batches [][]models.Click
+}
+
+func (s *memorySink) SaveClicks(ctx context.Context, clicks []models.Click) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, append([]models.Click(nil), clicks...))
+	return nil
+}
+
+func (s *memorySink) clicks() []models.Click {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var all []models.Click
+	for _, b := range s.batches {
+		all = append(all, b...)
+	}
+	return all
+}
+
+func TestRecorder_FlushesBatchesAndDrainsOnStop(t *testing.T) {
+	sink := &memorySink{}
+	rec := analytics.NewRecorder(sink, analytics.Options{BatchSize: 2, FlushInterval: time.Hour, Salt: "salt"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		rec.Run(ctx)
+		close(done)
+	}()
+
+	for i := 0; i < 3; i++ {
+		rec.Record(models.Click{ShortURL: "abc", ClickedAt: time.Now()}, "10.0.0.1")
+	}
+
+	// полная пачка записывается сразу, не дожидаясь таймера
+	require.Eventually(t, func() bool { return len(sink.clicks()) == 2 }, time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+
+	clicks := sink.clicks()
+	require.Len(t, clicks, 3)
+	assert.NotEmpty(t, clicks[0].IPHash)
+	assert.NotContains(t, clicks[0].IPHash, "10.0.0.1")
+	assert.Equal(t, clicks[0].IPHash, clicks[2].IPHash)
+}
+
+func TestRecorder_DropsWhenFull(t *testing.T) {
+	rec := analytics.NewRecorder(&memorySink{}, analytics.Options{BufferSize: 1})
+
+	rec.Record(models.Click{ShortURL: "abc"}, "")
+	rec.Record(models.Click{ShortURL: "abc"}, "")
+
+	assert.Equal(t, int64(1), rec.Dropped())
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"flag"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/caarlos0/env/v6"
 )
@@ -13,12 +15,91 @@ type Config struct {
 	ServerAddress     string `json:"server_address" env:"SERVER_ADDRESS" envDefault:"localhost:8080"`
 	BaseURL           string `json:"base_url" env:"BASE_URL" envDefault:"http://localhost:8080"`
 	LoggerLevel       string `json:"log_level" env:"LOG_LEVEL" envDefault:"info"`
-	FileStoragePath   string `json:"file_storage_path" env:"FILE_STORAGE_PATH" envDefault:"internal/app/storage/storage.json"`
+	FileStoragePath   string `json:"file_storage_path" env:"FILE_STORAGE_PATH"`
 	DatabaseDSN       string `json:"database_dsn" env:"DATABASE_DSN"`
 	EnableHTTPS       bool   `json:"enable_https" env:"ENABLE_HTTPS"`
 	ConfigFile        string `json:"-" env:"CONFIG"`
 	TrustedSubnet     string `json:"trusted_subnet" env:"TRUSTED_SUBNET"`
 	GRPCServerAddress string `json:"grpc_server_address" env:"GRPC_SERVER_ADDRESS" envDefault:"localhost:50051"`
+	// StorageEngine явно задаёт движок хранилища: memory, file, postgres или bolt.
+	// Если не задан, движок выбирается по наличию DatabaseDSN и FileStoragePath;
+	// по умолчанию оба пусты и ссылки хранятся в памяти.
+	StorageEngine string `json:"storage_engine" env:"STORAGE_ENGINE"`
+	// BoltPath — путь до файла встраиваемого хранилища bolt
+	BoltPath string `json:"bolt_path" env:"BOLT_PATH" envDefault:"shortener.db"`
+	// URLUniqueness — область уникальности оригинального URL: global (на всё хранилище), user (на пользователя)
+	// или none (повторные сокращения разрешены). При смене режима области существующих ссылок пересчитываются
+	// при запуске; если в новом режиме оригинальные URL повторяются, хранилища postgres и bolt не запускаются.
+	URLUniqueness string `json:"url_uniqueness" env:"URL_UNIQUENESS" envDefault:"global"`
+	// MetricsAddress — адрес отдельного сервера метрик Prometheus.
+	// Если не задан, /metrics обслуживается основным сервером для доверенной подсети.
+	MetricsAddress string `json:"metrics_address" env:"METRICS_ADDRESS"`
+	// TraceExporter — экспортёр спанов трассировки: none, stdout или file
+	TraceExporter string `json:"trace_exporter" env:"TRACE_EXPORTER" envDefault:"none"`
+	// TraceFile — файл, в который пишет спаны экспортёр file
+	TraceFile string `json:"trace_file" env:"TRACE_FILE" envDefault:"traces.json"`
+
+	// KeyGenerator — стратегия генерации коротких ключей: random, sequence, hashids или hash
+	KeyGenerator string `json:"key_generator" env:"KEY_GENERATOR" envDefault:"random"`
+	// KeyLength — длина генерируемого короткого ключа
+	KeyLength int `json:"key_length" env:"KEY_LENGTH" envDefault:"8"`
+	// KeySalt — соль генератора hashids
+	KeySalt string `json:"key_salt" env:"KEY_SALT"`
+	// PurgeInterval — период фоновой очистки ссылок с истёкшим сроком действия, 0 отключает очистку
+	PurgeInterval time.Duration `json:"purge_interval" env:"PURGE_INTERVAL" envDefault:"1m"`
+
+	// ClickBufferSize — ёмкость очереди переходов, ожидающих записи
+	ClickBufferSize int `json:"click_buffer_size" env:"CLICK_BUFFER_SIZE" envDefault:"4096"`
+	// ClickFlushInterval — максимальная задержка записи переходов
+	ClickFlushInterval time.Duration `json:"click_flush_interval" env:"CLICK_FLUSH_INTERVAL" envDefault:"1s"`
+	// ClickSalt — соль для хэширования IP-адресов посетителей
+	ClickSalt string `json:"click_salt" env:"CLICK_SALT"`
+	// ClickRetention — срок хранения переходов в хранилищах memory, file и bolt, 0 хранит их бессрочно
+	ClickRetention time.Duration `json:"click_retention" env:"CLICK_RETENTION" envDefault:"720h"`
+
+	// DeleteQueueSize — ёмкость очереди запросов на удаление ссылок
+	DeleteQueueSize int `json:"delete_queue_size" env:"DELETE_QUEUE_SIZE" envDefault:"1024"`
+	// DeleteBatchSize — число ссылок, удаляемых одной пачкой
+	DeleteBatchSize int `json:"delete_batch_size" env:"DELETE_BATCH_SIZE" envDefault:"500"`
+	// DeleteFlushInterval — максимальная задержка удаления ссылок
+	DeleteFlushInterval time.Duration `json:"delete_flush_interval" env:"DELETE_FLUSH_INTERVAL" envDefault:"1s"`
+
+	// DBMaxOpenConns — максимальное число соединений с PostgreSQL, 0 снимает ограничение
+	DBMaxOpenConns int `json:"db_max_open_conns" env:"DB_MAX_OPEN_CONNS" envDefault:"25"`
+	// DBMaxIdleConns — максимальное число простаивающих соединений с PostgreSQL
+	DBMaxIdleConns int `json:"db_max_idle_conns" env:"DB_MAX_IDLE_CONNS" envDefault:"10"`
+	// DBConnMaxLifetime — время, после которого соединение с PostgreSQL пересоздаётся
+	DBConnMaxLifetime time.Duration `json:"db_conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME" envDefault:"30m"`
+	// DBConnMaxIdleTime — время, после которого простаивающее соединение закрывается
+	DBConnMaxIdleTime time.Duration `json:"db_conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME" envDefault:"5m"`
+	// DBStatementTimeout — максимальное время выполнения одного запроса, 0 снимает ограничение
+	DBStatementTimeout time.Duration `json:"db_statement_timeout" env:"DB_STATEMENT_TIMEOUT" envDefault:"5s"`
+	// DBRetryAttempts — число повторов операции при временной ошибке PostgreSQL
+	DBRetryAttempts int `json:"db_retry_attempts" env:"DB_RETRY_ATTEMPTS" envDefault:"3"`
+	// DBRetryBackoff — задержка перед первым повтором, каждая следующая вдвое больше
+	DBRetryBackoff time.Duration `json:"db_retry_backoff" env:"DB_RETRY_BACKOFF" envDefault:"50ms"`
+	// DatabaseReplicaDSNs — DSN реплик PostgreSQL, на которые направляется чтение
+	DatabaseReplicaDSNs []string `json:"database_replica_dsns" env:"DATABASE_REPLICA_DSNS" envSeparator:","`
+	// DBReadYourWritesWindow — время, в течение которого после записи пользователь читает
+	// с основного сервера, 0 отключает закрепление
+	DBReadYourWritesWindow time.Duration `json:"db_read_your_writes_window" env:"DB_READ_YOUR_WRITES_WINDOW" envDefault:"5s"`
+	// AutoMigrate включает применение миграций PostgreSQL при запуске.
+	// Если выключено, схему обновляют явно командой shortener migrate up.
+	AutoMigrate bool `json:"auto_migrate" env:"AUTO_MIGRATE" envDefault:"true"`
+
+	// CacheSize — число ключей в кэше ссылок, 0 отключает кэш
+	CacheSize int `json:"cache_size" env:"CACHE_SIZE" envDefault:"10000"`
+	// CacheTTL — время хранения найденной ссылки в кэше
+	CacheTTL time.Duration `json:"cache_ttl" env:"CACHE_TTL" envDefault:"1m"`
+	// CacheNegativeTTL — время хранения в кэше ключа, которого нет в хранилище
+	CacheNegativeTTL time.Duration `json:"cache_negative_ttl" env:"CACHE_NEGATIVE_TTL" envDefault:"5s"`
+
+	// FileSyncPolicy определяет, когда журнал файлового хранилища сбрасывается на диск: always, interval или never
+	FileSyncPolicy string `json:"file_sync_policy" env:"FILE_SYNC_POLICY" envDefault:"always"`
+	// FileSyncInterval — период fsync журнала при политике interval
+	FileSyncInterval time.Duration `json:"file_sync_interval" env:"FILE_SYNC_INTERVAL" envDefault:"1s"`
+	// FileCompactInterval — период фоновой компактификации журнала, 0 отключает компактификацию
+	FileCompactInterval time.Duration `json:"file_compact_interval" env:"FILE_COMPACT_INTERVAL" envDefault:"10m"`
 }
 
 // LoadConfig загружает конфигурацию из переменных окружения и флагов командной строки или JSON конфиг файла
@@ -49,11 +130,47 @@ func ParseFlags(config *Config) {
 	flag.StringVar(&config.ServerAddress, "a", config.ServerAddress, "address and port to run server")
 	flag.StringVar(&config.BaseURL, "b", config.BaseURL, "address and port to link")
 	flag.StringVar(&config.LoggerLevel, "l", config.LoggerLevel, "log level")
-	flag.StringVar(&config.FileStoragePath, "f", config.FileStoragePath, "file storage path")
+	flag.StringVar(&config.FileStoragePath, "f", config.FileStoragePath, "file storage path; if empty and no database DSN is set, links are kept in memory")
 	flag.StringVar(&config.DatabaseDSN, "d", config.DatabaseDSN, "database DSN")
+	flag.IntVar(&config.DBMaxOpenConns, "db-max-open-conns", config.DBMaxOpenConns, "maximum number of open database connections, 0 is unlimited")
+	flag.IntVar(&config.DBMaxIdleConns, "db-max-idle-conns", config.DBMaxIdleConns, "maximum number of idle database connections")
+	flag.DurationVar(&config.DBConnMaxLifetime, "db-conn-max-lifetime", config.DBConnMaxLifetime, "maximum lifetime of a database connection")
+	flag.DurationVar(&config.DBConnMaxIdleTime, "db-conn-max-idle-time", config.DBConnMaxIdleTime, "maximum idle time of a database connection")
+	flag.DurationVar(&config.DBStatementTimeout, "db-statement-timeout", config.DBStatementTimeout, "maximum duration of a single database statement")
+	flag.IntVar(&config.DBRetryAttempts, "db-retry-attempts", config.DBRetryAttempts, "retries of a database operation after a transient error")
+	flag.DurationVar(&config.DBRetryBackoff, "db-retry-backoff", config.DBRetryBackoff, "delay before the first database retry")
+	flag.Func("database-replicas", "comma-separated DSNs of read replicas", func(value string) error {
+		config.DatabaseReplicaDSNs = strings.Split(value, ",")
+		return nil
+	})
+	flag.DurationVar(&config.DBReadYourWritesWindow, "db-read-your-writes", config.DBReadYourWritesWindow, "duration of reading from the primary after a write")
+	flag.BoolVar(&config.AutoMigrate, "auto-migrate", config.AutoMigrate, "apply database migrations at startup")
 	flag.BoolVar(&config.EnableHTTPS, "s", config.EnableHTTPS, "enable HTTPS")
 	flag.StringVar(&config.TrustedSubnet, "t", config.TrustedSubnet, "trusted subnet in CIDR format")
 	flag.StringVar(&config.GRPCServerAddress, "g", config.GRPCServerAddress, "grpc address and port to run server")
+	flag.StringVar(&config.StorageEngine, "e", config.StorageEngine, "storage engine: memory, file, postgres or bolt")
+	flag.StringVar(&config.BoltPath, "bolt-path", config.BoltPath, "bolt storage file path")
+	flag.StringVar(&config.URLUniqueness, "url-uniqueness", config.URLUniqueness, "original URL uniqueness scope: global, user or none")
+	flag.StringVar(&config.MetricsAddress, "metrics-address", config.MetricsAddress, "separate address for the Prometheus metrics server")
+	flag.StringVar(&config.TraceExporter, "trace-exporter", config.TraceExporter, "trace exporter: none, stdout or file")
+	flag.StringVar(&config.TraceFile, "trace-file", config.TraceFile, "file written by the file trace exporter")
+	flag.StringVar(&config.KeyGenerator, "key-generator", config.KeyGenerator, "short key generator: random, sequence, hashids or hash")
+	flag.IntVar(&config.KeyLength, "key-length", config.KeyLength, "short key length")
+	flag.StringVar(&config.KeySalt, "key-salt", config.KeySalt, "salt of the hashids key generator")
+	flag.DurationVar(&config.PurgeInterval, "purge-interval", config.PurgeInterval, "interval of purging expired links, 0 disables purging")
+	flag.IntVar(&config.ClickBufferSize, "click-buffer-size", config.ClickBufferSize, "capacity of the queue of clicks waiting to be saved")
+	flag.DurationVar(&config.ClickFlushInterval, "click-flush-interval", config.ClickFlushInterval, "maximum delay of saving clicks")
+	flag.StringVar(&config.ClickSalt, "click-salt", config.ClickSalt, "salt for hashing visitor IP addresses")
+	flag.DurationVar(&config.ClickRetention, "click-retention", config.ClickRetention, "retention of link clicks in memory, file and bolt storages, 0 keeps them forever")
+	flag.IntVar(&config.DeleteQueueSize, "delete-queue-size", config.DeleteQueueSize, "capacity of the queue of link deletion requests")
+	flag.IntVar(&config.DeleteBatchSize, "delete-batch-size", config.DeleteBatchSize, "number of links deleted in one batch")
+	flag.DurationVar(&config.DeleteFlushInterval, "delete-flush-interval", config.DeleteFlushInterval, "maximum delay of deleting links")
+	flag.IntVar(&config.CacheSize, "cache-size", config.CacheSize, "number of links kept in the lookup cache, 0 disables the cache")
+	flag.DurationVar(&config.CacheTTL, "cache-ttl", config.CacheTTL, "time a found link is kept in the lookup cache")
+	flag.DurationVar(&config.CacheNegativeTTL, "cache-negative-ttl", config.CacheNegativeTTL, "time a missing key is kept in the lookup cache")
+	flag.StringVar(&config.FileSyncPolicy, "file-sync-policy", config.FileSyncPolicy, "file storage journal sync policy: always, interval or never")
+	flag.DurationVar(&config.FileSyncInterval, "file-sync-interval", config.FileSyncInterval, "file storage journal sync interval for the interval policy")
+	flag.DurationVar(&config.FileCompactInterval, "file-compact-interval", config.FileCompactInterval, "file storage journal compaction interval, 0 disables compaction")
 
 	flag.Parse()
 }
@@ -65,7 +182,8 @@ func loadConfigFromFile(path string) (*Config, error) {
 	}
 	defer file.Close()
 
-	var cfg Config
+	// значения, по умолчанию включённые, остаются включёнными, если в файле их нет
+	cfg := Config{AutoMigrate: true}
 	if err := json.NewDecoder(file).Decode(&cfg); err != nil {
 		return nil, err
 	}
@@ -82,13 +200,79 @@ func (c *Config) isDefault(field string) bool {
 	case "LoggerLevel":
 		return c.LoggerLevel == "info"
 	case "FileStoragePath":
-		return c.FileStoragePath == "internal/app/storage/storage.json"
+		return c.FileStoragePath == ""
 	case "DatabaseDSN":
 		return c.DatabaseDSN == ""
+	case "DBMaxOpenConns":
+		return c.DBMaxOpenConns == 25
+	case "DBMaxIdleConns":
+		return c.DBMaxIdleConns == 10
+	case "DBConnMaxLifetime":
+		return c.DBConnMaxLifetime == 30*time.Minute
+	case "DBConnMaxIdleTime":
+		return c.DBConnMaxIdleTime == 5*time.Minute
+	case "DBStatementTimeout":
+		return c.DBStatementTimeout == 5*time.Second
+	case "DBRetryAttempts":
+		return c.DBRetryAttempts == 3
+	case "DBRetryBackoff":
+		return c.DBRetryBackoff == 50*time.Millisecond
+	case "DatabaseReplicaDSNs":
+		return len(c.DatabaseReplicaDSNs) == 0
+	case "DBReadYourWritesWindow":
+		return c.DBReadYourWritesWindow == 5*time.Second
+	case "AutoMigrate":
+		return c.AutoMigrate
 	case "EnableHTTPS":
 		return !c.EnableHTTPS
 	case "GRPCServerAddress":
 		return c.GRPCServerAddress == "localhost:50051"
+	case "StorageEngine":
+		return c.StorageEngine == ""
+	case "BoltPath":
+		return c.BoltPath == "shortener.db"
+	case "URLUniqueness":
+		return c.URLUniqueness == "global"
+	case "MetricsAddress":
+		return c.MetricsAddress == ""
+	case "TraceExporter":
+		return c.TraceExporter == "none"
+	case "TraceFile":
+		return c.TraceFile == "traces.json"
+	case "KeyGenerator":
+		return c.KeyGenerator == "random"
+	case "KeyLength":
+		return c.KeyLength == 8
+	case "KeySalt":
+		return c.KeySalt == ""
+	case "PurgeInterval":
+		return c.PurgeInterval == time.Minute
+	case "ClickBufferSize":
+		return c.ClickBufferSize == 4096
+	case "ClickFlushInterval":
+		return c.ClickFlushInterval == time.Second
+	case "ClickSalt":
+		return c.ClickSalt == ""
+	case "ClickRetention":
+		return c.ClickRetention == 720*time.Hour
+	case "DeleteQueueSize":
+		return c.DeleteQueueSize == 1024
+	case "DeleteBatchSize":
+		return c.DeleteBatchSize == 500
+	case "DeleteFlushInterval":
+		return c.DeleteFlushInterval == time.Second
+	case "CacheSize":
+		return c.CacheSize == 10000
+	case "CacheTTL":
+		return c.CacheTTL == time.Minute
+	case "CacheNegativeTTL":
+		return c.CacheNegativeTTL == 5*time.Second
+	case "FileSyncPolicy":
+		return c.FileSyncPolicy == "always"
+	case "FileSyncInterval":
+		return c.FileSyncInterval == time.Second
+	case "FileCompactInterval":
+		return c.FileCompactInterval == 10*time.Minute
 	default:
 		return false
 	}
@@ -110,6 +294,36 @@ func mergeConfigs(dst, src *Config) {
 	if src.DatabaseDSN != "" && dst.isDefault("DatabaseDSN") {
 		dst.DatabaseDSN = src.DatabaseDSN
 	}
+	if src.DBMaxOpenConns != 0 && dst.isDefault("DBMaxOpenConns") {
+		dst.DBMaxOpenConns = src.DBMaxOpenConns
+	}
+	if src.DBMaxIdleConns != 0 && dst.isDefault("DBMaxIdleConns") {
+		dst.DBMaxIdleConns = src.DBMaxIdleConns
+	}
+	if src.DBConnMaxLifetime != 0 && dst.isDefault("DBConnMaxLifetime") {
+		dst.DBConnMaxLifetime = src.DBConnMaxLifetime
+	}
+	if src.DBConnMaxIdleTime != 0 && dst.isDefault("DBConnMaxIdleTime") {
+		dst.DBConnMaxIdleTime = src.DBConnMaxIdleTime
+	}
+	if src.DBStatementTimeout != 0 && dst.isDefault("DBStatementTimeout") {
+		dst.DBStatementTimeout = src.DBStatementTimeout
+	}
+	if src.DBRetryAttempts != 0 && dst.isDefault("DBRetryAttempts") {
+		dst.DBRetryAttempts = src.DBRetryAttempts
+	}
+	if src.DBRetryBackoff != 0 && dst.isDefault("DBRetryBackoff") {
+		dst.DBRetryBackoff = src.DBRetryBackoff
+	}
+	if len(src.DatabaseReplicaDSNs) > 0 && dst.isDefault("DatabaseReplicaDSNs") {
+		dst.DatabaseReplicaDSNs = src.DatabaseReplicaDSNs
+	}
+	if src.DBReadYourWritesWindow != 0 && dst.isDefault("DBReadYourWritesWindow") {
+		dst.DBReadYourWritesWindow = src.DBReadYourWritesWindow
+	}
+	if !src.AutoMigrate && dst.isDefault("AutoMigrate") {
+		dst.AutoMigrate = false
+	}
 	if src.EnableHTTPS && dst.isDefault("EnableHTTPS") {
 		dst.EnableHTTPS = src.EnableHTTPS
 	}
@@ -119,4 +333,73 @@ func mergeConfigs(dst, src *Config) {
 	if src.GRPCServerAddress != "" && dst.isDefault("GRPCServerAddress") {
 		dst.GRPCServerAddress = src.GRPCServerAddress
 	}
+	if src.StorageEngine != "" && dst.isDefault("StorageEngine") {
+		dst.StorageEngine = src.StorageEngine
+	}
+	if src.BoltPath != "" && dst.isDefault("BoltPath") {
+		dst.BoltPath = src.BoltPath
+	}
+	if src.URLUniqueness != "" && dst.isDefault("URLUniqueness") {
+		dst.URLUniqueness = src.URLUniqueness
+	}
+	if src.MetricsAddress != "" && dst.isDefault("MetricsAddress") {
+		dst.MetricsAddress = src.MetricsAddress
+	}
+	if src.TraceExporter != "" && dst.isDefault("TraceExporter") {
+		dst.TraceExporter = src.TraceExporter
+	}
+	if src.TraceFile != "" && dst.isDefault("TraceFile") {
+		dst.TraceFile = src.TraceFile
+	}
+	if src.KeyGenerator != "" && dst.isDefault("KeyGenerator") {
+		dst.KeyGenerator = src.KeyGenerator
+	}
+	if src.KeyLength != 0 && dst.isDefault("KeyLength") {
+		dst.KeyLength = src.KeyLength
+	}
+	if src.KeySalt != "" && dst.isDefault("KeySalt") {
+		dst.KeySalt = src.KeySalt
+	}
+	if src.PurgeInterval != 0 && dst.isDefault("PurgeInterval") {
+		dst.PurgeInterval = src.PurgeInterval
+	}
+	if src.ClickBufferSize != 0 && dst.isDefault("ClickBufferSize") {
+		dst.ClickBufferSize = src.ClickBufferSize
+	}
+	if src.ClickFlushInterval != 0 && dst.isDefault("ClickFlushInterval") {
+		dst.ClickFlushInterval = src.ClickFlushInterval
+	}
+	if src.ClickSalt != "" && dst.isDefault("ClickSalt") {
+		dst.ClickSalt = src.ClickSalt
+	}
+	if src.ClickRetention != 0 && dst.isDefault("ClickRetention") {
+		dst.ClickRetention = src.ClickRetention
+	}
+	if src.DeleteQueueSize != 0 && dst.isDefault("DeleteQueueSize") {
+		dst.DeleteQueueSize = src.DeleteQueueSize
+	}
+	if src.DeleteBatchSize != 0 && dst.isDefault("DeleteBatchSize") {
+		dst.DeleteBatchSize = src.DeleteBatchSize
+	}
+	if src.DeleteFlushInterval != 0 && dst.isDefault("DeleteFlushInterval") {
+		dst.DeleteFlushInterval = src.DeleteFlushInterval
+	}
+	if src.CacheSize != 0 && dst.isDefault("CacheSize") {
+		dst.CacheSize = src.CacheSize
+	}
+	if src.CacheTTL != 0 && dst.isDefault("CacheTTL") {
+		dst.CacheTTL = src.CacheTTL
+	}
+	if src.CacheNegativeTTL != 0 && dst.isDefault("CacheNegativeTTL") {
+		dst.CacheNegativeTTL = src.CacheNegativeTTL
+	}
+	if src.FileSyncPolicy != "" && dst.isDefault("FileSyncPolicy") {
+		dst.FileSyncPolicy = src.FileSyncPolicy
+	}
+	if src.FileSyncInterval != 0 && dst.isDefault("FileSyncInterval") {
+		dst.FileSyncInterval = src.FileSyncInterval
+	}
+	if src.FileCompactInterval != 0 && dst.isDefault("FileCompactInterval") {
+		dst.FileCompactInterval = src.FileCompactInterval
+	}
 }
@@ -0,0 +1,427 @@
+// Package deleter содержит фоновый обработчик удаления ссылок пользователей.
+// Запросы из разных обработчиков попадают в ограниченную очередь и записываются в хранилище пачками.
+// Каждый запрос получает идентификатор задачи, по которому можно узнать результат удаления.
+package deleter
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/issafronov/shortener/internal/app/models"
+	"github.com/issafronov/shortener/internal/middleware/logger"
+	"go.uber.org/zap"
+)
+
+// Значения по умолчанию для Deleter
+const (
+	DefaultQueueSize     = 1024
+	DefaultBatchSize     = 500
+	DefaultFlushInterval = time.Second
+	DefaultMaxRetries    = 3
+	DefaultRetryDelay    = 100 * time.Millisecond
+	DefaultJobRetention  = time.Hour
+)
+
+// drainTimeout ограничивает запись оставшихся запросов при остановке
+const drainTimeout = 10 * time.Second
+
+// ErrQueueFull возвращается, если очередь удаления заполнена
+var ErrQueueFull = errors.New("delete queue is full")
+
+// ErrStopped возвращается, если обработчик удаления уже остановлен
+var ErrStopped = errors.New("deleter is stopped")
+
+// Store помечает ссылки удалёнными
+type Store interface {
+	DeleteURLsBatch(ctx context.Context, reqs []models.DeleteRequest) ([]models.DeleteOutcomes, error)
+}
+
+// Options настраивает Deleter
+type Options struct {
+	// QueueSize — ёмкость очереди запросов на удаление
+	QueueSize int
+	// BatchSize — число коротких ключей, при котором пачка записывается, не дожидаясь таймера
+	BatchSize int
+	// FlushInterval — максимальное время ожидания перед записью неполной пачки
+	FlushInterval time.Duration
+	// MaxRetries — число повторных попыток записи пачки при ошибке хранилища
+	MaxRetries int
+	// RetryDelay — задержка перед первой повторной попыткой, далее удваивается
+	RetryDelay time.Duration
+	// JobRetention — время хранения завершённых задач
+	JobRetention time.Duration
+}
+
+// Stats — счётчики работы обработчика удаления
+type Stats struct {
+	// Queued — число запросов, ожидающих записи в очереди
+	Queued int
+	// Accepted — число принятых запросов
+	Accepted int64
+	// Rejected — число запросов, отклонённых из-за переполнения очереди или остановки
+	Rejected int64
+	// Deleted — число коротких ключей, которые хранилище пометило удалёнными
+	Deleted int64
+	// Failed — число коротких ключей в пачках, которые не удалось записать
+	Failed int64
+	// Batches — число успешно записанных пачек
+	Batches int64
+	// Retries — число повторных попыток записи
+	Retries int64
+}
+
+// Deleter принимает запросы на удаление без блокировки вызывающего
+// и объединяет запросы разных пользователей в пачки
+type Deleter struct {
+	store Store
+	queue chan task
+	opts  Options
+
+	mu      sync.RWMutex
+	stopped bool
+
+	jobsMu sync.RWMutex
+	jobs   map[string]*models.DeleteJob
+
+	accepted atomic.Int64
+	rejected atomic.Int64
+	deleted  atomic.Int64
+	failed   atomic.Int64
+	batches  atomic.Int64
+	retries  atomic.Int64
+}
+
+// New создаёт Deleter. Для обработки очереди нужно запустить Run.
+func New(store Store, opts Options) *Deleter {
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultQueueSize
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = DefaultFlushInterval
+	}
+	if opts.MaxRetries <= 0 {
+		opts.MaxRetries = DefaultMaxRetries
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = DefaultRetryDelay
+	}
+	if opts.JobRetention <= 0 {
+		opts.JobRetention = DefaultJobRetention
+	}
+	return &Deleter{
+		store: store,
+		queue: make(chan task, opts.QueueSize),
+		opts:  opts,
+		jobs:  make(map[string]*models.DeleteJob),
+	}
+}
+
+// task — запрос на удаление вместе с идентификатором задачи
+type task struct {
+	jobID string
+	req   models.DeleteRequest
+}
+
+// Enqueue ставит запрос на удаление в очередь и возвращает идентификатор задачи.
+// Возвращает ErrQueueFull, если очередь заполнена, и ErrStopped после остановки обработчика.
+func (d *Deleter) Enqueue(userID string, ids []string) (string, error) {
+	jobID, err := newJobID()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	job := &models.DeleteJob{ID: jobID, UserID: userID, Status: models.JobPending, CreatedAt: now, UpdatedAt: now}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.stopped {
+		d.rejected.Add(1)
+		return "", ErrStopped
+	}
+
+	// задача регистрируется до постановки в очередь, чтобы обработчик мог обновить её состояние
+	d.jobsMu.Lock()
+	d.jobs[jobID] = job
+	d.jobsMu.Unlock()
+
+	select {
+	case d.queue <- task{jobID: jobID, req: models.DeleteRequest{UserID: userID, ShortURLs: ids}}:
+		d.accepted.Add(1)
+		return jobID, nil
+	default:
+		d.jobsMu.Lock()
+		delete(d.jobs, jobID)
+		d.jobsMu.Unlock()
+		d.rejected.Add(1)
+		return "", ErrQueueFull
+	}
+}
+
+// Job возвращает копию задачи по идентификатору
+func (d *Deleter) Job(id string) (models.DeleteJob, bool) {
+	d.jobsMu.RLock()
+	defer d.jobsMu.RUnlock()
+
+	job, ok := d.jobs[id]
+	if !ok {
+		return models.DeleteJob{}, false
+	}
+	result := *job
+	if job.Results != nil {
+		result.Results = make(models.DeleteOutcomes, len(job.Results))
+		for key, outcome := range job.Results {
+			result.Results[key] = outcome
+		}
+	}
+	return result, true
+}
+
+// newJobID генерирует случайный идентификатор задачи
+func newJobID() (string, error) {
+	var buf [16]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf[:]), nil
+}
+
+// Stats возвращает текущие значения счётчиков
+func (d *Deleter) Stats() Stats {
+	return Stats{
+		Queued:   len(d.queue),
+		Accepted: d.accepted.Load(),
+		Rejected: d.rejected.Load(),
+		Deleted:  d.deleted.Load(),
+		Failed:   d.failed.Load(),
+		Batches:  d.batches.Load(),
+		Retries:  d.retries.Load(),
+	}
+}
+
+// Run читает очередь и записывает пачки при заполнении или по таймеру.
+// После отмены контекста перестаёт принимать запросы, записывает оставшиеся в очереди и возвращает управление.
+func (d *Deleter) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.opts.FlushInterval)
+	defer ticker.Stop()
+
+	// пачка, запись которой прервана остановкой, остаётся в b и дописывается в drain
+	var b batch
+	for {
+		select {
+		case <-ctx.Done():
+			d.drain(b)
+			return
+		case t := <-d.queue:
+			b.add(t)
+			if b.size >= d.opts.BatchSize && d.flush(ctx, &b) {
+				b = batch{}
+			}
+		case now := <-ticker.C:
+			if len(b.tasks) > 0 && d.flush(ctx, &b) {
+				b = batch{}
+			}
+			d.pruneJobs(now)
+		}
+	}
+}
+
+// drain закрывает приём запросов и записывает всё, что осталось в очереди
+func (d *Deleter) drain(b batch) {
+	d.mu.Lock()
+	d.stopped = true
+	d.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	for {
+		select {
+		case t := <-d.queue:
+			b.add(t)
+			if b.size >= d.opts.BatchSize {
+				d.flushOrFail(ctx, &b)
+				b = batch{}
+			}
+		default:
+			if len(b.tasks) > 0 {
+				d.flushOrFail(ctx, &b)
+			}
+			return
+		}
+	}
+}
+
+// flushOrFail записывает пачку и помечает её задачи как неуспешные, если запись не успела завершиться
+func (d *Deleter) flushOrFail(ctx context.Context, b *batch) {
+	if !d.flush(ctx, b) {
+		d.fail(b, ctx.Err())
+	}
+}
+
+// flush записывает пачку, повторяя попытки с экспоненциальной задержкой, и обновляет задачи пачки.
+// Если все попытки исчерпаны, ошибка логируется, а задачи пачки помечаются как неуспешные.
+// Возвращает false, если запись прервана отменой контекста: задачи пачки остаются в работе,
+// и её можно записать повторно, так как удаление идемпотентно.
+func (d *Deleter) flush(ctx context.Context, b *batch) bool {
+	d.updateJobs(b.tasks, func(job *models.DeleteJob) {
+		job.Status = models.JobRunning
+	})
+
+	reqs := b.requests()
+	delay := d.opts.RetryDelay
+	for attempt := 0; ; attempt++ {
+		results, err := d.store.DeleteURLsBatch(ctx, reqs)
+		if err == nil {
+			d.deleted.Add(countDeleted(results))
+			d.batches.Add(1)
+			d.complete(b.tasks, reqs, results)
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+		if attempt >= d.opts.MaxRetries {
+			d.fail(b, err)
+			return true
+		}
+
+		d.retries.Add(1)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+// fail логирует ошибку записи пачки и помечает её задачи как неуспешные
+func (d *Deleter) fail(b *batch, err error) {
+	d.failed.Add(int64(b.size))
+	logger.Log.Error("failed to delete urls", zap.Int("count", b.size), zap.Error(err))
+	d.updateJobs(b.tasks, func(job *models.DeleteJob) {
+		job.Status = models.JobFailed
+		job.Error = err.Error()
+	})
+}
+
+// countDeleted возвращает число ключей, помеченных хранилищем удалёнными
+func countDeleted(results []models.DeleteOutcomes) int64 {
+	var deleted int64
+	for _, outcomes := range results {
+		for _, outcome := range outcomes {
+			if outcome == models.DeleteOutcomeDeleted {
+				deleted++
+			}
+		}
+	}
+	return deleted
+}
+
+// complete раскладывает результаты пачки по задачам, из которых она была собрана
+func (d *Deleter) complete(tasks []task, reqs []models.DeleteRequest, results []models.DeleteOutcomes) {
+	byUser := make(map[string]models.DeleteOutcomes, len(reqs))
+	for i, req := range reqs {
+		if i < len(results) {
+			byUser[req.UserID] = results[i]
+		}
+	}
+
+	d.jobsMu.Lock()
+	defer d.jobsMu.Unlock()
+
+	now := time.Now()
+	for _, t := range tasks {
+		job, ok := d.jobs[t.jobID]
+		if !ok {
+			continue
+		}
+		job.Results = make(models.DeleteOutcomes, len(t.req.ShortURLs))
+		for _, key := range t.req.ShortURLs {
+			job.Results[key] = byUser[t.req.UserID][key]
+		}
+		job.Status = models.JobDone
+		job.UpdatedAt = now
+	}
+}
+
+// updateJobs применяет изменение ко всем задачам пачки
+func (d *Deleter) updateJobs(tasks []task, update func(job *models.DeleteJob)) {
+	d.jobsMu.Lock()
+	defer d.jobsMu.Unlock()
+
+	now := time.Now()
+	for _, t := range tasks {
+		if job, ok := d.jobs[t.jobID]; ok {
+			update(job)
+			job.UpdatedAt = now
+		}
+	}
+}
+
+// pruneJobs удаляет завершённые задачи, хранящиеся дольше JobRetention
+func (d *Deleter) pruneJobs(now time.Time) {
+	d.jobsMu.Lock()
+	defer d.jobsMu.Unlock()
+
+	for id, job := range d.jobs {
+		finished := job.Status == models.JobDone || job.Status == models.JobFailed
+		if finished && now.Sub(job.UpdatedAt) > d.opts.JobRetention {
+			delete(d.jobs, id)
+		}
+	}
+}
+
+// batch объединяет запросы по пользователям, отбрасывая повторяющиеся ключи
+type batch struct {
+	tasks []task
+	users map[string]map[string]struct{}
+	order []string
+	size  int
+}
+
+// add добавляет запрос в пачку
+func (b *batch) add(t task) {
+	b.tasks = append(b.tasks, t)
+	req := t.req
+	if b.users == nil {
+		b.users = make(map[string]map[string]struct{})
+	}
+	keys, ok := b.users[req.UserID]
+	if !ok {
+		keys = make(map[string]struct{}, len(req.ShortURLs))
+		b.users[req.UserID] = keys
+		b.order = append(b.order, req.UserID)
+	}
+	for _, key := range req.ShortURLs {
+		if _, seen := keys[key]; seen {
+			continue
+		}
+		keys[key] = struct{}{}
+		b.size++
+	}
+}
+
+// requests возвращает по одному запросу на каждого пользователя пачки
+func (b *batch) requests() []models.DeleteRequest {
+	reqs := make([]models.DeleteRequest, 0, len(b.order))
+	for _, userID := range b.order {
+		keys := make([]string, 0, len(b.users[userID]))
+		for key := range b.users[userID] {
+			keys = append(keys, key)
+		}
+		reqs = append(reqs, models.DeleteRequest{UserID: userID, ShortURLs: keys})
+	}
+	return reqs
+}
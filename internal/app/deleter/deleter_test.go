@@ -0,0 +1,207 @@
+package deleter_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/issafronov/shortener/internal/app/deleter"
+	"github.com/issafronov/shortener/internal/app/models"
+	"github.com/issafronov/shortener/internal/app/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyStore отклоняет первые failures пачек и запоминает записанные
+type flakyStore struct {
+	mu       sync.Mutex
+	failures int
+	batches  [][]models.DeleteRequest
+}
+
+func (s *flakyStore) DeleteURLsBatch(ctx context.Context, reqs []models.DeleteRequest) ([]models.DeleteOutcomes, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return nil, errors.New("database unavailable")
+	}
+	s.batches = append(s.batches, reqs)
+
+	results := make([]models.DeleteOutcomes, 0, len(reqs))
+	for _, req := range reqs {
+		outcomes := make(models.DeleteOutcomes, len(req.ShortURLs))
+		for _, key := range req.ShortURLs {
+			outcomes[key] = models.DeleteOutcomeDeleted
+		}
+		results = append(results, outcomes)
+	}
+	return results, nil
+}
+
+func (s *flakyStore) written() [][]models.DeleteRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]models.DeleteRequest(nil), s.batches...)
+}
+
+func run(d *deleter.Deleter) (context.CancelFunc, <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+	return cancel, done
+}
+
+func TestDeleter_MergesRequestsAndDrainsOnStop(t *testing.T) {
+	ctx := context.Background()
+	st := storage.NewMemoryStorage()
+	for _, key := range []string{"a", "b", "c"} {
+		_, err := st.Create(ctx, storage.ShortenerURL{ShortURL: key, OriginalURL: "https://" + key + ".com", UserID: "user1"})
+		require.NoError(t, err)
+	}
+	_, err := st.Create(ctx, storage.ShortenerURL{ShortURL: "d", OriginalURL: "https://d.com", UserID: "user2"})
+	require.NoError(t, err)
+
+	d := deleter.New(st, deleter.Options{FlushInterval: time.Hour})
+	cancel, done := run(d)
+
+	first, err := d.Enqueue("user1", []string{"a", "b"})
+	require.NoError(t, err)
+	second, err := d.Enqueue("user1", []string{"b", "c", "d", "missing"})
+	require.NoError(t, err)
+	_, err = d.Enqueue("user2", []string{"d"})
+	require.NoError(t, err)
+
+	job, ok := d.Job(first)
+	require.True(t, ok)
+	assert.Equal(t, models.JobPending, job.Status)
+	assert.Equal(t, "user1", job.UserID)
+
+	cancel()
+	<-done
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		_, err := st.Get(ctx, key)
+		assert.ErrorIs(t, err, storage.ErrDeleted, key)
+	}
+
+	job, ok = d.Job(first)
+	require.True(t, ok)
+	assert.Equal(t, models.JobDone, job.Status)
+	assert.Equal(t, models.DeleteOutcomes{"a": models.DeleteOutcomeDeleted, "b": models.DeleteOutcomeDeleted}, job.Results)
+
+	job, ok = d.Job(second)
+	require.True(t, ok)
+	assert.Equal(t, models.DeleteOutcomes{
+		"b":       models.DeleteOutcomeDeleted,
+		"c":       models.DeleteOutcomeDeleted,
+		"d":       models.DeleteOutcomeNotOwned,
+		"missing": models.DeleteOutcomeNotFound,
+	}, job.Results)
+
+	stats := d.Stats()
+	assert.Equal(t, int64(3), stats.Accepted)
+	assert.Equal(t, int64(1), stats.Batches)
+	assert.Equal(t, int64(4), stats.Deleted, "only keys marked by the storage are counted")
+
+	// после остановки запросы отклоняются
+	_, err = d.Enqueue("user1", []string{"a"})
+	assert.ErrorIs(t, err, deleter.ErrStopped)
+}
+
+func TestDeleter_FlushesFullBatch(t *testing.T) {
+	store := &flakyStore{}
+	d := deleter.New(store, deleter.Options{BatchSize: 2, FlushInterval: time.Hour})
+	cancel, done := run(d)
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	_, err := d.Enqueue("user1", []string{"a"})
+	require.NoError(t, err)
+	_, err = d.Enqueue("user2", []string{"b"})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool { return len(store.written()) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Len(t, store.written()[0], 2)
+}
+
+func TestDeleter_RetriesFailedBatch(t *testing.T) {
+	store := &flakyStore{failures: 2}
+	d := deleter.New(store, deleter.Options{FlushInterval: 10 * time.Millisecond, RetryDelay: time.Millisecond})
+	cancel, done := run(d)
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	jobID, err := d.Enqueue("user1", []string{"a"})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		job, _ := d.Job(jobID)
+		return job.Status == models.JobDone
+	}, time.Second, 10*time.Millisecond)
+	stats := d.Stats()
+	assert.Equal(t, int64(2), stats.Retries)
+	assert.Equal(t, int64(0), stats.Failed)
+}
+
+func TestDeleter_StopInterruptsRetryDelay(t *testing.T) {
+	store := &flakyStore{failures: 1}
+	d := deleter.New(store, deleter.Options{FlushInterval: 10 * time.Millisecond, RetryDelay: time.Hour})
+	cancel, done := run(d)
+
+	jobID, err := d.Enqueue("user1", []string{"a"})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return d.Stats().Retries == 1 }, time.Second, 10*time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("deleter must not wait out the retry delay after stop")
+	}
+
+	// прерванная пачка дописывается при остановке
+	job, _ := d.Job(jobID)
+	assert.Equal(t, models.JobDone, job.Status)
+	assert.Len(t, store.written(), 1)
+}
+
+func TestDeleter_FailedJob(t *testing.T) {
+	store := &flakyStore{failures: 10}
+	d := deleter.New(store, deleter.Options{FlushInterval: 10 * time.Millisecond, MaxRetries: 1, RetryDelay: time.Millisecond})
+	cancel, done := run(d)
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	jobID, err := d.Enqueue("user1", []string{"a"})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		job, _ := d.Job(jobID)
+		return job.Status == models.JobFailed
+	}, time.Second, 10*time.Millisecond)
+	job, _ := d.Job(jobID)
+	assert.Equal(t, "database unavailable", job.Error)
+	assert.Equal(t, int64(1), d.Stats().Failed)
+}
+
+func TestDeleter_QueueFull(t *testing.T) {
+	d := deleter.New(&flakyStore{}, deleter.Options{QueueSize: 1})
+
+	_, err := d.Enqueue("user1", []string{"a"})
+	require.NoError(t, err)
+	_, err = d.Enqueue("user1", []string{"b"})
+	assert.ErrorIs(t, err, deleter.ErrQueueFull)
+	assert.Equal(t, int64(1), d.Stats().Rejected)
+}
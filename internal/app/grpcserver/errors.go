@@ -0,0 +1,76 @@
+package grpcserver
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/issafronov/shortener/internal/app/service"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// errorDomain — домен ошибок в подробностях ErrorInfo
+const errorDomain = "shortener"
+
+// retryDelay — рекомендуемая задержка повтора для временно недоступных операций
+const retryDelay = time.Second
+
+// kindCode сопоставляет категории ошибок сервиса кодам gRPC
+var kindCode = map[service.Kind]codes.Code{
+	service.KindNotFound:     codes.NotFound,
+	service.KindGone:         codes.FailedPrecondition,
+	service.KindConflict:     codes.AlreadyExists,
+	service.KindInvalid:      codes.InvalidArgument,
+	service.KindUnauthorized: codes.Unauthenticated,
+	service.KindUnavailable:  codes.Unavailable,
+}
+
+// invalidArgument возвращает ошибку некорректного запроса с пояснением
+func invalidArgument(detail string) error {
+	return fmt.Errorf("%w: %s", service.ErrInvalidRequest, detail)
+}
+
+// statusError переводит ошибку сервиса в статус gRPC.
+// В подробности статуса добавляется ErrorInfo с кодом ошибки, для конфликта оригинального URL —
+// существующая ссылка, для временно недоступных операций — RetryInfo.
+// Текст непредвиденных ошибок клиенту не раскрывается.
+func (h *GRPCHandler) statusError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var svcErr *service.Error
+	if !errors.As(err, &svcErr) || svcErr.Kind == service.KindInternal {
+		return status.Error(codes.Internal, "internal error")
+	}
+
+	info := &errdetails.ErrorInfo{
+		Reason: strings.ToUpper(svcErr.Code),
+		Domain: errorDomain,
+	}
+	if key, ok := service.ConflictKey(err); ok && key != "" {
+		info.Metadata = map[string]string{
+			"key":       key,
+			"short_url": fmt.Sprintf("%s/%s", h.config.BaseURL, key),
+		}
+	}
+
+	details := []protoadapt.MessageV1{info}
+	if svcErr.Kind == service.KindUnavailable {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(retryDelay)})
+	}
+
+	st := status.New(kindCode[svcErr.Kind], err.Error())
+	if detailed, derr := st.WithDetails(details...); derr == nil {
+		st = detailed
+	}
+	return st.Err()
+}
@@ -2,8 +2,9 @@ package grpcserver
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"sort"
+	"time"
 
 	"github.com/issafronov/shortener/internal/app/config"
 	"github.com/issafronov/shortener/internal/app/contextkeys"
@@ -11,6 +12,14 @@ import (
 	"github.com/issafronov/shortener/internal/app/service"
 	pb "github.com/issafronov/shortener/proto"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Интервалы гистограммы статистики переходов
+const (
+	defaultStatsBucket = time.Hour
+	minStatsBucket     = time.Minute
 )
 
 type GRPCHandler struct {
@@ -25,14 +34,25 @@ func NewGRPCHandler(svc service.Service, config *config.Config) *GRPCHandler {
 
 func (h *GRPCHandler) CreateShortURL(ctx context.Context, req *pb.CreateShortURLRequest) (*pb.ShortURLResponse, error) {
 	if req.Url == "" {
-		return nil, errors.New("url is empty")
+		return nil, h.statusError(invalidArgument("url is empty"))
 	}
 
-	userID, _ := getKeyFromCtx(ctx, string(contextkeys.UserIDKey))
-	shortKey, err := h.svc.CreateURL(ctx, req.Url, userID)
+	userID, err := h.userIDFromCtx(ctx)
 	if err != nil {
 		return nil, err
 	}
+	opts := []service.CreateOption{
+		service.WithAlias(req.Alias),
+		service.WithTTL(time.Duration(req.TtlSeconds) * time.Second),
+	}
+	if req.ExpiresAt != nil {
+		opts = append(opts, service.WithExpiresAt(req.ExpiresAt.AsTime()))
+	}
+
+	shortKey, err := h.svc.CreateURL(ctx, req.Url, userID, opts...)
+	if err != nil {
+		return nil, h.statusError(err)
+	}
 
 	fullURL := fmt.Sprintf("%s/%s", h.config.BaseURL, shortKey)
 	return &pb.ShortURLResponse{Result: fullURL}, nil
@@ -43,21 +63,19 @@ func (h *GRPCHandler) CreateShortURLJSON(ctx context.Context, req *pb.CreateShor
 }
 
 func (h *GRPCHandler) CreateShortURLBatch(ctx context.Context, req *pb.CreateShortURLBatchRequest) (*pb.CreateShortURLBatchResponse, error) {
-	if req.UserId == "" {
-		return nil, errors.New("user_id is empty")
+	userID, err := h.userIDFromCtx(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	var batchReqs []models.BatchURLData
 	for _, u := range req.Urls {
-		batchReqs = append(batchReqs, models.BatchURLData{
-			CorrelationID: u.CorrelationId,
-			OriginalURL:   u.OriginalUrl,
-		})
+		batchReqs = append(batchReqs, batchURLDataFromProto(u))
 	}
 
-	batchResponses, err := h.svc.CreateURLBatch(ctx, batchReqs, req.UserId)
+	batchResponses, err := h.svc.CreateURLBatch(ctx, batchReqs, userID, models.BatchMode(req.Mode))
 	if err != nil {
-		return nil, err
+		return nil, h.statusError(err)
 	}
 
 	var pbBatchResponses []*pb.BatchURLDataResponse
@@ -65,6 +83,8 @@ func (h *GRPCHandler) CreateShortURLBatch(ctx context.Context, req *pb.CreateSho
 		pbBatchResponses = append(pbBatchResponses, &pb.BatchURLDataResponse{
 			CorrelationId: r.CorrelationID,
 			ShortUrl:      r.ShortURL,
+			Status:        string(r.Status),
+			Error:         r.Error,
 		})
 	}
 
@@ -73,26 +93,39 @@ func (h *GRPCHandler) CreateShortURLBatch(ctx context.Context, req *pb.CreateSho
 
 func (h *GRPCHandler) GetOriginalURL(ctx context.Context, req *pb.GetOriginalURLRequest) (*pb.OriginalURLResponse, error) {
 	if req.ShortUrl == "" {
-		return nil, errors.New("short_url is empty")
+		return nil, h.statusError(invalidArgument("short_url is empty"))
 	}
 
 	originalURL, err := h.svc.GetOriginalURL(ctx, req.ShortUrl)
 	if err != nil {
-		return nil, err
+		return nil, h.statusError(err)
 	}
 
 	return &pb.OriginalURLResponse{OriginalUrl: originalURL}, nil
 }
 
 func (h *GRPCHandler) GetUserURLs(ctx context.Context, req *pb.UserIDRequest) (*pb.UserURLsResponse, error) {
-	if req.UserId == "" {
-		return nil, errors.New("user_id is empty")
+	userID, err := h.userIDFromCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// без limit и cursor возвращаются все ссылки, как до появления постраничного вывода
+	limit := int(req.Limit)
+	if limit == 0 && req.Cursor != "" {
+		limit = service.DefaultListLimit
 	}
 
 	host, _ := getKeyFromCtx(ctx, string(contextkeys.HostKey))
-	userURLs, err := h.svc.GetUserURLs(ctx, req.UserId, host)
+	userURLs, next, err := h.svc.ListUserURLs(ctx, userID, host, models.ListOptions{
+		Limit:          limit,
+		Cursor:         req.Cursor,
+		Query:          req.Query,
+		Sort:           req.Sort,
+		IncludeDeleted: req.IncludeDeleted,
+	})
 	if err != nil {
-		return nil, err
+		return nil, h.statusError(err)
 	}
 
 	var pbUserURLs []*pb.UserURL
@@ -100,44 +133,267 @@ func (h *GRPCHandler) GetUserURLs(ctx context.Context, req *pb.UserIDRequest) (*
 		pbUserURLs = append(pbUserURLs, &pb.UserURL{
 			ShortUrl:    u.ShortURL,
 			OriginalUrl: u.OriginalURL,
+			IsDeleted:   u.IsDeleted,
 		})
 	}
 
-	return &pb.UserURLsResponse{Urls: pbUserURLs}, nil
+	return &pb.UserURLsResponse{Urls: pbUserURLs, NextCursor: next}, nil
 }
 
 func (h *GRPCHandler) DeleteUserURLs(ctx context.Context, req *pb.DeleteUserURLsRequest) (*pb.DeleteUserURLsResponse, error) {
-	if req.UserId == "" || len(req.ShortUrlIds) == 0 {
-		return &pb.DeleteUserURLsResponse{Success: false}, errors.New("user_id or short_url_ids missing")
+	if len(req.ShortUrlIds) == 0 {
+		return &pb.DeleteUserURLsResponse{Success: false}, h.statusError(invalidArgument("short_url_ids missing"))
 	}
-
-	err := h.svc.DeleteUserURLs(ctx, req.UserId, req.ShortUrlIds)
+	userID, err := h.userIDFromCtx(ctx)
 	if err != nil {
 		return &pb.DeleteUserURLsResponse{Success: false}, err
 	}
 
-	return &pb.DeleteUserURLsResponse{Success: true}, nil
+	jobID, err := h.svc.DeleteUserURLs(ctx, userID, req.ShortUrlIds)
+	if err != nil {
+		return &pb.DeleteUserURLsResponse{Success: false}, h.statusError(err)
+	}
+
+	return &pb.DeleteUserURLsResponse{Success: true, JobId: jobID}, nil
+}
+
+// GetDeleteJob возвращает состояние задачи удаления ссылок пользователя
+func (h *GRPCHandler) GetDeleteJob(ctx context.Context, req *pb.DeleteJobRequest) (*pb.DeleteJobResponse, error) {
+	if req.JobId == "" {
+		return nil, h.statusError(invalidArgument("job_id missing"))
+	}
+	userID, err := h.userIDFromCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := h.svc.GetDeleteJob(ctx, userID, req.JobId)
+	if err != nil {
+		return nil, h.statusError(err)
+	}
+
+	keys := make([]string, 0, len(job.Results))
+	for key := range job.Results {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	results := make([]*pb.DeleteOutcome, 0, len(keys))
+	for _, key := range keys {
+		results = append(results, &pb.DeleteOutcome{ShortUrl: key, Outcome: string(job.Results[key])})
+	}
+
+	return &pb.DeleteJobResponse{
+		JobId:     job.ID,
+		Status:    string(job.Status),
+		Results:   results,
+		Error:     job.Error,
+		CreatedAt: timestamppb.New(job.CreatedAt),
+		UpdatedAt: timestamppb.New(job.UpdatedAt),
+	}, nil
 }
 
 func (h *GRPCHandler) Ping(ctx context.Context, req *pb.PingRequest) (*pb.PingResponse, error) {
-	err := h.svc.Ping(ctx)
+	_, err := h.svc.Ping(ctx)
 	if err != nil {
-		return &pb.PingResponse{Status: "FAIL"}, err
+		return &pb.PingResponse{Status: "FAIL"}, h.statusError(err)
 	}
 	return &pb.PingResponse{Status: "OK"}, nil
 }
 
 func (h *GRPCHandler) GetStats(ctx context.Context, req *pb.GetStatsRequest) (*pb.GetStatsResponse, error) {
-	urlsCount, usersCount, err := h.svc.GetStats(ctx)
+	stats, err := h.svc.GetStats(ctx)
 	if err != nil {
-		return nil, err
+		return nil, h.statusError(err)
 	}
 	return &pb.GetStatsResponse{
-		Urls:  urlsCount,
-		Users: usersCount,
+		Urls:  stats.URLs,
+		Users: stats.Users,
+	}, nil
+}
+
+// GetLinkStats возвращает статистику переходов по ссылке пользователя
+func (h *GRPCHandler) GetLinkStats(ctx context.Context, req *pb.LinkStatsRequest) (*pb.LinkStatsResponse, error) {
+	if req.ShortUrl == "" {
+		return nil, h.statusError(invalidArgument("short_url is empty"))
+	}
+
+	bucket := defaultStatsBucket
+	if req.BucketSeconds != 0 {
+		bucket = time.Duration(req.BucketSeconds) * time.Second
+		if bucket < minStatsBucket {
+			return nil, h.statusError(invalidArgument("bucket_seconds is too small"))
+		}
+	}
+
+	userID, err := h.userIDFromCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := h.svc.GetLinkStats(ctx, userID, req.ShortUrl, bucket)
+	if err != nil {
+		return nil, h.statusError(err)
+	}
+
+	histogram := make([]*pb.StatsBucket, 0, len(stats.Histogram))
+	for _, b := range stats.Histogram {
+		histogram = append(histogram, &pb.StatsBucket{
+			Start: timestamppb.New(b.Start),
+			Count: b.Count,
+		})
+	}
+
+	return &pb.LinkStatsResponse{
+		ShortUrl:       stats.ShortURL,
+		Total:          stats.Total,
+		UniqueVisitors: stats.UniqueVisitors,
+		Histogram:      histogram,
 	}, nil
 }
 
+// UpdateURL меняет оригинальный URL ссылки пользователя
+func (h *GRPCHandler) UpdateURL(ctx context.Context, req *pb.UpdateURLRequest) (*pb.URLVersion, error) {
+	if req.ShortUrl == "" || req.OriginalUrl == "" {
+		return nil, h.statusError(invalidArgument("short_url and original_url are required"))
+	}
+
+	userID, err := h.userIDFromCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	version, err := h.svc.UpdateURL(ctx, userID, req.ShortUrl, req.OriginalUrl)
+	if err != nil {
+		return nil, h.statusError(err)
+	}
+	return urlVersionToProto(version), nil
+}
+
+// GetURLHistory возвращает историю изменений ссылки пользователя
+func (h *GRPCHandler) GetURLHistory(ctx context.Context, req *pb.URLHistoryRequest) (*pb.URLHistoryResponse, error) {
+	if req.ShortUrl == "" {
+		return nil, h.statusError(invalidArgument("short_url is empty"))
+	}
+
+	userID, err := h.userIDFromCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	history, err := h.svc.GetURLHistory(ctx, userID, req.ShortUrl)
+	if err != nil {
+		return nil, h.statusError(err)
+	}
+
+	versions := make([]*pb.URLVersion, 0, len(history))
+	for _, v := range history {
+		versions = append(versions, urlVersionToProto(v))
+	}
+	return &pb.URLHistoryResponse{Versions: versions}, nil
+}
+
+// RollbackURL возвращает ссылке оригинальный URL указанной версии
+func (h *GRPCHandler) RollbackURL(ctx context.Context, req *pb.RollbackURLRequest) (*pb.URLVersion, error) {
+	if req.ShortUrl == "" || req.Version < 0 {
+		return nil, h.statusError(invalidArgument("invalid rollback request"))
+	}
+
+	userID, err := h.userIDFromCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	version, err := h.svc.RollbackURL(ctx, userID, req.ShortUrl, int(req.Version))
+	if err != nil {
+		return nil, h.statusError(err)
+	}
+	return urlVersionToProto(version), nil
+}
+
+// ImportURLs сохраняет поток записей пачками в режиме import, correlation_id записей необязателен.
+// Возвращает результат по каждой записи и прогресс после каждой пачки
+func (h *GRPCHandler) ImportURLs(stream pb.Shortener_ImportURLsServer) error {
+	ctx := stream.Context()
+	userID, err := h.userIDFromCtx(ctx)
+	if err != nil {
+		return err
+	}
+
+	source := func() (models.BatchURLData, error) {
+		req, err := stream.Recv()
+		if err != nil {
+			return models.BatchURLData{}, err
+		}
+		if req.Url == nil {
+			return models.BatchURLData{}, fmt.Errorf("%w: url is required", service.ErrInvalidRecord)
+		}
+		return batchURLDataFromProto(req.Url), nil
+	}
+	sink := func(event models.ImportEvent) error {
+		if p := event.Progress; p != nil {
+			return stream.Send(&pb.ImportURLsResponse{Event: &pb.ImportURLsResponse_Progress{Progress: &pb.ImportProgress{
+				Processed: int32(p.Processed),
+				Created:   int32(p.Created),
+				Existing:  int32(p.Existing),
+				Invalid:   int32(p.Invalid),
+			}}})
+		}
+		r := event.Result
+		return stream.Send(&pb.ImportURLsResponse{Event: &pb.ImportURLsResponse_Result{Result: &pb.ImportResult{
+			Record:        int32(r.Record),
+			CorrelationId: r.CorrelationID,
+			ShortUrl:      r.ShortURL,
+			Status:        string(r.Status),
+			Error:         r.Error,
+		}}})
+	}
+
+	if _, err := service.Import(ctx, h.svc, userID, service.DefaultImportChunkSize, source, sink); err != nil {
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		return h.statusError(err)
+	}
+	return nil
+}
+
+// batchURLDataFromProto преобразует запись пакета из сообщения protobuf
+func batchURLDataFromProto(u *pb.BatchURLData) models.BatchURLData {
+	return models.BatchURLData{
+		CorrelationID: u.CorrelationId,
+		OriginalURL:   u.OriginalUrl,
+		Alias:         u.Alias,
+		ExpiresAt:     timestampOrNil(u.ExpiresAt),
+		TTL:           u.TtlSeconds,
+	}
+}
+
+// urlVersionToProto преобразует версию ссылки в сообщение protobuf
+func urlVersionToProto(v models.URLVersion) *pb.URLVersion {
+	return &pb.URLVersion{
+		Version:     int32(v.Version),
+		OriginalUrl: v.OriginalURL,
+		PreviousUrl: v.PreviousURL,
+		ChangedBy:   v.ChangedBy,
+		ChangedAt:   timestamppb.New(v.ChangedAt),
+	}
+}
+
+// timestampOrNil преобразует необязательную метку времени protobuf
+func timestampOrNil(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
+
+// userIDFromCtx возвращает идентификатор пользователя, установленный интерцептором аутентификации
+func (h *GRPCHandler) userIDFromCtx(ctx context.Context) (string, error) {
+	userID, ok := ctx.Value(contextkeys.UserIDKey).(string)
+	if !ok || userID == "" {
+		return "", h.statusError(service.ErrUnauthorized)
+	}
+	return userID, nil
+}
+
 // getKeyFromCtx функция для получения данных из metadata
 func getKeyFromCtx(ctx context.Context, key string) (string, bool) {
 	md, ok := metadata.FromIncomingContext(ctx)
@@ -3,25 +3,39 @@ package grpcserver
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"testing"
+	"time"
 
 	"github.com/issafronov/shortener/internal/app/config"
+	"github.com/issafronov/shortener/internal/app/contextkeys"
 	"github.com/issafronov/shortener/internal/app/models"
+	"github.com/issafronov/shortener/internal/app/service"
+	"github.com/issafronov/shortener/internal/app/storage"
 	pb "github.com/issafronov/shortener/proto"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // stubService — простая реализация интерфейса Service для тестов
 type stubService struct {
 	CreateURLFn func(ctx context.Context, originalURL, userID string) (string, error)
-	PingFn      func(ctx context.Context) error
+	PingFn      func(ctx context.Context) (models.Health, error)
+
+	GetDeleteJobFn func(ctx context.Context, userID, jobID string) (models.DeleteJob, error)
+	ListUserURLsFn func(ctx context.Context, userID, host string, opts models.ListOptions) ([]models.ShortURLResponse, string, error)
 }
 
-func (s *stubService) CreateURL(ctx context.Context, originalURL, userID string) (string, error) {
+func (s *stubService) CreateURL(ctx context.Context, originalURL, userID string, opts ...service.CreateOption) (string, error) {
 	return s.CreateURLFn(ctx, originalURL, userID)
 }
 
-func (s *stubService) CreateURLBatch(ctx context.Context, batch []models.BatchURLData, userID string) ([]models.BatchURLDataResponse, error) {
+func (s *stubService) CreateURLBatch(ctx context.Context, batch []models.BatchURLData, userID string, mode models.BatchMode) ([]models.BatchURLDataResponse, error) {
 	return nil, nil
 }
 
@@ -29,32 +43,66 @@ func (s *stubService) GetOriginalURL(ctx context.Context, shortKey string) (stri
 	return "", nil
 }
 
-func (s *stubService) GetUserURLs(ctx context.Context, userID, host string) ([]models.ShortURLResponse, error) {
-	return nil, nil
+func (s *stubService) ListUserURLs(ctx context.Context, userID, host string, opts models.ListOptions) ([]models.ShortURLResponse, string, error) {
+	if s.ListUserURLsFn != nil {
+		return s.ListUserURLsFn(ctx, userID, host, opts)
+	}
+	return nil, "", nil
 }
 
-func (s *stubService) DeleteUserURLs(ctx context.Context, userID string, ids []string) error {
-	return nil
+func (s *stubService) DeleteUserURLs(ctx context.Context, userID string, ids []string) (string, error) {
+	return "", nil
 }
 
-func (s *stubService) GetStats(ctx context.Context) (int64, int64, error) {
-	return 0, 0, nil
+func (s *stubService) GetDeleteJob(ctx context.Context, userID, jobID string) (models.DeleteJob, error) {
+	if s.GetDeleteJobFn != nil {
+		return s.GetDeleteJobFn(ctx, userID, jobID)
+	}
+	return models.DeleteJob{}, service.ErrJobNotFound
+}
+
+func (s *stubService) GetStats(ctx context.Context) (models.Stats, error) {
+	return models.Stats{}, nil
 }
 
-func (s *stubService) Ping(ctx context.Context) error {
+func (s *stubService) Ping(ctx context.Context) (models.Health, error) {
 	return s.PingFn(ctx)
 }
 
+func (s *stubService) RecordClick(ctx context.Context, click models.Click, clientIP string) {}
+
+func (s *stubService) GetLinkStats(ctx context.Context, userID, shortKey string, bucket time.Duration) (models.LinkStats, error) {
+	return models.LinkStats{}, service.ErrNotFound
+}
+
+func (s *stubService) UpdateURL(ctx context.Context, userID, shortKey, originalURL string) (models.URLVersion, error) {
+	return models.URLVersion{}, service.ErrNotFound
+}
+
+func (s *stubService) GetURLHistory(ctx context.Context, userID, shortKey string) ([]models.URLVersion, error) {
+	return nil, service.ErrNotFound
+}
+
+func (s *stubService) RollbackURL(ctx context.Context, userID, shortKey string, version int) (models.URLVersion, error) {
+	return models.URLVersion{}, service.ErrNotFound
+}
+
+// userCtx возвращает контекст пользователя, как после интерцептора аутентификации
+func userCtx(userID string) context.Context {
+	return context.WithValue(context.Background(), contextkeys.UserIDKey, userID)
+}
+
 func TestCreateShortURL(t *testing.T) {
 	svc := &stubService{
 		CreateURLFn: func(ctx context.Context, originalURL, userID string) (string, error) {
+			assert.Equal(t, "user1", userID)
 			return "abc123", nil
 		},
 	}
 	cfg := &config.Config{BaseURL: "http://localhost"}
 	handler := NewGRPCHandler(svc, cfg)
 
-	resp, err := handler.CreateShortURL(context.Background(), &pb.CreateShortURLRequest{Url: "https://example.com"})
+	resp, err := handler.CreateShortURL(userCtx("user1"), &pb.CreateShortURLRequest{Url: "https://example.com"})
 	assert.NoError(t, err)
 	assert.Equal(t, "http://localhost/abc123", resp.Result)
 }
@@ -67,10 +115,22 @@ func TestCreateShortURL_EmptyURL(t *testing.T) {
 	assert.Nil(t, resp)
 }
 
+func TestCreateShortURL_AliasTaken(t *testing.T) {
+	svc := &stubService{
+		CreateURLFn: func(ctx context.Context, originalURL, userID string) (string, error) {
+			return "", service.ErrAliasTaken
+		},
+	}
+	handler := NewGRPCHandler(svc, &config.Config{})
+
+	_, err := handler.CreateShortURL(userCtx("user1"), &pb.CreateShortURLRequest{Url: "https://example.com", Alias: "spring-sale"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+}
+
 func TestPing_OK(t *testing.T) {
 	svc := &stubService{
-		PingFn: func(ctx context.Context) error {
-			return nil
+		PingFn: func(ctx context.Context) (models.Health, error) {
+			return models.Health{Status: models.HealthOK}, nil
 		},
 	}
 	handler := NewGRPCHandler(svc, &config.Config{})
@@ -82,8 +142,8 @@ func TestPing_OK(t *testing.T) {
 
 func TestPing_Fail(t *testing.T) {
 	svc := &stubService{
-		PingFn: func(ctx context.Context) error {
-			return errors.New("db down")
+		PingFn: func(ctx context.Context) (models.Health, error) {
+			return models.Health{}, errors.New("db down")
 		},
 	}
 	handler := NewGRPCHandler(svc, &config.Config{})
@@ -92,3 +152,139 @@ func TestPing_Fail(t *testing.T) {
 	assert.Error(t, err)
 	assert.Equal(t, "FAIL", resp.Status)
 }
+
+func TestGetDeleteJob(t *testing.T) {
+	svc := &stubService{
+		GetDeleteJobFn: func(ctx context.Context, userID, jobID string) (models.DeleteJob, error) {
+			if userID != "user1" {
+				return models.DeleteJob{}, service.ErrJobNotFound
+			}
+			return models.DeleteJob{
+				ID:     jobID,
+				Status: models.JobDone,
+				Results: models.DeleteOutcomes{
+					"b": models.DeleteOutcomeNotOwned,
+					"a": models.DeleteOutcomeDeleted,
+				},
+			}, nil
+		},
+	}
+	handler := NewGRPCHandler(svc, &config.Config{})
+
+	resp, err := handler.GetDeleteJob(userCtx("user1"), &pb.DeleteJobRequest{JobId: "job1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "done", resp.Status)
+	if assert.Len(t, resp.Results, 2) {
+		assert.Equal(t, "a", resp.Results[0].ShortUrl)
+		assert.Equal(t, "deleted", resp.Results[0].Outcome)
+		assert.Equal(t, "not_owned", resp.Results[1].Outcome)
+	}
+
+	// задача другого пользователя не видна
+	_, err = handler.GetDeleteJob(userCtx("user2"), &pb.DeleteJobRequest{JobId: "job1"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestGetUserURLs_DefaultLimit(t *testing.T) {
+	var limits []int
+	svc := &stubService{
+		ListUserURLsFn: func(ctx context.Context, userID, host string, opts models.ListOptions) ([]models.ShortURLResponse, string, error) {
+			limits = append(limits, opts.Limit)
+			return nil, "", nil
+		},
+	}
+	handler := NewGRPCHandler(svc, &config.Config{})
+
+	for _, req := range []*pb.UserIDRequest{{}, {Cursor: "abc"}, {Cursor: "abc", Limit: 5}} {
+		_, err := handler.GetUserURLs(userCtx("user1"), req)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []int{0, service.DefaultListLimit, 5}, limits)
+}
+
+func TestHandlers_RequireAuthenticatedUser(t *testing.T) {
+	handler := NewGRPCHandler(&stubService{}, &config.Config{})
+
+	_, err := handler.GetUserURLs(context.Background(), &pb.UserIDRequest{UserId: "user1"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = handler.DeleteUserURLs(context.Background(), &pb.DeleteUserURLsRequest{UserId: "user1", ShortUrlIds: []string{"abc"}})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestStatusError_Details(t *testing.T) {
+	handler := NewGRPCHandler(&stubService{}, &config.Config{BaseURL: "http://localhost"})
+
+	st := status.Convert(handler.statusError(&service.ConflictError{Key: "abc"}))
+	assert.Equal(t, codes.AlreadyExists, st.Code())
+	if assert.Len(t, st.Details(), 1) {
+		info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+		if assert.True(t, ok) {
+			assert.Equal(t, "URL_CONFLICT", info.Reason)
+			assert.Equal(t, "http://localhost/abc", info.Metadata["short_url"])
+		}
+	}
+
+	st = status.Convert(handler.statusError(fmt.Errorf("%w: queue is full", service.ErrDeleteUnavailable)))
+	assert.Equal(t, codes.Unavailable, st.Code())
+	assert.Len(t, st.Details(), 2)
+
+	st = status.Convert(handler.statusError(errors.New("connection reset")))
+	assert.Equal(t, codes.Internal, st.Code())
+	assert.NotContains(t, st.Message(), "connection reset")
+}
+
+func TestGetOriginalURL_EmptyKey(t *testing.T) {
+	handler := NewGRPCHandler(&stubService{}, &config.Config{})
+
+	_, err := handler.GetOriginalURL(context.Background(), &pb.GetOriginalURLRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+// importStream — заглушка потока ImportURLs, отдающая заранее заданные запросы
+type importStream struct {
+	grpc.ServerStream
+	ctx       context.Context
+	requests  []*pb.ImportURLsRequest
+	responses []*pb.ImportURLsResponse
+}
+
+func (s *importStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *importStream) Recv() (*pb.ImportURLsRequest, error) {
+	if len(s.requests) == 0 {
+		return nil, io.EOF
+	}
+	req := s.requests[0]
+	s.requests = s.requests[1:]
+	return req, nil
+}
+
+func (s *importStream) Send(resp *pb.ImportURLsResponse) error {
+	s.responses = append(s.responses, resp)
+	return nil
+}
+
+func TestImportURLs(t *testing.T) {
+	h := NewGRPCHandler(service.NewService(storage.NewMemoryStorage()), &config.Config{})
+	stream := &importStream{
+		ctx: context.WithValue(context.Background(), contextkeys.UserIDKey, "user1"),
+		requests: []*pb.ImportURLsRequest{
+			{Url: &pb.BatchURLData{CorrelationId: "1", OriginalUrl: "https://a.com"}},
+			{},
+			{Url: &pb.BatchURLData{CorrelationId: "3", OriginalUrl: "https://a.com"}},
+		},
+	}
+
+	require.NoError(t, h.ImportURLs(stream))
+	require.Len(t, stream.responses, 4)
+
+	created := stream.responses[0].GetResult()
+	assert.Equal(t, int32(1), created.Record)
+	assert.Equal(t, string(models.BatchItemCreated), created.Status)
+	assert.Equal(t, string(models.BatchItemInvalid), stream.responses[1].GetResult().Status)
+	assert.Equal(t, created.ShortUrl, stream.responses[2].GetResult().ShortUrl)
+	assert.Equal(t, &pb.ImportProgress{Processed: 3, Created: 1, Existing: 1, Invalid: 1}, stream.responses[3].GetProgress())
+}
@@ -57,13 +57,6 @@ func ExampleHandler_GetLinkHandle() {
 	originalURL := "https://example.com"
 	userID := "user1"
 
-	// Предварительно добавим ссылку в хранилище
-	storage.Urls["abc123"] = storage.ShortenerURL{
-		ShortURL:    "abc123",
-		OriginalURL: "https://example.com",
-		UserID:      "user1",
-	}
-
 	_, _ = store.Create(context.Background(), storage.ShortenerURL{
 		ShortURL:    shortURL,
 		OriginalURL: originalURL,
@@ -2,54 +2,95 @@
 package handlers
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"mime"
+	"net"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/issafronov/shortener/internal/app/config"
 	"github.com/issafronov/shortener/internal/app/contextkeys"
 	"github.com/issafronov/shortener/internal/app/models"
 	"github.com/issafronov/shortener/internal/app/service"
+	"github.com/issafronov/shortener/internal/middleware/logger"
+	"go.uber.org/zap"
+)
+
+// ndjsonContentType — тип содержимого потокового импорта
+const ndjsonContentType = "application/x-ndjson"
+
+// maxImportRecordSize — максимальный размер одной строки потокового импорта
+const maxImportRecordSize = 1 << 20
+
+// nextCursorHeader — заголовок с курсором следующей страницы списка
+const nextCursorHeader = "X-Next-Cursor"
+
+// Интервалы гистограммы статистики переходов
+const (
+	defaultStatsBucket = time.Hour
+	minStatsBucket     = time.Minute
 )
 
 // Handler обрабатывает входящие HTTP-запросы и взаимодействует с сервисным слоем.
 type Handler struct {
 	service service.Service
 	config  *config.Config
+	// trustedNet — подсеть прокси, которым разрешено передавать адрес клиента в X-Real-IP
+	trustedNet *net.IPNet
 }
 
 // NewHandler создает новый экземпляр Handler.
 func NewHandler(cfg *config.Config, svc service.Service) (*Handler, error) {
-	return &Handler{
+	h := &Handler{
 		config:  cfg,
 		service: svc,
-	}, nil
+	}
+	if cfg.TrustedSubnet != "" {
+		// некорректная подсеть не доверяет никому, как и в TrustedSubnetMiddleware
+		_, h.trustedNet, _ = net.ParseCIDR(cfg.TrustedSubnet)
+	}
+	return h, nil
 }
 
 // CreateLinkHandle обрабатывает POST-запрос с обычной строкой URL и создает сокращённую ссылку.
 func (h *Handler) CreateLinkHandle(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(contextkeys.UserIDKey).(string)
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
+		h.respondWithError(w, r, service.ErrUnauthorized)
 		return
 	}
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil || len(body) == 0 {
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		h.respondWithError(w, r, invalidRequest("body must contain the url"))
 		return
 	}
 	originalURL := string(body)
 
-	shortKey, err := h.service.CreateURL(r.Context(), originalURL, userID)
+	opts := []service.CreateOption{service.WithAlias(r.URL.Query().Get("alias"))}
+	if ttl := r.URL.Query().Get("ttl"); ttl != "" {
+		seconds, err := strconv.ParseInt(ttl, 10, 64)
+		if err != nil {
+			h.respondWithError(w, r, invalidRequest("invalid ttl"))
+			return
+		}
+		opts = append(opts, service.WithTTL(time.Duration(seconds)*time.Second))
+	}
+
+	shortKey, err := h.service.CreateURL(r.Context(), originalURL, userID, opts...)
 	if err != nil {
-		if errors.Is(err, service.ErrConflict) {
-			h.respondWithText(w, r, shortKey, http.StatusConflict)
+		if key, ok := service.ConflictKey(err); ok {
+			h.respondWithText(w, r, key, http.StatusConflict)
 			return
 		}
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		h.respondWithError(w, r, err)
 		return
 	}
 
@@ -60,23 +101,31 @@ func (h *Handler) CreateLinkHandle(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) CreateJSONLinkHandle(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(contextkeys.UserIDKey).(string)
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
+		h.respondWithError(w, r, service.ErrUnauthorized)
 		return
 	}
 
 	var urlData models.URLData
 	if err := json.NewDecoder(r.Body).Decode(&urlData); err != nil || urlData.URL == "" {
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		h.respondWithError(w, r, invalidRequest("body must be a JSON object with url"))
 		return
 	}
 
-	shortKey, err := h.service.CreateURL(r.Context(), urlData.URL, userID)
+	opts := []service.CreateOption{
+		service.WithAlias(urlData.Alias),
+		service.WithTTL(time.Duration(urlData.TTL) * time.Second),
+	}
+	if urlData.ExpiresAt != nil {
+		opts = append(opts, service.WithExpiresAt(*urlData.ExpiresAt))
+	}
+
+	shortKey, err := h.service.CreateURL(r.Context(), urlData.URL, userID, opts...)
 	if err != nil {
-		if errors.Is(err, service.ErrConflict) {
-			h.respondWithJSON(w, r, shortKey, http.StatusConflict)
+		if key, ok := service.ConflictKey(err); ok {
+			h.respondWithJSON(w, r, key, http.StatusConflict)
 			return
 		}
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		h.respondWithError(w, r, err)
 		return
 	}
 
@@ -84,60 +133,181 @@ func (h *Handler) CreateJSONLinkHandle(w http.ResponseWriter, r *http.Request) {
 }
 
 // CreateBatchJSONLinkHandle обрабатывает пакетный POST-запрос с JSON и возвращает множество сокращённых ссылок.
+// Параметр mode выбирает режим: atomic (по умолчанию) или best_effort.
+// В режиме best_effort ответ 201 возвращается, если создана хотя бы одна ссылка, иначе 200.
 func (h *Handler) CreateBatchJSONLinkHandle(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(contextkeys.UserIDKey).(string)
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
+		h.respondWithError(w, r, service.ErrUnauthorized)
 		return
 	}
 
 	var batch []models.BatchURLData
 	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		h.respondWithError(w, r, invalidRequest("body must be a JSON array of urls"))
 		return
 	}
 
-	result, err := h.service.CreateURLBatch(r.Context(), batch, userID)
+	mode := models.BatchMode(r.URL.Query().Get("mode"))
+	result, err := h.service.CreateURLBatch(r.Context(), batch, userID, mode)
 	if err != nil {
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		h.respondWithError(w, r, err)
 		return
 	}
 
+	status := http.StatusCreated
+	if mode == models.BatchModeBestEffort || mode == models.BatchModeImport {
+		status = http.StatusOK
+	}
 	for i := range result {
-		result[i].ShortURL = h.buildFullURL(r, result[i].ShortURL)
+		if result[i].ShortURL != "" {
+			result[i].ShortURL = h.buildFullURL(r, result[i].ShortURL)
+		}
+		if result[i].Status == models.BatchItemCreated {
+			status = http.StatusCreated
+		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(result)
 }
 
+// ImportLinksHandle принимает поток записей в формате NDJSON, по одной BatchURLData в строке,
+// и сохраняет их пачками в режиме import, correlation_id записей необязателен. В ответ построчно
+// передаются результат по каждой записи и прогресс после каждой пачки. Строки, которые не удалось разобрать,
+// возвращаются со статусом invalid; ошибка, прервавшая импорт, передаётся последней строкой.
+func (h *Handler) ImportLinksHandle(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextkeys.UserIDKey).(string)
+	if !ok {
+		h.respondWithError(w, r, service.ErrUnauthorized)
+		return
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != ndjsonContentType {
+		h.respondWithError(w, r, invalidRequest("content type must be "+ndjsonContentType))
+		return
+	}
+
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportRecordSize)
+	source := func() (models.BatchURLData, error) {
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			var data models.BatchURLData
+			if err := json.Unmarshal(line, &data); err != nil {
+				return data, fmt.Errorf("%w: malformed JSON", service.ErrInvalidRecord)
+			}
+			return data, nil
+		}
+		if errors.Is(scanner.Err(), bufio.ErrTooLong) {
+			return models.BatchURLData{}, invalidRequest(fmt.Sprintf("record exceeds %d bytes", maxImportRecordSize))
+		}
+		if err := scanner.Err(); err != nil {
+			return models.BatchURLData{}, err
+		}
+		return models.BatchURLData{}, io.EOF
+	}
+
+	w.Header().Set("Content-Type", ndjsonContentType)
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	rc := http.NewResponseController(w)
+	sink := func(event models.ImportEvent) error {
+		if event.Result != nil && event.Result.ShortURL != "" {
+			event.Result.ShortURL = h.buildFullURL(r, event.Result.ShortURL)
+		}
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+		if event.Progress != nil {
+			return rc.Flush()
+		}
+		return nil
+	}
+
+	if _, err := service.Import(r.Context(), h.service, userID, service.DefaultImportChunkSize, source, sink); err != nil {
+		message := "import aborted"
+		if service.KindOf(err) != service.KindInternal {
+			message = err.Error()
+		} else {
+			logger.FromContext(r.Context()).Error("import aborted", zap.Error(err))
+		}
+		_ = encoder.Encode(models.ImportEvent{Error: message})
+	}
+}
+
 // GetLinkHandle обрабатывает GET-запрос и перенаправляет на оригинальный URL по короткому ключу.
 func (h *Handler) GetLinkHandle(w http.ResponseWriter, r *http.Request) {
 	key := chi.URLParam(r, "key")
 	originalURL, err := h.service.GetOriginalURL(r.Context(), key)
 	if err != nil {
-		if errors.Is(err, service.ErrDeleted) {
-			http.Error(w, http.StatusText(http.StatusGone), http.StatusGone)
-		} else {
-			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
-		}
+		h.respondWithError(w, r, err)
 		return
 	}
+	h.service.RecordClick(r.Context(), models.Click{
+		ShortURL:  key,
+		ClickedAt: time.Now(),
+		Referrer:  r.Referer(),
+		UserAgent: r.UserAgent(),
+	}, h.clientIP(r))
 	http.Redirect(w, r, originalURL, http.StatusTemporaryRedirect)
 }
 
+// GetLinkStatsHandle возвращает статистику переходов по ссылке пользователя.
+// Параметр bucket задаёт интервал гистограммы, по умолчанию один час.
+func (h *Handler) GetLinkStatsHandle(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextkeys.UserIDKey).(string)
+	if !ok {
+		h.respondWithError(w, r, service.ErrUnauthorized)
+		return
+	}
+
+	bucket := defaultStatsBucket
+	if raw := r.URL.Query().Get("bucket"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil || parsed < minStatsBucket {
+			h.respondWithError(w, r, invalidRequest("invalid bucket"))
+			return
+		}
+		bucket = parsed
+	}
+
+	stats, err := h.service.GetLinkStats(r.Context(), userID, chi.URLParam(r, "key"), bucket)
+	if err != nil {
+		h.respondWithError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(stats)
+}
+
 // GetUserLinksHandle возвращает список сокращённых ссылок пользователя.
+// Поддерживает параметры limit, cursor, q (подстрока оригинального URL),
+// sort (created_asc или created_desc) и include_deleted. Без limit и cursor возвращаются
+// все ссылки, с cursor без limit — страница из service.DefaultListLimit ссылок.
+// Курсор следующей страницы возвращается в заголовке X-Next-Cursor.
 func (h *Handler) GetUserLinksHandle(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(contextkeys.UserIDKey).(string)
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
+		h.respondWithError(w, r, service.ErrUnauthorized)
 		return
 	}
 
-	links, err := h.service.GetUserURLs(r.Context(), userID, h.getBaseURL(r))
+	opts, err := parseListOptions(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		h.respondWithError(w, r, err)
+		return
+	}
+
+	links, next, err := h.service.ListUserURLs(r.Context(), userID, h.getBaseURL(r), opts)
+	if err != nil {
+		h.respondWithError(w, r, err)
 		return
 	}
 
@@ -146,45 +316,132 @@ func (h *Handler) GetUserLinksHandle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if next != "" {
+		w.Header().Set(nextCursorHeader, next)
+	}
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(links)
 }
 
+// UpdateLinkHandle обрабатывает PATCH-запрос и меняет оригинальный URL ссылки пользователя.
+// В ответе возвращается созданная версия из истории изменений.
+func (h *Handler) UpdateLinkHandle(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextkeys.UserIDKey).(string)
+	if !ok {
+		h.respondWithError(w, r, service.ErrUnauthorized)
+		return
+	}
+
+	var req models.UpdateURLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.OriginalURL == "" {
+		h.respondWithError(w, r, invalidRequest("body must be a JSON object with original_url"))
+		return
+	}
+
+	version, err := h.service.UpdateURL(r.Context(), userID, chi.URLParam(r, "key"), req.OriginalURL)
+	if err != nil {
+		h.respondWithError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(version)
+}
+
+// GetLinkHistoryHandle возвращает историю изменений ссылки пользователя.
+func (h *Handler) GetLinkHistoryHandle(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextkeys.UserIDKey).(string)
+	if !ok {
+		h.respondWithError(w, r, service.ErrUnauthorized)
+		return
+	}
+
+	history, err := h.service.GetURLHistory(r.Context(), userID, chi.URLParam(r, "key"))
+	if err != nil {
+		h.respondWithError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(history)
+}
+
+// RollbackLinkHandle возвращает ссылке оригинальный URL указанной версии.
+func (h *Handler) RollbackLinkHandle(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextkeys.UserIDKey).(string)
+	if !ok {
+		h.respondWithError(w, r, service.ErrUnauthorized)
+		return
+	}
+
+	var req models.RollbackURLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Version < 0 {
+		h.respondWithError(w, r, invalidRequest("body must be a JSON object with a non-negative version"))
+		return
+	}
+
+	version, err := h.service.RollbackURL(r.Context(), userID, chi.URLParam(r, "key"), req.Version)
+	if err != nil {
+		h.respondWithError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(version)
+}
+
 // DeleteLinksHandle обрабатывает запрос на удаление нескольких ссылок пользователя.
 func (h *Handler) DeleteLinksHandle(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(contextkeys.UserIDKey).(string)
 	if !ok {
-		w.WriteHeader(http.StatusUnauthorized)
+		h.respondWithError(w, r, service.ErrUnauthorized)
 		return
 	}
 
 	var ids []string
 	if err := json.NewDecoder(r.Body).Decode(&ids); err != nil {
-		http.Error(w, "bad request", http.StatusBadRequest)
+		h.respondWithError(w, r, invalidRequest("body must be a JSON array of short keys"))
 		return
 	}
 
-	go func() {
-		_ = h.service.DeleteUserURLs(r.Context(), userID, ids)
-	}()
+	jobID, err := h.service.DeleteUserURLs(r.Context(), userID, ids)
+	if err != nil {
+		h.respondWithError(w, r, err)
+		return
+	}
 
+	w.Header().Set("Content-Type", "application/json")
+	if jobID != "" {
+		w.Header().Set("Location", "/api/user/jobs/"+jobID)
+	}
 	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(models.DeleteJobResponse{JobID: jobID})
 }
 
-// InternalStats возвращает статистику по числу пользователей и URL-адресов.
-func (h *Handler) InternalStats(w http.ResponseWriter, r *http.Request) {
-	urls, users, err := h.service.GetStats(r.Context())
+// GetJobHandle возвращает состояние задачи удаления ссылок пользователя.
+func (h *Handler) GetJobHandle(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(contextkeys.UserIDKey).(string)
+	if !ok {
+		h.respondWithError(w, r, service.ErrUnauthorized)
+		return
+	}
+
+	job, err := h.service.GetDeleteJob(r.Context(), userID, chi.URLParam(r, "id"))
 	if err != nil {
-		http.Error(w, "Failed to get stats", http.StatusInternalServerError)
+		h.respondWithError(w, r, err)
 		return
 	}
 
-	stats := struct {
-		URLs  int64 `json:"urls"`
-		Users int64 `json:"users"`
-	}{
-		URLs:  urls,
-		Users: users,
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(job)
+}
+
+// InternalStats возвращает статистику по числу пользователей и URL-адресов и счётчики кэша ссылок.
+func (h *Handler) InternalStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.service.GetStats(r.Context())
+	if err != nil {
+		h.respondWithError(w, r, err)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -216,6 +473,49 @@ func (h *Handler) respondWithJSON(w http.ResponseWriter, r *http.Request, shortK
 	_ = json.NewEncoder(w).Encode(response)
 }
 
+// parseListOptions разбирает параметры списка ссылок из строки запроса
+func parseListOptions(r *http.Request) (models.ListOptions, error) {
+	query := r.URL.Query()
+	opts := models.ListOptions{
+		Cursor: query.Get("cursor"),
+		Query:  query.Get("q"),
+		Sort:   query.Get("sort"),
+	}
+
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return opts, fmt.Errorf("%w: invalid limit", service.ErrInvalidListOptions)
+		}
+		opts.Limit = limit
+	} else if opts.Cursor != "" {
+		opts.Limit = service.DefaultListLimit
+	}
+	if raw := query.Get("include_deleted"); raw != "" {
+		include, err := strconv.ParseBool(raw)
+		if err != nil {
+			return opts, fmt.Errorf("%w: invalid include_deleted", service.ErrInvalidListOptions)
+		}
+		opts.IncludeDeleted = include
+	}
+	return opts, nil
+}
+
+// clientIP возвращает IP-адрес клиента. Заголовок X-Real-IP учитывается, только если
+// запрос пришёл из доверенной подсети, иначе используется адрес соединения.
+func (h *Handler) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if ip := r.Header.Get("X-Real-IP"); ip != "" && h.trustedNet != nil {
+		if remote := net.ParseIP(host); remote != nil && h.trustedNet.Contains(remote) {
+			return ip
+		}
+	}
+	return host
+}
+
 // buildFullURL формирует полный URL из базового и короткого ключа.
 func (h *Handler) buildFullURL(r *http.Request, shortKey string) string {
 	base := h.getBaseURL(r)
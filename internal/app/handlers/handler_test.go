@@ -4,40 +4,82 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/issafronov/shortener/internal/app/config"
 	"github.com/issafronov/shortener/internal/app/contextkeys"
 	"github.com/issafronov/shortener/internal/app/handlers"
 	"github.com/issafronov/shortener/internal/app/models"
+	"github.com/issafronov/shortener/internal/app/service"
 	"github.com/issafronov/shortener/internal/app/storage"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type mockService struct {
 	CreateURLFunc      func(ctx context.Context, originalURL, userID string) (string, error)
-	CreateURLBatchFunc func(ctx context.Context, batch []models.BatchURLData, userID string) ([]models.BatchURLDataResponse, error)
+	CreateURLBatchFunc func(ctx context.Context, batch []models.BatchURLData, userID string, mode models.BatchMode) ([]models.BatchURLDataResponse, error)
 	GetOriginalURLFunc func(ctx context.Context, shortKey string) (string, error)
-	GetUserURLsFunc    func(ctx context.Context, userID, host string) ([]models.ShortURLResponse, error)
-	DeleteUserURLsFunc func(ctx context.Context, userID string, ids []string) error
-	GetStatsFunc       func(ctx context.Context) (int64, int64, error)
-	PingFunc           func(ctx context.Context) error
+	DeleteUserURLsFunc func(ctx context.Context, userID string, ids []string) (string, error)
+	GetStatsFunc       func(ctx context.Context) (models.Stats, error)
+	PingFunc           func(ctx context.Context) (models.Health, error)
+	GetLinkStatsFunc   func(ctx context.Context, userID, shortKey string, bucket time.Duration) (models.LinkStats, error)
+	ListUserURLsFunc   func(ctx context.Context, userID, host string, opts models.ListOptions) ([]models.ShortURLResponse, string, error)
+	UpdateURLFunc      func(ctx context.Context, userID, shortKey, originalURL string) (models.URLVersion, error)
+	RecordClickFunc    func(ctx context.Context, click models.Click, clientIP string)
 }
 
-func (m *mockService) CreateURL(ctx context.Context, originalURL, userID string) (string, error) {
+func (m *mockService) UpdateURL(ctx context.Context, userID, shortKey, originalURL string) (models.URLVersion, error) {
+	if m.UpdateURLFunc != nil {
+		return m.UpdateURLFunc(ctx, userID, shortKey, originalURL)
+	}
+	return models.URLVersion{}, nil
+}
+
+func (m *mockService) GetURLHistory(ctx context.Context, userID, shortKey string) ([]models.URLVersion, error) {
+	return nil, nil
+}
+
+func (m *mockService) RollbackURL(ctx context.Context, userID, shortKey string, version int) (models.URLVersion, error) {
+	return models.URLVersion{}, nil
+}
+
+func (m *mockService) ListUserURLs(ctx context.Context, userID, host string, opts models.ListOptions) ([]models.ShortURLResponse, string, error) {
+	if m.ListUserURLsFunc != nil {
+		return m.ListUserURLsFunc(ctx, userID, host, opts)
+	}
+	return nil, "", nil
+}
+
+func (m *mockService) RecordClick(ctx context.Context, click models.Click, clientIP string) {
+	if m.RecordClickFunc != nil {
+		m.RecordClickFunc(ctx, click, clientIP)
+	}
+}
+
+func (m *mockService) GetLinkStats(ctx context.Context, userID, shortKey string, bucket time.Duration) (models.LinkStats, error) {
+	if m.GetLinkStatsFunc != nil {
+		return m.GetLinkStatsFunc(ctx, userID, shortKey, bucket)
+	}
+	return models.LinkStats{}, nil
+}
+
+func (m *mockService) CreateURL(ctx context.Context, originalURL, userID string, opts ...service.CreateOption) (string, error) {
 	if m.CreateURLFunc != nil {
 		return m.CreateURLFunc(ctx, originalURL, userID)
 	}
 	return "", nil
 }
 
-func (m *mockService) CreateURLBatch(ctx context.Context, batch []models.BatchURLData, userID string) ([]models.BatchURLDataResponse, error) {
+func (m *mockService) CreateURLBatch(ctx context.Context, batch []models.BatchURLData, userID string, mode models.BatchMode) ([]models.BatchURLDataResponse, error) {
 	if m.CreateURLBatchFunc != nil {
-		return m.CreateURLBatchFunc(ctx, batch, userID)
+		return m.CreateURLBatchFunc(ctx, batch, userID, mode)
 	}
 	return nil, nil
 }
@@ -49,32 +91,29 @@ func (m *mockService) GetOriginalURL(ctx context.Context, shortKey string) (stri
 	return "", nil
 }
 
-func (m *mockService) GetUserURLs(ctx context.Context, userID, host string) ([]models.ShortURLResponse, error) {
-	if m.GetUserURLsFunc != nil {
-		return m.GetUserURLsFunc(ctx, userID, host)
-	}
-	return nil, nil
-}
-
-func (m *mockService) DeleteUserURLs(ctx context.Context, userID string, ids []string) error {
+func (m *mockService) DeleteUserURLs(ctx context.Context, userID string, ids []string) (string, error) {
 	if m.DeleteUserURLsFunc != nil {
 		return m.DeleteUserURLsFunc(ctx, userID, ids)
 	}
-	return nil
+	return "", nil
+}
+
+func (m *mockService) GetDeleteJob(ctx context.Context, userID, jobID string) (models.DeleteJob, error) {
+	return models.DeleteJob{}, service.ErrJobNotFound
 }
 
-func (m *mockService) GetStats(ctx context.Context) (int64, int64, error) {
+func (m *mockService) GetStats(ctx context.Context) (models.Stats, error) {
 	if m.GetStatsFunc != nil {
 		return m.GetStatsFunc(ctx)
 	}
-	return 0, 0, nil
+	return models.Stats{}, nil
 }
 
-func (m *mockService) Ping(ctx context.Context) error {
+func (m *mockService) Ping(ctx context.Context) (models.Health, error) {
 	if m.PingFunc != nil {
 		return m.PingFunc(ctx)
 	}
-	return nil
+	return models.Health{Status: models.HealthOK}, nil
 }
 
 type mockStorage struct {
@@ -131,8 +170,8 @@ func (m *mockStorage) CountUsers(ctx context.Context) (int64, error) {
 func TestCreateJSONLinkHandle(t *testing.T) {
 	cfg := &config.Config{BaseURL: "http://localhost"}
 	svc := &mockService{
-		GetStatsFunc: func(ctx context.Context) (int64, int64, error) {
-			return 42, 10, nil
+		GetStatsFunc: func(ctx context.Context) (models.Stats, error) {
+			return models.Stats{URLs: 42, Users: 10}, nil
 		},
 		CreateURLFunc: func(ctx context.Context, originalURL, userID string) (string, error) {
 			return "shortKey", nil
@@ -158,8 +197,8 @@ func TestCreateJSONLinkHandle(t *testing.T) {
 func TestCreateJSONLinkHandle_Unauthorized(t *testing.T) {
 	cfg := &config.Config{}
 	svc := &mockService{
-		GetStatsFunc: func(ctx context.Context) (int64, int64, error) {
-			return 42, 10, nil
+		GetStatsFunc: func(ctx context.Context) (models.Stats, error) {
+			return models.Stats{URLs: 42, Users: 10}, nil
 		},
 		CreateURLFunc: func(ctx context.Context, originalURL, userID string) (string, error) {
 			return "shortKey", nil
@@ -179,8 +218,8 @@ func TestCreateJSONLinkHandle_Unauthorized(t *testing.T) {
 func TestCreateJSONLinkHandle_InvalidBody(t *testing.T) {
 	cfg := &config.Config{}
 	svc := &mockService{
-		GetStatsFunc: func(ctx context.Context) (int64, int64, error) {
-			return 42, 10, nil
+		GetStatsFunc: func(ctx context.Context) (models.Stats, error) {
+			return models.Stats{URLs: 42, Users: 10}, nil
 		},
 		CreateURLFunc: func(ctx context.Context, originalURL, userID string) (string, error) {
 			return "shortKey", nil
@@ -228,11 +267,50 @@ func TestGetLinkHandle_Found(t *testing.T) {
 	assert.Equal(t, http.StatusTemporaryRedirect, res.StatusCode)
 }
 
+func TestGetLinkHandle_ClientIP(t *testing.T) {
+	var clientIP string
+	svc := &mockService{
+		GetOriginalURLFunc: func(ctx context.Context, key string) (string, error) {
+			return "https://example.com", nil
+		},
+		RecordClickFunc: func(ctx context.Context, click models.Click, ip string) {
+			clientIP = ip
+		},
+	}
+	h, _ := handlers.NewHandler(&config.Config{TrustedSubnet: "10.0.0.0/8"}, svc)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		realIP     string
+		want       string
+	}{
+		{name: "trusted proxy", remoteAddr: "10.1.2.3:1234", realIP: "203.0.113.7", want: "203.0.113.7"},
+		{name: "untrusted client", remoteAddr: "198.51.100.1:1234", realIP: "203.0.113.7", want: "198.51.100.1"},
+		{name: "no header", remoteAddr: "10.1.2.3:1234", want: "10.1.2.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/abc123", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("key", "abc123")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			h.GetLinkHandle(httptest.NewRecorder(), req)
+			assert.Equal(t, tt.want, clientIP)
+		})
+	}
+}
+
 func TestGetUserLinksHandle_NoContent(t *testing.T) {
 	cfg := &config.Config{BaseURL: "http://localhost"}
 	svc := &mockService{
-		GetUserURLsFunc: func(ctx context.Context, userID, host string) ([]models.ShortURLResponse, error) {
-			return []models.ShortURLResponse{}, nil
+		ListUserURLsFunc: func(ctx context.Context, userID, host string, opts models.ListOptions) ([]models.ShortURLResponse, string, error) {
+			return []models.ShortURLResponse{}, "", nil
 		},
 	}
 
@@ -251,8 +329,8 @@ func TestGetUserLinksHandle_NoContent(t *testing.T) {
 func TestDeleteLinksHandle(t *testing.T) {
 	cfg := &config.Config{}
 	svc := &mockService{
-		DeleteUserURLsFunc: func(ctx context.Context, userID string, ids []string) error {
-			return nil
+		DeleteUserURLsFunc: func(ctx context.Context, userID string, ids []string) (string, error) {
+			return "job1", nil
 		},
 	}
 
@@ -267,6 +345,32 @@ func TestDeleteLinksHandle(t *testing.T) {
 	res := w.Result()
 	defer res.Body.Close()
 	assert.Equal(t, http.StatusAccepted, res.StatusCode)
+	assert.Equal(t, "/api/user/jobs/job1", res.Header.Get("Location"))
+
+	var resp models.DeleteJobResponse
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&resp))
+	assert.Equal(t, "job1", resp.JobID)
+}
+
+func TestDeleteLinksHandle_QueueFull(t *testing.T) {
+	svc := &mockService{
+		DeleteUserURLsFunc: func(ctx context.Context, userID string, ids []string) (string, error) {
+			return "", fmt.Errorf("%w: queue is full", service.ErrDeleteUnavailable)
+		},
+	}
+
+	h, _ := handlers.NewHandler(&config.Config{}, svc)
+
+	body, _ := json.Marshal([]string{"abc123"})
+	req := httptest.NewRequest(http.MethodDelete, "/api/user/urls", bytes.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), contextkeys.UserIDKey, "user1"))
+	w := httptest.NewRecorder()
+
+	h.DeleteLinksHandle(w, req)
+	res := w.Result()
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	assert.Equal(t, "1", res.Header.Get("Retry-After"))
 }
 
 func TestCreateLinkHandle(t *testing.T) {
@@ -293,7 +397,7 @@ func TestCreateLinkHandle(t *testing.T) {
 func TestCreateBatchJSONLinkHandle(t *testing.T) {
 	cfg := &config.Config{BaseURL: "http://localhost"}
 	svc := &mockService{
-		CreateURLBatchFunc: func(ctx context.Context, batch []models.BatchURLData, userID string) ([]models.BatchURLDataResponse, error) {
+		CreateURLBatchFunc: func(ctx context.Context, batch []models.BatchURLData, userID string, mode models.BatchMode) ([]models.BatchURLDataResponse, error) {
 			resp := make([]models.BatchURLDataResponse, len(batch))
 			for i, item := range batch {
 				resp[i] = models.BatchURLDataResponse{
@@ -323,11 +427,99 @@ func TestCreateBatchJSONLinkHandle(t *testing.T) {
 	assert.Equal(t, http.StatusCreated, res.StatusCode)
 }
 
+func TestCreateBatchJSONLinkHandle_BestEffort(t *testing.T) {
+	cfg := &config.Config{BaseURL: "http://localhost"}
+	var gotMode models.BatchMode
+	svc := &mockService{
+		CreateURLBatchFunc: func(ctx context.Context, batch []models.BatchURLData, userID string, mode models.BatchMode) ([]models.BatchURLDataResponse, error) {
+			gotMode = mode
+			return []models.BatchURLDataResponse{
+				{CorrelationID: "1", ShortURL: "abc", Status: models.BatchItemExisting},
+				{CorrelationID: "2", Status: models.BatchItemInvalid, Error: "invalid request"},
+			}, nil
+		},
+	}
+	h, _ := handlers.NewHandler(cfg, svc)
+
+	body := `[{"correlation_id":"1","original_url":"https://a.com"},{"correlation_id":"2"}]`
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten/batch?mode=best_effort", strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), contextkeys.UserIDKey, "user1"))
+	w := httptest.NewRecorder()
+
+	h.CreateBatchJSONLinkHandle(w, req)
+	res := w.Result()
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, models.BatchModeBestEffort, gotMode)
+
+	var resp []models.BatchURLDataResponse
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&resp))
+	assert.Equal(t, "http://localhost/abc", resp[0].ShortURL)
+	assert.Empty(t, resp[1].ShortURL)
+	assert.Equal(t, models.BatchItemInvalid, resp[1].Status)
+}
+
+func TestImportLinksHandle(t *testing.T) {
+	cfg := &config.Config{BaseURL: "http://localhost"}
+	h, _ := handlers.NewHandler(cfg, service.NewService(storage.NewMemoryStorage()))
+
+	body := strings.Join([]string{
+		`{"correlation_id":"1","original_url":"https://a.com"}`,
+		``,
+		`{"correlation_id":"2","original_url":`,
+		`{"request_id":"user-001","title":"Add feature","body":"..."}`,
+		`{"correlation_id":"4","original_url":"https://a.com"}`,
+	}, "\n")
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten/import", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req = req.WithContext(context.WithValue(req.Context(), contextkeys.UserIDKey, "user1"))
+	w := httptest.NewRecorder()
+
+	h.ImportLinksHandle(w, req)
+	res := w.Result()
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "application/x-ndjson", res.Header.Get("Content-Type"))
+
+	var events []models.ImportEvent
+	decoder := json.NewDecoder(res.Body)
+	for decoder.More() {
+		var event models.ImportEvent
+		require.NoError(t, decoder.Decode(&event))
+		events = append(events, event)
+	}
+	require.Len(t, events, 5)
+	assert.Equal(t, models.BatchItemCreated, events[0].Result.Status)
+	assert.True(t, strings.HasPrefix(events[0].Result.ShortURL, "http://localhost/"))
+	assert.Equal(t, models.ImportResult{Record: 2, BatchURLDataResponse: models.BatchURLDataResponse{
+		Status: models.BatchItemInvalid, Error: "invalid import record: malformed JSON",
+	}}, *events[1].Result)
+	assert.Equal(t, 3, events[2].Result.Record)
+	assert.Equal(t, models.BatchItemInvalid, events[2].Result.Status)
+	assert.Equal(t, events[0].Result.ShortURL, events[3].Result.ShortURL)
+	assert.Equal(t, models.BatchItemExisting, events[3].Result.Status)
+	assert.Equal(t, &models.ImportProgress{Processed: 4, Created: 1, Existing: 1, Invalid: 2}, events[4].Progress)
+}
+
+func TestImportLinksHandle_RequiresNDJSON(t *testing.T) {
+	h, _ := handlers.NewHandler(&config.Config{}, &mockService{})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten/import", strings.NewReader(`[]`))
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(context.WithValue(req.Context(), contextkeys.UserIDKey, "user1"))
+	w := httptest.NewRecorder()
+
+	h.ImportLinksHandle(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
 func TestGetLinkHandle_NotFound(t *testing.T) {
 	cfg := &config.Config{}
 	svc := &mockService{
 		GetOriginalURLFunc: func(ctx context.Context, key string) (string, error) {
-			return "", errors.New("url not found")
+			return "", service.ErrNotFound
 		},
 	}
 
@@ -344,3 +536,262 @@ func TestGetLinkHandle_NotFound(t *testing.T) {
 	defer res.Body.Close()
 	assert.Equal(t, http.StatusNotFound, res.StatusCode)
 }
+
+func TestCreateJSONLinkHandle_Alias(t *testing.T) {
+	cfg := &config.Config{BaseURL: "http://localhost"}
+	h, _ := handlers.NewHandler(cfg, service.NewService(storage.NewMemoryStorage()))
+
+	tests := []struct {
+		name       string
+		data       models.URLData
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "created",
+			data:       models.URLData{URL: "https://example.com/sale", Alias: "spring-sale"},
+			wantStatus: http.StatusCreated,
+			wantBody:   "http://localhost/spring-sale",
+		},
+		{
+			name:       "taken",
+			data:       models.URLData{URL: "https://example.com/other", Alias: "spring-sale"},
+			wantStatus: http.StatusConflict,
+			wantBody:   "alias already taken",
+		},
+		{
+			name:       "reserved",
+			data:       models.URLData{URL: "https://example.com/api", Alias: "api"},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "reserved",
+		},
+		{
+			name:       "invalid charset",
+			data:       models.URLData{URL: "https://example.com/space", Alias: "spring sale"},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "invalid alias",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.data)
+			req := httptest.NewRequest(http.MethodPost, "/api/shorten", bytes.NewReader(body))
+			req = req.WithContext(context.WithValue(req.Context(), contextkeys.UserIDKey, "user1"))
+			w := httptest.NewRecorder()
+
+			h.CreateJSONLinkHandle(w, req)
+
+			res := w.Result()
+			defer res.Body.Close()
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+			assert.Contains(t, w.Body.String(), tt.wantBody)
+		})
+	}
+}
+
+func TestGetLinkHandle_Expired(t *testing.T) {
+	cfg := &config.Config{}
+	svc := &mockService{
+		GetOriginalURLFunc: func(ctx context.Context, key string) (string, error) {
+			return "", service.ErrExpired
+		},
+	}
+
+	h, _ := handlers.NewHandler(cfg, svc)
+
+	req := httptest.NewRequest(http.MethodGet, "/spring-sale", nil)
+	ctx := chi.NewRouteContext()
+	ctx.URLParams.Add("key", "spring-sale")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, ctx))
+	w := httptest.NewRecorder()
+
+	h.GetLinkHandle(w, req)
+	res := w.Result()
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusGone, res.StatusCode)
+}
+
+func TestGetLinkStatsHandle(t *testing.T) {
+	ctx := context.Background()
+	st := storage.NewMemoryStorage()
+	_, err := st.Create(ctx, storage.ShortenerURL{ShortURL: "abc", OriginalURL: "https://example.com", UserID: "user1"})
+	assert.NoError(t, err)
+	assert.NoError(t, st.SaveClicks(ctx, []models.Click{
+		{ShortURL: "abc", ClickedAt: time.Now(), IPHash: "a"},
+		{ShortURL: "abc", ClickedAt: time.Now(), IPHash: "a"},
+	}))
+
+	h, _ := handlers.NewHandler(&config.Config{}, service.NewService(st))
+	r := chi.NewRouter()
+	r.Get("/api/user/urls/{key}/stats", h.GetLinkStatsHandle)
+
+	tests := []struct {
+		name       string
+		userID     string
+		query      string
+		wantStatus int
+	}{
+		{name: "owner", userID: "user1", wantStatus: http.StatusOK},
+		{name: "other user", userID: "user2", wantStatus: http.StatusNotFound},
+		{name: "invalid bucket", userID: "user1", query: "?bucket=1s", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/user/urls/abc/stats"+tt.query, nil)
+			req = req.WithContext(context.WithValue(req.Context(), contextkeys.UserIDKey, tt.userID))
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			res := w.Result()
+			defer res.Body.Close()
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var stats models.LinkStats
+			assert.NoError(t, json.NewDecoder(res.Body).Decode(&stats))
+			assert.Equal(t, int64(2), stats.Total)
+			assert.Equal(t, int64(1), stats.UniqueVisitors)
+		})
+	}
+}
+
+func TestGetUserLinksHandle_Pagination(t *testing.T) {
+	ctx := context.Background()
+	st := storage.NewMemoryStorage()
+	for _, key := range []string{"a", "b", "c", "d"} {
+		_, err := st.Create(ctx, storage.ShortenerURL{ShortURL: key, OriginalURL: "https://example.com/" + key, UserID: "user1"})
+		assert.NoError(t, err)
+	}
+	_, err := st.Create(ctx, storage.ShortenerURL{ShortURL: "other", OriginalURL: "https://other.com", UserID: "user1"})
+	assert.NoError(t, err)
+	assert.NoError(t, st.DeleteURLs(ctx, "user1", []string{"b"}))
+
+	h, _ := handlers.NewHandler(&config.Config{BaseURL: "http://localhost"}, service.NewService(st))
+
+	get := func(query string) (*http.Response, []models.ShortURLResponse) {
+		req := httptest.NewRequest(http.MethodGet, "/api/user/urls?"+query, nil)
+		req = req.WithContext(context.WithValue(req.Context(), contextkeys.UserIDKey, "user1"))
+		w := httptest.NewRecorder()
+		h.GetUserLinksHandle(w, req)
+
+		res := w.Result()
+		defer res.Body.Close()
+		var links []models.ShortURLResponse
+		if res.StatusCode == http.StatusOK {
+			assert.NoError(t, json.NewDecoder(res.Body).Decode(&links))
+		}
+		return res, links
+	}
+
+	res, links := get("limit=2&q=example.com")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, []models.ShortURLResponse{
+		{ShortURL: "http://localhost/a", OriginalURL: "https://example.com/a"},
+		{ShortURL: "http://localhost/c", OriginalURL: "https://example.com/c"},
+	}, links)
+	cursor := res.Header.Get("X-Next-Cursor")
+	assert.NotEmpty(t, cursor)
+
+	res, links = get("limit=2&q=example.com&cursor=" + cursor)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Len(t, links, 1)
+	assert.Equal(t, "http://localhost/d", links[0].ShortURL)
+	assert.Empty(t, res.Header.Get("X-Next-Cursor"))
+
+	_, links = get("sort=created_desc&include_deleted=true&limit=4")
+	assert.Len(t, links, 4)
+	assert.Equal(t, "http://localhost/other", links[0].ShortURL)
+	assert.True(t, links[3].IsDeleted)
+
+	res, _ = get("sort=random")
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	for _, limit := range []string{"0", "-1", "1001"} {
+		res, _ = get("limit=" + limit)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, limit)
+	}
+	res, _ = get("cursor=abc")
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestGetUserLinksHandle_DefaultLimit(t *testing.T) {
+	var limits []int
+	svc := &mockService{
+		ListUserURLsFunc: func(ctx context.Context, userID, host string, opts models.ListOptions) ([]models.ShortURLResponse, string, error) {
+			limits = append(limits, opts.Limit)
+			return nil, "", nil
+		},
+	}
+	h, _ := handlers.NewHandler(&config.Config{}, svc)
+
+	for _, target := range []string{"/api/user/urls", "/api/user/urls?cursor=abc", "/api/user/urls?cursor=abc&limit=5"} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req = req.WithContext(context.WithValue(req.Context(), contextkeys.UserIDKey, "user1"))
+		h.GetUserLinksHandle(httptest.NewRecorder(), req)
+	}
+	// без limit и cursor клиенты по-прежнему получают все ссылки
+	assert.Equal(t, []int{0, service.DefaultListLimit, 5}, limits)
+}
+
+func TestUpdateLinkHandle(t *testing.T) {
+	ctx := context.Background()
+	st := storage.NewMemoryStorage()
+	_, err := st.Create(ctx, storage.ShortenerURL{ShortURL: "abc", OriginalURL: "https://a.com", UserID: "user1"})
+	assert.NoError(t, err)
+
+	h, _ := handlers.NewHandler(&config.Config{}, service.NewService(st))
+	r := chi.NewRouter()
+	r.Patch("/api/user/urls/{key}", h.UpdateLinkHandle)
+	r.Get("/api/user/urls/{key}/history", h.GetLinkHistoryHandle)
+	r.Post("/api/user/urls/{key}/rollback", h.RollbackLinkHandle)
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		userID     string
+		body       string
+		wantStatus int
+	}{
+		{name: "update", method: http.MethodPatch, target: "/api/user/urls/abc", userID: "user1", body: `{"original_url":"https://b.com"}`, wantStatus: http.StatusOK},
+		{name: "empty url", method: http.MethodPatch, target: "/api/user/urls/abc", userID: "user1", body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "other user", method: http.MethodPatch, target: "/api/user/urls/abc", userID: "user2", body: `{"original_url":"https://c.com"}`, wantStatus: http.StatusNotFound},
+		{name: "unknown version", method: http.MethodPost, target: "/api/user/urls/abc/rollback", userID: "user1", body: `{"version":5}`, wantStatus: http.StatusNotFound},
+		{name: "rollback", method: http.MethodPost, target: "/api/user/urls/abc/rollback", userID: "user1", body: `{"version":0}`, wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), contextkeys.UserIDKey, tt.userID))
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			res := w.Result()
+			defer res.Body.Close()
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/user/urls/abc/history", nil)
+	req = req.WithContext(context.WithValue(req.Context(), contextkeys.UserIDKey, "user1"))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	res := w.Result()
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	var history []models.URLVersion
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&history))
+	if assert.Len(t, history, 2) {
+		assert.Equal(t, "https://b.com", history[0].OriginalURL)
+		assert.Equal(t, "https://a.com", history[1].OriginalURL)
+	}
+
+	got, err := st.Get(ctx, "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://a.com", got)
+}
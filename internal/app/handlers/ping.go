@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"encoding/json"
 	"net/http"
 
 	"github.com/issafronov/shortener/internal/middleware/logger"
@@ -8,14 +9,18 @@ import (
 )
 
 // Ping - handler для проверки работоспособности сервиса.
+// В теле ответа возвращается состояние пула соединений с базой данных, если он есть.
 func (h *Handler) Ping(w http.ResponseWriter, r *http.Request) {
-	logger.Log.Info("PingHandle", zap.String("url", r.URL.String()))
+	logger.FromContext(r.Context()).Info("PingHandle", zap.String("url", r.URL.String()))
 
-	if err := h.service.Ping(r.Context()); err != nil {
-		logger.Log.Error("service ping failed", zap.Error(err))
+	health, err := h.service.Ping(r.Context())
+	if err != nil {
+		logger.FromContext(r.Context()).Error("service ping failed", zap.Error(err))
 		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(health)
 }
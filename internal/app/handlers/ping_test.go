@@ -9,13 +9,14 @@ import (
 
 	"github.com/issafronov/shortener/internal/app/config"
 	"github.com/issafronov/shortener/internal/app/handlers"
+	"github.com/issafronov/shortener/internal/app/models"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestPing_Success(t *testing.T) {
 	svc := &mockService{
-		PingFunc: func(ctx context.Context) error {
-			return nil
+		PingFunc: func(ctx context.Context) (models.Health, error) {
+			return models.Health{Status: models.HealthOK}, nil
 		},
 	}
 
@@ -34,8 +35,8 @@ func TestPing_Success(t *testing.T) {
 
 func TestPing_Failure(t *testing.T) {
 	svc := &mockService{
-		PingFunc: func(ctx context.Context) error {
-			return errors.New("db connection failed")
+		PingFunc: func(ctx context.Context) (models.Health, error) {
+			return models.Health{}, errors.New("db connection failed")
 		},
 	}
 
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/issafronov/shortener/internal/app/models"
+	"github.com/issafronov/shortener/internal/app/service"
+	"github.com/issafronov/shortener/internal/middleware/logger"
+	"go.uber.org/zap"
+)
+
+// problemContentType — тип содержимого ответа об ошибке по RFC 7807
+const problemContentType = "application/problem+json"
+
+// problemTypePrefix — префикс URI типа ошибки; к нему добавляется код ошибки сервиса
+const problemTypePrefix = "urn:shortener:problem:"
+
+// kindStatus сопоставляет категории ошибок сервиса HTTP-статусам
+var kindStatus = map[service.Kind]int{
+	service.KindNotFound:     http.StatusNotFound,
+	service.KindGone:         http.StatusGone,
+	service.KindConflict:     http.StatusConflict,
+	service.KindInvalid:      http.StatusBadRequest,
+	service.KindUnauthorized: http.StatusUnauthorized,
+	service.KindUnavailable:  http.StatusServiceUnavailable,
+}
+
+// invalidRequest возвращает ошибку некорректного запроса с пояснением
+func invalidRequest(detail string) error {
+	return fmt.Errorf("%w: %s", service.ErrInvalidRequest, detail)
+}
+
+// respondWithError записывает ошибку сервиса в формате application/problem+json.
+// Текст непредвиденных ошибок клиенту не раскрывается, а записывается в журнал.
+func (h *Handler) respondWithError(w http.ResponseWriter, r *http.Request, err error) {
+	problem := models.Problem{
+		Type:     problemTypePrefix + "internal",
+		Title:    http.StatusText(http.StatusInternalServerError),
+		Status:   http.StatusInternalServerError,
+		Instance: r.URL.Path,
+		Code:     "internal",
+	}
+
+	var svcErr *service.Error
+	if errors.As(err, &svcErr) && svcErr.Kind != service.KindInternal {
+		problem.Status = kindStatus[svcErr.Kind]
+		problem.Title = http.StatusText(problem.Status)
+		problem.Type = problemTypePrefix + svcErr.Code
+		problem.Code = svcErr.Code
+		problem.Detail = err.Error()
+	} else {
+		logger.FromContext(r.Context()).Error("request failed", zap.String("path", r.URL.Path), zap.Error(err))
+	}
+
+	if key, ok := service.ConflictKey(err); ok && key != "" {
+		problem.ShortURL = h.buildFullURL(r, key)
+	}
+	if problem.Status == http.StatusServiceUnavailable {
+		w.Header().Set("Retry-After", "1")
+	}
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(problem.Status)
+	_ = json.NewEncoder(w).Encode(problem)
+}
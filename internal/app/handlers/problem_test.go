@@ -0,0 +1,97 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/issafronov/shortener/internal/app/config"
+	"github.com/issafronov/shortener/internal/app/contextkeys"
+	"github.com/issafronov/shortener/internal/app/handlers"
+	"github.com/issafronov/shortener/internal/app/models"
+	"github.com/issafronov/shortener/internal/app/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateJSONLinkHandle_Problems(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "invalid alias", err: service.ErrInvalidAlias, wantStatus: http.StatusBadRequest, wantCode: "invalid_alias"},
+		{name: "alias taken", err: service.ErrAliasTaken, wantStatus: http.StatusConflict, wantCode: "alias_taken"},
+		{name: "unexpected", err: errors.New("connection reset"), wantStatus: http.StatusInternalServerError, wantCode: "internal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &mockService{
+				CreateURLFunc: func(ctx context.Context, originalURL, userID string) (string, error) {
+					return "", tt.err
+				},
+			}
+			h, _ := handlers.NewHandler(&config.Config{BaseURL: "http://localhost"}, svc)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/shorten", bytes.NewReader([]byte(`{"url":"https://example.com"}`)))
+			req = req.WithContext(context.WithValue(req.Context(), contextkeys.UserIDKey, "user1"))
+			w := httptest.NewRecorder()
+
+			h.CreateJSONLinkHandle(w, req)
+			res := w.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+			assert.Equal(t, "application/problem+json", res.Header.Get("Content-Type"))
+
+			var problem models.Problem
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&problem))
+			assert.Equal(t, tt.wantStatus, problem.Status)
+			assert.Equal(t, tt.wantCode, problem.Code)
+			assert.Equal(t, "urn:shortener:problem:"+tt.wantCode, problem.Type)
+			assert.Equal(t, "/api/shorten", problem.Instance)
+			assert.NotContains(t, problem.Detail, "connection reset")
+		})
+	}
+}
+
+func TestCreateLinkHandle_ConflictReturnsExistingLink(t *testing.T) {
+	svc := &mockService{
+		CreateURLFunc: func(ctx context.Context, originalURL, userID string) (string, error) {
+			return "", &service.ConflictError{Key: "existing"}
+		},
+	}
+	h, _ := handlers.NewHandler(&config.Config{BaseURL: "http://localhost"}, svc)
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte("https://example.com")))
+	req = req.WithContext(context.WithValue(req.Context(), contextkeys.UserIDKey, "user1"))
+	w := httptest.NewRecorder()
+
+	h.CreateLinkHandle(w, req)
+	res := w.Result()
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, res.StatusCode)
+	assert.Equal(t, "http://localhost/existing", string(body))
+}
+
+func TestGetJobHandle_Unauthorized(t *testing.T) {
+	h, _ := handlers.NewHandler(&config.Config{}, &mockService{})
+
+	w := httptest.NewRecorder()
+	h.GetJobHandle(w, httptest.NewRequest(http.MethodGet, "/api/user/jobs/abc", nil))
+	res := w.Result()
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	assert.Equal(t, "application/problem+json", res.Header.Get("Content-Type"))
+}
@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor считает gRPC-вызовы и время их обработки по полному имени метода
+func (m *Metrics) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		m.observeGRPC(info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor считает потоковые gRPC-вызовы и время их обработки так же, как UnaryServerInterceptor
+func (m *Metrics) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+
+		m.observeGRPC(info.FullMethod, start, err)
+		return err
+	}
+}
+
+// observeGRPC учитывает завершённый gRPC-вызов
+func (m *Metrics) observeGRPC(method string, start time.Time, err error) {
+	m.grpcRequests.WithLabelValues(method, status.Code(err).String()).Inc()
+	m.grpcDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
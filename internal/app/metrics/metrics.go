@@ -0,0 +1,124 @@
+// Package metrics содержит метрики Prometheus для HTTP и gRPC серверов, хранилища и сервиса
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace — общий префикс имён метрик
+const namespace = "shortener"
+
+// Metrics хранит собственный реестр и коллекторы приложения
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+	grpcRequests *prometheus.CounterVec
+	grpcDuration *prometheus.HistogramVec
+
+	storageDuration *prometheus.HistogramVec
+	storageErrors   *prometheus.CounterVec
+
+	linksCreated prometheus.Counter
+	redirects    prometheus.Counter
+}
+
+// New создаёт набор метрик и регистрирует его в новом реестре
+// вместе со стандартными метриками Go и процесса
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Количество HTTP-запросов по маршруту, методу и коду ответа.",
+		}, []string{"route", "method", "code"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Время обработки HTTP-запросов.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		grpcRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "grpc",
+			Name:      "requests_total",
+			Help:      "Количество gRPC-вызовов по методу и коду статуса.",
+		}, []string{"method", "code"}),
+		grpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "grpc",
+			Name:      "request_duration_seconds",
+			Help:      "Время обработки gRPC-вызовов.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method"}),
+		storageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "storage",
+			Name:      "operation_duration_seconds",
+			Help:      "Время выполнения операций хранилища.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"backend", "operation"}),
+		storageErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "storage",
+			Name:      "errors_total",
+			Help:      "Количество неожиданных ошибок операций хранилища.",
+		}, []string{"backend", "operation"}),
+		linksCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "links_created_total",
+			Help:      "Количество созданных коротких ссылок.",
+		}),
+		redirects: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "redirects_total",
+			Help:      "Количество выполненных перенаправлений.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.grpcRequests,
+		m.grpcDuration,
+		m.storageDuration,
+		m.storageErrors,
+		m.linksCreated,
+		m.redirects,
+	)
+	return m
+}
+
+// Handler возвращает обработчик, отдающий метрики в формате Prometheus
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// LinksCreated увеличивает счётчик созданных ссылок
+func (m *Metrics) LinksCreated(n int) {
+	m.linksCreated.Add(float64(n))
+}
+
+// RedirectServed увеличивает счётчик перенаправлений
+func (m *Metrics) RedirectServed() {
+	m.redirects.Inc()
+}
+
+// RegisterGauge регистрирует метрику, значение которой вычисляется при каждом сборе
+func (m *Metrics) RegisterGauge(name, help string, value func() float64) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, value))
+}
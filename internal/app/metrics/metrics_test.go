@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/issafronov/shortener/internal/app/storage"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestHTTPMiddleware_UsesRoutePattern(t *testing.T) {
+	m := New()
+	r := chi.NewRouter()
+	r.Use(m.HTTPMiddleware)
+	r.Get("/{key}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	})
+
+	for _, path := range []string{"/abc", "/def"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/abc", nil))
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("/{key}", http.MethodGet, "307")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.httpRequests.WithLabelValues(unmatchedRoute, http.MethodPost, "405")))
+	assert.Equal(t, 2, testutil.CollectAndCount(m.httpRequests))
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	m := New()
+	interceptor := m.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/shortener.Shortener/GetOriginalURL"}
+
+	_, err := interceptor(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.NotFound, "not found")
+	})
+	assert.Error(t, err)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.grpcRequests.WithLabelValues(info.FullMethod, codes.NotFound.String())))
+}
+
+// contextStream — серверный поток с заданным контекстом
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s contextStream) Context() context.Context { return s.ctx }
+
+func TestStreamServerInterceptor(t *testing.T) {
+	m := New()
+	interceptor := m.StreamServerInterceptor()
+	info := &grpc.StreamServerInfo{FullMethod: "/shortener.Shortener/ImportURLs", IsClientStream: true}
+	stream := contextStream{ctx: context.Background()}
+
+	for _, want := range []error{nil, status.Error(codes.Unauthenticated, "no token")} {
+		err := interceptor(nil, stream, info, func(srv any, ss grpc.ServerStream) error { return want })
+		assert.Equal(t, want, err)
+	}
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.grpcRequests.WithLabelValues(info.FullMethod, codes.OK.String())))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.grpcRequests.WithLabelValues(info.FullMethod, codes.Unauthenticated.String())))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.grpcDuration))
+}
+
+// failingStorage возвращает заданную ошибку из Get
+type failingStorage struct {
+	storage.Storage
+	err error
+}
+
+func (s failingStorage) Get(ctx context.Context, url string) (string, error) {
+	return "", s.err
+}
+
+func TestStorage_CountsUnexpectedErrors(t *testing.T) {
+	m := New()
+	ctx := context.Background()
+
+	s := NewStorage(failingStorage{err: storage.ErrNotFound}, "memory", m)
+	_, err := s.Get(ctx, "abc")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.storageErrors.WithLabelValues("memory", "get")))
+
+	s = NewStorage(failingStorage{err: errors.New("connection reset")}, "memory", m)
+	_, err = s.Get(ctx, "abc")
+	assert.Error(t, err)
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.storageErrors.WithLabelValues("memory", "get")))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.storageDuration))
+}
+
+func TestHandler_ExposesMetrics(t *testing.T) {
+	m := New()
+	m.LinksCreated(3)
+	m.RedirectServed()
+	m.RegisterGauge("delete_queue_depth", "test", func() float64 { return 7 })
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body, err := io.ReadAll(w.Result().Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "shortener_links_created_total 3")
+	assert.Contains(t, string(body), "shortener_redirects_total 1")
+	assert.Contains(t, string(body), "shortener_delete_queue_depth 7")
+}
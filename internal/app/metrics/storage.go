@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/issafronov/shortener/internal/app/models"
+	"github.com/issafronov/shortener/internal/app/storage"
+)
+
+var _ storage.Storage = (*Storage)(nil)
+
+// Storage оборачивает хранилище и замеряет время и ошибки каждой операции
+type Storage struct {
+	next    storage.Storage
+	backend string
+	metrics *Metrics
+}
+
+// NewStorage возвращает хранилище с замером операций; backend используется как метка метрик
+func NewStorage(next storage.Storage, backend string, m *Metrics) *Storage {
+	return &Storage{next: next, backend: backend, metrics: m}
+}
+
+// Unwrap возвращает исходное хранилище
+func (s *Storage) Unwrap() storage.Storage {
+	return s.next
+}
+
+// observe записывает длительность операции и неожиданную ошибку.
+// Ошибки, описывающие состояние ссылки (конфликт, не найдена, удалена), ошибками хранилища не считаются.
+func (s *Storage) observe(op string, start time.Time, err error) {
+	s.metrics.storageDuration.WithLabelValues(s.backend, op).Observe(time.Since(start).Seconds())
+	if err != nil && !storage.IsExpectedError(err) {
+		s.metrics.storageErrors.WithLabelValues(s.backend, op).Inc()
+	}
+}
+
+// Create сохраняет ссылку
+func (s *Storage) Create(ctx context.Context, url storage.ShortenerURL) (key string, err error) {
+	defer func(start time.Time) { s.observe("create", start, err) }(time.Now())
+	return s.next.Create(ctx, url)
+}
+
+// CreateBatch атомарно сохраняет пакет записей
+func (s *Storage) CreateBatch(ctx context.Context, urls []storage.ShortenerURL) (err error) {
+	defer func(start time.Time) { s.observe("create_batch", start, err) }(time.Now())
+	return s.next.CreateBatch(ctx, urls)
+}
+
+// Get возвращает оригинальный URL по короткому ключу
+func (s *Storage) Get(ctx context.Context, url string) (originalURL string, err error) {
+	defer func(start time.Time) { s.observe("get", start, err) }(time.Now())
+	return s.next.Get(ctx, url)
+}
+
+// GetByUser возвращает ссылки пользователя
+func (s *Storage) GetByUser(ctx context.Context, username string) (urls []models.ShortURLResponse, err error) {
+	defer func(start time.Time) { s.observe("get_by_user", start, err) }(time.Now())
+	return s.next.GetByUser(ctx, username)
+}
+
+// Ping проверяет доступность хранилища
+func (s *Storage) Ping(ctx context.Context) (err error) {
+	defer func(start time.Time) { s.observe("ping", start, err) }(time.Now())
+	return s.next.Ping(ctx)
+}
+
+// DeleteURLs помечает ссылки пользователя удалёнными
+func (s *Storage) DeleteURLs(ctx context.Context, userID string, urls []string) (err error) {
+	defer func(start time.Time) { s.observe("delete_urls", start, err) }(time.Now())
+	return s.next.DeleteURLs(ctx, userID, urls)
+}
+
+// DeleteURLsBatch помечает удалёнными ссылки нескольких пользователей
+func (s *Storage) DeleteURLsBatch(ctx context.Context, reqs []models.DeleteRequest) (results []models.DeleteOutcomes, err error) {
+	defer func(start time.Time) { s.observe("delete_urls_batch", start, err) }(time.Now())
+	return s.next.DeleteURLsBatch(ctx, reqs)
+}
+
+// CountURLs возвращает количество не удалённых ссылок
+func (s *Storage) CountURLs(ctx context.Context) (count int64, err error) {
+	defer func(start time.Time) { s.observe("count_urls", start, err) }(time.Now())
+	return s.next.CountURLs(ctx)
+}
+
+// CountUsers возвращает количество пользователей
+func (s *Storage) CountUsers(ctx context.Context) (count int64, err error) {
+	defer func(start time.Time) { s.observe("count_users", start, err) }(time.Now())
+	return s.next.CountUsers(ctx)
+}
+
+// Scan возвращает страницу записей после курсора
+func (s *Storage) Scan(ctx context.Context, cursor string, limit int) (urls []storage.ShortenerURL, next string, err error) {
+	defer func(start time.Time) { s.observe("scan", start, err) }(time.Now())
+	return s.next.Scan(ctx, cursor, limit)
+}
+
+// Import сохраняет записи как есть
+func (s *Storage) Import(ctx context.Context, urls []storage.ShortenerURL) (imported int, err error) {
+	defer func(start time.Time) { s.observe("import", start, err) }(time.Now())
+	return s.next.Import(ctx, urls)
+}
+
+// PurgeExpired удаляет ссылки с истёкшим сроком действия
+func (s *Storage) PurgeExpired(ctx context.Context, now time.Time) (purged int64, err error) {
+	defer func(start time.Time) { s.observe("purge_expired", start, err) }(time.Now())
+	return s.next.PurgeExpired(ctx, now)
+}
+
+// ListByUser возвращает страницу ссылок пользователя
+func (s *Storage) ListByUser(ctx context.Context, userID string, opts models.ListOptions) (urls []storage.ShortenerURL, next string, err error) {
+	defer func(start time.Time) { s.observe("list_by_user", start, err) }(time.Now())
+	return s.next.ListByUser(ctx, userID, opts)
+}
+
+// UpdateURL меняет оригинальный URL ссылки
+func (s *Storage) UpdateURL(ctx context.Context, userID, key, originalURL string) (version models.URLVersion, err error) {
+	defer func(start time.Time) { s.observe("update_url", start, err) }(time.Now())
+	return s.next.UpdateURL(ctx, userID, key, originalURL)
+}
+
+// URLHistory возвращает историю изменений ссылки
+func (s *Storage) URLHistory(ctx context.Context, key string) (history []models.URLVersion, err error) {
+	defer func(start time.Time) { s.observe("url_history", start, err) }(time.Now())
+	return s.next.URLHistory(ctx, key)
+}
+
+// GetURL возвращает запись по короткому ключу
+func (s *Storage) GetURL(ctx context.Context, key string) (url storage.ShortenerURL, err error) {
+	defer func(start time.Time) { s.observe("get_url", start, err) }(time.Now())
+	return s.next.GetURL(ctx, key)
+}
+
+// SaveClicks сохраняет пачку переходов
+func (s *Storage) SaveClicks(ctx context.Context, clicks []models.Click) (err error) {
+	defer func(start time.Time) { s.observe("save_clicks", start, err) }(time.Now())
+	return s.next.SaveClicks(ctx, clicks)
+}
+
+// ClickStats возвращает статистику переходов по ссылке
+func (s *Storage) ClickStats(ctx context.Context, key string, bucket time.Duration) (stats models.LinkStats, err error) {
+	defer func(start time.Time) { s.observe("click_stats", start, err) }(time.Now())
+	return s.next.ClickStats(ctx, key, bucket)
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Click описывает один переход по сокращённой ссылке
+type Click struct {
+	ShortURL  string    `json:"short_url"`
+	ClickedAt time.Time `json:"clicked_at"`
+	Referrer  string    `json:"referrer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	// IPHash — хэш IP-адреса клиента с солью, сам адрес не сохраняется
+	IPHash string `json:"ip_hash,omitempty"`
+}
+
+// StatsBucket — количество переходов за интервал гистограммы
+type StatsBucket struct {
+	Start time.Time `json:"start"`
+	Count int64     `json:"count"`
+}
+
+// LinkStats содержит статистику переходов по ссылке
+type LinkStats struct {
+	ShortURL       string        `json:"short_url"`
+	Total          int64         `json:"total"`
+	UniqueVisitors int64         `json:"unique_visitors"`
+	Histogram      []StatsBucket `json:"histogram"`
+}
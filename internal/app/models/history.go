@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// URLVersion — запись истории изменений оригинального URL короткой ссылки.
+// Версия 0 соответствует адресу, с которым ссылка была создана; каждая правка
+// получает следующий номер версии.
+type URLVersion struct {
+	Version     int       `json:"version"`
+	OriginalURL string    `json:"original_url"`
+	PreviousURL string    `json:"previous_url"`
+	ChangedBy   string    `json:"changed_by"`
+	ChangedAt   time.Time `json:"changed_at"`
+}
+
+// UpdateURLRequest — тело запроса на изменение оригинального URL ссылки
+type UpdateURLRequest struct {
+	OriginalURL string `json:"original_url"`
+}
+
+// RollbackURLRequest — тело запроса на откат ссылки к одной из прежних версий
+type RollbackURLRequest struct {
+	Version int `json:"version"`
+}
@@ -1,8 +1,16 @@
 package models
 
+import "time"
+
 // URLData представляет входную структуру для сокращения URL
 type URLData struct {
 	URL string `json:"url"`
+	// Alias — необязательный пользовательский короткий ключ
+	Alias string `json:"alias,omitempty"`
+	// ExpiresAt — необязательный момент истечения срока действия ссылки
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// TTL — необязательный срок действия ссылки в секундах
+	TTL int64 `json:"ttl,omitempty"`
 }
 
 // ShortURLData представляет выходную структуру после создания короткой ссылки
@@ -15,6 +23,35 @@ type ShortURLData struct {
 type ShortURLResponse struct {
 	ShortURL    string `json:"short_url"`
 	OriginalURL string `json:"original_url"`
+	// IsDeleted заполняется только при запросе списка с удалёнными ссылками
+	IsDeleted bool `json:"is_deleted,omitempty"`
+}
+
+// Порядок сортировки списка ссылок пользователя
+const (
+	// SortCreatedAsc — от старых к новым
+	SortCreatedAsc = "created_asc"
+	// SortCreatedDesc — от новых к старым
+	SortCreatedDesc = "created_desc"
+)
+
+// ListOptions задаёт страницу, фильтр и порядок списка ссылок пользователя
+type ListOptions struct {
+	// Limit — максимальное количество ссылок на странице, 0 — без ограничения
+	Limit int
+	// Cursor — курсор, полученный вместе с предыдущей страницей
+	Cursor string
+	// Query — подстрока, которую должен содержать оригинальный URL
+	Query string
+	// Sort — порядок по времени создания: SortCreatedAsc (по умолчанию) или SortCreatedDesc
+	Sort string
+	// IncludeDeleted включает в список удалённые ссылки
+	IncludeDeleted bool
+}
+
+// Descending сообщает, что список нужно отдавать от новых ссылок к старым
+func (o ListOptions) Descending() bool {
+	return o.Sort == SortCreatedDesc
 }
 
 // BatchURLData используется для пакетной отправки ссылок на сокращение
@@ -22,11 +59,174 @@ type ShortURLResponse struct {
 type BatchURLData struct {
 	CorrelationID string `json:"correlation_id"`
 	OriginalURL   string `json:"original_url"`
+	// Alias — необязательный пользовательский короткий ключ
+	Alias string `json:"alias,omitempty"`
+	// ExpiresAt — необязательный момент истечения срока действия ссылки
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// TTL — необязательный срок действия ссылки в секундах
+	TTL int64 `json:"ttl,omitempty"`
 }
 
 // BatchURLDataResponse используется для ответа на пакетную обработку ссылок
 // Каждая запись содержит correlation_id и соответствующий короткий URL
 type BatchURLDataResponse struct {
 	CorrelationID string `json:"correlation_id"`
-	ShortURL      string `json:"short_url"`
+	ShortURL      string `json:"short_url,omitempty"`
+	// Status — результат обработки записи
+	Status BatchItemStatus `json:"status,omitempty"`
+	// Error — причина, по которой запись не обработана, для статуса invalid
+	Error string `json:"error,omitempty"`
+}
+
+// BatchMode — режим пакетного создания ссылок
+type BatchMode string
+
+// Режимы пакетного создания ссылок
+const (
+	// BatchModeAtomic — пакет сохраняется целиком или не сохраняется вовсе
+	BatchModeAtomic BatchMode = "atomic"
+	// BatchModeBestEffort — записи обрабатываются независимо, результат возвращается по каждой
+	BatchModeBestEffort BatchMode = "best_effort"
+	// BatchModeImport — записи обрабатываются как в best_effort, но correlation_id необязателен:
+	// результаты потокового импорта сопоставляются с записями по их номеру в потоке
+	BatchModeImport BatchMode = "import"
+)
+
+// BatchItemStatus — результат обработки одной записи пакета
+type BatchItemStatus string
+
+// Результаты обработки записи пакета
+const (
+	// BatchItemCreated — создана новая ссылка
+	BatchItemCreated BatchItemStatus = "created"
+	// BatchItemExisting — оригинальный URL уже сокращён, возвращена существующая ссылка
+	BatchItemExisting BatchItemStatus = "existing"
+	// BatchItemInvalid — запись некорректна и не сохранена
+	BatchItemInvalid BatchItemStatus = "invalid"
+)
+
+// ImportResult — результат импорта одной записи потока
+type ImportResult struct {
+	// Record — порядковый номер записи в потоке, начиная с 1
+	Record int `json:"record"`
+	BatchURLDataResponse
+}
+
+// ImportProgress — сводка потокового импорта по уже обработанным записям
+type ImportProgress struct {
+	Processed int `json:"processed"`
+	Created   int `json:"created"`
+	Existing  int `json:"existing"`
+	Invalid   int `json:"invalid"`
+}
+
+// ImportEvent — сообщение потокового импорта: результат записи, прогресс после пачки
+// или ошибка, прервавшая импорт
+type ImportEvent struct {
+	Result   *ImportResult   `json:"result,omitempty"`
+	Progress *ImportProgress `json:"progress,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// DeleteRequest — запрос пользователя на удаление его ссылок
+type DeleteRequest struct {
+	UserID    string
+	ShortURLs []string
+}
+
+// DeleteOutcome — результат удаления одной ссылки
+type DeleteOutcome string
+
+// Результаты удаления ссылки
+const (
+	DeleteOutcomeDeleted  DeleteOutcome = "deleted"
+	DeleteOutcomeNotFound DeleteOutcome = "not_found"
+	DeleteOutcomeNotOwned DeleteOutcome = "not_owned"
+)
+
+// DeleteOutcomes — результаты удаления по коротким ключам
+type DeleteOutcomes map[string]DeleteOutcome
+
+// JobStatus — состояние фоновой задачи
+type JobStatus string
+
+// Состояния задачи удаления
+const (
+	JobPending JobStatus = "pending"
+	JobRunning JobStatus = "running"
+	JobDone    JobStatus = "done"
+	JobFailed  JobStatus = "failed"
+)
+
+// DeleteJob — задача удаления ссылок пользователя
+type DeleteJob struct {
+	ID        string         `json:"id"`
+	UserID    string         `json:"-"`
+	Status    JobStatus      `json:"status"`
+	Results   DeleteOutcomes `json:"results,omitempty"`
+	Error     string         `json:"error,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// DeleteJobResponse — ответ на запрос удаления ссылок
+type DeleteJobResponse struct {
+	JobID string `json:"job_id"`
+}
+
+// Stats — общая статистика сервиса
+type Stats struct {
+	URLs  int64 `json:"urls"`
+	Users int64 `json:"users"`
+	// Cache — статистика кэша ссылок, nil если кэш отключён
+	Cache *CacheStats `json:"cache,omitempty"`
+}
+
+// CacheStats — счётчики кэша ссылок
+type CacheStats struct {
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+	Entries int   `json:"entries"`
+}
+
+// Health — результат проверки работоспособности сервиса
+type Health struct {
+	// Status — ok или saturated, если заняты все соединения пула
+	Status string `json:"status"`
+	// Pool — состояние пула соединений с базой данных, nil для хранилищ без пула
+	Pool *PoolStats `json:"pool,omitempty"`
+}
+
+// Состояния сервиса в Health
+const (
+	HealthOK        = "ok"
+	HealthSaturated = "saturated"
+)
+
+// PoolStats — состояние пула соединений с базой данных
+type PoolStats struct {
+	MaxOpen        int   `json:"max_open"`
+	Open           int   `json:"open"`
+	InUse          int   `json:"in_use"`
+	Idle           int   `json:"idle"`
+	WaitCount      int64 `json:"wait_count"`
+	WaitDurationMS int64 `json:"wait_duration_ms"`
+}
+
+// Problem — описание ошибки HTTP API в формате RFC 7807 (application/problem+json)
+type Problem struct {
+	// Type — URI, идентифицирующий тип ошибки
+	Type string `json:"type"`
+	// Title — краткое описание типа ошибки
+	Title string `json:"title"`
+	// Status — HTTP-статус ответа
+	Status int `json:"status"`
+	// Detail — описание конкретного случая ошибки
+	Detail string `json:"detail,omitempty"`
+	// Instance — путь запроса, при обработке которого возникла ошибка
+	Instance string `json:"instance,omitempty"`
+	// Code — машиночитаемый код ошибки сервиса
+	Code string `json:"code"`
+	// ShortURL — существующая сокращённая ссылка при конфликте оригинального URL
+	ShortURL string `json:"short_url,omitempty"`
 }
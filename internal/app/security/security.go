@@ -1,6 +1,8 @@
 package security
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"time"
 
@@ -17,6 +19,12 @@ type Claims struct {
 
 const exp = time.Hour * 24
 
+// ErrTokenExpired возвращается, если срок действия JWT-токена истёк
+var ErrTokenExpired = errors.New("token expired")
+
+// ErrInvalidToken возвращается, если подпись или содержимое JWT-токена недействительны
+var ErrInvalidToken = errors.New("invalid token")
+
 // GenerateJWT создает JWT-токен для указанного userID
 func GenerateJWT(userID string) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
@@ -26,16 +34,38 @@ func GenerateJWT(userID string) (string, error) {
 		UserID: userID,
 	})
 
-	key := os.Getenv("SECRET_KEY")
-	if key == "" {
-		logger.Log.Info("SECRET_KEY environment variable not set")
-		key = "secret"
-	}
-
-	tokenString, err := token.SignedString([]byte(key))
+	tokenString, err := token.SignedString(secretKey())
 	if err != nil {
 		return "", err
 	}
 
 	return tokenString, nil
 }
+
+// ParseJWT проверяет подпись и срок действия JWT-токена и возвращает его claims
+func ParseJWT(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return secretKey(), nil
+	})
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrTokenExpired
+		}
+		return nil, fmt.Errorf("parse token: %w", err)
+	}
+	if !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// secretKey возвращает ключ подписи токенов из переменной окружения SECRET_KEY
+func secretKey() []byte {
+	key := os.Getenv("SECRET_KEY")
+	if key == "" {
+		logger.Log.Info("SECRET_KEY environment variable not set")
+		key = "secret"
+	}
+	return []byte(key)
+}
@@ -49,3 +49,26 @@ func TestGenerateJWT_DefaultSecret(t *testing.T) {
 	assert.True(t, ok)
 	assert.Equal(t, userID, claims.UserID)
 }
+
+func TestParseJWT(t *testing.T) {
+	t.Setenv("SECRET_KEY", "testsecret")
+
+	tokenStr, err := GenerateJWT("user1")
+	assert.NoError(t, err)
+
+	claims, err := ParseJWT(tokenStr)
+	assert.NoError(t, err)
+	assert.Equal(t, "user1", claims.UserID)
+
+	expired, err := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute))},
+		UserID:           "user1",
+	}).SignedString([]byte("testsecret"))
+	assert.NoError(t, err)
+	_, err = ParseJWT(expired)
+	assert.ErrorIs(t, err, ErrTokenExpired)
+
+	t.Setenv("SECRET_KEY", "othersecret")
+	_, err = ParseJWT(tokenStr)
+	assert.Error(t, err)
+}
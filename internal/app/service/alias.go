@@ -0,0 +1,45 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Ограничения на пользовательский короткий ключ
+const (
+	minAliasLength = 3
+	maxAliasLength = 64
+)
+
+// reservedAliases — зарезервированные ключи, совпадающие с маршрутами сервиса
+var reservedAliases = map[string]struct{}{
+	"api":      {},
+	"ping":     {},
+	"debug":    {},
+	"internal": {},
+	"metrics":  {},
+}
+
+// ValidateAlias проверяет пользовательский короткий ключ: длину, допустимые символы
+// (латинские буквы, цифры, '-' и '_') и отсутствие среди зарезервированных слов
+func ValidateAlias(alias string) error {
+	if len(alias) < minAliasLength || len(alias) > maxAliasLength {
+		return fmt.Errorf("%w: length must be between %d and %d", ErrInvalidAlias, minAliasLength, maxAliasLength)
+	}
+	for _, c := range alias {
+		if !isAliasChar(c) {
+			return fmt.Errorf("%w: unexpected character %q", ErrInvalidAlias, c)
+		}
+	}
+	if _, reserved := reservedAliases[strings.ToLower(alias)]; reserved {
+		return fmt.Errorf("%w: %q is reserved", ErrInvalidAlias, alias)
+	}
+	return nil
+}
+
+func isAliasChar(c rune) bool {
+	return c >= 'a' && c <= 'z' ||
+		c >= 'A' && c <= 'Z' ||
+		c >= '0' && c <= '9' ||
+		c == '-' || c == '_'
+}
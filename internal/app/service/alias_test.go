@@ -0,0 +1,34 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateAlias(t *testing.T) {
+	tests := []struct {
+		name    string
+		alias   string
+		wantErr bool
+	}{
+		{name: "valid", alias: "spring-sale_2025"},
+		{name: "too short", alias: "ab", wantErr: true},
+		{name: "too long", alias: strings.Repeat("a", maxAliasLength+1), wantErr: true},
+		{name: "slash", alias: "spring/sale", wantErr: true},
+		{name: "unicode", alias: "распродажа", wantErr: true},
+		{name: "reserved", alias: "ping", wantErr: true},
+		{name: "reserved case insensitive", alias: "API", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAlias(tt.alias)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidAlias)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
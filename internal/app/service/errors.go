@@ -0,0 +1,150 @@
+package service
+
+import "errors"
+
+// Kind — категория ошибки сервиса, по которой транспорт выбирает код ответа
+type Kind int
+
+// Категории ошибок сервиса
+const (
+	// KindInternal — непредвиденная ошибка, в том числе любая ошибка не из таксономии сервиса
+	KindInternal Kind = iota
+	// KindNotFound — ссылка, версия или задача не найдены либо принадлежат другому пользователю
+	KindNotFound
+	// KindGone — ссылка была, но удалена или истёк её срок действия
+	KindGone
+	// KindConflict — ресурс уже существует
+	KindConflict
+	// KindInvalid — некорректные входные данные
+	KindInvalid
+	// KindUnauthorized — вызывающий не аутентифицирован
+	KindUnauthorized
+	// KindUnavailable — операция временно недоступна, её можно повторить позже
+	KindUnavailable
+)
+
+// String возвращает имя категории
+func (k Kind) String() string {
+	switch k {
+	case KindNotFound:
+		return "not_found"
+	case KindGone:
+		return "gone"
+	case KindConflict:
+		return "conflict"
+	case KindInvalid:
+		return "invalid"
+	case KindUnauthorized:
+		return "unauthorized"
+	case KindUnavailable:
+		return "unavailable"
+	default:
+		return "internal"
+	}
+}
+
+// Error — ошибка сервиса с категорией и машиночитаемым кодом.
+// Сторожевые ошибки пакета имеют этот тип, поэтому их можно сравнивать через errors.Is
+// и получать категорию через errors.As или KindOf.
+type Error struct {
+	// Kind — категория ошибки
+	Kind Kind
+	// Code — стабильный машиночитаемый код ошибки, например url_not_found
+	Code string
+
+	msg string
+}
+
+// newError создаёт сторожевую ошибку сервиса
+func newError(kind Kind, code, msg string) *Error {
+	return &Error{Kind: kind, Code: code, msg: msg}
+}
+
+// Error возвращает текст ошибки
+func (e *Error) Error() string {
+	return e.msg
+}
+
+// ConflictError сообщает, что оригинальный URL уже сокращён.
+// Key содержит короткий ключ существующей ссылки, чтобы клиент мог её использовать.
+type ConflictError struct {
+	Key string
+}
+
+// Error возвращает текст ошибки
+func (e *ConflictError) Error() string {
+	return ErrConflict.Error()
+}
+
+// Unwrap позволяет сопоставлять ошибку с ErrConflict
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+// KindOf возвращает категорию ошибки сервиса из цепочки err; для прочих ошибок — KindInternal
+func KindOf(err error) Kind {
+	var svcErr *Error
+	if errors.As(err, &svcErr) {
+		return svcErr.Kind
+	}
+	return KindInternal
+}
+
+// ConflictKey возвращает короткий ключ существующей ссылки, если err — конфликт оригинального URL
+func ConflictKey(err error) (string, bool) {
+	var conflict *ConflictError
+	if errors.As(err, &conflict) {
+		return conflict.Key, true
+	}
+	return "", false
+}
+
+// ErrNotFound возвращается, если ссылка не найдена или не принадлежит пользователю
+var ErrNotFound = newError(KindNotFound, "url_not_found", "url not found")
+
+// ErrJobNotFound возвращается, если задача не найдена или создана другим пользователем
+var ErrJobNotFound = newError(KindNotFound, "job_not_found", "job not found")
+
+// ErrVersionNotFound возвращается, если в истории ссылки нет запрошенной версии
+var ErrVersionNotFound = newError(KindNotFound, "version_not_found", "url version not found")
+
+// ErrDeleted возвращается, если ссылка удалена
+var ErrDeleted = newError(KindGone, "url_deleted", "url gone")
+
+// ErrExpired возвращается, если срок действия ссылки истёк
+var ErrExpired = newError(KindGone, "url_expired", "url expired")
+
+// ErrConflict возвращается, если оригинальный URL уже сокращён.
+// При создании ссылки ошибка приходит как *ConflictError с ключом существующей ссылки.
+var ErrConflict = newError(KindConflict, "url_conflict", "url conflict")
+
+// ErrAliasTaken возвращается, если пользовательский короткий ключ уже занят
+var ErrAliasTaken = newError(KindConflict, "alias_taken", "alias already taken")
+
+// ErrInvalidAlias возвращается, если пользовательский короткий ключ не прошёл проверку
+var ErrInvalidAlias = newError(KindInvalid, "invalid_alias", "invalid alias")
+
+// ErrInvalidExpiry возвращается, если срок действия ссылки задан некорректно
+var ErrInvalidExpiry = newError(KindInvalid, "invalid_expiry", "invalid expiry")
+
+// ErrInvalidListOptions возвращается при некорректных параметрах списка ссылок
+var ErrInvalidListOptions = newError(KindInvalid, "invalid_list_options", "invalid list options")
+
+// ErrInvalidRequest возвращается, если запрос не удалось разобрать или в нём нет обязательных полей
+var ErrInvalidRequest = newError(KindInvalid, "invalid_request", "invalid request")
+
+// ErrInvalidRecord возвращается, если запись потокового импорта не удалось разобрать
+var ErrInvalidRecord = newError(KindInvalid, "invalid_record", "invalid import record")
+
+// ErrUnauthorized возвращается, если вызывающий не аутентифицирован
+var ErrUnauthorized = newError(KindUnauthorized, "unauthorized", "unauthorized")
+
+// ErrDeleteUnavailable возвращается, если очередь удаления не может принять запрос
+var ErrDeleteUnavailable = newError(KindUnavailable, "delete_unavailable", "delete queue unavailable")
+
+// ErrConcurrentChange возвращается, если ссылку не удалось сохранить из-за одновременного
+// удаления конфликтующей ссылки; запрос можно повторить
+var ErrConcurrentChange = newError(KindUnavailable, "concurrent_change", "conflicting url changed concurrently")
+
+// ErrKeyExhausted возвращается, если за отведённое число попыток не удалось сгенерировать свободный ключ
+var ErrKeyExhausted = newError(KindInternal, "key_exhausted", "failed to generate unique short key")
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/issafronov/shortener/internal/app/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKindOf(t *testing.T) {
+	assert.Equal(t, KindNotFound, KindOf(ErrNotFound))
+	assert.Equal(t, KindGone, KindOf(fmt.Errorf("wrapped: %w", ErrExpired)))
+	assert.Equal(t, KindConflict, KindOf(&ConflictError{Key: "abc"}))
+	assert.Equal(t, KindInvalid, KindOf(fmt.Errorf("%w: bad", ErrInvalidAlias)))
+	assert.Equal(t, KindUnauthorized, KindOf(ErrUnauthorized))
+	assert.Equal(t, KindInternal, KindOf(errors.New("connection reset")))
+}
+
+func TestCreateURL_ConflictReturnsExistingKey(t *testing.T) {
+	ctx := context.Background()
+	st, err := storage.NewBoltStorage(filepath.Join(t.TempDir(), "shortener.db"))
+	require.NoError(t, err)
+	defer st.Close()
+
+	svc := NewService(st, WithKeyGenerator(fixedKeys{"first", "second"}))
+	key, err := svc.CreateURL(ctx, "https://example.com", "user1")
+	require.NoError(t, err)
+
+	_, err = svc.CreateURL(ctx, "https://example.com", "user2")
+	assert.ErrorIs(t, err, ErrConflict)
+	existing, ok := ConflictKey(err)
+	assert.True(t, ok)
+	assert.Equal(t, key, existing)
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/issafronov/shortener/internal/app/models"
+)
+
+// DefaultImportChunkSize — число записей потокового импорта, сохраняемых одной пачкой
+const DefaultImportChunkSize = 500
+
+// ImportSource возвращает очередную запись потока импорта.
+// io.EOF означает конец потока, ошибка ErrInvalidRecord — запись не удалось разобрать,
+// и импорт продолжается со следующей. Прочие ошибки прерывают импорт.
+type ImportSource func() (models.BatchURLData, error)
+
+// ImportSink получает результаты по записям и прогресс импорта.
+// Ошибка прерывает импорт, например если клиент отключился.
+type ImportSink func(models.ImportEvent) error
+
+// importRecord — прочитанная, но ещё не сохранённая запись потока
+type importRecord struct {
+	number int
+	data   models.BatchURLData
+	err    error
+}
+
+// Import читает записи из source и сохраняет их пачками по chunkSize в режиме import:
+// как best_effort, но correlation_id записей необязателен.
+// После сохранения каждой пачки в sink передаются результаты по её записям и прогресс.
+// Возвращает итоговый прогресс, в том числе при ошибке.
+func Import(ctx context.Context, svc Service, userID string, chunkSize int, source ImportSource, sink ImportSink) (models.ImportProgress, error) {
+	if chunkSize <= 0 {
+		chunkSize = DefaultImportChunkSize
+	}
+
+	var progress models.ImportProgress
+	chunk := make([]importRecord, 0, chunkSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return progress, err
+		}
+
+		data, err := source()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil && !errors.Is(err, ErrInvalidRecord) {
+			return progress, err
+		}
+
+		chunk = append(chunk, importRecord{number: progress.Processed + len(chunk) + 1, data: data, err: err})
+		if len(chunk) < chunkSize {
+			continue
+		}
+		if err := importChunk(ctx, svc, userID, chunk, &progress, sink); err != nil {
+			return progress, err
+		}
+		chunk = chunk[:0]
+	}
+
+	if len(chunk) > 0 {
+		if err := importChunk(ctx, svc, userID, chunk, &progress, sink); err != nil {
+			return progress, err
+		}
+	}
+	return progress, nil
+}
+
+// importChunk сохраняет пачку записей и передаёт в sink результат по каждой из них и прогресс
+func importChunk(ctx context.Context, svc Service, userID string, chunk []importRecord, progress *models.ImportProgress, sink ImportSink) error {
+	batch := make([]models.BatchURLData, 0, len(chunk))
+	for _, rec := range chunk {
+		if rec.err == nil {
+			batch = append(batch, rec.data)
+		}
+	}
+
+	var responses []models.BatchURLDataResponse
+	if len(batch) > 0 {
+		var err error
+		responses, err = svc.CreateURLBatch(ctx, batch, userID, models.BatchModeImport)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, rec := range chunk {
+		result := &models.ImportResult{Record: rec.number}
+		if rec.err != nil {
+			result.CorrelationID = rec.data.CorrelationID
+			result.Status, result.Error = models.BatchItemInvalid, rec.err.Error()
+		} else {
+			result.BatchURLDataResponse, responses = responses[0], responses[1:]
+		}
+
+		progress.Processed++
+		switch result.Status {
+		case models.BatchItemCreated:
+			progress.Created++
+		case models.BatchItemExisting:
+			progress.Existing++
+		case models.BatchItemInvalid:
+			progress.Invalid++
+		}
+		if err := sink(models.ImportEvent{Result: result}); err != nil {
+			return err
+		}
+	}
+
+	snapshot := *progress
+	return sink(models.ImportEvent{Progress: &snapshot})
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/issafronov/shortener/internal/app/models"
+	"github.com/issafronov/shortener/internal/app/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sliceSource возвращает источник импорта, отдающий записи по порядку;
+// пустая запись считается неразобранной
+func sliceSource(records []models.BatchURLData) ImportSource {
+	return func() (models.BatchURLData, error) {
+		if len(records) == 0 {
+			return models.BatchURLData{}, io.EOF
+		}
+		rec := records[0]
+		records = records[1:]
+		if rec == (models.BatchURLData{}) {
+			return rec, fmt.Errorf("%w: malformed JSON", ErrInvalidRecord)
+		}
+		return rec, nil
+	}
+}
+
+func TestImport(t *testing.T) {
+	ctx := context.Background()
+	st := storage.NewMemoryStorage()
+	_, err := st.Create(ctx, storage.ShortenerURL{ShortURL: "abc", OriginalURL: "https://a.com", UserID: "user1"})
+	require.NoError(t, err)
+	svc := NewService(st)
+
+	var events []models.ImportEvent
+	progress, err := Import(ctx, svc, "user1", 2, sliceSource([]models.BatchURLData{
+		{CorrelationID: "1", OriginalURL: "https://new.com"},
+		{},
+		{CorrelationID: "3", OriginalURL: "https://a.com"},
+		{CorrelationID: "4", OriginalURL: "https://other.com"},
+		{CorrelationID: "5"},
+	}), func(event models.ImportEvent) error {
+		events = append(events, event)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.ImportProgress{Processed: 5, Created: 2, Existing: 1, Invalid: 2}, progress)
+
+	// три пачки: по две записи, затем одна, и прогресс после каждой
+	require.Len(t, events, 8)
+	assert.Equal(t, 1, events[0].Result.Record)
+	assert.Equal(t, models.BatchItemCreated, events[0].Result.Status)
+	assert.Equal(t, models.BatchItemInvalid, events[1].Result.Status)
+	assert.Contains(t, events[1].Result.Error, "malformed JSON")
+	assert.Equal(t, &models.ImportProgress{Processed: 2, Created: 1, Invalid: 1}, events[2].Progress)
+	assert.Equal(t, models.ImportResult{Record: 3, BatchURLDataResponse: models.BatchURLDataResponse{
+		CorrelationID: "3", ShortURL: "abc", Status: models.BatchItemExisting,
+	}}, *events[3].Result)
+	assert.Equal(t, models.BatchItemCreated, events[4].Result.Status)
+	assert.Equal(t, 5, events[6].Result.Record)
+	assert.Equal(t, models.BatchItemInvalid, events[6].Result.Status)
+	assert.Equal(t, &progress, events[7].Progress)
+}
+
+func TestImport_OptionalCorrelationID(t *testing.T) {
+	svc := NewService(storage.NewMemoryStorage())
+
+	var results []models.ImportResult
+	progress, err := Import(context.Background(), svc, "user1", 10, sliceSource([]models.BatchURLData{
+		{OriginalURL: "https://a.com"},
+		{OriginalURL: "https://b.com"},
+		{CorrelationID: "1", OriginalURL: "https://c.com"},
+		{CorrelationID: "1", OriginalURL: "https://d.com"},
+	}), func(event models.ImportEvent) error {
+		if event.Result != nil {
+			results = append(results, *event.Result)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.ImportProgress{Processed: 4, Created: 3, Invalid: 1}, progress)
+
+	require.Len(t, results, 4)
+	for _, result := range results[:3] {
+		assert.Equal(t, models.BatchItemCreated, result.Status, result.Record)
+		assert.NotEmpty(t, result.ShortURL)
+	}
+	assert.Empty(t, results[0].CorrelationID)
+	assert.Equal(t, models.BatchItemInvalid, results[3].Status, "non-empty correlation_id must still be unique")
+
+	// в обычном пакете correlation_id по-прежнему обязателен
+	resp, err := svc.CreateURLBatch(context.Background(), []models.BatchURLData{{OriginalURL: "https://e.com"}}, "user1", models.BatchModeBestEffort)
+	require.NoError(t, err)
+	assert.Equal(t, models.BatchItemInvalid, resp[0].Status)
+}
+
+func TestImport_AbortsOnSourceError(t *testing.T) {
+	svc := NewService(storage.NewMemoryStorage())
+	broken := errors.New("connection reset")
+
+	calls := 0
+	progress, err := Import(context.Background(), svc, "user1", 10, func() (models.BatchURLData, error) {
+		calls++
+		if calls > 1 {
+			return models.BatchURLData{}, broken
+		}
+		return models.BatchURLData{CorrelationID: "1", OriginalURL: "https://new.com"}, nil
+	}, func(models.ImportEvent) error { return nil })
+	assert.ErrorIs(t, err, broken)
+	assert.Zero(t, progress.Processed)
+}
@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/issafronov/shortener/internal/app/models"
 )
@@ -9,23 +11,121 @@ import (
 // Service определяет бизнес-логику для работы с сокращёнными URL
 type Service interface {
 	// CreateURL создаёт сокращённый URL для одного оригинального URL
-	CreateURL(ctx context.Context, originalURL, userID string) (shortKey string, err error)
+	CreateURL(ctx context.Context, originalURL, userID string, opts ...CreateOption) (shortKey string, err error)
 
-	// CreateURLBatch создаёт сокращённые URL по батч-запросу
-	CreateURLBatch(ctx context.Context, batch []models.BatchURLData, userID string) ([]models.BatchURLDataResponse, error)
+	// CreateURLBatch создаёт сокращённые URL по батч-запросу.
+	// Режим atomic (по умолчанию) сохраняет пакет целиком или не сохраняет вовсе,
+	// режим best_effort возвращает результат по каждой записи.
+	CreateURLBatch(ctx context.Context, batch []models.BatchURLData, userID string, mode models.BatchMode) ([]models.BatchURLDataResponse, error)
 
 	// GetOriginalURL возвращает оригинальный URL по его короткому ключу
 	GetOriginalURL(ctx context.Context, shortKey string) (string, error)
 
-	// GetUserURLs возвращает все сокращённые URL, созданные пользователем
-	GetUserURLs(ctx context.Context, userID, host string) ([]models.ShortURLResponse, error)
+	// ListUserURLs возвращает страницу сокращённых URL пользователя и курсор следующей страницы.
+	// Нулевой opts.Limit возвращает все ссылки.
+	ListUserURLs(ctx context.Context, userID, host string, opts models.ListOptions) ([]models.ShortURLResponse, string, error)
 
-	// DeleteUserURLs удаляет список сокращённых ссылок пользователя
-	DeleteUserURLs(ctx context.Context, userID string, ids []string) error
+	// DeleteUserURLs удаляет список сокращённых ссылок пользователя.
+	// При наличии очереди удаления ссылки удаляются асинхронно и возвращается идентификатор задачи.
+	DeleteUserURLs(ctx context.Context, userID string, ids []string) (jobID string, err error)
 
-	// GetStats возвращает статистику: количество URL и количество пользователей
-	GetStats(ctx context.Context) (urls int64, users int64, err error)
+	// GetDeleteJob возвращает задачу удаления ссылок пользователя
+	GetDeleteJob(ctx context.Context, userID, jobID string) (models.DeleteJob, error)
 
-	// Ping пингует сервис
-	Ping(ctx context.Context) error
+	// GetStats возвращает статистику: количество URL и пользователей и счётчики кэша ссылок
+	GetStats(ctx context.Context) (models.Stats, error)
+
+	// Ping проверяет доступность хранилища и возвращает состояние пула соединений
+	Ping(ctx context.Context) (models.Health, error)
+
+	// RecordClick асинхронно записывает переход по ссылке
+	RecordClick(ctx context.Context, click models.Click, clientIP string)
+
+	// GetLinkStats возвращает статистику переходов по ссылке пользователя
+	GetLinkStats(ctx context.Context, userID, shortKey string, bucket time.Duration) (models.LinkStats, error)
+
+	// UpdateURL меняет оригинальный URL ссылки пользователя
+	UpdateURL(ctx context.Context, userID, shortKey, originalURL string) (models.URLVersion, error)
+
+	// GetURLHistory возвращает историю изменений ссылки пользователя
+	GetURLHistory(ctx context.Context, userID, shortKey string) ([]models.URLVersion, error)
+
+	// RollbackURL возвращает ссылке оригинальный URL одной из прежних версий
+	RollbackURL(ctx context.Context, userID, shortKey string, version int) (models.URLVersion, error)
+}
+
+// DeletionQueue принимает запросы на удаление ссылок для фоновой обработки
+type DeletionQueue interface {
+	Enqueue(userID string, ids []string) (string, error)
+	Job(id string) (models.DeleteJob, bool)
+}
+
+// Observer получает уведомления о событиях сервиса, например для сбора метрик
+type Observer interface {
+	LinksCreated(n int)
+	RedirectServed()
+}
+
+// noopObserver — наблюдатель по умолчанию, игнорирующий события
+type noopObserver struct{}
+
+func (noopObserver) LinksCreated(int) {}
+func (noopObserver) RedirectServed()  {}
+
+// ClickRecorder принимает переходы по ссылкам для асинхронной записи
+type ClickRecorder interface {
+	Record(click models.Click, clientIP string)
+}
+
+// createOptions содержит необязательные параметры создания ссылки
+type createOptions struct {
+	alias     string
+	expiresAt time.Time
+	ttl       time.Duration
+}
+
+// CreateOption задаёт необязательный параметр создания ссылки
+type CreateOption func(*createOptions)
+
+// WithAlias задаёт пользовательский короткий ключ вместо сгенерированного.
+// Пустое значение игнорируется.
+func WithAlias(alias string) CreateOption {
+	return func(o *createOptions) {
+		o.alias = alias
+	}
+}
+
+// WithExpiresAt задаёт момент истечения срока действия ссылки.
+// Нулевое значение игнорируется.
+func WithExpiresAt(expiresAt time.Time) CreateOption {
+	return func(o *createOptions) {
+		o.expiresAt = expiresAt
+	}
+}
+
+// WithTTL задаёт срок действия ссылки относительно момента создания.
+// Нулевое значение игнорируется.
+func WithTTL(ttl time.Duration) CreateOption {
+	return func(o *createOptions) {
+		o.ttl = ttl
+	}
+}
+
+// expiry вычисляет момент истечения срока действия ссылки по параметрам создания
+func (o createOptions) expiry(now time.Time) (*time.Time, error) {
+	switch {
+	case !o.expiresAt.IsZero() && o.ttl != 0:
+		return nil, fmt.Errorf("%w: expires_at and ttl are mutually exclusive", ErrInvalidExpiry)
+	case o.ttl < 0:
+		return nil, fmt.Errorf("%w: ttl must be positive", ErrInvalidExpiry)
+	case o.ttl > 0:
+		expiresAt := now.Add(o.ttl)
+		return &expiresAt, nil
+	case o.expiresAt.IsZero():
+		return nil, nil
+	case !o.expiresAt.After(now):
+		return nil, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidExpiry)
+	}
+	expiresAt := o.expiresAt
+	return &expiresAt, nil
 }
@@ -3,113 +3,580 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
-	"github.com/issafronov/shortener/internal/app/contextkeys"
 	"github.com/issafronov/shortener/internal/app/models"
 	"github.com/issafronov/shortener/internal/app/storage"
 	"github.com/issafronov/shortener/internal/app/utils"
 )
 
-const shortKeyLength = 8
+// Размеры страницы списка ссылок пользователя
+const (
+	// DefaultListLimit — размер страницы, если клиент передал курсор без размера
+	DefaultListLimit = 100
+	// MaxListLimit — максимальный размер страницы
+	MaxListLimit = 1000
+)
 
-var ErrDeleted = errors.New("url gone")
-var ErrConflict = errors.New("url conflict")
+// maxKeyAttempts — максимальное число попыток сгенерировать свободный короткий ключ
+const maxKeyAttempts = 5
 
 type shortenerService struct {
-	storage storage.Storage
+	storage  storage.Storage
+	keys     utils.KeyGenerator
+	clicks   ClickRecorder
+	deletes  DeletionQueue
+	observer Observer
+	// dedup объединяет повторяющиеся оригинальные URL пакета в одну ссылку
+	dedup bool
+}
+
+// Option задаёт необязательный параметр сервиса
+type Option func(*shortenerService)
+
+// WithKeyGenerator задаёт генератор коротких ключей.
+// По умолчанию используются случайные ключи длиной 8 символов.
+func WithKeyGenerator(keys utils.KeyGenerator) Option {
+	return func(s *shortenerService) {
+		s.keys = keys
+	}
+}
+
+// WithClickRecorder задаёт получатель переходов по ссылкам.
+// Без него переходы не записываются.
+func WithClickRecorder(clicks ClickRecorder) Option {
+	return func(s *shortenerService) {
+		s.clicks = clicks
+	}
+}
+
+// WithDeletionQueue задаёт очередь фонового удаления ссылок.
+// Без неё ссылки удаляются синхронно.
+func WithDeletionQueue(deletes DeletionQueue) Option {
+	return func(s *shortenerService) {
+		s.deletes = deletes
+	}
+}
+
+// WithURLUniqueness задаёт область уникальности оригинального URL, выбранную в хранилище.
+// В режиме storage.UniquenessNone повторяющиеся оригинальные URL пакета сокращаются отдельными ссылками.
+func WithURLUniqueness(mode string) Option {
+	return func(s *shortenerService) {
+		s.dedup = mode != storage.UniquenessNone
+	}
+}
+
+// WithObserver задаёт получатель событий сервиса
+func WithObserver(observer Observer) Option {
+	return func(s *shortenerService) {
+		s.observer = observer
+	}
 }
 
 // NewService создаёт новый экземпляр сервиса
-func NewService(storage storage.Storage) Service {
-	return &shortenerService{storage: storage}
+func NewService(storage storage.Storage, opts ...Option) Service {
+	s := &shortenerService{
+		storage:  storage,
+		keys:     utils.NewRandomGenerator(utils.DefaultKeyLength),
+		observer: noopObserver{},
+		dedup:    true,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // CreateURL создаёт сокращённый URL
-func (s *shortenerService) CreateURL(ctx context.Context, originalURL, userID string) (string, error) {
-	shortKey := utils.CreateShortKey(shortKeyLength)
+func (s *shortenerService) CreateURL(ctx context.Context, originalURL, userID string, opts ...CreateOption) (string, error) {
+	var options createOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	expiresAt, err := options.expiry(time.Now())
+	if err != nil {
+		return "", err
+	}
 
 	shortenerURL := storage.ShortenerURL{
-		ShortURL:    shortKey,
 		OriginalURL: originalURL,
 		UserID:      userID,
+		ExpiresAt:   expiresAt,
+	}
+	shortKey, err := s.create(ctx, shortenerURL, options.alias)
+	if err != nil {
+		return shortKey, err
+	}
+	s.observer.LinksCreated(1)
+	return shortKey, nil
+}
+
+// CreateURLBatch создаёт пакет ссылок в выбранном режиме; пустой режим означает atomic.
+// В режиме atomic пакет сохраняется одной операцией хранилища, и любая некорректная запись
+// или конфликт отменяют весь пакет. В режиме best_effort записи обрабатываются независимо,
+// а результат возвращается по каждой из них.
+func (s *shortenerService) CreateURLBatch(ctx context.Context, batch []models.BatchURLData, userID string, mode models.BatchMode) ([]models.BatchURLDataResponse, error) {
+	switch mode {
+	case "", models.BatchModeAtomic:
+		return s.createBatchAtomic(ctx, batch, userID)
+	case models.BatchModeBestEffort, models.BatchModeImport:
+		return s.createBatchBestEffort(ctx, batch, userID, mode)
+	default:
+		return nil, fmt.Errorf("%w: unknown batch mode %q", ErrInvalidRequest, mode)
 	}
+}
 
-	_, err := s.storage.Create(ctx, shortenerURL)
+// createBatchAtomic сохраняет пакет целиком или не сохраняет вовсе.
+// Повторяющиеся в пакете оригинальные URL получают одну ссылку.
+func (s *shortenerService) createBatchAtomic(ctx context.Context, batch []models.BatchURLData, userID string) ([]models.BatchURLDataResponse, error) {
+	plan, err := s.planBatch(batch, userID, models.BatchModeAtomic)
 	if err != nil {
-		if errors.Is(err, storage.ErrConflict) {
-			return "", ErrConflict
+		return nil, err
+	}
+
+	attempts := make([]int, len(plan.urls))
+	for {
+		err := s.storage.CreateBatch(ctx, plan.urls)
+		if err == nil {
+			break
+		}
+		var batchErr *storage.BatchError
+		if !errors.As(err, &batchErr) {
+			return nil, err
+		}
+
+		i := batchErr.Index
+		url := plan.urls[i]
+		if !errors.Is(err, storage.ErrKeyExists) || !plan.generated[i] {
+			alias := ""
+			if !plan.generated[i] {
+				alias = url.ShortURL
+			}
+			return nil, fmt.Errorf("%w: correlation_id %q", createError(batchErr.Err, batchErr.Existing, alias), url.CorrelationID)
+		}
+		attempts[i]++
+		if attempts[i] >= maxKeyAttempts {
+			return nil, ErrKeyExhausted
+		}
+		if plan.urls[i].ShortURL, err = s.keys.Generate(url.OriginalURL, attempts[i]); err != nil {
+			return nil, err
 		}
-		return "", err
 	}
 
-	return shortKey, nil
+	for i, url := range plan.urls {
+		plan.results[i] = models.BatchURLDataResponse{ShortURL: url.ShortURL, Status: models.BatchItemCreated}
+	}
+	return s.finishBatch(plan), nil
+}
+
+// createBatchBestEffort обрабатывает записи пакета независимо.
+// Сначала пакет сохраняется одной операцией хранилища; если это не удалось из-за конфликта,
+// записи сохраняются по одной. Некорректные записи получают статус invalid,
+// уже сокращённые URL — existing; пакет прерывается только при сбое хранилища.
+func (s *shortenerService) createBatchBestEffort(ctx context.Context, batch []models.BatchURLData, userID string, mode models.BatchMode) ([]models.BatchURLDataResponse, error) {
+	plan, err := s.planBatch(batch, userID, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.storage.CreateBatch(ctx, plan.urls)
+	var batchErr *storage.BatchError
+	switch {
+	case err == nil:
+		for i, url := range plan.urls {
+			plan.results[i] = models.BatchURLDataResponse{ShortURL: url.ShortURL, Status: models.BatchItemCreated}
+		}
+		return s.finishBatch(plan), nil
+	case !errors.As(err, &batchErr):
+		return nil, err
+	}
+
+	for i, url := range plan.urls {
+		alias := ""
+		if !plan.generated[i] {
+			alias = url.ShortURL
+		}
+		key, err := s.create(ctx, url, alias)
+		switch conflictKey, conflict := ConflictKey(err); {
+		case err == nil:
+			plan.results[i] = models.BatchURLDataResponse{ShortURL: key, Status: models.BatchItemCreated}
+		case conflict:
+			plan.results[i] = models.BatchURLDataResponse{ShortURL: conflictKey, Status: models.BatchItemExisting}
+		case KindOf(err) == KindInvalid || errors.Is(err, ErrAliasTaken):
+			plan.results[i] = models.BatchURLDataResponse{Status: models.BatchItemInvalid, Error: err.Error()}
+		default:
+			return nil, err
+		}
+	}
+	return s.finishBatch(plan), nil
 }
 
-// CreateURLBatch создаёт пакет ссылок
-func (s *shortenerService) CreateURLBatch(ctx context.Context, batch []models.BatchURLData, userID string) ([]models.BatchURLDataResponse, error) {
-	var responses []models.BatchURLDataResponse
+// batchPlan — записи пакета, подготовленные к сохранению
+type batchPlan struct {
+	// urls — корректные записи с неповторяющимися оригинальными URL
+	urls []storage.ShortenerURL
+	// generated отмечает записи urls со сгенерированным, а не пользовательским ключом
+	generated []bool
+	// results — результат сохранения каждой записи urls
+	results []models.BatchURLDataResponse
+	// refs — номер записи в urls для каждой записи пакета, -1 для некорректных
+	refs []int
+	// responses — ответ по каждой записи пакета до сохранения
+	responses []models.BatchURLDataResponse
+}
+
+// planBatch проверяет записи пакета, объединяет повторяющиеся оригинальные URL,
+// если они должны быть уникальными, и генерирует короткие ключи. В режиме atomic первая некорректная запись возвращается как ошибка.
+func (s *shortenerService) planBatch(batch []models.BatchURLData, userID string, mode models.BatchMode) (*batchPlan, error) {
+	strict := mode == models.BatchModeAtomic
+	now := time.Now()
+	plan := &batchPlan{
+		refs:      make([]int, 0, len(batch)),
+		responses: make([]models.BatchURLDataResponse, 0, len(batch)),
+	}
+	first := make(map[string]int, len(batch))
+	correlations := make(map[string]struct{}, len(batch))
 
 	for _, item := range batch {
-		if item.OriginalURL == "" || item.CorrelationID == "" {
+		resp := models.BatchURLDataResponse{CorrelationID: item.CorrelationID}
+		url, err := batchURL(item, userID, now, correlations, mode != models.BatchModeImport)
+		switch i, repeated := first[url.OriginalURL]; {
+		case err != nil && strict:
+			return nil, err
+		case err != nil:
+			resp.Status, resp.Error = models.BatchItemInvalid, err.Error()
+			plan.refs = append(plan.refs, -1)
+		case repeated && s.dedup:
+			resp.Status = models.BatchItemExisting
+			plan.refs = append(plan.refs, i)
+		default:
+			first[url.OriginalURL] = len(plan.urls)
+			plan.refs = append(plan.refs, len(plan.urls))
+			plan.generated = append(plan.generated, url.ShortURL == "")
+			plan.urls = append(plan.urls, url)
+		}
+		plan.responses = append(plan.responses, resp)
+	}
+
+	for i := range plan.urls {
+		if !plan.generated[i] {
 			continue
 		}
+		key, err := s.keys.Generate(plan.urls[i].OriginalURL, 0)
+		if err != nil {
+			return nil, err
+		}
+		plan.urls[i].ShortURL = key
+	}
+	plan.results = make([]models.BatchURLDataResponse, len(plan.urls))
+	return plan, nil
+}
+
+// finishBatch собирает ответ по каждой записи пакета из результатов сохранения
+func (s *shortenerService) finishBatch(plan *batchPlan) []models.BatchURLDataResponse {
+	created := 0
+	for _, result := range plan.results {
+		if result.Status == models.BatchItemCreated {
+			created++
+		}
+	}
+
+	for n, i := range plan.refs {
+		if i < 0 {
+			continue
+		}
+		resp, result := &plan.responses[n], plan.results[i]
+		resp.ShortURL, resp.Error = result.ShortURL, result.Error
+		if resp.Status == "" || result.Status == models.BatchItemInvalid {
+			resp.Status = result.Status
+		}
+	}
+
+	s.observer.LinksCreated(created)
+	return plan.responses
+}
+
+// batchURL проверяет запись пакета и готовит ссылку к сохранению.
+// Пользовательский ключ, если он задан, становится коротким ключом ссылки.
+// Если requireCorrelation ложно, correlation_id может быть пустым, а непустые не должны повторяться.
+func batchURL(item models.BatchURLData, userID string, now time.Time, correlations map[string]struct{}, requireCorrelation bool) (storage.ShortenerURL, error) {
+	switch {
+	case item.CorrelationID == "" && requireCorrelation:
+		return storage.ShortenerURL{}, fmt.Errorf("%w: correlation_id is empty", ErrInvalidRequest)
+	case item.OriginalURL == "":
+		return storage.ShortenerURL{}, fmt.Errorf("%w: original_url is empty for correlation_id %q", ErrInvalidRequest, item.CorrelationID)
+	}
+	if item.CorrelationID != "" {
+		if _, repeated := correlations[item.CorrelationID]; repeated {
+			return storage.ShortenerURL{}, fmt.Errorf("%w: duplicate correlation_id %q", ErrInvalidRequest, item.CorrelationID)
+		}
+		correlations[item.CorrelationID] = struct{}{}
+	}
+
+	if item.Alias != "" {
+		if err := ValidateAlias(item.Alias); err != nil {
+			return storage.ShortenerURL{}, fmt.Errorf("%w: correlation_id %q", err, item.CorrelationID)
+		}
+	}
+
+	options := createOptions{ttl: time.Duration(item.TTL) * time.Second}
+	if item.ExpiresAt != nil {
+		options.expiresAt = *item.ExpiresAt
+	}
+	expiresAt, err := options.expiry(now)
+	if err != nil {
+		return storage.ShortenerURL{}, fmt.Errorf("%w: correlation_id %q", err, item.CorrelationID)
+	}
 
-		shortKey := utils.CreateShortKey(shortKeyLength)
-		shortenerURL := storage.ShortenerURL{
-			ShortURL:      shortKey,
-			OriginalURL:   item.OriginalURL,
-			CorrelationID: item.CorrelationID,
-			UserID:        userID,
+	return storage.ShortenerURL{
+		ShortURL:      item.Alias,
+		OriginalURL:   item.OriginalURL,
+		CorrelationID: item.CorrelationID,
+		UserID:        userID,
+		ExpiresAt:     expiresAt,
+	}, nil
+}
+
+// create сохраняет ссылку с пользовательским ключом, если он задан, иначе со сгенерированным.
+// При коллизии сгенерированного ключа генерация повторяется; прочие ошибки возвращаются сразу.
+func (s *shortenerService) create(ctx context.Context, shortenerURL storage.ShortenerURL, alias string) (string, error) {
+	if alias != "" {
+		if err := ValidateAlias(alias); err != nil {
+			return "", err
+		}
+		shortenerURL.ShortURL = alias
+		if key, err := s.storage.Create(ctx, shortenerURL); err != nil {
+			return key, createError(err, key, alias)
 		}
+		return alias, nil
+	}
 
-		_, err := s.storage.Create(ctx, shortenerURL)
+	for attempt := 0; attempt < maxKeyAttempts; attempt++ {
+		shortKey, err := s.keys.Generate(shortenerURL.OriginalURL, attempt)
 		if err != nil {
-			if errors.Is(err, storage.ErrConflict) {
-				return nil, ErrConflict
-			}
-			return nil, err
+			return "", err
 		}
+		shortenerURL.ShortURL = shortKey
 
-		responses = append(responses, models.BatchURLDataResponse{
-			ShortURL:      shortKey,
-			CorrelationID: item.CorrelationID,
-		})
+		key, err := s.storage.Create(ctx, shortenerURL)
+		if errors.Is(err, storage.ErrKeyExists) {
+			continue
+		}
+		if err != nil {
+			return key, createError(err, key, "")
+		}
+		return shortKey, nil
 	}
+	return "", ErrKeyExhausted
+}
 
-	return responses, nil
+// createError переводит ошибку хранилища при создании ссылки в ошибку сервиса.
+// При конфликте key — ключ уже существующей ссылки с тем же оригинальным URL.
+func createError(err error, key, alias string) error {
+	switch {
+	case errors.Is(err, storage.ErrConflict):
+		return &ConflictError{Key: key}
+	case errors.Is(err, storage.ErrKeyExists) && alias != "":
+		return fmt.Errorf("%w: %s", ErrAliasTaken, alias)
+	case errors.Is(err, storage.ErrConcurrentChange):
+		return ErrConcurrentChange
+	}
+	return err
 }
 
 // GetOriginalURL возвращает оригинальный URL по ключу
 func (s *shortenerService) GetOriginalURL(ctx context.Context, shortKey string) (string, error) {
-	return s.storage.Get(ctx, shortKey)
+	originalURL, err := s.storage.Get(ctx, shortKey)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			return "", ErrNotFound
+		case errors.Is(err, storage.ErrDeleted):
+			return "", ErrDeleted
+		case errors.Is(err, storage.ErrExpired):
+			return "", ErrExpired
+		}
+		return "", err
+	}
+	s.observer.RedirectServed()
+	return originalURL, nil
 }
 
-// GetUserURLs возвращает все URL пользователя
-func (s *shortenerService) GetUserURLs(ctx context.Context, userID, host string) ([]models.ShortURLResponse, error) {
-	ctx = context.WithValue(ctx, contextkeys.HostKey, host)
-	return s.storage.GetByUser(ctx, userID)
+// ListUserURLs возвращает страницу URL пользователя
+func (s *shortenerService) ListUserURLs(ctx context.Context, userID, host string, opts models.ListOptions) ([]models.ShortURLResponse, string, error) {
+	if opts.Sort != "" && opts.Sort != models.SortCreatedAsc && opts.Sort != models.SortCreatedDesc {
+		return nil, "", fmt.Errorf("%w: unknown sort %q", ErrInvalidListOptions, opts.Sort)
+	}
+	if opts.Limit < 0 || opts.Limit > MaxListLimit {
+		return nil, "", fmt.Errorf("%w: limit must be between 0 and %d", ErrInvalidListOptions, MaxListLimit)
+	}
+
+	urls, next, err := s.storage.ListByUser(ctx, userID, opts)
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidCursor) {
+			return nil, "", fmt.Errorf("%w: %w", ErrInvalidListOptions, err)
+		}
+		return nil, "", err
+	}
+
+	result := make([]models.ShortURLResponse, 0, len(urls))
+	for _, url := range urls {
+		result = append(result, models.ShortURLResponse{
+			ShortURL:    host + "/" + url.ShortURL,
+			OriginalURL: url.OriginalURL,
+			IsDeleted:   url.IsDeleted,
+		})
+	}
+	return result, next, nil
 }
 
-// DeleteUserURLs удаляет список ссылок
-func (s *shortenerService) DeleteUserURLs(ctx context.Context, userID string, ids []string) error {
-	return s.storage.DeleteURLs(ctx, userID, ids)
+// DeleteUserURLs ставит ссылки в очередь на удаление и возвращает идентификатор задачи.
+// Если очередь не задана, ссылки удаляются сразу, а идентификатор задачи пуст.
+func (s *shortenerService) DeleteUserURLs(ctx context.Context, userID string, ids []string) (string, error) {
+	if s.deletes == nil {
+		return "", s.storage.DeleteURLs(ctx, userID, ids)
+	}
+	jobID, err := s.deletes.Enqueue(userID, ids)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrDeleteUnavailable, err)
+	}
+	return jobID, nil
 }
 
-// GetStats возвращает общее количество URL и пользователей
-func (s *shortenerService) GetStats(ctx context.Context) (int64, int64, error) {
+// GetDeleteJob возвращает задачу удаления, если она создана пользователем
+func (s *shortenerService) GetDeleteJob(ctx context.Context, userID, jobID string) (models.DeleteJob, error) {
+	if s.deletes == nil {
+		return models.DeleteJob{}, ErrJobNotFound
+	}
+	job, ok := s.deletes.Job(jobID)
+	if !ok || job.UserID != userID {
+		return models.DeleteJob{}, ErrJobNotFound
+	}
+	return job, nil
+}
+
+// GetStats возвращает общее количество URL и пользователей.
+// Если хранилище обёрнуто кэшем, в статистику добавляются его счётчики.
+func (s *shortenerService) GetStats(ctx context.Context) (models.Stats, error) {
 	urls, err := s.storage.CountURLs(ctx)
 	if err != nil {
-		return 0, 0, err
+		return models.Stats{}, err
 	}
 
 	users, err := s.storage.CountUsers(ctx)
 	if err != nil {
-		return 0, 0, err
+		return models.Stats{}, err
+	}
+
+	stats := models.Stats{URLs: urls, Users: users}
+	if cache, ok := storage.Find[interface{ CacheStats() models.CacheStats }](s.storage); ok {
+		cacheStats := cache.CacheStats()
+		stats.Cache = &cacheStats
+	}
+	return stats, nil
+}
+
+// Ping проверяет доступность хранилища и, если у него есть пул соединений, сообщает его состояние.
+// Пул считается насыщенным, если заняты все соединения.
+func (s *shortenerService) Ping(ctx context.Context) (models.Health, error) {
+	if err := s.storage.Ping(ctx); err != nil {
+		return models.Health{}, err
+	}
+
+	health := models.Health{Status: models.HealthOK}
+	if pool, ok := storage.Find[interface{ PoolStats() models.PoolStats }](s.storage); ok {
+		stats := pool.PoolStats()
+		health.Pool = &stats
+		if stats.MaxOpen > 0 && stats.InUse >= stats.MaxOpen {
+			health.Status = models.HealthSaturated
+		}
 	}
+	return health, nil
+}
+
+// RecordClick передаёт переход получателю, не дожидаясь записи
+func (s *shortenerService) RecordClick(ctx context.Context, click models.Click, clientIP string) {
+	if s.clicks == nil {
+		return
+	}
+	s.clicks.Record(click, clientIP)
+}
 
-	return urls, users, nil
+// GetLinkStats возвращает статистику переходов по ссылке, если она принадлежит пользователю
+func (s *shortenerService) GetLinkStats(ctx context.Context, userID, shortKey string, bucket time.Duration) (models.LinkStats, error) {
+	url, err := s.storage.GetURL(ctx, shortKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return models.LinkStats{}, ErrNotFound
+		}
+		return models.LinkStats{}, err
+	}
+	if url.UserID != userID {
+		return models.LinkStats{}, ErrNotFound
+	}
+	return s.storage.ClickStats(ctx, shortKey, bucket)
 }
 
-func (s *shortenerService) Ping(ctx context.Context) error {
-	return s.storage.Ping(ctx)
+// UpdateURL меняет оригинальный URL ссылки; изменение сохраняется в истории версий
+func (s *shortenerService) UpdateURL(ctx context.Context, userID, shortKey, originalURL string) (models.URLVersion, error) {
+	version, err := s.storage.UpdateURL(ctx, userID, shortKey, originalURL)
+	if err != nil {
+		return models.URLVersion{}, updateError(err)
+	}
+	return version, nil
+}
+
+// GetURLHistory возвращает историю изменений ссылки, если она принадлежит пользователю
+func (s *shortenerService) GetURLHistory(ctx context.Context, userID, shortKey string) ([]models.URLVersion, error) {
+	url, err := s.storage.GetURL(ctx, shortKey)
+	if err != nil {
+		return nil, updateError(err)
+	}
+	if url.UserID != userID {
+		return nil, ErrNotFound
+	}
+
+	history, err := s.storage.URLHistory(ctx, shortKey)
+	if err != nil {
+		return nil, err
+	}
+	if history == nil {
+		history = []models.URLVersion{}
+	}
+	return history, nil
+}
+
+// RollbackURL возвращает ссылке оригинальный URL указанной версии.
+// Версия 0 — адрес, с которым ссылка была создана. Откат сохраняется в истории как новая версия.
+func (s *shortenerService) RollbackURL(ctx context.Context, userID, shortKey string, version int) (models.URLVersion, error) {
+	history, err := s.GetURLHistory(ctx, userID, shortKey)
+	if err != nil {
+		return models.URLVersion{}, err
+	}
+
+	var target string
+	switch {
+	case version == 0 && len(history) > 0:
+		target = history[0].PreviousURL
+	case version > 0 && version <= len(history):
+		target = history[version-1].OriginalURL
+	default:
+		return models.URLVersion{}, fmt.Errorf("%w: %d", ErrVersionNotFound, version)
+	}
+	return s.UpdateURL(ctx, userID, shortKey, target)
+}
+
+// updateError переводит ошибку хранилища при изменении ссылки в ошибку сервиса
+func updateError(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return ErrNotFound
+	case errors.Is(err, storage.ErrDeleted):
+		return ErrDeleted
+	case errors.Is(err, storage.ErrConflict):
+		return ErrConflict
+	}
+	return err
 }
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/issafronov/shortener/internal/app/models"
+	"github.com/issafronov/shortener/internal/app/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedKeys выдаёт ключи из списка по номеру попытки
+type fixedKeys []string
+
+func (f fixedKeys) Generate(originalURL string, attempt int) (string, error) {
+	return f[attempt%len(f)], nil
+}
+
+func TestCreateURL_RetriesOnKeyCollision(t *testing.T) {
+	ctx := context.Background()
+	st := storage.NewMemoryStorage()
+	_, err := st.Create(ctx, storage.ShortenerURL{ShortURL: "taken", OriginalURL: "https://a.com", UserID: "user1"})
+	require.NoError(t, err)
+
+	svc := NewService(st, WithKeyGenerator(fixedKeys{"taken", "free"}))
+
+	key, err := svc.CreateURL(ctx, "https://b.com", "user1")
+	require.NoError(t, err)
+	assert.Equal(t, "free", key)
+}
+
+func TestCreateURL_KeyExhausted(t *testing.T) {
+	ctx := context.Background()
+	st := storage.NewMemoryStorage()
+	_, err := st.Create(ctx, storage.ShortenerURL{ShortURL: "taken", OriginalURL: "https://a.com", UserID: "user1"})
+	require.NoError(t, err)
+
+	svc := NewService(st, WithKeyGenerator(fixedKeys{"taken"}))
+
+	_, err = svc.CreateURL(ctx, "https://b.com", "user1")
+	assert.ErrorIs(t, err, ErrKeyExhausted)
+}
+
+// racingStorage всегда проигрывает гонку с удалением конфликтующей ссылки
+type racingStorage struct {
+	storage.Storage
+}
+
+func (racingStorage) Create(ctx context.Context, url storage.ShortenerURL) (string, error) {
+	return "", storage.ErrConcurrentChange
+}
+
+func TestCreateURL_ConcurrentChange(t *testing.T) {
+	svc := NewService(racingStorage{storage.NewMemoryStorage()})
+
+	_, err := svc.CreateURL(context.Background(), "https://a.com", "user1")
+	assert.ErrorIs(t, err, ErrConcurrentChange)
+	assert.Equal(t, KindUnavailable, KindOf(err))
+}
+
+func TestCreateURL_AliasNotRetried(t *testing.T) {
+	ctx := context.Background()
+	st := storage.NewMemoryStorage()
+	svc := NewService(st, WithKeyGenerator(fixedKeys{"generated"}))
+
+	_, err := svc.CreateURL(ctx, "https://a.com", "user1", WithAlias("spring-sale"))
+	require.NoError(t, err)
+
+	_, err = svc.CreateURL(ctx, "https://b.com", "user1", WithAlias("spring-sale"))
+	assert.ErrorIs(t, err, ErrAliasTaken)
+}
+
+func TestCreateURL_Expiry(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService(storage.NewMemoryStorage())
+
+	_, err := svc.CreateURL(ctx, "https://a.com", "user1", WithExpiresAt(time.Now().Add(-time.Minute)))
+	assert.ErrorIs(t, err, ErrInvalidExpiry)
+
+	_, err = svc.CreateURL(ctx, "https://a.com", "user1", WithTTL(time.Hour), WithExpiresAt(time.Now().Add(time.Hour)))
+	assert.ErrorIs(t, err, ErrInvalidExpiry)
+
+	key, err := svc.CreateURL(ctx, "https://a.com", "user1", WithTTL(time.Nanosecond))
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond)
+
+	_, err = svc.GetOriginalURL(ctx, key)
+	assert.ErrorIs(t, err, ErrExpired)
+}
+
+func TestRollbackURL(t *testing.T) {
+	ctx := context.Background()
+	st := storage.NewMemoryStorage()
+	_, err := st.Create(ctx, storage.ShortenerURL{ShortURL: "abc", OriginalURL: "https://a.com", UserID: "user1"})
+	require.NoError(t, err)
+	svc := NewService(st)
+
+	_, err = svc.RollbackURL(ctx, "user1", "abc", 0)
+	assert.ErrorIs(t, err, ErrVersionNotFound)
+
+	_, err = svc.UpdateURL(ctx, "user1", "abc", "https://b.com")
+	require.NoError(t, err)
+	_, err = svc.UpdateURL(ctx, "user1", "abc", "https://c.com")
+	require.NoError(t, err)
+
+	version, err := svc.RollbackURL(ctx, "user1", "abc", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 3, version.Version)
+	assert.Equal(t, "https://a.com", version.OriginalURL)
+	assert.Equal(t, "https://c.com", version.PreviousURL)
+
+	version, err = svc.RollbackURL(ctx, "user1", "abc", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "https://b.com", version.OriginalURL)
+
+	_, err = svc.RollbackURL(ctx, "user1", "abc", 10)
+	assert.ErrorIs(t, err, ErrVersionNotFound)
+	_, err = svc.RollbackURL(ctx, "user2", "abc", 1)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = svc.GetURLHistory(ctx, "user2", "abc")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestCreateURLBatch_AtomicRollsBackOnConflict(t *testing.T) {
+	ctx := context.Background()
+	st := storage.NewMemoryStorage()
+	_, err := st.Create(ctx, storage.ShortenerURL{ShortURL: "abc", OriginalURL: "https://a.com", UserID: "user1"})
+	require.NoError(t, err)
+	svc := NewService(st)
+
+	_, err = svc.CreateURLBatch(ctx, []models.BatchURLData{
+		{CorrelationID: "1", OriginalURL: "https://new.com"},
+		{CorrelationID: "2", OriginalURL: "https://a.com"},
+	}, "user1", models.BatchModeAtomic)
+	assert.ErrorIs(t, err, ErrConflict)
+	key, ok := ConflictKey(err)
+	assert.True(t, ok)
+	assert.Equal(t, "abc", key)
+
+	_, err = svc.CreateURLBatch(ctx, []models.BatchURLData{
+		{CorrelationID: "1", OriginalURL: "https://new.com"},
+		{CorrelationID: "2"},
+	}, "user1", "")
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+
+	count, err := st.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestCreateURLBatch_AtomicRetriesOnKeyCollision(t *testing.T) {
+	ctx := context.Background()
+	st := storage.NewMemoryStorage()
+	_, err := st.Create(ctx, storage.ShortenerURL{ShortURL: "taken", OriginalURL: "https://a.com", UserID: "user1"})
+	require.NoError(t, err)
+	svc := NewService(st, WithKeyGenerator(fixedKeys{"taken", "free"}))
+
+	resp, err := svc.CreateURLBatch(ctx, []models.BatchURLData{
+		{CorrelationID: "1", OriginalURL: "https://b.com"},
+		{CorrelationID: "2", OriginalURL: "https://b.com"},
+	}, "user1", models.BatchModeAtomic)
+	require.NoError(t, err)
+	assert.Equal(t, []models.BatchURLDataResponse{
+		{CorrelationID: "1", ShortURL: "free", Status: models.BatchItemCreated},
+		{CorrelationID: "2", ShortURL: "free", Status: models.BatchItemExisting},
+	}, resp)
+}
+
+func TestCreateURLBatch_BestEffort(t *testing.T) {
+	ctx := context.Background()
+	st := storage.NewMemoryStorage()
+	_, err := st.Create(ctx, storage.ShortenerURL{ShortURL: "abc", OriginalURL: "https://a.com", UserID: "user1"})
+	require.NoError(t, err)
+	svc := NewService(st)
+
+	resp, err := svc.CreateURLBatch(ctx, []models.BatchURLData{
+		{CorrelationID: "1", OriginalURL: "https://new.com"},
+		{CorrelationID: "2", OriginalURL: "https://a.com"},
+		{CorrelationID: "3"},
+		{CorrelationID: "4", OriginalURL: "https://new.com"},
+		{CorrelationID: "5", OriginalURL: "https://other.com", Alias: "abc"},
+	}, "user1", models.BatchModeBestEffort)
+	require.NoError(t, err)
+	require.Len(t, resp, 5)
+
+	assert.Equal(t, models.BatchItemCreated, resp[0].Status)
+	assert.NotEmpty(t, resp[0].ShortURL)
+	assert.Equal(t, models.BatchURLDataResponse{CorrelationID: "2", ShortURL: "abc", Status: models.BatchItemExisting}, resp[1])
+	assert.Equal(t, models.BatchItemInvalid, resp[2].Status)
+	assert.NotEmpty(t, resp[2].Error)
+	assert.Equal(t, models.BatchURLDataResponse{CorrelationID: "4", ShortURL: resp[0].ShortURL, Status: models.BatchItemExisting}, resp[3])
+	assert.Equal(t, models.BatchItemInvalid, resp[4].Status)
+
+	_, err = svc.CreateURLBatch(ctx, nil, "user1", "sometimes")
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+}
+
+func TestCreateURLBatch_NoUniqueness(t *testing.T) {
+	ctx := context.Background()
+	st := storage.NewMemoryStorage(storage.WithUniqueness(storage.UniquenessNone))
+	_, err := st.Create(ctx, storage.ShortenerURL{ShortURL: "abc", OriginalURL: "https://a.com", UserID: "user1"})
+	require.NoError(t, err)
+	svc := NewService(st, WithURLUniqueness(storage.UniquenessNone))
+
+	resp, err := svc.CreateURLBatch(ctx, []models.BatchURLData{
+		{CorrelationID: "1", OriginalURL: "https://a.com"},
+		{CorrelationID: "2", OriginalURL: "https://a.com"},
+	}, "user1", models.BatchModeAtomic)
+	require.NoError(t, err)
+	require.Len(t, resp, 2)
+	for _, item := range resp {
+		assert.Equal(t, models.BatchItemCreated, item.Status)
+	}
+	assert.NotEqual(t, resp[0].ShortURL, resp[1].ShortURL)
+	assert.NotEqual(t, "abc", resp[0].ShortURL)
+
+	count, err := st.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+}
+
+func TestGetStats_IncludesCache(t *testing.T) {
+	ctx := context.Background()
+	st := storage.NewMemoryStorage()
+	_, err := st.Create(ctx, storage.ShortenerURL{ShortURL: "abc", OriginalURL: "https://a.com", UserID: "user1"})
+	require.NoError(t, err)
+
+	stats, err := NewService(st).GetStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, models.Stats{URLs: 1, Users: 1}, stats)
+
+	svc := NewService(storage.NewCachedStorage(st, 10, time.Minute, time.Second))
+	_, err = svc.GetOriginalURL(ctx, "abc")
+	require.NoError(t, err)
+	_, err = svc.GetOriginalURL(ctx, "abc")
+	require.NoError(t, err)
+
+	stats, err = svc.GetStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &models.CacheStats{Hits: 1, Misses: 1, Entries: 1}, stats.Cache)
+}
+
+// pooledStorage — хранилище с заданным состоянием пула соединений
+type pooledStorage struct {
+	storage.Storage
+	stats models.PoolStats
+}
+
+func (s pooledStorage) PoolStats() models.PoolStats {
+	return s.stats
+}
+
+func TestPing_ReportsPool(t *testing.T) {
+	ctx := context.Background()
+
+	health, err := NewService(storage.NewMemoryStorage()).Ping(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, models.Health{Status: models.HealthOK}, health)
+
+	st := pooledStorage{Storage: storage.NewMemoryStorage(), stats: models.PoolStats{MaxOpen: 2, Open: 2, InUse: 2, WaitCount: 7}}
+	health, err = NewService(st).Ping(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, models.HealthSaturated, health.Status)
+	assert.Equal(t, &st.stats, health.Pool)
+}
@@ -0,0 +1,877 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/issafronov/shortener/internal/app/contextkeys"
+	"github.com/issafronov/shortener/internal/app/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Бакеты встраиваемого хранилища
+var (
+	// boltURLs: короткий ключ -> JSON ShortenerURL
+	boltURLs = []byte("urls")
+	// boltOriginals: область уникальности + 0x00 + оригинальный URL -> короткий ключ не удалённой ссылки.
+	// В глобальной области ключ — сам оригинальный URL.
+	boltOriginals = []byte("originals")
+	// boltUsers: user_id + 0x00 + время создания (unix nano, big endian) + uuid (big endian) -> короткий ключ
+	boltUsers = []byte("users")
+	// boltOwners: user_id -> пустое значение, используется для подсчёта пользователей
+	boltOwners = []byte("owners")
+	// boltMeta: служебные счётчики
+	boltMeta = []byte("meta")
+	// boltClicks: короткий ключ + 0x00 + порядковый номер (big endian) -> JSON models.Click
+	boltClicks = []byte("clicks")
+	// boltClickTimes: время перехода (unix nano, big endian) + ключ в бакете clicks -> пустое значение
+	boltClickTimes = []byte("click_times")
+	// boltExpires: время истечения (unix nano, big endian) + короткий ключ -> пустое значение
+	boltExpires = []byte("expires")
+	// boltHistory: короткий ключ + 0x00 + версия (big endian) -> JSON models.URLVersion
+	boltHistory = []byte("history")
+)
+
+// boltLiveURLs — ключ счётчика не удалённых ссылок в бакете meta
+var boltLiveURLs = []byte("live_urls")
+
+// boltUsersByCreated — ключ в бакете meta, отмечающий, что индекс users упорядочен по времени создания.
+// Индекс файлов, созданных до этого, упорядочен по uuid и перестраивается при открытии.
+var boltUsersByCreated = []byte("users_by_created_at")
+
+// boltKeyCounterPrefix — префикс ключей счётчиков генератора коротких ключей в бакете meta
+const boltKeyCounterPrefix = "key_counter:"
+
+// BoltStorage реализует интерфейс Storage поверх встраиваемого B+tree хранилища bbolt.
+// Все данные хранятся в одном файле, в памяти держится только кэш страниц.
+type BoltStorage struct {
+	db         *bolt.DB
+	opts       options
+	clickPrune clickPruner
+}
+
+// NewBoltStorage открывает (или создаёт) файл хранилища и подготавливает бакеты
+func NewBoltStorage(path string, opts ...Option) (*BoltStorage, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		// индекс времени переходов строится по уже записанным переходам при первом открытии
+		indexClicks := tx.Bucket(boltClickTimes) == nil
+		for _, name := range [][]byte{boltURLs, boltOriginals, boltUsers, boltOwners, boltMeta, boltExpires, boltClicks, boltHistory, boltClickTimes} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		if indexClicks {
+			if err := indexClickTimes(tx); err != nil {
+				return err
+			}
+		}
+		return indexUsersByCreated(tx)
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	b := &BoltStorage{db: db, opts: newOptions(opts)}
+	if err := b.syncUniqueness(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+// syncUniqueness перестраивает бакет originals, если режим уникальности изменился
+// с прошлого открытия. Если в новом режиме оригинальные URL повторяются,
+// режим не меняется и возвращается ошибка.
+func (b *BoltStorage) syncUniqueness() error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		meta := tx.Bucket(boltMeta)
+		current := string(meta.Get([]byte(uniquenessSetting)))
+		if current == b.opts.uniqueness {
+			return nil
+		}
+
+		if err := tx.DeleteBucket(boltOriginals); err != nil {
+			return err
+		}
+		originals, err := tx.CreateBucket(boltOriginals)
+		if err != nil {
+			return err
+		}
+		err = tx.Bucket(boltURLs).ForEach(func(k, v []byte) error {
+			var url ShortenerURL
+			if err := json.Unmarshal(v, &url); err != nil {
+				return err
+			}
+			if url.IsDeleted {
+				return nil
+			}
+			key := b.originalKey(url.UserID, url.ShortURL, url.OriginalURL)
+			if originals.Get(key) != nil {
+				return errUniquenessChange(current, b.opts.uniqueness)
+			}
+			return originals.Put(key, []byte(url.ShortURL))
+		})
+		if err != nil {
+			return err
+		}
+		return meta.Put([]byte(uniquenessSetting), []byte(b.opts.uniqueness))
+	})
+}
+
+// originalKey возвращает ключ ссылки в бакете originals с учётом области уникальности
+func (b *BoltStorage) originalKey(userID, key, originalURL string) []byte {
+	return []byte(originalKey(b.opts.scope(userID, key), originalURL))
+}
+
+// releaseOriginal удаляет ссылку из бакета originals, если оригинальный URL закреплён за ней
+func (b *BoltStorage) releaseOriginal(tx *bolt.Tx, url ShortenerURL) error {
+	originals := tx.Bucket(boltOriginals)
+	key := b.originalKey(url.UserID, url.ShortURL, url.OriginalURL)
+	if string(originals.Get(key)) != url.ShortURL {
+		return nil
+	}
+	return originals.Delete(key)
+}
+
+// userIndexKey формирует ключ индекса ссылок пользователя.
+// Ключи одного пользователя упорядочены по времени создания ссылки, затем по uuid.
+func userIndexKey(userID string, createdAt time.Time, uuid uint64) []byte {
+	key := seqIndexKey(userID, uint64(createdNanos(createdAt)))
+	return binary.BigEndian.AppendUint64(key, uuid)
+}
+
+// seqIndexKey формирует ключ name + 0x00 + seq (big endian); ключи одного name упорядочены по seq
+func seqIndexKey(name string, seq uint64) []byte {
+	key := make([]byte, 0, len(name)+17)
+	key = append(key, name...)
+	key = append(key, 0)
+	return binary.BigEndian.AppendUint64(key, seq)
+}
+
+// clickIndexKey формирует ключ перехода; ключи одной ссылки упорядочены по времени записи
+func clickIndexKey(shortURL string, seq uint64) []byte {
+	return seqIndexKey(shortURL, seq)
+}
+
+// clickIndexPrefix возвращает префикс ключей переходов по ссылке
+func clickIndexPrefix(shortURL string) []byte {
+	return userIndexPrefix(shortURL)
+}
+
+// expiresIndexKey формирует ключ индекса истечения ссылок.
+// Ключи упорядочены по времени истечения.
+func expiresIndexKey(expiresAt time.Time, shortURL string) []byte {
+	key := binary.BigEndian.AppendUint64(make([]byte, 0, 8+len(shortURL)), uint64(expiresAt.UnixNano()))
+	return append(key, shortURL...)
+}
+
+// clickTimeKey формирует ключ индекса времени переходов.
+// Ключи упорядочены по времени перехода.
+func clickTimeKey(clickedAt time.Time, clickKey []byte) []byte {
+	key := binary.BigEndian.AppendUint64(make([]byte, 0, 8+len(clickKey)), uint64(clickedAt.UnixNano()))
+	return append(key, clickKey...)
+}
+
+// indexClickTimes заполняет индекс времени по всем записанным переходам
+func indexClickTimes(tx *bolt.Tx) error {
+	times := tx.Bucket(boltClickTimes)
+	return tx.Bucket(boltClicks).ForEach(func(k, v []byte) error {
+		var click models.Click
+		if err := json.Unmarshal(v, &click); err != nil {
+			return err
+		}
+		return times.Put(clickTimeKey(click.ClickedAt, k), nil)
+	})
+}
+
+// indexUsersByCreated перестраивает индекс ссылок пользователей в порядке времени создания,
+// если он построен в прежнем формате
+func indexUsersByCreated(tx *bolt.Tx) error {
+	meta := tx.Bucket(boltMeta)
+	if meta.Get(boltUsersByCreated) != nil {
+		return nil
+	}
+	if err := tx.DeleteBucket(boltUsers); err != nil {
+		return err
+	}
+	users, err := tx.CreateBucket(boltUsers)
+	if err != nil {
+		return err
+	}
+	err = tx.Bucket(boltURLs).ForEach(func(k, v []byte) error {
+		var url ShortenerURL
+		if err := json.Unmarshal(v, &url); err != nil {
+			return err
+		}
+		return users.Put(userIndexKey(url.UserID, url.CreatedAt, uint64(url.UUID)), k)
+	})
+	if err != nil {
+		return err
+	}
+	return meta.Put(boltUsersByCreated, []byte{1})
+}
+
+// userIndexPrefix возвращает префикс ключей индекса для пользователя
+func userIndexPrefix(userID string) []byte {
+	return append([]byte(userID), 0)
+}
+
+// addCounter изменяет служебный счётчик на delta
+func addCounter(meta *bolt.Bucket, name []byte, delta int64) error {
+	var value int64
+	if raw := meta.Get(name); raw != nil {
+		value = int64(binary.BigEndian.Uint64(raw))
+	}
+	value += delta
+	return meta.Put(name, binary.BigEndian.AppendUint64(nil, uint64(value)))
+}
+
+// readURL читает и декодирует запись по короткому ключу
+func readURL(urls *bolt.Bucket, key string) (ShortenerURL, bool, error) {
+	raw := urls.Get([]byte(key))
+	if raw == nil {
+		return ShortenerURL{}, false, nil
+	}
+	var url ShortenerURL
+	if err := json.Unmarshal(raw, &url); err != nil {
+		return ShortenerURL{}, false, err
+	}
+	return url, true, nil
+}
+
+// writeURL кодирует и сохраняет запись
+func writeURL(urls *bolt.Bucket, url ShortenerURL) error {
+	data, err := json.Marshal(url)
+	if err != nil {
+		return err
+	}
+	return urls.Put([]byte(url.ShortURL), data)
+}
+
+// Ping проверяет доступность встраиваемого хранилища
+func (b *BoltStorage) Ping(ctx context.Context) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		return nil
+	})
+}
+
+// Create сохраняет новую запись. Если оригинальный URL уже сокращён,
+// возвращает существующий короткий ключ и ErrConflict, если занят короткий ключ — ErrKeyExists.
+func (b *BoltStorage) Create(ctx context.Context, url ShortenerURL) (string, error) {
+	var existing string
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		if key := tx.Bucket(boltOriginals).Get(b.originalKey(url.UserID, url.ShortURL, url.OriginalURL)); key != nil {
+			var err error
+			existing, err = b.opts.conflict(string(key))
+			return err
+		}
+		if tx.Bucket(boltURLs).Get([]byte(url.ShortURL)) != nil {
+			return ErrKeyExists
+		}
+		return b.insertURL(tx, url)
+	})
+	if err != nil {
+		return existing, err
+	}
+	return url.ShortURL, nil
+}
+
+// CreateBatch сохраняет пакет записей в одной транзакции
+func (b *BoltStorage) CreateBatch(ctx context.Context, urls []ShortenerURL) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		keyOf := func(url ShortenerURL) string {
+			return string(b.originalKey(url.UserID, url.ShortURL, url.OriginalURL))
+		}
+		original := func(key string) (string, bool) {
+			existing := tx.Bucket(boltOriginals).Get([]byte(key))
+			return string(existing), existing != nil
+		}
+		keyExists := func(key string) bool {
+			return tx.Bucket(boltURLs).Get([]byte(key)) != nil
+		}
+		if err := b.opts.checkBatch(urls, keyOf, original, keyExists); err != nil {
+			return err
+		}
+		for _, url := range urls {
+			if err := b.insertURL(tx, url); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// insertURL добавляет новую запись и обновляет все индексы
+func (b *BoltStorage) insertURL(tx *bolt.Tx, url ShortenerURL) error {
+	urls := tx.Bucket(boltURLs)
+
+	seq, err := urls.NextSequence()
+	if err != nil {
+		return err
+	}
+	url.UUID = int(seq)
+	url = stampCreated(url, time.Now())
+
+	if err := writeURL(urls, url); err != nil {
+		return err
+	}
+	if !url.IsDeleted {
+		if err := tx.Bucket(boltOriginals).Put(b.originalKey(url.UserID, url.ShortURL, url.OriginalURL), []byte(url.ShortURL)); err != nil {
+			return err
+		}
+	}
+	if err := tx.Bucket(boltUsers).Put(userIndexKey(url.UserID, url.CreatedAt, seq), []byte(url.ShortURL)); err != nil {
+		return err
+	}
+	if err := tx.Bucket(boltOwners).Put([]byte(url.UserID), nil); err != nil {
+		return err
+	}
+	if url.ExpiresAt != nil && !url.IsDeleted {
+		if err := tx.Bucket(boltExpires).Put(expiresIndexKey(*url.ExpiresAt, url.ShortURL), nil); err != nil {
+			return err
+		}
+	}
+	if url.IsDeleted {
+		return nil
+	}
+	return addCounter(tx.Bucket(boltMeta), boltLiveURLs, 1)
+}
+
+// Get возвращает оригинальный URL по сокращённому
+func (b *BoltStorage) Get(ctx context.Context, key string) (string, error) {
+	var originalURL string
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		url, ok, err := readURL(tx.Bucket(boltURLs), key)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrNotFound
+		}
+		if url.IsDeleted {
+			return ErrDeleted
+		}
+		if url.Expired(time.Now()) {
+			return ErrExpired
+		}
+		originalURL = url.OriginalURL
+		return nil
+	})
+	return originalURL, err
+}
+
+// GetByUser возвращает все URL-ы пользователя в порядке их создания
+func (b *BoltStorage) GetByUser(ctx context.Context, username string) ([]models.ShortURLResponse, error) {
+	host, _ := ctx.Value(contextkeys.HostKey).(string)
+	var result []models.ShortURLResponse
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		urls := tx.Bucket(boltURLs)
+		prefix := userIndexPrefix(username)
+
+		c := tx.Bucket(boltUsers).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			url, ok, err := readURL(urls, string(v))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			result = append(result, models.ShortURLResponse{
+				ShortURL:    host + "/" + url.ShortURL,
+				OriginalURL: url.OriginalURL,
+			})
+		}
+		return nil
+	})
+	return result, err
+}
+
+// ListByUser возвращает страницу ссылок пользователя, проходя индекс пользователя
+// от курсора в нужном направлении
+func (b *BoltStorage) ListByUser(ctx context.Context, userID string, opts models.ListOptions) ([]ShortenerURL, string, error) {
+	cursor, err := parseListCursor(opts.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var result []ShortenerURL
+	var next string
+
+	err = b.db.View(func(tx *bolt.Tx) error {
+		urls := tx.Bucket(boltURLs)
+		prefix := userIndexPrefix(userID)
+		c := tx.Bucket(boltUsers).Cursor()
+
+		var k, v []byte
+		step := c.Next
+		switch {
+		case opts.Descending():
+			step = c.Prev
+			if opts.Cursor != "" {
+				k, v = c.Seek(userIndexKey(userID, cursor.createdAt, uint64(cursor.id)))
+			} else {
+				// первый ключ после всех ключей пользователя
+				k, v = c.Seek(append([]byte(userID), 1))
+			}
+			if k == nil {
+				k, v = c.Last()
+			} else {
+				k, v = c.Prev()
+			}
+		case opts.Cursor != "":
+			k, v = c.Seek(userIndexKey(userID, cursor.createdAt, uint64(cursor.id)+1))
+		default:
+			k, v = c.Seek(prefix)
+		}
+
+		for ; k != nil && bytes.HasPrefix(k, prefix); k, v = step() {
+			url, ok, err := readURL(urls, string(v))
+			if err != nil {
+				return err
+			}
+			if !ok || !listMatches(url, opts) {
+				continue
+			}
+			if opts.Limit > 0 && len(result) == opts.Limit {
+				next = cursorOf(result[len(result)-1]).String()
+				return nil
+			}
+			result = append(result, url)
+		}
+		return nil
+	})
+	return result, next, err
+}
+
+// DeleteURLs помечает ссылки пользователя как удалённые
+func (b *BoltStorage) DeleteURLs(ctx context.Context, userID string, ids []string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		_, err := b.deleteURLs(tx, userID, ids)
+		return err
+	})
+}
+
+// DeleteURLsBatch помечает удалёнными ссылки нескольких пользователей в одной транзакции
+func (b *BoltStorage) DeleteURLsBatch(ctx context.Context, reqs []models.DeleteRequest) ([]models.DeleteOutcomes, error) {
+	results := make([]models.DeleteOutcomes, 0, len(reqs))
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		for _, req := range reqs {
+			outcomes, err := b.deleteURLs(tx, req.UserID, req.ShortURLs)
+			if err != nil {
+				return err
+			}
+			results = append(results, outcomes)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// deleteURLs помечает ссылки пользователя удалёнными, освобождает их оригинальные URL,
+// уменьшает счётчик живых ссылок и возвращает результат по каждому ключу
+func (b *BoltStorage) deleteURLs(tx *bolt.Tx, userID string, ids []string) (models.DeleteOutcomes, error) {
+	urls := tx.Bucket(boltURLs)
+	meta := tx.Bucket(boltMeta)
+
+	outcomes := make(models.DeleteOutcomes, len(ids))
+	now := time.Now()
+	for _, id := range ids {
+		url, ok, err := readURL(urls, id)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case !ok:
+			outcomes[id] = models.DeleteOutcomeNotFound
+			continue
+		case url.UserID != userID:
+			outcomes[id] = models.DeleteOutcomeNotOwned
+			continue
+		}
+		outcomes[id] = models.DeleteOutcomeDeleted
+		if url.IsDeleted {
+			continue
+		}
+		if err := b.releaseOriginal(tx, url); err != nil {
+			return nil, err
+		}
+		if err := writeURL(urls, markDeleted(url, now)); err != nil {
+			return nil, err
+		}
+		if err := addCounter(meta, boltLiveURLs, -1); err != nil {
+			return nil, err
+		}
+	}
+	return outcomes, nil
+}
+
+// CountURLs возвращает количество не удалённых URL в хранилище.
+func (b *BoltStorage) CountURLs(ctx context.Context) (int64, error) {
+	var count int64
+	err := b.db.View(func(tx *bolt.Tx) error {
+		if raw := tx.Bucket(boltMeta).Get(boltLiveURLs); raw != nil {
+			count = int64(binary.BigEndian.Uint64(raw))
+		}
+		return nil
+	})
+	return count, err
+}
+
+// CountUsers возвращает количество пользователей в хранилище.
+func (b *BoltStorage) CountUsers(ctx context.Context) (int64, error) {
+	var count int64
+	err := b.db.View(func(tx *bolt.Tx) error {
+		count = int64(tx.Bucket(boltOwners).Stats().KeyN)
+		return nil
+	})
+	return count, err
+}
+
+// Scan возвращает записи в порядке коротких ключей, начиная после курсора
+func (b *BoltStorage) Scan(ctx context.Context, cursor string, limit int) ([]ShortenerURL, string, error) {
+	var result []ShortenerURL
+	var next string
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltURLs).Cursor()
+
+		k, v := c.First()
+		if cursor != "" {
+			k, v = c.Seek([]byte(cursor))
+			if k != nil && string(k) == cursor {
+				k, v = c.Next()
+			}
+		}
+
+		for ; k != nil; k, v = c.Next() {
+			if limit > 0 && len(result) == limit {
+				next = result[len(result)-1].ShortURL
+				return nil
+			}
+			var url ShortenerURL
+			if err := json.Unmarshal(v, &url); err != nil {
+				return err
+			}
+			result = append(result, url)
+		}
+		return nil
+	})
+	return result, next, err
+}
+
+// Import сохраняет записи как есть в одной транзакции.
+// Записи с уже существующим коротким ключом или оригинальным URL пропускаются.
+func (b *BoltStorage) Import(ctx context.Context, urls []ShortenerURL) (int, error) {
+	imported := 0
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		imported = 0
+		for _, url := range urls {
+			if tx.Bucket(boltURLs).Get([]byte(url.ShortURL)) != nil {
+				continue
+			}
+			if !url.IsDeleted && tx.Bucket(boltOriginals).Get(b.originalKey(url.UserID, url.ShortURL, url.OriginalURL)) != nil {
+				continue
+			}
+			if err := b.insertURL(tx, url); err != nil {
+				return err
+			}
+			imported++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return imported, nil
+}
+
+// PurgeExpired помечает удалёнными ссылки с истёкшим сроком действия,
+// проходя индекс истечения до момента now
+func (b *BoltStorage) PurgeExpired(ctx context.Context, now time.Time) (int64, error) {
+	var purged int64
+	limit := binary.BigEndian.AppendUint64(nil, uint64(now.UnixNano()))
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		urls := tx.Bucket(boltURLs)
+		meta := tx.Bucket(boltMeta)
+
+		var done [][]byte
+		c := tx.Bucket(boltExpires).Cursor()
+		for k, _ := c.First(); k != nil && bytes.Compare(k[:8], limit) <= 0; k, _ = c.Next() {
+			done = append(done, k)
+
+			url, ok, err := readURL(urls, string(k[8:]))
+			if err != nil {
+				return err
+			}
+			if !ok || url.IsDeleted {
+				continue
+			}
+			if err := b.releaseOriginal(tx, url); err != nil {
+				return err
+			}
+			if err := writeURL(urls, markDeleted(url, now)); err != nil {
+				return err
+			}
+			if err := addCounter(meta, boltLiveURLs, -1); err != nil {
+				return err
+			}
+			purged++
+		}
+
+		for _, k := range done {
+			if err := tx.Bucket(boltExpires).Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
+
+// GetURL возвращает запись по короткому ключу
+func (b *BoltStorage) GetURL(ctx context.Context, key string) (ShortenerURL, error) {
+	var url ShortenerURL
+	err := b.db.View(func(tx *bolt.Tx) error {
+		var ok bool
+		var err error
+		url, ok, err = readURL(tx.Bucket(boltURLs), key)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrNotFound
+		}
+		return nil
+	})
+	return url, err
+}
+
+// ReserveKeys резервирует значения счётчика генератора ключей в бакете meta
+func (b *BoltStorage) ReserveKeys(ctx context.Context, name string, floor, n uint64) (uint64, error) {
+	var value uint64
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		meta := tx.Bucket(boltMeta)
+		key := []byte(boltKeyCounterPrefix + name)
+		if raw := meta.Get(key); raw != nil {
+			value = binary.BigEndian.Uint64(raw)
+		} else {
+			value = uint64(tx.Bucket(boltURLs).Stats().KeyN)
+		}
+		value = max(value, floor) + n
+		return meta.Put(key, binary.BigEndian.AppendUint64(nil, value))
+	})
+	return value, err
+}
+
+// SaveClicks сохраняет пачку переходов в одной транзакции.
+// Переходы старше срока хранения периодически удаляются в той же транзакции.
+func (b *BoltStorage) SaveClicks(ctx context.Context, clicks []models.Click) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltClicks)
+		times := tx.Bucket(boltClickTimes)
+		for _, click := range clicks {
+			seq, err := bucket.NextSequence()
+			if err != nil {
+				return err
+			}
+			data, err := json.Marshal(click)
+			if err != nil {
+				return err
+			}
+			key := clickIndexKey(click.ShortURL, seq)
+			if err := bucket.Put(key, data); err != nil {
+				return err
+			}
+			if err := times.Put(clickTimeKey(click.ClickedAt, key), nil); err != nil {
+				return err
+			}
+		}
+		if cutoff, ok := b.clickPrune.due(b.opts.clickRetention, time.Now()); ok {
+			return pruneClicks(tx, cutoff)
+		}
+		return nil
+	})
+}
+
+// pruneClicks удаляет переходы раньше cutoff по индексу времени
+func pruneClicks(tx *bolt.Tx, cutoff time.Time) error {
+	clicks, times := tx.Bucket(boltClicks), tx.Bucket(boltClickTimes)
+	limit := binary.BigEndian.AppendUint64(nil, uint64(cutoff.UnixNano()))
+
+	var done [][]byte
+	c := times.Cursor()
+	for k, _ := c.First(); k != nil && bytes.Compare(k[:8], limit) < 0; k, _ = c.Next() {
+		done = append(done, k)
+	}
+	for _, k := range done {
+		if err := clicks.Delete(k[8:]); err != nil {
+			return err
+		}
+		if err := times.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClickStats возвращает статистику переходов по ссылке
+func (b *BoltStorage) ClickStats(ctx context.Context, key string, bucket time.Duration) (models.LinkStats, error) {
+	clicks, err := b.Clicks(ctx, key)
+	if err != nil {
+		return models.LinkStats{}, err
+	}
+	return aggregateClicks(key, clicks, bucket), nil
+}
+
+// Clicks возвращает все сохранённые переходы по ссылке
+func (b *BoltStorage) Clicks(ctx context.Context, key string) ([]models.Click, error) {
+	var clicks []models.Click
+	err := b.db.View(func(tx *bolt.Tx) error {
+		prefix := clickIndexPrefix(key)
+		c := tx.Bucket(boltClicks).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var click models.Click
+			if err := json.Unmarshal(v, &click); err != nil {
+				return err
+			}
+			clicks = append(clicks, click)
+		}
+		return nil
+	})
+	return clicks, err
+}
+
+// UpdateURL меняет оригинальный URL ссылки и добавляет версию в историю в одной транзакции.
+// Если новый URL уже сокращён другой ссылкой, возвращает ErrConflict.
+func (b *BoltStorage) UpdateURL(ctx context.Context, userID, key, originalURL string) (models.URLVersion, error) {
+	var version models.URLVersion
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		urls := tx.Bucket(boltURLs)
+		url, ok, err := readURL(urls, key)
+		if err != nil {
+			return err
+		}
+		if !ok || url.UserID != userID {
+			return ErrNotFound
+		}
+		if url.IsDeleted {
+			return ErrDeleted
+		}
+
+		originals := tx.Bucket(boltOriginals)
+		if owner := originals.Get(b.originalKey(userID, key, originalURL)); owner != nil && string(owner) != key {
+			return ErrConflict
+		}
+		if err := b.releaseOriginal(tx, url); err != nil {
+			return err
+		}
+		if err := originals.Put(b.originalKey(userID, key, originalURL), []byte(key)); err != nil {
+			return err
+		}
+
+		history := tx.Bucket(boltHistory)
+		version = models.URLVersion{
+			Version:     lastHistoryVersion(history, key) + 1,
+			OriginalURL: originalURL,
+			PreviousURL: url.OriginalURL,
+			ChangedBy:   userID,
+			ChangedAt:   time.Now(),
+		}
+		data, err := json.Marshal(version)
+		if err != nil {
+			return err
+		}
+		if err := history.Put(historyIndexKey(key, version.Version), data); err != nil {
+			return err
+		}
+
+		url.OriginalURL = originalURL
+		return writeURL(urls, url)
+	})
+	if err != nil {
+		return models.URLVersion{}, err
+	}
+	return version, nil
+}
+
+// URLHistory возвращает историю изменений ссылки
+func (b *BoltStorage) URLHistory(ctx context.Context, key string) ([]models.URLVersion, error) {
+	var history []models.URLVersion
+	err := b.db.View(func(tx *bolt.Tx) error {
+		prefix := userIndexPrefix(key)
+		c := tx.Bucket(boltHistory).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var version models.URLVersion
+			if err := json.Unmarshal(v, &version); err != nil {
+				return err
+			}
+			history = append(history, version)
+		}
+		return nil
+	})
+	return history, err
+}
+
+// ImportHistory сохраняет перенесённую историю изменений ссылки как есть, с исходными номерами версий
+func (b *BoltStorage) ImportHistory(ctx context.Context, key string, history []models.URLVersion) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltHistory)
+		for _, version := range history {
+			data, err := json.Marshal(version)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(historyIndexKey(key, version.Version), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// historyIndexKey формирует ключ версии ссылки; ключи одной ссылки упорядочены по номеру версии
+func historyIndexKey(shortURL string, version int) []byte {
+	return seqIndexKey(shortURL, uint64(version))
+}
+
+// lastHistoryVersion возвращает номер последней версии ссылки или 0, если ссылку не меняли
+func lastHistoryVersion(history *bolt.Bucket, shortURL string) int {
+	c := history.Cursor()
+	k, _ := c.Seek(seqIndexKey(shortURL, math.MaxUint64))
+	if k == nil {
+		k, _ = c.Last()
+	} else {
+		k, _ = c.Prev()
+	}
+	if k == nil || !bytes.HasPrefix(k, userIndexPrefix(shortURL)) {
+		return 0
+	}
+	return int(binary.BigEndian.Uint64(k[len(k)-8:]))
+}
+
+// Close закрывает файл хранилища
+func (b *BoltStorage) Close() error {
+	return b.db.Close()
+}
@@ -0,0 +1,251 @@
+package storage_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/issafronov/shortener/internal/app/config"
+	"github.com/issafronov/shortener/internal/app/contextkeys"
+	"github.com/issafronov/shortener/internal/app/models"
+	"github.com/issafronov/shortener/internal/app/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBoltStorage(t *testing.T) (*storage.BoltStorage, string) {
+	path := filepath.Join(t.TempDir(), "shortener.db")
+	s, err := storage.NewBoltStorage(path)
+	require.NoError(t, err)
+	return s, path
+}
+
+func TestBoltStorage_CreateAndGet(t *testing.T) {
+	s, _ := newTestBoltStorage(t)
+	defer s.Close()
+	ctx := context.Background()
+
+	key, err := s.Create(ctx, storage.ShortenerURL{ShortURL: "abc123", OriginalURL: "https://example.com", UserID: "user1"})
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", key)
+
+	got, err := s.Get(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", got)
+
+	_, err = s.Get(ctx, "missing")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func TestBoltStorage_Create_Conflict(t *testing.T) {
+	s, _ := newTestBoltStorage(t)
+	defer s.Close()
+	ctx := context.Background()
+
+	_, err := s.Create(ctx, storage.ShortenerURL{ShortURL: "abc123", OriginalURL: "https://example.com", UserID: "user1"})
+	require.NoError(t, err)
+
+	existing, err := s.Create(ctx, storage.ShortenerURL{ShortURL: "xyz789", OriginalURL: "https://example.com", UserID: "user2"})
+	assert.ErrorIs(t, err, storage.ErrConflict)
+	assert.Equal(t, "abc123", existing)
+
+	_, err = s.Create(ctx, storage.ShortenerURL{ShortURL: "abc123", OriginalURL: "https://other.com", UserID: "user2"})
+	assert.ErrorIs(t, err, storage.ErrKeyExists)
+}
+
+func TestBoltStorage_UserIndexAndDelete(t *testing.T) {
+	s, path := newTestBoltStorage(t)
+	ctx := context.WithValue(context.Background(), contextkeys.HostKey, "http://localhost")
+
+	for _, url := range []storage.ShortenerURL{
+		{ShortURL: "a", OriginalURL: "https://a.com", UserID: "user1"},
+		{ShortURL: "b", OriginalURL: "https://b.com", UserID: "user2"},
+		{ShortURL: "c", OriginalURL: "https://c.com", UserID: "user1"},
+	} {
+		_, err := s.Create(ctx, url)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, s.DeleteURLs(ctx, "user1", []string{"a", "b"}))
+	require.NoError(t, s.Close())
+
+	// состояние сохраняется между открытиями файла
+	s, err := storage.NewBoltStorage(path)
+	require.NoError(t, err)
+	defer s.Close()
+
+	links, err := s.GetByUser(ctx, "user1")
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+	assert.Equal(t, "http://localhost/a", links[0].ShortURL)
+	assert.Equal(t, "http://localhost/c", links[1].ShortURL)
+
+	_, err = s.Get(ctx, "a")
+	assert.ErrorIs(t, err, storage.ErrDeleted)
+	_, err = s.Get(ctx, "b")
+	assert.NoError(t, err)
+
+	urls, err := s.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), urls)
+
+	users, err := s.CountUsers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), users)
+}
+
+func TestResolveEngine(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.Config
+		want string
+	}{
+		{name: "explicit", cfg: config.Config{StorageEngine: storage.EngineBolt, DatabaseDSN: "dsn"}, want: storage.EngineBolt},
+		{name: "postgres", cfg: config.Config{DatabaseDSN: "dsn", FileStoragePath: "file.json"}, want: storage.EnginePostgres},
+		{name: "file", cfg: config.Config{FileStoragePath: "file.json"}, want: storage.EngineFile},
+		{name: "memory", cfg: config.Config{}, want: storage.EngineMemory},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, storage.ResolveEngine(&tt.cfg))
+		})
+	}
+}
+
+func TestBoltStorage_PurgeExpired(t *testing.T) {
+	s, _ := newTestBoltStorage(t)
+	defer s.Close()
+	ctx := context.Background()
+	now := time.Now()
+	soon := now.Add(time.Minute)
+	later := now.Add(time.Hour)
+
+	_, err := s.Create(ctx, storage.ShortenerURL{ShortURL: "soon", OriginalURL: "https://soon.com", UserID: "user1", ExpiresAt: &soon})
+	require.NoError(t, err)
+	_, err = s.Create(ctx, storage.ShortenerURL{ShortURL: "later", OriginalURL: "https://later.com", UserID: "user1", ExpiresAt: &later})
+	require.NoError(t, err)
+
+	purged, err := s.PurgeExpired(ctx, now.Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	// повторная очистка не трогает уже обработанные записи
+	purged, err = s.PurgeExpired(ctx, now.Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+
+	_, err = s.Get(ctx, "soon")
+	assert.ErrorIs(t, err, storage.ErrDeleted)
+	_, err = s.Get(ctx, "later")
+	assert.NoError(t, err)
+
+	count, err := s.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestBoltStorage_ClickStats(t *testing.T) {
+	s, _ := newTestBoltStorage(t)
+	defer s.Close()
+	ctx := context.Background()
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	require.NoError(t, s.SaveClicks(ctx, []models.Click{
+		{ShortURL: "abc", ClickedAt: base, IPHash: "a"},
+		{ShortURL: "abcd", ClickedAt: base, IPHash: "b"},
+		{ShortURL: "abc", ClickedAt: base.Add(time.Minute), IPHash: "b"},
+	}))
+
+	stats, err := s.ClickStats(ctx, "abc", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats.Total)
+	assert.Equal(t, int64(2), stats.UniqueVisitors)
+	assert.Equal(t, []models.StatsBucket{{Start: base, Count: 2}}, stats.Histogram)
+}
+
+func TestBoltStorage_ListByUser(t *testing.T) {
+	s, _ := newTestBoltStorage(t)
+	defer s.Close()
+	ctx := context.Background()
+
+	for _, url := range []storage.ShortenerURL{
+		{ShortURL: "a", OriginalURL: "https://a.com", UserID: "user1"},
+		{ShortURL: "x", OriginalURL: "https://x.com", UserID: "user2"},
+		{ShortURL: "b", OriginalURL: "https://b.com", UserID: "user1"},
+		{ShortURL: "c", OriginalURL: "https://c.com", UserID: "user1"},
+		{ShortURL: "d", OriginalURL: "https://d.org", UserID: "user1"},
+	} {
+		_, err := s.Create(ctx, url)
+		require.NoError(t, err)
+	}
+	require.NoError(t, s.DeleteURLs(ctx, "user1", []string{"b"}))
+
+	keys := func(urls []storage.ShortenerURL) []string {
+		var result []string
+		for _, url := range urls {
+			result = append(result, url.ShortURL)
+		}
+		return result
+	}
+
+	page, next, err := s.ListByUser(ctx, "user1", models.ListOptions{Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "c"}, keys(page))
+	page, next, err = s.ListByUser(ctx, "user1", models.ListOptions{Limit: 2, Cursor: next})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d"}, keys(page))
+	assert.Empty(t, next)
+
+	page, next, err = s.ListByUser(ctx, "user1", models.ListOptions{Limit: 2, Sort: models.SortCreatedDesc, IncludeDeleted: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d", "c"}, keys(page))
+	page, _, err = s.ListByUser(ctx, "user1", models.ListOptions{Limit: 2, Sort: models.SortCreatedDesc, IncludeDeleted: true, Cursor: next})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "a"}, keys(page))
+
+	page, _, err = s.ListByUser(ctx, "user1", models.ListOptions{Query: ".com", Sort: models.SortCreatedDesc})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c", "a"}, keys(page))
+
+	page, _, err = s.ListByUser(ctx, "user2", models.ListOptions{Sort: models.SortCreatedDesc})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"x"}, keys(page))
+}
+
+func TestBoltStorage_UpdateURL(t *testing.T) {
+	s, _ := newTestBoltStorage(t)
+	defer s.Close()
+	ctx := context.Background()
+
+	_, err := s.Create(ctx, storage.ShortenerURL{ShortURL: "abc", OriginalURL: "https://a.com", UserID: "user1"})
+	require.NoError(t, err)
+	_, err = s.Create(ctx, storage.ShortenerURL{ShortURL: "xyz", OriginalURL: "https://x.com", UserID: "user1"})
+	require.NoError(t, err)
+
+	_, err = s.UpdateURL(ctx, "intruder", "abc", "https://b.com")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	_, err = s.UpdateURL(ctx, "user1", "abc", "https://x.com")
+	assert.ErrorIs(t, err, storage.ErrConflict)
+
+	for i, target := range []string{"https://b.com", "https://c.com"} {
+		version, err := s.UpdateURL(ctx, "user1", "abc", target)
+		require.NoError(t, err)
+		assert.Equal(t, i+1, version.Version)
+	}
+
+	got, err := s.Get(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://c.com", got)
+
+	// прежний оригинальный URL освобождается и может быть сокращён заново
+	key, err := s.Create(ctx, storage.ShortenerURL{ShortURL: "new", OriginalURL: "https://a.com", UserID: "user2"})
+	require.NoError(t, err)
+	assert.Equal(t, "new", key)
+
+	history, err := s.URLHistory(ctx, "abc")
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "https://a.com", history[0].PreviousURL)
+	assert.Equal(t, "https://b.com", history[1].PreviousURL)
+}
@@ -0,0 +1,267 @@
+package storage
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/issafronov/shortener/internal/app/models"
+	"golang.org/x/sync/singleflight"
+)
+
+var _ Storage = (*CachedStorage)(nil)
+
+// cacheLoadTimeout ограничивает общий запрос к хранилищу при промахе:
+// он не отменяется вместе с запросом вызывающего, чтобы не подвести остальных ожидающих
+const cacheLoadTimeout = 10 * time.Second
+
+// cacheEntry — результат поиска ссылки: оригинальный URL или ошибка ErrNotFound, ErrDeleted, ErrExpired
+type cacheEntry struct {
+	key      string
+	original string
+	err      error
+	until    time.Time
+}
+
+// loadGeneration — поколение ключа, которое увеличивается при каждом его сбросе, пока ключ
+// читается из хранилища, чтобы не сохранять в кэш результат, прочитанный до изменения ссылки
+type loadGeneration struct {
+	gen   uint64
+	loads int
+}
+
+// CachedStorage кэширует результаты Get в ограниченном LRU-кэше.
+// Неизвестные, удалённые и истёкшие ключи тоже кэшируются. Одновременные промахи по одному ключу
+// объединяются в один запрос к хранилищу. Записи сбрасываются при изменении ссылок через этот экземпляр,
+// изменения из других экземпляров сервиса становятся видны по истечении ttl.
+// Остальные методы передаются хранилищу без изменений.
+type CachedStorage struct {
+	Storage
+
+	size        int
+	ttl         time.Duration
+	negativeTTL time.Duration
+	now         func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+	// loading хранит поколения ключей, которые сейчас читаются из хранилища
+	loading map[string]*loadGeneration
+
+	loads  singleflight.Group
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// NewCachedStorage возвращает хранилище с кэшем не более чем на size ключей.
+// Найденные ссылки хранятся в кэше ttl, неизвестные ключи — negativeTTL.
+func NewCachedStorage(next Storage, size int, ttl, negativeTTL time.Duration) *CachedStorage {
+	return &CachedStorage{
+		Storage:     next,
+		size:        size,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		now:         time.Now,
+		entries:     make(map[string]*list.Element, size),
+		order:       list.New(),
+		loading:     make(map[string]*loadGeneration),
+	}
+}
+
+// Unwrap возвращает исходное хранилище
+func (c *CachedStorage) Unwrap() Storage {
+	return c.Storage
+}
+
+// CacheStats возвращает число попаданий и промахов кэша и число ключей в нём
+func (c *CachedStorage) CacheStats() models.CacheStats {
+	c.mu.Lock()
+	entries := c.order.Len()
+	c.mu.Unlock()
+	return models.CacheStats{Hits: c.hits.Load(), Misses: c.misses.Load(), Entries: entries}
+}
+
+// Get возвращает оригинальный URL по короткому ключу, обращаясь к хранилищу только при промахе
+func (c *CachedStorage) Get(ctx context.Context, url string) (string, error) {
+	if entry, ok := c.lookup(url); ok {
+		c.hits.Add(1)
+		return entry.original, entry.err
+	}
+	c.misses.Add(1)
+
+	results := c.loads.DoChan(url, func() (any, error) {
+		// запрос мог завершиться, пока вызывающий ждал своей очереди
+		if entry, ok := c.lookup(url); ok {
+			return entry, nil
+		}
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cacheLoadTimeout)
+		defer cancel()
+		return c.load(loadCtx, url)
+	})
+	// каждый ожидающий прекращает ждать по своему контексту, общий запрос продолжается
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case res := <-results:
+		if res.Err != nil {
+			return "", res.Err
+		}
+		entry := res.Val.(cacheEntry)
+		return entry.original, entry.err
+	}
+}
+
+// lookup возвращает действующую запись кэша и поднимает её в начало очереди вытеснения
+func (c *CachedStorage) lookup(key string) (cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return cacheEntry{}, false
+	}
+	entry := el.Value.(cacheEntry)
+	if !c.now().Before(entry.until) {
+		c.remove(el)
+		return cacheEntry{}, false
+	}
+	c.order.MoveToFront(el)
+	return entry, true
+}
+
+// load читает ссылку из хранилища и сохраняет результат в кэш.
+// Ошибки хранилища не кэшируются и возвращаются вызывающему.
+func (c *CachedStorage) load(ctx context.Context, key string) (cacheEntry, error) {
+	c.mu.Lock()
+	g, ok := c.loading[key]
+	if !ok {
+		g = &loadGeneration{}
+		c.loading[key] = g
+	}
+	g.loads++
+	gen := g.gen
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if g.loads--; g.loads == 0 {
+			delete(c.loading, key)
+		}
+	}()
+
+	url, err := c.Storage.GetURL(ctx, key)
+	now := c.now()
+	entry := cacheEntry{key: key, until: now.Add(c.ttl)}
+	switch {
+	case errors.Is(err, ErrNotFound):
+		entry.err, entry.until = ErrNotFound, now.Add(c.negativeTTL)
+	case err != nil:
+		return cacheEntry{}, err
+	case url.IsDeleted:
+		entry.err = ErrDeleted
+	case url.Expired(now):
+		entry.err = ErrExpired
+	default:
+		entry.original = url.OriginalURL
+		// ссылка не должна пережить в кэше свой срок действия
+		if url.ExpiresAt != nil && url.ExpiresAt.Before(entry.until) {
+			entry.until = *url.ExpiresAt
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if g.gen == gen {
+		c.store(entry)
+	}
+	return entry, nil
+}
+
+// store добавляет запись в кэш, вытесняя самую давно использованную при переполнении
+func (c *CachedStorage) store(entry cacheEntry) {
+	if el, ok := c.entries[entry.key]; ok {
+		el.Value = entry
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[entry.key] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+// remove удаляет запись из кэша
+func (c *CachedStorage) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(cacheEntry).key)
+}
+
+// invalidate сбрасывает ключи после изменения ссылок
+func (c *CachedStorage) invalidate(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		if g, ok := c.loading[key]; ok {
+			g.gen++
+		}
+		c.loads.Forget(key)
+		if el, ok := c.entries[key]; ok {
+			c.remove(el)
+		}
+	}
+}
+
+// Create сохраняет ссылку и сбрасывает отрицательный результат поиска её ключа
+func (c *CachedStorage) Create(ctx context.Context, url ShortenerURL) (string, error) {
+	defer c.invalidate(url.ShortURL)
+	return c.Storage.Create(ctx, url)
+}
+
+// CreateBatch атомарно сохраняет пакет записей и сбрасывает их ключи
+func (c *CachedStorage) CreateBatch(ctx context.Context, urls []ShortenerURL) error {
+	defer c.invalidate(shortURLs(urls)...)
+	return c.Storage.CreateBatch(ctx, urls)
+}
+
+// Import сохраняет записи как есть и сбрасывает их ключи
+func (c *CachedStorage) Import(ctx context.Context, urls []ShortenerURL) (int, error) {
+	defer c.invalidate(shortURLs(urls)...)
+	return c.Storage.Import(ctx, urls)
+}
+
+// DeleteURLs помечает ссылки пользователя удалёнными и сбрасывает их ключи
+func (c *CachedStorage) DeleteURLs(ctx context.Context, userID string, urls []string) error {
+	defer c.invalidate(urls...)
+	return c.Storage.DeleteURLs(ctx, userID, urls)
+}
+
+// DeleteURLsBatch помечает удалёнными ссылки нескольких пользователей и сбрасывает их ключи
+func (c *CachedStorage) DeleteURLsBatch(ctx context.Context, reqs []models.DeleteRequest) ([]models.DeleteOutcomes, error) {
+	var keys []string
+	for _, req := range reqs {
+		keys = append(keys, req.ShortURLs...)
+	}
+	defer c.invalidate(keys...)
+	return c.Storage.DeleteURLsBatch(ctx, reqs)
+}
+
+// UpdateURL меняет оригинальный URL ссылки и сбрасывает её ключ
+func (c *CachedStorage) UpdateURL(ctx context.Context, userID, key, originalURL string) (models.URLVersion, error) {
+	defer c.invalidate(key)
+	return c.Storage.UpdateURL(ctx, userID, key, originalURL)
+}
+
+// shortURLs возвращает короткие ключи записей
+func shortURLs(urls []ShortenerURL) []string {
+	keys := make([]string, len(urls))
+	for i, url := range urls {
+		keys[i] = url.ShortURL
+	}
+	return keys
+}
@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/issafronov/shortener/internal/app/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingStorage считает обращения к GetURL и может задерживать их до закрытия gate
+type countingStorage struct {
+	*MemoryStorage
+	loads atomic.Int64
+	gate  chan struct{}
+}
+
+func (s *countingStorage) GetURL(ctx context.Context, key string) (ShortenerURL, error) {
+	s.loads.Add(1)
+	if s.gate != nil {
+		<-s.gate
+	}
+	return s.MemoryStorage.GetURL(ctx, key)
+}
+
+func TestCachedStorage_Get(t *testing.T) {
+	ctx := context.Background()
+	next := &countingStorage{MemoryStorage: NewMemoryStorage()}
+	_, err := next.Create(ctx, ShortenerURL{ShortURL: "abc", OriginalURL: "https://a.com", UserID: "user1"})
+	require.NoError(t, err)
+	cache := NewCachedStorage(next, 10, time.Minute, time.Minute)
+
+	for range 3 {
+		got, err := cache.Get(ctx, "abc")
+		require.NoError(t, err)
+		assert.Equal(t, "https://a.com", got)
+	}
+	for range 2 {
+		_, err = cache.Get(ctx, "unknown")
+		assert.ErrorIs(t, err, ErrNotFound)
+	}
+	assert.Equal(t, int64(2), next.loads.Load())
+	assert.Equal(t, models.CacheStats{Hits: 3, Misses: 2, Entries: 2}, cache.CacheStats())
+
+	_, err = cache.Create(ctx, ShortenerURL{ShortURL: "unknown", OriginalURL: "https://b.com", UserID: "user1"})
+	require.NoError(t, err)
+	got, err := cache.Get(ctx, "unknown")
+	require.NoError(t, err)
+	assert.Equal(t, "https://b.com", got)
+
+	_, err = cache.UpdateURL(ctx, "user1", "abc", "https://c.com")
+	require.NoError(t, err)
+	got, err = cache.Get(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://c.com", got)
+
+	require.NoError(t, cache.DeleteURLs(ctx, "user1", []string{"abc"}))
+	_, err = cache.Get(ctx, "abc")
+	assert.ErrorIs(t, err, ErrDeleted)
+
+	_, err = cache.DeleteURLsBatch(ctx, []models.DeleteRequest{{UserID: "user1", ShortURLs: []string{"unknown"}}})
+	require.NoError(t, err)
+	_, err = cache.Get(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrDeleted)
+}
+
+func TestCachedStorage_Expiry(t *testing.T) {
+	ctx := context.Background()
+	next := &countingStorage{MemoryStorage: NewMemoryStorage()}
+	now := time.Now()
+	expiresAt := now.Add(30 * time.Second)
+	_, err := next.Create(ctx, ShortenerURL{ShortURL: "abc", OriginalURL: "https://a.com", ExpiresAt: &expiresAt})
+	require.NoError(t, err)
+
+	cache := NewCachedStorage(next, 10, time.Minute, time.Second)
+	cache.now = func() time.Time { return now }
+
+	_, err = cache.Get(ctx, "abc")
+	require.NoError(t, err)
+	_, err = cache.Get(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	now = now.Add(2 * time.Second)
+	_, err = cache.Get(ctx, "abc")
+	require.NoError(t, err)
+	_, err = cache.Get(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, int64(3), next.loads.Load(), "negative entry must expire after negative ttl")
+
+	now = expiresAt
+	_, err = cache.Get(ctx, "abc")
+	assert.ErrorIs(t, err, ErrExpired, "link must not outlive its expiry in the cache")
+}
+
+func TestCachedStorage_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	next := &countingStorage{MemoryStorage: NewMemoryStorage()}
+	for _, key := range []string{"a", "b", "c"} {
+		_, err := next.Create(ctx, ShortenerURL{ShortURL: key, OriginalURL: "https://" + key + ".com"})
+		require.NoError(t, err)
+	}
+	cache := NewCachedStorage(next, 2, time.Minute, time.Minute)
+
+	for _, key := range []string{"a", "b", "a", "c", "a"} {
+		_, err := cache.Get(ctx, key)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int64(3), next.loads.Load())
+
+	_, err := cache.Get(ctx, "b")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), next.loads.Load(), "b must have been evicted")
+	assert.Equal(t, 2, cache.CacheStats().Entries)
+}
+
+func TestCachedStorage_CollapsesConcurrentMisses(t *testing.T) {
+	ctx := context.Background()
+	next := &countingStorage{MemoryStorage: NewMemoryStorage(), gate: make(chan struct{})}
+	_, err := next.Create(ctx, ShortenerURL{ShortURL: "hot", OriginalURL: "https://hot.com"})
+	require.NoError(t, err)
+	cache := NewCachedStorage(next, 10, time.Minute, time.Minute)
+
+	const callers = 8
+	var wg sync.WaitGroup
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := cache.Get(ctx, "hot")
+			assert.NoError(t, err)
+			assert.Equal(t, "https://hot.com", got)
+		}()
+	}
+	require.Eventually(t, func() bool {
+		return cache.CacheStats().Misses == callers
+	}, time.Second, time.Millisecond)
+	close(next.gate)
+	wg.Wait()
+
+	assert.Equal(t, int64(1), next.loads.Load())
+}
+
+func TestCachedStorage_CancelledCallerDoesNotFailWaiters(t *testing.T) {
+	ctx := context.Background()
+	next := &countingStorage{MemoryStorage: NewMemoryStorage(), gate: make(chan struct{})}
+	_, err := next.Create(ctx, ShortenerURL{ShortURL: "hot", OriginalURL: "https://hot.com"})
+	require.NoError(t, err)
+	cache := NewCachedStorage(next, 10, time.Minute, time.Minute)
+
+	firstCtx, cancel := context.WithCancel(ctx)
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := cache.Get(firstCtx, "hot")
+		firstErr <- err
+	}()
+	require.Eventually(t, func() bool { return next.loads.Load() == 1 }, time.Second, time.Millisecond)
+
+	second := make(chan string, 1)
+	go func() {
+		got, err := cache.Get(ctx, "hot")
+		assert.NoError(t, err)
+		second <- got
+	}()
+	require.Eventually(t, func() bool { return cache.CacheStats().Misses == 2 }, time.Second, time.Millisecond)
+
+	// первый вызывающий уходит, не дождавшись хранилища
+	cancel()
+	assert.ErrorIs(t, <-firstErr, context.Canceled)
+
+	close(next.gate)
+	assert.Equal(t, "https://hot.com", <-second, "waiters must not fail because the first caller was cancelled")
+	assert.Equal(t, int64(1), next.loads.Load())
+
+	got, err := cache.Get(firstCtx, "hot")
+	require.NoError(t, err, "the shared load must be cached despite the cancellation")
+	assert.Equal(t, "https://hot.com", got)
+}
+
+func TestCachedStorage_InvalidationDuringLoadIsPerKey(t *testing.T) {
+	ctx := context.Background()
+	next := &countingStorage{MemoryStorage: NewMemoryStorage(), gate: make(chan struct{})}
+	for _, key := range []string{"hot", "warm"} {
+		_, err := next.Create(ctx, ShortenerURL{ShortURL: key, OriginalURL: "https://" + key + ".com", UserID: "user1"})
+		require.NoError(t, err)
+	}
+	cache := NewCachedStorage(next, 10, time.Minute, time.Minute)
+
+	done := make(chan struct{})
+	for _, key := range []string{"hot", "warm"} {
+		go func() {
+			_, err := cache.Get(ctx, key)
+			assert.NoError(t, err)
+			done <- struct{}{}
+		}()
+	}
+	require.Eventually(t, func() bool { return next.loads.Load() == 2 }, time.Second, time.Millisecond)
+
+	// пока оба ключа читаются, меняется только один из них
+	_, err := cache.UpdateURL(ctx, "user1", "warm", "https://warm2.com")
+	require.NoError(t, err)
+	close(next.gate)
+	<-done
+	<-done
+
+	got, err := cache.Get(ctx, "hot")
+	require.NoError(t, err)
+	assert.Equal(t, "https://hot.com", got)
+	assert.Equal(t, int64(2), next.loads.Load(), "a write to another key must not keep the load out of the cache")
+
+	got, err = cache.Get(ctx, "warm")
+	require.NoError(t, err)
+	assert.Equal(t, "https://warm2.com", got)
+	assert.Equal(t, int64(3), next.loads.Load(), "a load that raced with a write to its key must not be cached")
+
+	cache.mu.Lock()
+	assert.Empty(t, cache.loading, "generations are kept only while a key is being loaded")
+	cache.mu.Unlock()
+}
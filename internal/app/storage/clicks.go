@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/issafronov/shortener/internal/app/models"
+)
+
+// aggregateClicks считает статистику переходов по ссылке: общее количество,
+// количество уникальных посетителей по хэшу IP и гистограмму по интервалам bucket.
+// В гистограмму попадают только непустые интервалы в порядке возрастания.
+func aggregateClicks(key string, clicks []models.Click, bucket time.Duration) models.LinkStats {
+	stats := models.LinkStats{ShortURL: key, Histogram: []models.StatsBucket{}}
+	visitors := make(map[string]struct{})
+	counts := make(map[time.Time]int64)
+
+	for _, click := range clicks {
+		stats.Total++
+		visitors[click.IPHash] = struct{}{}
+		counts[click.ClickedAt.UTC().Truncate(bucket)]++
+	}
+	stats.UniqueVisitors = int64(len(visitors))
+
+	for start, count := range counts {
+		stats.Histogram = append(stats.Histogram, models.StatsBucket{Start: start, Count: count})
+	}
+	sort.Slice(stats.Histogram, func(i, j int) bool {
+		return stats.Histogram[i].Start.Before(stats.Histogram[j].Start)
+	})
+	return stats
+}
+
+// clickPruneInterval — минимальный период между очистками устаревших переходов
+const clickPruneInterval = time.Minute
+
+// clickPruner ограничивает частоту очистки устаревших переходов
+type clickPruner struct {
+	last atomic.Int64
+}
+
+// due возвращает границу хранения переходов, если очистка включена и с прошлой очистки
+// прошло не меньше clickPruneInterval. Переходы раньше границы подлежат удалению.
+func (p *clickPruner) due(retention time.Duration, now time.Time) (time.Time, bool) {
+	if retention <= 0 {
+		return time.Time{}, false
+	}
+	last := p.last.Load()
+	if last != 0 && now.Sub(time.Unix(0, last)) < clickPruneInterval {
+		return time.Time{}, false
+	}
+	if !p.last.CompareAndSwap(last, now.UnixNano()) {
+		return time.Time{}, false
+	}
+	return now.Add(-retention), true
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/issafronov/shortener/internal/app/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestClickPruner_Due(t *testing.T) {
+	var p clickPruner
+	now := time.Now()
+
+	_, ok := p.due(0, now)
+	assert.False(t, ok, "zero retention keeps clicks forever")
+
+	cutoff, ok := p.due(time.Hour, now)
+	require.True(t, ok)
+	assert.Equal(t, now.Add(-time.Hour), cutoff)
+
+	_, ok = p.due(time.Hour, now.Add(clickPruneInterval/2))
+	assert.False(t, ok, "pruning must not run more often than clickPruneInterval")
+
+	_, ok = p.due(time.Hour, now.Add(clickPruneInterval))
+	assert.True(t, ok)
+}
+
+func TestMemoryStorage_ClickRetention(t *testing.T) {
+	s := NewMemoryStorage(WithClickRetention(24 * time.Hour))
+	ctx := context.Background()
+	now := time.Now()
+
+	require.NoError(t, s.SaveClicks(ctx, []models.Click{
+		{ShortURL: "abc", ClickedAt: now.Add(-48 * time.Hour)},
+		{ShortURL: "abc", ClickedAt: now.Add(-time.Hour)},
+		{ShortURL: "old", ClickedAt: now.Add(-23 * time.Hour)},
+	}))
+	stats, err := s.ClickStats(ctx, "abc", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Total, "clicks older than the retention must not be saved")
+
+	// переход устаревает, пока хранится; очистка запускается следующей записью
+	s.clicksMu.Lock()
+	s.clicks["old"][0].ClickedAt = now.Add(-25 * time.Hour)
+	s.clicksMu.Unlock()
+	s.clickPrune.last.Store(0)
+	require.NoError(t, s.SaveClicks(ctx, []models.Click{{ShortURL: "abc", ClickedAt: now}}))
+
+	stats, err = s.ClickStats(ctx, "old", time.Hour)
+	require.NoError(t, err)
+	assert.Zero(t, stats.Total)
+	assert.NotContains(t, s.clicks, "old")
+	stats, err = s.ClickStats(ctx, "abc", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats.Total)
+}
+
+func TestBoltStorage_ClickRetention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shortener.db")
+	ctx := context.Background()
+	now := time.Now()
+
+	// переходы записаны хранилищем без индекса времени
+	s, err := NewBoltStorage(path)
+	require.NoError(t, err)
+	require.NoError(t, s.SaveClicks(ctx, []models.Click{
+		{ShortURL: "abc", ClickedAt: now.Add(-48 * time.Hour)},
+		{ShortURL: "abc", ClickedAt: now.Add(-time.Hour)},
+	}))
+	require.NoError(t, s.db.Update(func(tx *bolt.Tx) error {
+		return tx.DeleteBucket(boltClickTimes)
+	}))
+	require.NoError(t, s.Close())
+
+	s, err = NewBoltStorage(path, WithClickRetention(24*time.Hour))
+	require.NoError(t, err)
+	defer s.Close()
+
+	require.NoError(t, s.SaveClicks(ctx, []models.Click{{ShortURL: "abc", ClickedAt: now}}))
+	stats, err := s.ClickStats(ctx, "abc", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats.Total, "clicks older than the retention must be pruned")
+
+	require.NoError(t, s.db.View(func(tx *bolt.Tx) error {
+		assert.Equal(t, 2, tx.Bucket(boltClickTimes).Stats().KeyN)
+		return nil
+	}))
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/issafronov/shortener/internal/app/config"
+)
+
+// Движки хранилища, которые можно выбрать параметром storage_engine
+const (
+	EngineMemory   = "memory"
+	EngineFile     = "file"
+	EnginePostgres = "postgres"
+	EngineBolt     = "bolt"
+)
+
+// ResolveEngine определяет движок хранилища по конфигурации.
+// Явно заданный storage_engine имеет приоритет, иначе используется PostgreSQL
+// при заданном DSN, файл при заданном пути и память в остальных случаях.
+func ResolveEngine(cfg *config.Config) string {
+	switch {
+	case cfg.StorageEngine != "":
+		return cfg.StorageEngine
+	case cfg.DatabaseDSN != "":
+		return EnginePostgres
+	case cfg.FileStoragePath != "":
+		return EngineFile
+	default:
+		return EngineMemory
+	}
+}
+
+// New создаёт хранилище движка, выбранного в конфигурации
+func New(ctx context.Context, cfg *config.Config) (Storage, error) {
+	uniqueness := cfg.URLUniqueness
+	if uniqueness == "" {
+		uniqueness = UniquenessGlobal
+	}
+	if err := ValidateUniqueness(uniqueness); err != nil {
+		return nil, err
+	}
+
+	switch engine := ResolveEngine(cfg); engine {
+	case EngineMemory:
+		return NewMemoryStorage(WithUniqueness(uniqueness), WithClickRetention(cfg.ClickRetention)), nil
+	case EngineFile:
+		return NewFileStorage(cfg)
+	case EnginePostgres:
+		pg, err := NewPostgresStorage(ctx, cfg.DatabaseDSN, WithUniqueness(uniqueness), WithPool(PoolOptions{
+			MaxOpenConns:     cfg.DBMaxOpenConns,
+			MaxIdleConns:     cfg.DBMaxIdleConns,
+			ConnMaxLifetime:  cfg.DBConnMaxLifetime,
+			ConnMaxIdleTime:  cfg.DBConnMaxIdleTime,
+			StatementTimeout: cfg.DBStatementTimeout,
+		}), WithReplicas(cfg.DatabaseReplicaDSNs...), WithReadYourWrites(cfg.DBReadYourWritesWindow),
+			WithAutoMigrate(cfg.AutoMigrate))
+		if err != nil {
+			return nil, err
+		}
+		retrying, err := NewRetryStorage(pg, RetryPolicy{
+			Attempts:   cfg.DBRetryAttempts,
+			Backoff:    cfg.DBRetryBackoff,
+			MaxBackoff: time.Second,
+		}, PostgresRetryable)
+		if err != nil {
+			_ = pg.Close()
+			return nil, err
+		}
+		return retrying, nil
+	case EngineBolt:
+		return NewBoltStorage(cfg.BoltPath, WithUniqueness(uniqueness), WithClickRetention(cfg.ClickRetention))
+	default:
+		return nil, fmt.Errorf("unknown storage engine %q", engine)
+	}
+}
@@ -0,0 +1,635 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"maps"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/issafronov/shortener/internal/app/config"
+	"github.com/issafronov/shortener/internal/app/models"
+	"github.com/issafronov/shortener/internal/middleware/logger"
+	"go.uber.org/zap"
+)
+
+// FileStorage реализует интерфейс Storage с использованием файлового хранилища.
+// Файл является журналом операций (create, update, delete), который периодически
+// компактифицируется в снимок актуального состояния. Актуальное состояние,
+// включая индекс ссылок по пользователям, хранится в памяти.
+type FileStorage struct {
+	mu         sync.Mutex
+	index      *MemoryStorage
+	path       string
+	file       *os.File
+	writer     *bufio.Writer
+	syncPolicy string
+	records    int
+	dirty      bool
+	recovery   RecoveryStats
+
+	syncInterval    time.Duration
+	compactInterval time.Duration
+
+	// clicksMu защищает журнал переходов, который хранится в отдельном файле рядом с основным
+	clicksMu   sync.Mutex
+	clicksFile *os.File
+	// clickRecords — количество строк в журнале переходов
+	clickRecords int
+	// clicksDirty — в журнале переходов есть записи, ещё не сброшенные на диск
+	clicksDirty bool
+
+	// counters — счётчики генератора ключей, сохраняемые в отдельном файле; защищены mu
+	counters map[string]uint64
+}
+
+// NewFileStorage создаёт экземпляр FileStorage с указанием пути до файла
+// и восстанавливает состояние из журнала. Повреждённые записи в середине журнала
+// пропускаются, оборванный хвост отрезается.
+func NewFileStorage(config *config.Config) (*FileStorage, error) {
+	file, err := os.OpenFile(config.FileStoragePath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	index := NewMemoryStorage(WithUniqueness(config.URLUniqueness), WithClickRetention(config.ClickRetention))
+	stats, err := recoverLog(file, index)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if stats.Skipped > 0 || stats.TruncatedBytes > 0 {
+		logger.Log.Warn("file storage recovered with errors",
+			zap.String("path", config.FileStoragePath),
+			zap.Int("recovered", stats.Recovered),
+			zap.Int("skipped", stats.Skipped),
+			zap.Int64("truncated_bytes", stats.TruncatedBytes),
+		)
+	}
+
+	clicksFile, err := os.OpenFile(clicksPath(config.FileStoragePath), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	clickRecords, err := replayClicks(clicksFile, index)
+	if err != nil {
+		_ = file.Close()
+		_ = clicksFile.Close()
+		return nil, err
+	}
+
+	counters, err := loadKeyCounters(keysPath(config.FileStoragePath))
+	if err != nil {
+		_ = file.Close()
+		_ = clicksFile.Close()
+		return nil, err
+	}
+
+	syncPolicy := config.FileSyncPolicy
+	if syncPolicy == "" {
+		syncPolicy = SyncAlways
+	}
+
+	return &FileStorage{
+		index:           index,
+		path:            config.FileStoragePath,
+		file:            file,
+		writer:          bufio.NewWriter(file),
+		syncPolicy:      syncPolicy,
+		records:         stats.Recovered + stats.Skipped,
+		recovery:        stats,
+		syncInterval:    config.FileSyncInterval,
+		compactInterval: config.FileCompactInterval,
+		clicksFile:      clicksFile,
+		clickRecords:    clickRecords,
+		counters:        counters,
+	}, nil
+}
+
+// Recovery возвращает статистику восстановления журнала при открытии хранилища
+func (f *FileStorage) Recovery() RecoveryStats {
+	return f.recovery
+}
+
+// Ping проверяет доступность файлового хранилища
+func (f *FileStorage) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Create сохраняет URL в файл. Если оригинальный URL уже сокращён,
+// возвращает существующий короткий ключ и ErrConflict, если занят короткий ключ — ErrKeyExists.
+func (f *FileStorage) Create(ctx context.Context, url ShortenerURL) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if existing, taken := f.index.original(url.UserID, url.ShortURL, url.OriginalURL); taken {
+		return f.index.opts.conflict(existing)
+	}
+	if _, exists := f.index.lookup(url.ShortURL); exists {
+		return "", ErrKeyExists
+	}
+
+	url = stampCreated(url, time.Now())
+	url.UUID = f.index.nextID()
+
+	if err := f.appendRecord(logRecord{Op: opCreate, ShortenerURL: url}); err != nil {
+		return "", err
+	}
+	f.index.put(url)
+
+	return url.ShortURL, nil
+}
+
+// CreateBatch проверяет весь пакет по индексу и дописывает его в журнал одной операцией записи
+func (f *FileStorage) CreateBatch(ctx context.Context, urls []ShortenerURL) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	keyExists := func(key string) bool {
+		_, exists := f.index.lookup(key)
+		return exists
+	}
+	if err := f.index.opts.checkBatch(urls, f.index.originalKey, f.index.originalAt, keyExists); err != nil {
+		return err
+	}
+
+	recs := make([]logRecord, 0, len(urls))
+	now := time.Now()
+	for _, url := range urls {
+		url = stampCreated(url, now)
+		url.UUID = f.index.nextID()
+		recs = append(recs, logRecord{Op: opCreate, ShortenerURL: url})
+	}
+	if err := f.appendRecords(recs); err != nil {
+		return err
+	}
+	for _, rec := range recs {
+		f.index.put(rec.ShortenerURL)
+	}
+	return nil
+}
+
+// Get возвращает оригинальный URL по сокращённому
+func (f *FileStorage) Get(ctx context.Context, url string) (string, error) {
+	return f.index.Get(ctx, url)
+}
+
+// GetByUser возвращает все URL-ы, сохранённые пользователем
+func (f *FileStorage) GetByUser(ctx context.Context, username string) ([]models.ShortURLResponse, error) {
+	return f.index.GetByUser(ctx, username)
+}
+
+// ListByUser возвращает страницу ссылок пользователя из индекса в памяти
+func (f *FileStorage) ListByUser(ctx context.Context, userID string, opts models.ListOptions) ([]ShortenerURL, string, error) {
+	return f.index.ListByUser(ctx, userID, opts)
+}
+
+// DeleteURLs помечает переданные ссылки как удалённые и записывает в журнал tombstone-записи
+func (f *FileStorage) DeleteURLs(ctx context.Context, userID string, ids []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, err := f.deleteURLs(userID, ids)
+	return err
+}
+
+// DeleteURLsBatch записывает tombstone-записи для ссылок нескольких пользователей под одной блокировкой
+func (f *FileStorage) DeleteURLsBatch(ctx context.Context, reqs []models.DeleteRequest) ([]models.DeleteOutcomes, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	results := make([]models.DeleteOutcomes, 0, len(reqs))
+	for _, req := range reqs {
+		outcomes, err := f.deleteURLs(req.UserID, req.ShortURLs)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, outcomes)
+	}
+	return results, nil
+}
+
+// deleteURLs помечает ссылки пользователя удалёнными и возвращает результат по каждому ключу.
+// Вызывающий должен удерживать блокировку.
+func (f *FileStorage) deleteURLs(userID string, ids []string) (models.DeleteOutcomes, error) {
+	outcomes := make(models.DeleteOutcomes, len(ids))
+	now := time.Now()
+	for _, id := range ids {
+		shortenerURL, exists := f.index.lookup(id)
+		switch {
+		case !exists:
+			outcomes[id] = models.DeleteOutcomeNotFound
+			continue
+		case shortenerURL.UserID != userID:
+			outcomes[id] = models.DeleteOutcomeNotOwned
+			continue
+		}
+		outcomes[id] = models.DeleteOutcomeDeleted
+		if shortenerURL.IsDeleted {
+			continue
+		}
+		rec := logRecord{Op: opDelete, ChangedAt: &now, ShortenerURL: ShortenerURL{ShortURL: id, UserID: userID}}
+		if err := f.appendRecord(rec); err != nil {
+			return nil, err
+		}
+		f.index.put(markDeleted(shortenerURL, now))
+	}
+	return outcomes, nil
+}
+
+// CountURLs возвращает количество не удалённых URL в хранилище.
+func (f *FileStorage) CountURLs(ctx context.Context) (int64, error) {
+	return f.index.CountURLs(ctx)
+}
+
+// ReserveKeys резервирует значения счётчика генератора ключей и сохраняет счётчики в файл
+func (f *FileStorage) ReserveKeys(ctx context.Context, name string, floor, n uint64) (uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	value, ok := f.counters[name]
+	if !ok {
+		value = uint64(f.index.len())
+	}
+	value = max(value, floor) + n
+
+	counters := maps.Clone(f.counters)
+	counters[name] = value
+	if err := saveKeyCounters(keysPath(f.path), counters); err != nil {
+		return 0, err
+	}
+	f.counters = counters
+	return value, nil
+}
+
+// CountUsers возвращает количество пользователей в хранилище.
+func (f *FileStorage) CountUsers(ctx context.Context) (int64, error) {
+	return f.index.CountUsers(ctx)
+}
+
+// Scan возвращает записи по возрастанию идентификатора, начиная после курсора
+func (f *FileStorage) Scan(ctx context.Context, cursor string, limit int) ([]ShortenerURL, string, error) {
+	return f.index.Scan(ctx, cursor, limit)
+}
+
+// Import дописывает в журнал записи как есть, пропуская уже существующие короткие ключи
+// и оригинальные URL, которые уже сокращены
+func (f *FileStorage) Import(ctx context.Context, urls []ShortenerURL) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	imported := 0
+	for _, url := range urls {
+		if _, exists := f.index.lookup(url.ShortURL); exists {
+			continue
+		}
+		if _, taken := f.index.original(url.UserID, url.ShortURL, url.OriginalURL); taken && !url.IsDeleted {
+			continue
+		}
+		url = stampCreated(url, time.Now())
+		url.UUID = f.index.nextID()
+		if err := f.appendRecord(logRecord{Op: opCreate, ShortenerURL: url}); err != nil {
+			return imported, err
+		}
+		f.index.put(url)
+		imported++
+	}
+	return imported, nil
+}
+
+// PurgeExpired записывает в журнал tombstone-записи для ссылок с истёкшим сроком действия
+func (f *FileStorage) PurgeExpired(ctx context.Context, now time.Time) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var purged int64
+	for _, url := range f.index.expired(now) {
+		rec := logRecord{Op: opDelete, ChangedAt: &now, ShortenerURL: ShortenerURL{ShortURL: url.ShortURL, UserID: url.UserID}}
+		if err := f.appendRecord(rec); err != nil {
+			return purged, err
+		}
+		f.index.put(markDeleted(url, now))
+		purged++
+	}
+	return purged, nil
+}
+
+// UpdateURL дописывает в журнал запись update и обновляет индекс.
+// Если новый URL уже сокращён другой ссылкой, возвращает ErrConflict.
+func (f *FileStorage) UpdateURL(ctx context.Context, userID, key, originalURL string) (models.URLVersion, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	url, exists := f.index.lookup(key)
+	if !exists || url.UserID != userID {
+		return models.URLVersion{}, ErrNotFound
+	}
+	if url.IsDeleted {
+		return models.URLVersion{}, ErrDeleted
+	}
+	if owner, taken := f.index.original(userID, key, originalURL); taken && owner != key {
+		return models.URLVersion{}, ErrConflict
+	}
+
+	now := time.Now()
+	rec := logRecord{
+		Op:           opUpdate,
+		ChangedAt:    &now,
+		ShortenerURL: ShortenerURL{ShortURL: key, OriginalURL: originalURL, UserID: userID},
+	}
+	if err := f.appendRecord(rec); err != nil {
+		return models.URLVersion{}, err
+	}
+	applyRecord(f.index, rec)
+
+	history, err := f.index.URLHistory(ctx, key)
+	if err != nil {
+		return models.URLVersion{}, err
+	}
+	return history[len(history)-1], nil
+}
+
+// URLHistory возвращает историю изменений ссылки из индекса в памяти
+func (f *FileStorage) URLHistory(ctx context.Context, key string) ([]models.URLVersion, error) {
+	return f.index.URLHistory(ctx, key)
+}
+
+// ImportHistory дописывает в журнал перенесённую историю изменений ссылки записями update
+// с исходными предыдущими URL. Ссылка должна уже существовать, её текущий URL становится
+// URL последней версии.
+func (f *FileStorage) ImportHistory(ctx context.Context, key string, history []models.URLVersion) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, exists := f.index.lookup(key); !exists {
+		return ErrNotFound
+	}
+	records := make([]logRecord, 0, len(history))
+	for _, v := range history {
+		changedAt := v.ChangedAt
+		records = append(records, logRecord{
+			Op:           opUpdate,
+			ChangedAt:    &changedAt,
+			PreviousURL:  v.PreviousURL,
+			ShortenerURL: ShortenerURL{ShortURL: key, OriginalURL: v.OriginalURL, UserID: v.ChangedBy},
+		})
+	}
+	if err := f.appendRecords(records); err != nil {
+		return err
+	}
+	for _, rec := range records {
+		applyRecord(f.index, rec)
+	}
+	return nil
+}
+
+// GetURL возвращает запись по короткому ключу
+func (f *FileStorage) GetURL(ctx context.Context, key string) (ShortenerURL, error) {
+	return f.index.GetURL(ctx, key)
+}
+
+// SaveClicks дописывает пачку переходов в журнал переходов
+func (f *FileStorage) SaveClicks(ctx context.Context, clicks []models.Click) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, click := range clicks {
+		if err := encoder.Encode(click); err != nil {
+			return err
+		}
+	}
+
+	f.clicksMu.Lock()
+	defer f.clicksMu.Unlock()
+
+	if _, err := f.clicksFile.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	f.clickRecords += len(clicks)
+	if f.syncPolicy == SyncAlways {
+		if err := f.clicksFile.Sync(); err != nil {
+			return err
+		}
+	} else {
+		f.clicksDirty = true
+	}
+	return f.index.SaveClicks(ctx, clicks)
+}
+
+// Clicks возвращает все сохранённые переходы по ссылке из индекса в памяти
+func (f *FileStorage) Clicks(ctx context.Context, key string) ([]models.Click, error) {
+	return f.index.Clicks(ctx, key)
+}
+
+// ClickStats возвращает статистику переходов по ссылке
+func (f *FileStorage) ClickStats(ctx context.Context, key string, bucket time.Duration) (models.LinkStats, error) {
+	return f.index.ClickStats(ctx, key, bucket)
+}
+
+// appendRecord дописывает запись в журнал с учётом политики сброса на диск.
+// Вызывающий должен удерживать блокировку.
+func (f *FileStorage) appendRecord(rec logRecord) error {
+	return f.appendRecords([]logRecord{rec})
+}
+
+// appendRecords дописывает записи в журнал одной операцией записи с учётом политики сброса на диск.
+// Вызывающий должен удерживать блокировку.
+func (f *FileStorage) appendRecords(recs []logRecord) error {
+	var buf bytes.Buffer
+	for _, rec := range recs {
+		data, err := json.Marshal(rec)
+		if err != nil {
+			logger.Log.Info("Failed to marshal log record", zap.Error(err))
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	if _, err := f.writer.Write(buf.Bytes()); err != nil {
+		logger.Log.Info("Failed to write log records", zap.Int("records", len(recs)), zap.Error(err))
+		return err
+	}
+	if err := f.writer.Flush(); err != nil {
+		return err
+	}
+	f.records += len(recs)
+
+	if f.syncPolicy == SyncAlways {
+		return f.file.Sync()
+	}
+	f.dirty = true
+	return nil
+}
+
+// Sync сбрасывает на диск журнал и журнал переходов, если с момента последнего сброса в них были записи
+func (f *FileStorage) Sync() error {
+	if err := f.syncLog(); err != nil {
+		return err
+	}
+	return f.syncClicks()
+}
+
+// syncLog сбрасывает журнал на диск, если с момента последнего сброса были записи
+func (f *FileStorage) syncLog() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.dirty {
+		return nil
+	}
+	f.dirty = false
+	return f.file.Sync()
+}
+
+// syncClicks сбрасывает журнал переходов на диск, если с момента последнего сброса были записи
+func (f *FileStorage) syncClicks() error {
+	f.clicksMu.Lock()
+	defer f.clicksMu.Unlock()
+
+	if !f.clicksDirty {
+		return nil
+	}
+	f.clicksDirty = false
+	return f.clicksFile.Sync()
+}
+
+// Compact переписывает журнал в снимок актуального состояния, а журнал переходов —
+// в переходы, не вышедшие за срок хранения. Снимок сначала пишется во временный файл,
+// который затем атомарно заменяет журнал.
+func (f *FileStorage) Compact() error {
+	if err := f.compactLog(); err != nil {
+		return err
+	}
+	return f.compactClicks()
+}
+
+// compactLog переписывает журнал операций, если в нём есть записи, не попадающие в снимок
+func (f *FileStorage) compactLog() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// снимок содержит по записи на ссылку, включая удалённые, и по записи на каждую версию истории
+	if f.records <= f.index.len()+f.index.historyLen() {
+		return nil
+	}
+
+	tmpPath := f.path + ".compact"
+	records, err := writeSnapshot(tmpPath, f.index.snapshot(), f.index.historySnapshot())
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	file, err := replaceFile(tmpPath, f.path)
+	if err != nil {
+		return err
+	}
+
+	if err := f.file.Close(); err != nil {
+		logger.Log.Warn("failed to close compacted file storage log", zap.Error(err))
+	}
+	f.file = file
+	f.writer = bufio.NewWriter(file)
+	f.records = records
+	f.dirty = false
+
+	if err := syncDir(f.path); err != nil {
+		return err
+	}
+	logger.Log.Info("file storage compacted", zap.String("path", f.path), zap.Int("records", records))
+	return nil
+}
+
+// compactClicks переписывает журнал переходов, если в нём есть строки, которых нет в индексе:
+// повреждённые или удалённые по сроку хранения
+func (f *FileStorage) compactClicks() error {
+	f.clicksMu.Lock()
+	defer f.clicksMu.Unlock()
+
+	clicks := f.index.clicksSnapshot()
+	if f.clickRecords <= len(clicks) {
+		return nil
+	}
+
+	path := clicksPath(f.path)
+	tmpPath := path + ".compact"
+	records, err := writeClicksSnapshot(tmpPath, clicks)
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	file, err := replaceFile(tmpPath, path)
+	if err != nil {
+		return err
+	}
+
+	if err := f.clicksFile.Close(); err != nil {
+		logger.Log.Warn("failed to close compacted clicks log", zap.Error(err))
+	}
+	f.clicksFile = file
+	f.clickRecords = records
+	f.clicksDirty = false
+
+	if err := syncDir(path); err != nil {
+		return err
+	}
+	logger.Log.Info("file storage clicks compacted", zap.String("path", path), zap.Int("records", records))
+	return nil
+}
+
+// Run выполняет фоновое обслуживание журнала: периодический fsync и компактификацию.
+// Блокируется до отмены контекста.
+func (f *FileStorage) Run(ctx context.Context) {
+	var syncC, compactC <-chan time.Time
+
+	if f.syncPolicy == SyncInterval && f.syncInterval > 0 {
+		ticker := time.NewTicker(f.syncInterval)
+		defer ticker.Stop()
+		syncC = ticker.C
+	}
+	if f.compactInterval > 0 {
+		ticker := time.NewTicker(f.compactInterval)
+		defer ticker.Stop()
+		compactC = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-syncC:
+			if err := f.Sync(); err != nil {
+				logger.Log.Error("failed to sync file storage", zap.Error(err))
+			}
+		case <-compactC:
+			if err := f.Compact(); err != nil {
+				logger.Log.Error("failed to compact file storage", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Close сбрасывает журнал на диск и закрывает файл
+func (f *FileStorage) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.writer.Flush(); err != nil {
+		return err
+	}
+	if err := f.file.Sync(); err != nil {
+		return err
+	}
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+
+	f.clicksMu.Lock()
+	defer f.clicksMu.Unlock()
+	if err := f.clicksFile.Sync(); err != nil {
+		return err
+	}
+	return f.clicksFile.Close()
+}
@@ -0,0 +1,361 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/issafronov/shortener/internal/app/models"
+)
+
+// Типы операций журнала файлового хранилища
+const (
+	opCreate = "create"
+	opDelete = "delete"
+	opUpdate = "update"
+)
+
+// Политики сброса журнала на диск
+const (
+	// SyncAlways — fsync после каждой записи
+	SyncAlways = "always"
+	// SyncInterval — fsync в фоне с заданным периодом
+	SyncInterval = "interval"
+	// SyncNever — fsync выполняет только операционная система
+	SyncNever = "never"
+)
+
+// logRecord — запись журнала операций файлового хранилища.
+// Записи без поля op (старый формат файла и снимки после компактификации) трактуются как create.
+// Для записей update поле user_id содержит автора изменения, а changed_at — момент изменения;
+// для записей delete changed_at содержит момент удаления. previous_url задаётся только у версий,
+// перенесённых из другого хранилища: без него предыдущим считается текущий URL ссылки.
+type logRecord struct {
+	Op          string     `json:"op,omitempty"`
+	ChangedAt   *time.Time `json:"changed_at,omitempty"`
+	PreviousURL string     `json:"previous_url,omitempty"`
+	ShortenerURL
+}
+
+// RecoveryStats описывает результат восстановления файлового хранилища из журнала
+type RecoveryStats struct {
+	// Recovered — количество применённых записей
+	Recovered int
+	// Skipped — количество повреждённых записей в середине журнала, которые были пропущены
+	Skipped int
+	// TruncatedBytes — размер повреждённого хвоста журнала, отрезанного при восстановлении
+	TruncatedBytes int64
+}
+
+// applyRecord применяет запись журнала к индексу в памяти
+func applyRecord(index *MemoryStorage, rec logRecord) {
+	switch rec.Op {
+	case "", opCreate:
+		url := rec.ShortenerURL
+		if url.UUID <= 0 {
+			url.UUID = index.nextID()
+		}
+		index.put(url)
+	case opUpdate:
+		url, exists := index.lookup(rec.ShortURL)
+		if !exists {
+			return
+		}
+		var changedAt time.Time
+		if rec.ChangedAt != nil {
+			changedAt = *rec.ChangedAt
+		}
+		previous := url.OriginalURL
+		if rec.PreviousURL != "" {
+			previous = rec.PreviousURL
+		}
+		index.appendHistory(url.ShortURL, previous, rec.OriginalURL, rec.UserID, changedAt)
+		url.OriginalURL = rec.OriginalURL
+		index.put(url)
+	case opDelete:
+		url, exists := index.lookup(rec.ShortURL)
+		if !exists || url.UserID != rec.UserID {
+			return
+		}
+		// записи delete, сохранённые до появления changed_at, не содержат момента удаления
+		if rec.ChangedAt != nil {
+			url = markDeleted(url, *rec.ChangedAt)
+		}
+		url.IsDeleted = true
+		index.put(url)
+	}
+}
+
+// replayLog последовательно применяет записи журнала к индексу.
+// Повреждённые записи пропускаются. Возвращает статистику и смещение конца
+// последней корректной записи, а также признак того, что она не завершена переводом строки.
+func replayLog(r io.Reader, index *MemoryStorage) (RecoveryStats, int64, bool, error) {
+	var (
+		stats        RecoveryStats
+		offset       int64
+		validEnd     int64
+		missingNL    bool
+		pendingSkips int
+		reader       = bufio.NewReader(r)
+	)
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return stats, validEnd, missingNL, err
+		}
+		if len(line) == 0 {
+			break
+		}
+		offset += int64(len(line))
+		complete := line[len(line)-1] == '\n'
+
+		data := line
+		if complete {
+			data = line[:len(line)-1]
+		}
+		if len(data) == 0 {
+			continue
+		}
+
+		var rec logRecord
+		if jsonErr := json.Unmarshal(data, &rec); jsonErr != nil || rec.ShortURL == "" {
+			pendingSkips++
+		} else {
+			applyRecord(index, rec)
+			stats.Recovered++
+			stats.Skipped += pendingSkips
+			pendingSkips = 0
+			validEnd = offset
+			missingNL = !complete
+		}
+
+		if err != nil {
+			break
+		}
+	}
+
+	// повреждённые записи после последней корректной считаются оборванным хвостом
+	stats.TruncatedBytes = offset - validEnd
+	return stats, validEnd, missingNL, nil
+}
+
+// recoverLog восстанавливает индекс из файла журнала, отрезая повреждённый хвост
+func recoverLog(file *os.File, index *MemoryStorage) (RecoveryStats, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return RecoveryStats{}, err
+	}
+
+	stats, validEnd, missingNL, err := replayLog(file, index)
+	if err != nil {
+		return stats, err
+	}
+
+	if stats.TruncatedBytes > 0 {
+		if err := file.Truncate(validEnd); err != nil {
+			return stats, err
+		}
+	}
+	if missingNL {
+		if _, err := file.Write([]byte{'\n'}); err != nil {
+			return stats, err
+		}
+	}
+	if stats.TruncatedBytes > 0 || missingNL {
+		if err := file.Sync(); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// writeSnapshot записывает переданные записи в файл как последовательность записей create.
+// Для ссылок с историей изменений create содержит исходный URL, за которым следуют записи update,
+// чтобы история пережила компактификацию. Возвращает количество записанных записей.
+func writeSnapshot(path string, urls []ShortenerURL, history map[string][]models.URLVersion) (int, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	records := 0
+	for _, url := range urls {
+		versions := history[url.ShortURL]
+		if len(versions) > 0 {
+			url.OriginalURL = versions[0].PreviousURL
+		}
+		if err := encoder.Encode(logRecord{ShortenerURL: url}); err != nil {
+			return records, err
+		}
+		records++
+
+		for _, v := range versions {
+			changedAt := v.ChangedAt
+			rec := logRecord{
+				Op:        opUpdate,
+				ChangedAt: &changedAt,
+				ShortenerURL: ShortenerURL{
+					ShortURL:    url.ShortURL,
+					OriginalURL: v.OriginalURL,
+					UserID:      v.ChangedBy,
+				},
+			}
+			if err := encoder.Encode(rec); err != nil {
+				return records, err
+			}
+			records++
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return records, err
+	}
+	return records, file.Sync()
+}
+
+// writeClicksSnapshot записывает переходы в новый файл журнала переходов.
+// Возвращает количество записанных строк.
+func writeClicksSnapshot(path string, clicks []models.Click) (int, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for i, click := range clicks {
+		if err := encoder.Encode(click); err != nil {
+			return i, err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return 0, err
+	}
+	return len(clicks), file.Sync()
+}
+
+// replaceFile атомарно заменяет файл path файлом tmpPath и возвращает новый файл, открытый на дозапись.
+// Новый файл открывается до переименования, поэтому при ошибке прежний файл остаётся в работе.
+func replaceFile(tmpPath, path string) (*os.File, error) {
+	file, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_APPEND, 0666)
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return nil, err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = file.Close()
+		_ = os.Remove(tmpPath)
+		return nil, err
+	}
+	return file, nil
+}
+
+// syncDir сбрасывает на диск метаданные каталога после переименования файла
+func syncDir(path string) error {
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+// clicksPath возвращает путь до журнала переходов для файла хранилища
+func clicksPath(path string) string {
+	return path + ".clicks"
+}
+
+// keysPath возвращает путь до файла счётчиков генератора ключей для файла хранилища
+func keysPath(path string) string {
+	return path + ".keys"
+}
+
+// loadKeyCounters читает счётчики генератора ключей; отсутствующий файл означает, что счётчиков ещё нет
+func loadKeyCounters(path string) (map[string]uint64, error) {
+	counters := make(map[string]uint64)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return counters, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &counters); err != nil {
+		return nil, err
+	}
+	return counters, nil
+}
+
+// saveKeyCounters атомарно перезаписывает файл счётчиков генератора ключей
+func saveKeyCounters(path string, counters map[string]uint64) error {
+	data, err := json.Marshal(counters)
+	if err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return syncDir(path)
+}
+
+// replayClicks загружает переходы из журнала в индекс и возвращает количество строк журнала.
+// Повреждённые строки пропускаются, незавершённая последняя строка дополняется переводом строки,
+// чтобы не склеиться со следующей записью.
+func replayClicks(file *os.File, index *MemoryStorage) (int, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	var clicks []models.Click
+	reader := bufio.NewReader(file)
+	missingNL := false
+	lines := 0
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return lines, err
+		}
+		if len(line) > 0 {
+			lines++
+			missingNL = line[len(line)-1] != '\n'
+			var click models.Click
+			if json.Unmarshal(line, &click) == nil && click.ShortURL != "" {
+				clicks = append(clicks, click)
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	if missingNL {
+		if _, err := file.Write([]byte{'\n'}); err != nil {
+			return lines, err
+		}
+	}
+	return lines, index.SaveClicks(context.Background(), clicks)
+}
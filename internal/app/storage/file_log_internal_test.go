@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/issafronov/shortener/internal/app/config"
+	"github.com/issafronov/shortener/internal/app/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplaceFile_KeepsOriginalOnError(t *testing.T) {
+	dir := t.TempDir()
+	tmpPath := filepath.Join(dir, "log.compact")
+	require.NoError(t, os.WriteFile(tmpPath, []byte("snapshot\n"), 0666))
+
+	// каталог на месте журнала не даёт выполнить переименование
+	target := filepath.Join(dir, "log")
+	require.NoError(t, os.MkdirAll(filepath.Join(target, "busy"), 0755))
+
+	_, err := replaceFile(tmpPath, target)
+	require.Error(t, err)
+	assert.NoFileExists(t, tmpPath)
+	assert.DirExists(t, target)
+
+	target = filepath.Join(dir, "journal")
+	require.NoError(t, os.WriteFile(target, []byte("old\n"), 0666))
+	require.NoError(t, os.WriteFile(tmpPath, []byte("snapshot\n"), 0666))
+
+	file, err := replaceFile(tmpPath, target)
+	require.NoError(t, err)
+	defer file.Close()
+	_, err = file.WriteString("appended\n")
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, "snapshot\nappended\n", string(data))
+}
+
+func TestFileStorage_SyncFlushesClicksLog(t *testing.T) {
+	fs, err := NewFileStorage(&config.Config{
+		FileStoragePath: filepath.Join(t.TempDir(), "storage.json"),
+		FileSyncPolicy:  SyncInterval,
+	})
+	require.NoError(t, err)
+	defer fs.Close()
+
+	err = fs.SaveClicks(context.Background(), []models.Click{{ShortURL: "a", ClickedAt: time.Now()}})
+	require.NoError(t, err)
+	assert.True(t, fs.clicksDirty)
+
+	require.NoError(t, fs.Sync())
+	assert.False(t, fs.clicksDirty, "Sync must flush the clicks log as well")
+}
@@ -0,0 +1,304 @@
+package storage_test
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/issafronov/shortener/internal/app/config"
+	"github.com/issafronov/shortener/internal/app/contextkeys"
+	"github.com/issafronov/shortener/internal/app/models"
+	"github.com/issafronov/shortener/internal/app/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeLogFile(t *testing.T, content string) string {
+	tmpFile, err := os.CreateTemp("", "storage-test-*.json")
+	require.NoError(t, err)
+	_, err = tmpFile.WriteString(content)
+	require.NoError(t, err)
+	require.NoError(t, tmpFile.Close())
+	t.Cleanup(func() {
+		os.Remove(tmpFile.Name())
+		os.Remove(tmpFile.Name() + ".clicks")
+		os.Remove(tmpFile.Name() + ".keys")
+	})
+	return tmpFile.Name()
+}
+
+func countLines(t *testing.T, path string) int {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines++
+	}
+	require.NoError(t, scanner.Err())
+	return lines
+}
+
+func TestNewFileStorage_ReplaysLog(t *testing.T) {
+	path := writeLogFile(t, strings.Join([]string{
+		`{"uuid":1,"short_url":"old","original_url":"https://old.com","user_id":"user1"}`,
+		`{"op":"create","uuid":2,"short_url":"abc","original_url":"https://example.com","user_id":"user1"}`,
+		`{"op":"update","short_url":"abc","original_url":"https://updated.com"}`,
+		`{"op":"delete","short_url":"old","user_id":"user1"}`,
+		`{"op":"delete","short_url":"abc","user_id":"intruder"}`,
+	}, "\n")+"\n")
+
+	fs, err := storage.NewFileStorage(&config.Config{FileStoragePath: path})
+	require.NoError(t, err)
+	defer fs.Close()
+
+	assert.Equal(t, storage.RecoveryStats{Recovered: 5}, fs.Recovery())
+
+	ctx := context.WithValue(context.Background(), contextkeys.HostKey, "http://localhost")
+	_, err = fs.Get(ctx, "old")
+	assert.ErrorIs(t, err, storage.ErrDeleted)
+
+	got, err := fs.Get(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://updated.com", got)
+
+	links, err := fs.GetByUser(ctx, "user1")
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+	assert.Equal(t, "http://localhost/old", links[0].ShortURL)
+	assert.Equal(t, "http://localhost/abc", links[1].ShortURL)
+}
+
+func TestNewFileStorage_CorruptedLog(t *testing.T) {
+	path := writeLogFile(t, strings.Join([]string{
+		`{"uuid":1,"short_url":"a","original_url":"https://a.com","user_id":"user1"}`,
+		`garbage`,
+		`{"uuid":2,"short_url":"b","original_url":"https://b.com","user_id":"user1"}`,
+		`{"uuid":3,"short_url":"c","origi`,
+	}, "\n"))
+
+	fs, err := storage.NewFileStorage(&config.Config{FileStoragePath: path})
+	require.NoError(t, err)
+
+	stats := fs.Recovery()
+	assert.Equal(t, 2, stats.Recovered)
+	assert.Equal(t, 1, stats.Skipped)
+	assert.Equal(t, int64(len(`{"uuid":3,"short_url":"c","origi`)), stats.TruncatedBytes)
+
+	// новая запись не должна склеиться с отрезанным хвостом
+	_, err = fs.Create(context.Background(), storage.ShortenerURL{ShortURL: "d", OriginalURL: "https://d.com", UserID: "user2"})
+	require.NoError(t, err)
+	require.NoError(t, fs.Close())
+
+	fs, err = storage.NewFileStorage(&config.Config{FileStoragePath: path})
+	require.NoError(t, err)
+	defer fs.Close()
+
+	assert.Equal(t, storage.RecoveryStats{Recovered: 3, Skipped: 1}, fs.Recovery())
+	got, err := fs.Get(context.Background(), "d")
+	require.NoError(t, err)
+	assert.Equal(t, "https://d.com", got)
+}
+
+func TestFileStorage_DeleteSurvivesRestart(t *testing.T) {
+	path := writeLogFile(t, "")
+	cfg := &config.Config{FileStoragePath: path}
+
+	fs, err := storage.NewFileStorage(cfg)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	_, err = fs.Create(ctx, storage.ShortenerURL{ShortURL: "abc", OriginalURL: "https://example.com", UserID: "user1"})
+	require.NoError(t, err)
+	require.NoError(t, fs.DeleteURLs(ctx, "user1", []string{"abc"}))
+	require.NoError(t, fs.Close())
+
+	fs, err = storage.NewFileStorage(cfg)
+	require.NoError(t, err)
+	defer fs.Close()
+
+	_, err = fs.Get(ctx, "abc")
+	assert.ErrorIs(t, err, storage.ErrDeleted)
+}
+
+func TestFileStorage_Compact(t *testing.T) {
+	path := writeLogFile(t, "")
+	cfg := &config.Config{FileStoragePath: path, FileSyncPolicy: storage.SyncNever}
+
+	fs, err := storage.NewFileStorage(cfg)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	_, err = fs.Create(ctx, storage.ShortenerURL{ShortURL: "a", OriginalURL: "https://a.com", UserID: "user1"})
+	require.NoError(t, err)
+	_, err = fs.Create(ctx, storage.ShortenerURL{ShortURL: "b", OriginalURL: "https://b.com", UserID: "user1"})
+	require.NoError(t, err)
+	require.NoError(t, fs.DeleteURLs(ctx, "user1", []string{"a"}))
+	assert.Equal(t, 3, countLines(t, path))
+
+	require.NoError(t, fs.Compact())
+	assert.Equal(t, 2, countLines(t, path))
+
+	// после компактификации журнал продолжает принимать записи
+	_, err = fs.Create(ctx, storage.ShortenerURL{ShortURL: "c", OriginalURL: "https://c.com", UserID: "user2"})
+	require.NoError(t, err)
+	require.NoError(t, fs.Close())
+
+	fs, err = storage.NewFileStorage(cfg)
+	require.NoError(t, err)
+	defer fs.Close()
+
+	assert.Equal(t, 3, fs.Recovery().Recovered)
+	_, err = fs.Get(ctx, "a")
+	assert.ErrorIs(t, err, storage.ErrDeleted)
+	got, err := fs.Get(ctx, "c")
+	require.NoError(t, err)
+	assert.Equal(t, "https://c.com", got)
+}
+
+func TestFileStorage_CompactSkipsCompactLog(t *testing.T) {
+	path := writeLogFile(t, "")
+	cfg := &config.Config{FileStoragePath: path, FileSyncPolicy: storage.SyncNever}
+
+	fs, err := storage.NewFileStorage(cfg)
+	require.NoError(t, err)
+	defer fs.Close()
+
+	ctx := context.Background()
+	_, err = fs.Create(ctx, storage.ShortenerURL{ShortURL: "a", OriginalURL: "https://a.com", UserID: "user1"})
+	require.NoError(t, err)
+	_, err = fs.UpdateURL(ctx, "user1", "a", "https://b.com")
+	require.NoError(t, err)
+
+	before, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, fs.Compact())
+	after, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(before, after), "log without redundant records must not be rewritten")
+}
+
+func TestFileStorage_CompactClicks(t *testing.T) {
+	path := writeLogFile(t, "")
+	old := time.Now().Add(-48 * time.Hour).UTC().Format(time.RFC3339Nano)
+	require.NoError(t, os.WriteFile(path+".clicks", []byte(strings.Join([]string{
+		`{"short_url":"abc","clicked_at":"` + old + `","ip_hash":"a"}`,
+		`{"short_url":"abc",`,
+		"",
+	}, "\n")), 0666))
+	cfg := &config.Config{FileStoragePath: path, ClickRetention: 24 * time.Hour}
+
+	fs, err := storage.NewFileStorage(cfg)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, fs.SaveClicks(ctx, []models.Click{{ShortURL: "abc", ClickedAt: time.Now(), IPHash: "b"}}))
+	assert.Equal(t, 3, countLines(t, path+".clicks"))
+
+	require.NoError(t, fs.Compact())
+	assert.Equal(t, 1, countLines(t, path+".clicks"), "expired and corrupted clicks must be dropped")
+
+	// после компактификации журнал переходов продолжает принимать записи
+	require.NoError(t, fs.SaveClicks(ctx, []models.Click{{ShortURL: "abc", ClickedAt: time.Now(), IPHash: "c"}}))
+	require.NoError(t, fs.Close())
+
+	fs, err = storage.NewFileStorage(cfg)
+	require.NoError(t, err)
+	defer fs.Close()
+
+	stats, err := fs.ClickStats(ctx, "abc", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats.Total)
+}
+
+func TestFileStorage_PurgeExpiredSurvivesRestart(t *testing.T) {
+	path := writeLogFile(t, "")
+	cfg := &config.Config{FileStoragePath: path}
+
+	fs, err := storage.NewFileStorage(cfg)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Minute)
+	_, err = fs.Create(ctx, storage.ShortenerURL{ShortURL: "abc", OriginalURL: "https://example.com", UserID: "user1", ExpiresAt: &expiresAt})
+	require.NoError(t, err)
+
+	purged, err := fs.PurgeExpired(ctx, expiresAt)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+	require.NoError(t, fs.Close())
+
+	fs, err = storage.NewFileStorage(cfg)
+	require.NoError(t, err)
+	defer fs.Close()
+
+	_, err = fs.Get(ctx, "abc")
+	assert.ErrorIs(t, err, storage.ErrDeleted)
+}
+
+func TestFileStorage_ClicksSurviveRestart(t *testing.T) {
+	path := writeLogFile(t, "")
+	cfg := &config.Config{FileStoragePath: path}
+
+	fs, err := storage.NewFileStorage(cfg)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, fs.SaveClicks(ctx, []models.Click{
+		{ShortURL: "abc", ClickedAt: time.Now(), IPHash: "a"},
+		{ShortURL: "abc", ClickedAt: time.Now(), IPHash: "b"},
+	}))
+	require.NoError(t, fs.Close())
+
+	fs, err = storage.NewFileStorage(cfg)
+	require.NoError(t, err)
+	defer fs.Close()
+
+	stats, err := fs.ClickStats(ctx, "abc", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats.Total)
+}
+
+func TestFileStorage_HistorySurvivesCompaction(t *testing.T) {
+	path := writeLogFile(t, "")
+	cfg := &config.Config{FileStoragePath: path, FileSyncPolicy: storage.SyncNever}
+
+	fs, err := storage.NewFileStorage(cfg)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	_, err = fs.Create(ctx, storage.ShortenerURL{ShortURL: "abc", OriginalURL: "https://a.com", UserID: "user1"})
+	require.NoError(t, err)
+	_, err = fs.Create(ctx, storage.ShortenerURL{ShortURL: "old", OriginalURL: "https://old.com", UserID: "user1"})
+	require.NoError(t, err)
+	_, err = fs.UpdateURL(ctx, "user1", "abc", "https://b.com")
+	require.NoError(t, err)
+	_, err = fs.UpdateURL(ctx, "user1", "abc", "https://c.com")
+	require.NoError(t, err)
+	require.NoError(t, fs.DeleteURLs(ctx, "user1", []string{"old"}))
+
+	require.NoError(t, fs.Compact())
+	assert.Equal(t, 4, countLines(t, path))
+	require.NoError(t, fs.Close())
+
+	fs, err = storage.NewFileStorage(cfg)
+	require.NoError(t, err)
+	defer fs.Close()
+
+	got, err := fs.Get(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://c.com", got)
+
+	history, err := fs.URLHistory(ctx, "abc")
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "https://a.com", history[0].PreviousURL)
+	assert.Equal(t, "https://c.com", history[1].OriginalURL)
+	assert.Equal(t, "user1", history[1].ChangedBy)
+	assert.False(t, history[1].ChangedAt.IsZero())
+}
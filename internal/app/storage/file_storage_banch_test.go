@@ -3,21 +3,34 @@ package storage
 import (
 	"context"
 	"fmt"
+	"os"
 	"testing"
+
+	"github.com/issafronov/shortener/internal/app/config"
 )
 
 func BenchmarkDeleteURLs(b *testing.B) {
 	ctx := context.Background()
 	userID := "bench-user"
-	fileStorage := &FileStorage{}
+	tmpFile, err := os.CreateTemp("", "storage-bench-*.json")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.Remove(tmpFile.Name())
+	defer os.Remove(tmpFile.Name() + ".clicks")
+
+	fileStorage, err := NewFileStorage(&config.Config{FileStoragePath: tmpFile.Name()})
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer fileStorage.Close()
 
-	// Подготовка: заполняем Urls и UsersUrls
+	// Подготовка: заполняем индекс хранилища
 	keys := make([]string, 1000)
 	for i := 0; i < 1000; i++ {
 		key := fmt.Sprintf("short-%d", i)
 		original := fmt.Sprintf("https://example.com/%d", i)
-		Urls[key] = ShortenerURL{OriginalURL: original}
-		UsersUrls[userID] = []string{key, original}
+		fileStorage.index.put(ShortenerURL{UUID: i + 1, ShortURL: key, OriginalURL: original})
 		keys[i] = key
 	}
 
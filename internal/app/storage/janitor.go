@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/issafronov/shortener/internal/middleware/logger"
+	"go.uber.org/zap"
+)
+
+// RunJanitor периодически помечает удалёнными ссылки с истёкшим сроком действия.
+// Блокируется до отмены контекста; неположительный интервал отключает очистку.
+func RunJanitor(ctx context.Context, s Storage, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			purged, err := s.PurgeExpired(ctx, now)
+			if err != nil {
+				logger.Log.Error("failed to purge expired urls", zap.Error(err))
+				continue
+			}
+			if purged > 0 {
+				logger.Log.Info("expired urls purged", zap.Int64("count", purged))
+			}
+		}
+	}
+}
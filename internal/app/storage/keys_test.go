@@ -0,0 +1,78 @@
+package storage_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/issafronov/shortener/internal/app/config"
+	"github.com/issafronov/shortener/internal/app/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorage_ReserveKeys(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{FileStoragePath: filepath.Join(dir, "storage.json")}
+	boltPath := filepath.Join(dir, "shortener.db")
+
+	engines := map[string]struct {
+		open func() storage.Storage
+		// persistent — счётчик переживает перезапуск
+		persistent bool
+	}{
+		storage.EngineMemory: {open: func() storage.Storage { return storage.NewMemoryStorage() }},
+		storage.EngineFile: {open: func() storage.Storage {
+			fs, err := storage.NewFileStorage(cfg)
+			require.NoError(t, err)
+			return fs
+		}, persistent: true},
+		storage.EngineBolt: {open: func() storage.Storage {
+			bs, err := storage.NewBoltStorage(boltPath)
+			require.NoError(t, err)
+			return bs
+		}, persistent: true},
+	}
+
+	for engine, tt := range engines {
+		t.Run(engine, func(t *testing.T) {
+			ctx := context.Background()
+			s := tt.open()
+
+			for _, key := range []string{"a", "b", "c"} {
+				_, err := s.Create(ctx, storage.ShortenerURL{ShortURL: key, OriginalURL: "https://" + key + ".com", UserID: "user1"})
+				require.NoError(t, err)
+			}
+			require.NoError(t, s.DeleteURLs(ctx, "user1", []string{"a", "b"}))
+
+			counter, ok := s.(storage.KeyCounter)
+			require.True(t, ok)
+
+			// новый счётчик начинается с общего числа ссылок, включая удалённые
+			end, err := counter.ReserveKeys(ctx, "sequence", 0, 10)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(13), end)
+
+			end, err = counter.ReserveKeys(ctx, "sequence", 20, 10)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(30), end)
+
+			end, err = counter.ReserveKeys(ctx, "hashids", 0, 1)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(4), end, "counters must be independent")
+
+			if !tt.persistent {
+				return
+			}
+			closer, ok := s.(interface{ Close() error })
+			require.True(t, ok)
+			require.NoError(t, closer.Close())
+
+			s = tt.open()
+			defer s.(interface{ Close() error }).Close()
+			end, err = s.(storage.KeyCounter).ReserveKeys(ctx, "sequence", 0, 10)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(40), end, "counter must survive restart")
+		})
+	}
+}
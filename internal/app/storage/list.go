@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/issafronov/shortener/internal/app/models"
+)
+
+// listMatches проверяет, попадает ли запись в список с учётом фильтров, без учёта курсора
+func listMatches(url ShortenerURL, opts models.ListOptions) bool {
+	if url.IsDeleted && !opts.IncludeDeleted {
+		return false
+	}
+	return opts.Query == "" || strings.Contains(url.OriginalURL, opts.Query)
+}
+
+// listCursor — позиция в списке ссылок пользователя: время создания и идентификатор
+// последней записи страницы. Ссылки упорядочены по этой паре.
+type listCursor struct {
+	createdAt time.Time
+	id        int
+}
+
+// createdNanos возвращает время создания в наносекундах Unix, 0 для записей без времени создания
+func createdNanos(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// cursorOf возвращает курсор, указывающий на запись
+func cursorOf(url ShortenerURL) listCursor {
+	return listCursor{createdAt: url.CreatedAt, id: url.UUID}
+}
+
+// String кодирует курсор в виде <время создания в наносекундах>_<идентификатор>
+func (c listCursor) String() string {
+	return strconv.FormatInt(createdNanos(c.createdAt), 10) + "_" + strconv.Itoa(c.id)
+}
+
+// parseListCursor разбирает курсор списка ссылок пользователя
+func parseListCursor(cursor string) (listCursor, error) {
+	if cursor == "" {
+		return listCursor{}, nil
+	}
+	rawTime, rawID, ok := strings.Cut(cursor, "_")
+	nanos, timeErr := strconv.ParseInt(rawTime, 10, 64)
+	id, idErr := strconv.Atoi(rawID)
+	if !ok || timeErr != nil || idErr != nil || nanos < 0 || id < 0 {
+		return listCursor{}, fmt.Errorf("%w %q", ErrInvalidCursor, cursor)
+	}
+	c := listCursor{id: id}
+	if nanos > 0 {
+		c.createdAt = time.Unix(0, nanos).UTC()
+	}
+	return c, nil
+}
+
+// compare сравнивает позицию записи с курсором: -1, если запись раньше, 1, если позже
+func (c listCursor) compare(url ShortenerURL) int {
+	if cmp := url.CreatedAt.Compare(c.createdAt); cmp != 0 {
+		return cmp
+	}
+	switch {
+	case url.UUID < c.id:
+		return -1
+	case url.UUID > c.id:
+		return 1
+	}
+	return 0
+}
+
+// listLess сравнивает записи в порядке создания: по времени создания, затем по идентификатору
+func listLess(a, b ShortenerURL) bool {
+	return cursorOf(b).compare(a) < 0
+}
+
+// afterCursor проверяет, что запись идёт после курсора в заданном порядке
+func afterCursor(url ShortenerURL, cursor listCursor, opts models.ListOptions) bool {
+	if opts.Cursor == "" {
+		return true
+	}
+	if opts.Descending() {
+		return cursor.compare(url) < 0
+	}
+	return cursor.compare(url) > 0
+}
+
+// listPage собирает страницу из записей, упорядоченных в нужном порядке.
+// Следующий курсор возвращается, только если после страницы остались записи.
+func listPage(urls []ShortenerURL, opts models.ListOptions) ([]ShortenerURL, string, error) {
+	cursor, err := parseListCursor(opts.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var page []ShortenerURL
+	for _, url := range urls {
+		if !afterCursor(url, cursor, opts) || !listMatches(url, opts) {
+			continue
+		}
+		if opts.Limit > 0 && len(page) == opts.Limit {
+			return page, cursorOf(page[len(page)-1]).String(), nil
+		}
+		page = append(page, url)
+	}
+	return page, "", nil
+}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/issafronov/shortener/internal/app/config"
+	"github.com/issafronov/shortener/internal/app/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestListCursor(t *testing.T) {
+	created := time.Date(2025, 3, 1, 12, 0, 0, 123456000, time.UTC)
+	for _, url := range []ShortenerURL{{UUID: 7, CreatedAt: created}, {UUID: 3}} {
+		c, err := parseListCursor(cursorOf(url).String())
+		require.NoError(t, err)
+		assert.Equal(t, cursorOf(url), c)
+		assert.Zero(t, c.compare(url))
+	}
+
+	for _, raw := range []string{"5", "abc", "1_x", "-1_2", "1_-2"} {
+		_, err := parseListCursor(raw)
+		assert.ErrorIs(t, err, ErrInvalidCursor, raw)
+	}
+}
+
+func TestStorage_ListByUserOrdersByCreatedAt(t *testing.T) {
+	dir := t.TempDir()
+	engines := map[string]func() Storage{
+		EngineMemory: func() Storage { return NewMemoryStorage() },
+		EngineFile: func() Storage {
+			fs, err := NewFileStorage(&config.Config{FileStoragePath: filepath.Join(dir, "storage.json")})
+			require.NoError(t, err)
+			return fs
+		},
+		EngineBolt: func() Storage {
+			bs, err := NewBoltStorage(filepath.Join(dir, "shortener.db"))
+			require.NoError(t, err)
+			return bs
+		},
+	}
+
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for engine, open := range engines {
+		t.Run(engine, func(t *testing.T) {
+			ctx := context.Background()
+			s := open()
+			if closer, ok := s.(interface{ Close() error }); ok {
+				defer closer.Close()
+			}
+
+			// записи импортированы не в порядке создания
+			_, err := s.Import(ctx, []ShortenerURL{
+				{ShortURL: "c", OriginalURL: "https://c.com", UserID: "user1", CreatedAt: base.Add(3 * time.Hour)},
+				{ShortURL: "a", OriginalURL: "https://a.com", UserID: "user1", CreatedAt: base.Add(time.Hour)},
+				{ShortURL: "b", OriginalURL: "https://b.com", UserID: "user1", CreatedAt: base.Add(2 * time.Hour)},
+			})
+			require.NoError(t, err)
+			_, err = s.Create(ctx, ShortenerURL{ShortURL: "d", OriginalURL: "https://d.com", UserID: "user1"})
+			require.NoError(t, err)
+
+			var keys []string
+			opts := models.ListOptions{Limit: 2}
+			for {
+				page, next, err := s.ListByUser(ctx, "user1", opts)
+				require.NoError(t, err)
+				for _, url := range page {
+					keys = append(keys, url.ShortURL)
+				}
+				if next == "" {
+					break
+				}
+				opts.Cursor = next
+			}
+			assert.Equal(t, []string{"a", "b", "c", "d"}, keys)
+
+			page, next, err := s.ListByUser(ctx, "user1", models.ListOptions{Limit: 3, Sort: models.SortCreatedDesc})
+			require.NoError(t, err)
+			require.Len(t, page, 3)
+			assert.Equal(t, "d", page[0].ShortURL)
+			assert.Equal(t, "b", page[2].ShortURL)
+			page, _, err = s.ListByUser(ctx, "user1", models.ListOptions{Limit: 3, Sort: models.SortCreatedDesc, Cursor: next})
+			require.NoError(t, err)
+			require.Len(t, page, 1)
+			assert.Equal(t, "a", page[0].ShortURL)
+		})
+	}
+}
+
+func TestBoltStorage_ReindexesUsersByCreated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shortener.db")
+	ctx := context.Background()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	s, err := NewBoltStorage(path)
+	require.NoError(t, err)
+	_, err = s.Import(ctx, []ShortenerURL{
+		{ShortURL: "b", OriginalURL: "https://b.com", UserID: "user1", CreatedAt: base.Add(time.Hour)},
+		{ShortURL: "a", OriginalURL: "https://a.com", UserID: "user1", CreatedAt: base},
+	})
+	require.NoError(t, err)
+	// индекс в прежнем формате: ключи упорядочены по uuid
+	require.NoError(t, s.db.Update(func(tx *bolt.Tx) error {
+		require.NoError(t, tx.Bucket(boltMeta).Delete(boltUsersByCreated))
+		require.NoError(t, tx.DeleteBucket(boltUsers))
+		users, err := tx.CreateBucket(boltUsers)
+		require.NoError(t, err)
+		require.NoError(t, users.Put(seqIndexKey("user1", 1), []byte("b")))
+		return users.Put(seqIndexKey("user1", 2), []byte("a"))
+	}))
+	require.NoError(t, s.Close())
+
+	s, err = NewBoltStorage(path)
+	require.NoError(t, err)
+	defer s.Close()
+
+	page, _, err := s.ListByUser(ctx, "user1", models.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, "a", page[0].ShortURL)
+	assert.Equal(t, "b", page[1].ShortURL)
+}
@@ -0,0 +1,652 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/issafronov/shortener/internal/app/contextkeys"
+	"github.com/issafronov/shortener/internal/app/models"
+)
+
+// memoryShardCount — количество шардов в MemoryStorage.
+// Ключи распределяются по шардам по хэшу, что снижает конкуренцию за блокировки.
+const memoryShardCount = 32
+
+// memoryShard — часть хранилища со своей блокировкой
+type memoryShard struct {
+	mu   sync.RWMutex
+	urls map[string]ShortenerURL
+}
+
+// MemoryStorage реализует интерфейс Storage полностью в памяти.
+// Состояние принадлежит экземпляру, доступ к нему безопасен для конкурентного использования.
+type MemoryStorage struct {
+	shards [memoryShardCount]*memoryShard
+
+	usersMu sync.RWMutex
+	users   map[string][]string
+
+	seq atomic.Int64
+
+	clicksMu   sync.RWMutex
+	clicks     map[string][]models.Click
+	clickPrune clickPruner
+
+	historyMu sync.RWMutex
+	history   map[string][]models.URLVersion
+
+	countersMu sync.Mutex
+	counters   map[string]uint64
+
+	// originalsMu защищает индекс оригинальных URL; берётся после блокировки шарда
+	originalsMu sync.Mutex
+	// originals: область уникальности и оригинальный URL -> короткий ключ не удалённой ссылки
+	originals map[string]string
+
+	opts options
+}
+
+// NewMemoryStorage создаёт пустое хранилище в памяти
+func NewMemoryStorage(opts ...Option) *MemoryStorage {
+	m := &MemoryStorage{
+		users:     make(map[string][]string),
+		clicks:    make(map[string][]models.Click),
+		history:   make(map[string][]models.URLVersion),
+		counters:  make(map[string]uint64),
+		originals: make(map[string]string),
+		opts:      newOptions(opts),
+	}
+	for i := range m.shards {
+		m.shards[i] = &memoryShard{urls: make(map[string]ShortenerURL)}
+	}
+	return m
+}
+
+// shard возвращает шард, отвечающий за ключ
+func (m *MemoryStorage) shard(key string) *memoryShard {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return m.shards[h.Sum32()%memoryShardCount]
+}
+
+// Ping проверяет доступность хранилища в памяти
+func (m *MemoryStorage) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Create сохраняет URL в памяти. Если оригинальный URL уже сокращён,
+// возвращает существующий короткий ключ и ErrConflict, если занят короткий ключ — ErrKeyExists.
+func (m *MemoryStorage) Create(ctx context.Context, url ShortenerURL) (string, error) {
+	if existing, err := m.insert(url); err != nil {
+		return existing, err
+	}
+	return url.ShortURL, nil
+}
+
+// insert добавляет новую запись с очередным идентификатором.
+// При конфликте оригинального URL возвращает ключ существующей ссылки.
+func (m *MemoryStorage) insert(url ShortenerURL) (string, error) {
+	url = stampCreated(url, time.Now())
+	sh := m.shard(url.ShortURL)
+	key := m.originalKey(url)
+
+	sh.mu.Lock()
+	m.originalsMu.Lock()
+	if existing, taken := m.originals[key]; taken && !url.IsDeleted {
+		m.originalsMu.Unlock()
+		sh.mu.Unlock()
+		return m.opts.conflict(existing)
+	}
+	if _, exists := sh.urls[url.ShortURL]; exists {
+		m.originalsMu.Unlock()
+		sh.mu.Unlock()
+		return "", ErrKeyExists
+	}
+	if !url.IsDeleted {
+		m.originals[key] = url.ShortURL
+	}
+	m.originalsMu.Unlock()
+	url.UUID = int(m.seq.Add(1))
+	sh.urls[url.ShortURL] = url
+	sh.mu.Unlock()
+
+	m.usersMu.Lock()
+	m.users[url.UserID] = append(m.users[url.UserID], url.ShortURL)
+	m.usersMu.Unlock()
+
+	return "", nil
+}
+
+// CreateBatch сохраняет пакет записей атомарно.
+// На время проверки и вставки удерживаются блокировки всех шардов.
+func (m *MemoryStorage) CreateBatch(ctx context.Context, urls []ShortenerURL) error {
+	for _, sh := range m.shards {
+		sh.mu.Lock()
+	}
+	m.originalsMu.Lock()
+
+	original := func(key string) (string, bool) {
+		existing, ok := m.originals[key]
+		return existing, ok
+	}
+	keyExists := func(key string) bool {
+		_, exists := m.shard(key).urls[key]
+		return exists
+	}
+	err := m.opts.checkBatch(urls, m.originalKey, original, keyExists)
+	if err == nil {
+		now := time.Now()
+		for _, url := range urls {
+			url = stampCreated(url, now)
+			url.UUID = int(m.seq.Add(1))
+			m.shard(url.ShortURL).urls[url.ShortURL] = url
+			if !url.IsDeleted {
+				m.originals[m.originalKey(url)] = url.ShortURL
+			}
+		}
+	}
+
+	m.originalsMu.Unlock()
+	for i := len(m.shards) - 1; i >= 0; i-- {
+		m.shards[i].mu.Unlock()
+	}
+	if err != nil {
+		return err
+	}
+
+	m.usersMu.Lock()
+	for _, url := range urls {
+		m.users[url.UserID] = append(m.users[url.UserID], url.ShortURL)
+	}
+	m.usersMu.Unlock()
+	return nil
+}
+
+// originalKey возвращает ключ ссылки в индексе оригинальных URL с учётом области уникальности
+func (m *MemoryStorage) originalKey(url ShortenerURL) string {
+	return originalKey(m.opts.scope(url.UserID, url.ShortURL), url.OriginalURL)
+}
+
+// original возвращает короткий ключ не удалённой ссылки пользователя с тем же оригинальным URL,
+// key — короткий ключ проверяемой ссылки
+func (m *MemoryStorage) original(userID, key, originalURL string) (string, bool) {
+	return m.originalAt(originalKey(m.opts.scope(userID, key), originalURL))
+}
+
+// originalAt возвращает короткий ключ ссылки по ключу индекса оригинальных URL
+func (m *MemoryStorage) originalAt(key string) (string, bool) {
+	m.originalsMu.Lock()
+	defer m.originalsMu.Unlock()
+	existing, ok := m.originals[key]
+	return existing, ok
+}
+
+// releaseOriginal освобождает оригинальный URL ссылки, если он закреплён за ней.
+// Вызывающий должен удерживать блокировку шарда ссылки.
+func (m *MemoryStorage) releaseOriginal(url ShortenerURL) {
+	key := m.originalKey(url)
+	m.originalsMu.Lock()
+	if m.originals[key] == url.ShortURL {
+		delete(m.originals, key)
+	}
+	m.originalsMu.Unlock()
+}
+
+// Get возвращает оригинальный URL по сокращённому
+func (m *MemoryStorage) Get(ctx context.Context, url string) (string, error) {
+	sh := m.shard(url)
+
+	sh.mu.RLock()
+	link, ok := sh.urls[url]
+	sh.mu.RUnlock()
+
+	if !ok {
+		return "", ErrNotFound
+	}
+	if link.IsDeleted {
+		return "", ErrDeleted
+	}
+	if link.Expired(time.Now()) {
+		return "", ErrExpired
+	}
+	return link.OriginalURL, nil
+}
+
+// GetByUser возвращает все URL-ы, сохранённые пользователем
+func (m *MemoryStorage) GetByUser(ctx context.Context, username string) ([]models.ShortURLResponse, error) {
+	host, _ := ctx.Value(contextkeys.HostKey).(string)
+
+	m.usersMu.RLock()
+	keys := make([]string, len(m.users[username]))
+	copy(keys, m.users[username])
+	m.usersMu.RUnlock()
+
+	var result []models.ShortURLResponse
+	for _, key := range keys {
+		sh := m.shard(key)
+		sh.mu.RLock()
+		link, ok := sh.urls[key]
+		sh.mu.RUnlock()
+		if !ok {
+			continue
+		}
+		result = append(result, models.ShortURLResponse{
+			ShortURL:    host + "/" + link.ShortURL,
+			OriginalURL: link.OriginalURL,
+		})
+	}
+	return result, nil
+}
+
+// ListByUser возвращает страницу ссылок пользователя в порядке времени создания
+func (m *MemoryStorage) ListByUser(ctx context.Context, userID string, opts models.ListOptions) ([]ShortenerURL, string, error) {
+	m.usersMu.RLock()
+	keys := make([]string, len(m.users[userID]))
+	copy(keys, m.users[userID])
+	m.usersMu.RUnlock()
+
+	urls := make([]ShortenerURL, 0, len(keys))
+	for _, key := range keys {
+		if link, ok := m.lookup(key); ok {
+			urls = append(urls, link)
+		}
+	}
+	sort.Slice(urls, func(i, j int) bool {
+		if opts.Descending() {
+			return listLess(urls[j], urls[i])
+		}
+		return listLess(urls[i], urls[j])
+	})
+
+	return listPage(urls, opts)
+}
+
+// DeleteURLs помечает переданные ссылки пользователя как удалённые
+func (m *MemoryStorage) DeleteURLs(ctx context.Context, userID string, ids []string) error {
+	m.deleteURLs(userID, ids)
+	return nil
+}
+
+// deleteURLs помечает ссылки пользователя удалёнными и возвращает результат по каждому ключу
+func (m *MemoryStorage) deleteURLs(userID string, ids []string) models.DeleteOutcomes {
+	outcomes := make(models.DeleteOutcomes, len(ids))
+	now := time.Now()
+	for _, id := range ids {
+		sh := m.shard(id)
+		sh.mu.Lock()
+		link, exists := sh.urls[id]
+		switch {
+		case !exists:
+			outcomes[id] = models.DeleteOutcomeNotFound
+		case link.UserID != userID:
+			outcomes[id] = models.DeleteOutcomeNotOwned
+		default:
+			if !link.IsDeleted {
+				m.releaseOriginal(link)
+			}
+			sh.urls[id] = markDeleted(link, now)
+			outcomes[id] = models.DeleteOutcomeDeleted
+		}
+		sh.mu.Unlock()
+	}
+	return outcomes
+}
+
+// DeleteURLsBatch помечает удалёнными ссылки нескольких пользователей
+func (m *MemoryStorage) DeleteURLsBatch(ctx context.Context, reqs []models.DeleteRequest) ([]models.DeleteOutcomes, error) {
+	results := make([]models.DeleteOutcomes, 0, len(reqs))
+	for _, req := range reqs {
+		results = append(results, m.deleteURLs(req.UserID, req.ShortURLs))
+	}
+	return results, nil
+}
+
+// CountURLs возвращает количество не удалённых URL в хранилище.
+func (m *MemoryStorage) CountURLs(ctx context.Context) (int64, error) {
+	var count int64
+	for _, sh := range m.shards {
+		sh.mu.RLock()
+		for _, link := range sh.urls {
+			if !link.IsDeleted {
+				count++
+			}
+		}
+		sh.mu.RUnlock()
+	}
+	return count, nil
+}
+
+// CountUsers возвращает количество пользователей в хранилище.
+func (m *MemoryStorage) CountUsers(ctx context.Context) (int64, error) {
+	m.usersMu.RLock()
+	defer m.usersMu.RUnlock()
+	return int64(len(m.users)), nil
+}
+
+// Scan возвращает записи по возрастанию идентификатора, начиная после курсора
+func (m *MemoryStorage) Scan(ctx context.Context, cursor string, limit int) ([]ShortenerURL, string, error) {
+	after, err := parseIDCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	urls := m.snapshot()
+	start := sort.Search(len(urls), func(i int) bool { return urls[i].UUID > after })
+	end := len(urls)
+	if limit > 0 && start+limit < end {
+		end = start + limit
+	}
+
+	page := urls[start:end]
+	if end == len(urls) {
+		return page, "", nil
+	}
+	return page, strconv.Itoa(page[len(page)-1].UUID), nil
+}
+
+// Import сохраняет записи как есть, пропуская уже существующие короткие ключи
+// и оригинальные URL, которые уже сокращены
+func (m *MemoryStorage) Import(ctx context.Context, urls []ShortenerURL) (int, error) {
+	imported := 0
+	for _, url := range urls {
+		if _, err := m.insert(url); err == nil {
+			imported++
+		}
+	}
+	return imported, nil
+}
+
+// PurgeExpired помечает удалёнными ссылки с истёкшим сроком действия
+func (m *MemoryStorage) PurgeExpired(ctx context.Context, now time.Time) (int64, error) {
+	var purged int64
+	for _, sh := range m.shards {
+		sh.mu.Lock()
+		for key, link := range sh.urls {
+			if !link.IsDeleted && link.Expired(now) {
+				m.releaseOriginal(link)
+				sh.urls[key] = markDeleted(link, now)
+				purged++
+			}
+		}
+		sh.mu.Unlock()
+	}
+	return purged, nil
+}
+
+// expired возвращает не удалённые ссылки с истёкшим сроком действия
+func (m *MemoryStorage) expired(now time.Time) []ShortenerURL {
+	var urls []ShortenerURL
+	for _, sh := range m.shards {
+		sh.mu.RLock()
+		for _, link := range sh.urls {
+			if !link.IsDeleted && link.Expired(now) {
+				urls = append(urls, link)
+			}
+		}
+		sh.mu.RUnlock()
+	}
+	return urls
+}
+
+// UpdateURL меняет оригинальный URL ссылки пользователя и добавляет запись в историю.
+// Если новый URL уже сокращён другой ссылкой, возвращает ErrConflict.
+func (m *MemoryStorage) UpdateURL(ctx context.Context, userID, key, originalURL string) (models.URLVersion, error) {
+	sh := m.shard(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	link, ok := sh.urls[key]
+	if !ok || link.UserID != userID {
+		return models.URLVersion{}, ErrNotFound
+	}
+	if link.IsDeleted {
+		return models.URLVersion{}, ErrDeleted
+	}
+
+	updated := link
+	updated.OriginalURL = originalURL
+	m.originalsMu.Lock()
+	if owner, taken := m.originals[m.originalKey(updated)]; taken && owner != key {
+		m.originalsMu.Unlock()
+		return models.URLVersion{}, ErrConflict
+	}
+	if m.originals[m.originalKey(link)] == key {
+		delete(m.originals, m.originalKey(link))
+	}
+	m.originals[m.originalKey(updated)] = key
+	m.originalsMu.Unlock()
+
+	version := m.appendHistory(key, link.OriginalURL, originalURL, userID, time.Now())
+	sh.urls[key] = updated
+	return version, nil
+}
+
+// URLHistory возвращает историю изменений ссылки
+func (m *MemoryStorage) URLHistory(ctx context.Context, key string) ([]models.URLVersion, error) {
+	m.historyMu.RLock()
+	defer m.historyMu.RUnlock()
+	return append([]models.URLVersion(nil), m.history[key]...), nil
+}
+
+// ImportHistory сохраняет перенесённую историю изменений ссылки как есть, заменяя текущую
+func (m *MemoryStorage) ImportHistory(ctx context.Context, key string, history []models.URLVersion) error {
+	m.historyMu.Lock()
+	defer m.historyMu.Unlock()
+	m.history[key] = append([]models.URLVersion(nil), history...)
+	return nil
+}
+
+// appendHistory добавляет в историю ссылки очередную версию
+func (m *MemoryStorage) appendHistory(key, previous, originalURL, changedBy string, changedAt time.Time) models.URLVersion {
+	m.historyMu.Lock()
+	defer m.historyMu.Unlock()
+
+	version := models.URLVersion{
+		Version:     len(m.history[key]) + 1,
+		OriginalURL: originalURL,
+		PreviousURL: previous,
+		ChangedBy:   changedBy,
+		ChangedAt:   changedAt,
+	}
+	m.history[key] = append(m.history[key], version)
+	return version
+}
+
+// historySnapshot возвращает копию истории изменений всех ссылок
+func (m *MemoryStorage) historySnapshot() map[string][]models.URLVersion {
+	m.historyMu.RLock()
+	defer m.historyMu.RUnlock()
+
+	history := make(map[string][]models.URLVersion, len(m.history))
+	for key, versions := range m.history {
+		history[key] = append([]models.URLVersion(nil), versions...)
+	}
+	return history
+}
+
+// GetURL возвращает запись по короткому ключу
+func (m *MemoryStorage) GetURL(ctx context.Context, key string) (ShortenerURL, error) {
+	link, ok := m.lookup(key)
+	if !ok {
+		return ShortenerURL{}, ErrNotFound
+	}
+	return link, nil
+}
+
+// SaveClicks сохраняет пачку переходов в памяти.
+// Переходы старше срока хранения не сохраняются и периодически удаляются.
+func (m *MemoryStorage) SaveClicks(ctx context.Context, clicks []models.Click) error {
+	now := time.Now()
+	m.clicksMu.Lock()
+	defer m.clicksMu.Unlock()
+	if cutoff, ok := m.clickPrune.due(m.opts.clickRetention, now); ok {
+		m.pruneClicks(cutoff)
+	}
+	for _, click := range clicks {
+		if m.opts.clickRetention > 0 && click.ClickedAt.Before(now.Add(-m.opts.clickRetention)) {
+			continue
+		}
+		m.clicks[click.ShortURL] = append(m.clicks[click.ShortURL], click)
+	}
+	return nil
+}
+
+// pruneClicks удаляет переходы раньше cutoff; вызывается под clicksMu
+func (m *MemoryStorage) pruneClicks(cutoff time.Time) {
+	for key, clicks := range m.clicks {
+		clicks = slices.DeleteFunc(clicks, func(click models.Click) bool {
+			return click.ClickedAt.Before(cutoff)
+		})
+		if len(clicks) == 0 {
+			delete(m.clicks, key)
+			continue
+		}
+		m.clicks[key] = clicks
+	}
+}
+
+// Clicks возвращает все сохранённые переходы по ссылке
+func (m *MemoryStorage) Clicks(ctx context.Context, key string) ([]models.Click, error) {
+	m.clicksMu.RLock()
+	defer m.clicksMu.RUnlock()
+	return append([]models.Click(nil), m.clicks[key]...), nil
+}
+
+// ClickStats возвращает статистику переходов по ссылке
+func (m *MemoryStorage) ClickStats(ctx context.Context, key string, bucket time.Duration) (models.LinkStats, error) {
+	m.clicksMu.RLock()
+	defer m.clicksMu.RUnlock()
+	return aggregateClicks(key, m.clicks[key], bucket), nil
+}
+
+// parseIDCursor разбирает курсор, содержащий числовой идентификатор записи
+func parseIDCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	id, err := strconv.Atoi(cursor)
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("%w %q", ErrInvalidCursor, cursor)
+	}
+	return id, nil
+}
+
+// lookup возвращает запись по короткому ключу
+func (m *MemoryStorage) lookup(key string) (ShortenerURL, bool) {
+	sh := m.shard(key)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	link, ok := sh.urls[key]
+	return link, ok
+}
+
+// put сохраняет запись как есть, не меняя её идентификатор.
+// Используется при восстановлении состояния из внешнего источника.
+// Индекс оригинальных URL обновляется, но конфликты не проверяются:
+// если URL уже закреплён за другой ссылкой, закрепление сохраняется.
+func (m *MemoryStorage) put(url ShortenerURL) {
+	sh := m.shard(url.ShortURL)
+	sh.mu.Lock()
+	previous, exists := sh.urls[url.ShortURL]
+	if exists && !previous.IsDeleted {
+		m.releaseOriginal(previous)
+	}
+	if !url.IsDeleted {
+		key := m.originalKey(url)
+		m.originalsMu.Lock()
+		if _, taken := m.originals[key]; !taken {
+			m.originals[key] = url.ShortURL
+		}
+		m.originalsMu.Unlock()
+	}
+	sh.urls[url.ShortURL] = url
+	sh.mu.Unlock()
+
+	if !exists {
+		m.usersMu.Lock()
+		m.users[url.UserID] = append(m.users[url.UserID], url.ShortURL)
+		m.usersMu.Unlock()
+	}
+
+	for {
+		current := m.seq.Load()
+		if int64(url.UUID) <= current || m.seq.CompareAndSwap(current, int64(url.UUID)) {
+			return
+		}
+	}
+}
+
+// nextID резервирует идентификатор для новой записи
+func (m *MemoryStorage) nextID() int {
+	return int(m.seq.Add(1))
+}
+
+// snapshot возвращает копию всех записей, упорядоченную по идентификатору
+func (m *MemoryStorage) snapshot() []ShortenerURL {
+	var urls []ShortenerURL
+	for _, sh := range m.shards {
+		sh.mu.RLock()
+		for _, link := range sh.urls {
+			urls = append(urls, link)
+		}
+		sh.mu.RUnlock()
+	}
+	sort.Slice(urls, func(i, j int) bool { return urls[i].UUID < urls[j].UUID })
+	return urls
+}
+
+// ReserveKeys резервирует значения счётчика генератора ключей; счётчик живёт, пока живёт хранилище
+func (m *MemoryStorage) ReserveKeys(ctx context.Context, name string, floor, n uint64) (uint64, error) {
+	m.countersMu.Lock()
+	defer m.countersMu.Unlock()
+
+	value, ok := m.counters[name]
+	if !ok {
+		value = uint64(m.len())
+	}
+	value = max(value, floor) + n
+	m.counters[name] = value
+	return value, nil
+}
+
+// historyLen возвращает общее количество версий в истории изменений ссылок
+func (m *MemoryStorage) historyLen() int {
+	m.historyMu.RLock()
+	defer m.historyMu.RUnlock()
+
+	count := 0
+	for _, versions := range m.history {
+		count += len(versions)
+	}
+	return count
+}
+
+// clicksSnapshot возвращает копию всех сохранённых переходов
+func (m *MemoryStorage) clicksSnapshot() []models.Click {
+	m.clicksMu.RLock()
+	defer m.clicksMu.RUnlock()
+
+	var clicks []models.Click
+	for _, list := range m.clicks {
+		clicks = append(clicks, list...)
+	}
+	return clicks
+}
+
+// len возвращает общее количество записей, включая удалённые
+func (m *MemoryStorage) len() int {
+	count := 0
+	for _, sh := range m.shards {
+		sh.mu.RLock()
+		count += len(sh.urls)
+		sh.mu.RUnlock()
+	}
+	return count
+}
@@ -0,0 +1,202 @@
+package storage_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/issafronov/shortener/internal/app/contextkeys"
+	"github.com/issafronov/shortener/internal/app/models"
+	"github.com/issafronov/shortener/internal/app/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStorage_CreateAndGet(t *testing.T) {
+	s := storage.NewMemoryStorage()
+	ctx := context.Background()
+
+	key, err := s.Create(ctx, storage.ShortenerURL{ShortURL: "abc123", OriginalURL: "https://example.com", UserID: "user1"})
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", key)
+
+	got, err := s.Get(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", got)
+
+	_, err = s.Get(ctx, "no-such-key")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func TestMemoryStorage_Create_Conflict(t *testing.T) {
+	s := storage.NewMemoryStorage()
+	ctx := context.Background()
+
+	_, err := s.Create(ctx, storage.ShortenerURL{ShortURL: "abc123", OriginalURL: "https://example.com", UserID: "user1"})
+	require.NoError(t, err)
+
+	_, err = s.Create(ctx, storage.ShortenerURL{ShortURL: "abc123", OriginalURL: "https://other.com", UserID: "user2"})
+	assert.ErrorIs(t, err, storage.ErrKeyExists)
+}
+
+func TestMemoryStorage_DeleteURLs(t *testing.T) {
+	s := storage.NewMemoryStorage()
+	ctx := context.Background()
+
+	_, err := s.Create(ctx, storage.ShortenerURL{ShortURL: "own", OriginalURL: "https://own.com", UserID: "user1"})
+	require.NoError(t, err)
+	_, err = s.Create(ctx, storage.ShortenerURL{ShortURL: "foreign", OriginalURL: "https://foreign.com", UserID: "user2"})
+	require.NoError(t, err)
+
+	require.NoError(t, s.DeleteURLs(ctx, "user1", []string{"own", "foreign", "missing"}))
+
+	_, err = s.Get(ctx, "own")
+	assert.ErrorIs(t, err, storage.ErrDeleted)
+
+	got, err := s.Get(ctx, "foreign")
+	require.NoError(t, err)
+	assert.Equal(t, "https://foreign.com", got)
+
+	count, err := s.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestMemoryStorage_GetByUser(t *testing.T) {
+	s := storage.NewMemoryStorage()
+	ctx := context.WithValue(context.Background(), contextkeys.HostKey, "http://localhost")
+
+	_, err := s.Create(ctx, storage.ShortenerURL{ShortURL: "a", OriginalURL: "https://a.com", UserID: "user1"})
+	require.NoError(t, err)
+	_, err = s.Create(ctx, storage.ShortenerURL{ShortURL: "b", OriginalURL: "https://b.com", UserID: "user1"})
+	require.NoError(t, err)
+	_, err = s.Create(ctx, storage.ShortenerURL{ShortURL: "c", OriginalURL: "https://c.com", UserID: "user2"})
+	require.NoError(t, err)
+
+	links, err := s.GetByUser(ctx, "user1")
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+	assert.Equal(t, "http://localhost/a", links[0].ShortURL)
+	assert.Equal(t, "https://b.com", links[1].OriginalURL)
+
+	users, err := s.CountUsers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), users)
+}
+
+func TestMemoryStorage_Concurrent(t *testing.T) {
+	s := storage.NewMemoryStorage()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			userID := fmt.Sprintf("user-%d", w)
+			keys := make([]string, 0, 100)
+			for i := 0; i < 100; i++ {
+				key := fmt.Sprintf("k-%d-%d", w, i)
+				_, err := s.Create(ctx, storage.ShortenerURL{ShortURL: key, OriginalURL: "https://example.com/" + key, UserID: userID})
+				assert.NoError(t, err)
+				keys = append(keys, key)
+			}
+			assert.NoError(t, s.DeleteURLs(ctx, userID, keys[:50]))
+		}(w)
+	}
+	wg.Wait()
+
+	count, err := s.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8*50), count)
+}
+
+func TestMemoryStorage_Expiry(t *testing.T) {
+	s := storage.NewMemoryStorage()
+	ctx := context.Background()
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+
+	_, err := s.Create(ctx, storage.ShortenerURL{ShortURL: "old", OriginalURL: "https://old.com", UserID: "user1", ExpiresAt: &past})
+	require.NoError(t, err)
+	_, err = s.Create(ctx, storage.ShortenerURL{ShortURL: "new", OriginalURL: "https://new.com", UserID: "user1", ExpiresAt: &future})
+	require.NoError(t, err)
+
+	_, err = s.Get(ctx, "old")
+	assert.ErrorIs(t, err, storage.ErrExpired)
+	_, err = s.Get(ctx, "new")
+	assert.NoError(t, err)
+
+	purged, err := s.PurgeExpired(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	_, err = s.Get(ctx, "old")
+	assert.ErrorIs(t, err, storage.ErrDeleted)
+
+	count, err := s.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestMemoryStorage_ClickStats(t *testing.T) {
+	s := storage.NewMemoryStorage()
+	ctx := context.Background()
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	require.NoError(t, s.SaveClicks(ctx, []models.Click{
+		{ShortURL: "abc", ClickedAt: base.Add(5 * time.Minute), IPHash: "a"},
+		{ShortURL: "abc", ClickedAt: base.Add(50 * time.Minute), IPHash: "b"},
+		{ShortURL: "abc", ClickedAt: base.Add(2 * time.Hour), IPHash: "a"},
+		{ShortURL: "other", ClickedAt: base, IPHash: "c"},
+	}))
+
+	stats, err := s.ClickStats(ctx, "abc", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), stats.Total)
+	assert.Equal(t, int64(2), stats.UniqueVisitors)
+	assert.Equal(t, []models.StatsBucket{
+		{Start: base, Count: 2},
+		{Start: base.Add(2 * time.Hour), Count: 1},
+	}, stats.Histogram)
+
+	stats, err = s.ClickStats(ctx, "missing", time.Hour)
+	require.NoError(t, err)
+	assert.Zero(t, stats.Total)
+	assert.Empty(t, stats.Histogram)
+}
+
+func TestMemoryStorage_UpdateURL(t *testing.T) {
+	s := storage.NewMemoryStorage()
+	ctx := context.Background()
+	_, err := s.Create(ctx, storage.ShortenerURL{ShortURL: "abc", OriginalURL: "https://a.com", UserID: "user1"})
+	require.NoError(t, err)
+
+	_, err = s.UpdateURL(ctx, "intruder", "abc", "https://evil.com")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	_, err = s.UpdateURL(ctx, "user1", "missing", "https://b.com")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	version, err := s.UpdateURL(ctx, "user1", "abc", "https://b.com")
+	require.NoError(t, err)
+	assert.Equal(t, 1, version.Version)
+	assert.Equal(t, "https://a.com", version.PreviousURL)
+	_, err = s.UpdateURL(ctx, "user1", "abc", "https://c.com")
+	require.NoError(t, err)
+
+	got, err := s.Get(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://c.com", got)
+
+	history, err := s.URLHistory(ctx, "abc")
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, 2, history[1].Version)
+	assert.Equal(t, "user1", history[1].ChangedBy)
+
+	require.NoError(t, s.DeleteURLs(ctx, "user1", []string{"abc"}))
+	_, err = s.UpdateURL(ctx, "user1", "abc", "https://d.com")
+	assert.ErrorIs(t, err, storage.ErrDeleted)
+}
@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"github.com/issafronov/shortener/internal/app/models"
+)
+
+// DefaultCopyBatchSize — размер пачки при переносе записей по умолчанию
+const DefaultCopyBatchSize = 500
+
+// ErrArchiveUnsupported возвращается, если хранилище не умеет переносить историю изменений и переходы
+var ErrArchiveUnsupported = errors.New("storage does not support copying link history and clicks")
+
+// LinkArchive — хранилище, из которого и в которое можно перенести данные ссылки помимо самой записи
+type LinkArchive interface {
+	// Clicks возвращает все сохранённые переходы по ссылке
+	Clicks(ctx context.Context, key string) ([]models.Click, error)
+	// ImportHistory сохраняет перенесённую историю изменений существующей ссылки с исходными номерами версий
+	ImportHistory(ctx context.Context, key string, history []models.URLVersion) error
+}
+
+// CopyOptions настраивает перенос записей между хранилищами
+type CopyOptions struct {
+	// BatchSize — количество записей, читаемых и записываемых за один шаг
+	BatchSize int
+	// Cursor — курсор источника, с которого продолжается прерванный перенос
+	Cursor string
+	// OnBatch вызывается после записи каждой пачки, например для сохранения контрольной точки
+	OnBatch func(progress CopyProgress) error
+}
+
+// CopyProgress описывает состояние переноса
+type CopyProgress struct {
+	// Cursor — курсор источника, с которого нужно продолжить перенос; пустой после завершения
+	Cursor string `json:"cursor"`
+	// Read — количество прочитанных из источника записей
+	Read int `json:"read"`
+	// Written — количество записанных в приёмник записей
+	Written int `json:"written"`
+	// Versions — количество перенесённых версий из истории изменений ссылок
+	Versions int `json:"versions"`
+	// Clicks — количество перенесённых переходов
+	Clicks int `json:"clicks"`
+	// Done — признак завершения переноса
+	Done bool `json:"done"`
+}
+
+// Copy потоково переносит все записи из src в dst пачками, сохраняя короткие ключи,
+// владельцев, correlation_id, моменты создания и удаления, историю изменений и переходы.
+// Записи, уже существующие в dst, пропускаются, а история и переходы переносятся только
+// для ссылок, у которых в dst их ещё нет, поэтому перенос можно безопасно повторить
+// с последней контрольной точки. Если src или dst не реализуют LinkArchive,
+// перенос не начинается и возвращается ErrArchiveUnsupported.
+func Copy(ctx context.Context, src, dst Storage, opts CopyOptions) (CopyProgress, error) {
+	progress := CopyProgress{Cursor: opts.Cursor}
+
+	srcArchive, srcOK := Find[LinkArchive](src)
+	dstArchive, dstOK := Find[LinkArchive](dst)
+	if !srcOK || !dstOK {
+		return progress, ErrArchiveUnsupported
+	}
+
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultCopyBatchSize
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return progress, err
+		}
+
+		page, next, err := src.Scan(ctx, progress.Cursor, batchSize)
+		if err != nil {
+			return progress, err
+		}
+
+		if len(page) > 0 {
+			written, err := dst.Import(ctx, page)
+			if err != nil {
+				return progress, err
+			}
+			progress.Read += len(page)
+			progress.Written += written
+
+			for _, url := range page {
+				versions, clicks, err := copyLinkData(ctx, url, src, dst, srcArchive, dstArchive)
+				if err != nil {
+					return progress, err
+				}
+				progress.Versions += versions
+				progress.Clicks += clicks
+			}
+		}
+
+		progress.Cursor = next
+		progress.Done = next == ""
+
+		if opts.OnBatch != nil {
+			if err := opts.OnBatch(progress); err != nil {
+				return progress, errors.Join(errors.New("checkpoint failed"), err)
+			}
+		}
+		if progress.Done {
+			return progress, nil
+		}
+	}
+}
+
+// copyLinkData переносит историю изменений и переходы ссылки, если в dst лежит та же ссылка,
+// а её истории или переходов там ещё нет. Возвращает количество перенесённых версий и переходов.
+func copyLinkData(ctx context.Context, url ShortenerURL, src, dst Storage, srcArchive, dstArchive LinkArchive) (int, int, error) {
+	stored, err := dst.GetURL(ctx, url.ShortURL)
+	if errors.Is(err, ErrNotFound) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	// ключ в dst занят другой ссылкой
+	if stored.UserID != url.UserID || stored.OriginalURL != url.OriginalURL {
+		return 0, 0, nil
+	}
+
+	var versions, copied int
+	history, err := src.URLHistory(ctx, url.ShortURL)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(history) > 0 {
+		existing, err := dst.URLHistory(ctx, url.ShortURL)
+		if err != nil {
+			return 0, 0, err
+		}
+		if len(existing) == 0 {
+			if err := dstArchive.ImportHistory(ctx, url.ShortURL, history); err != nil {
+				return 0, 0, err
+			}
+			versions = len(history)
+		}
+	}
+
+	clicks, err := srcArchive.Clicks(ctx, url.ShortURL)
+	if err != nil {
+		return versions, 0, err
+	}
+	if len(clicks) > 0 {
+		existing, err := dstArchive.Clicks(ctx, url.ShortURL)
+		if err != nil {
+			return versions, 0, err
+		}
+		if len(existing) == 0 {
+			if err := dst.SaveClicks(ctx, clicks); err != nil {
+				return versions, 0, err
+			}
+			copied = len(clicks)
+		}
+	}
+	return versions, copied, nil
+}
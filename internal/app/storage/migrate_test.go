@@ -0,0 +1,187 @@
+package storage_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/issafronov/shortener/internal/app/config"
+	"github.com/issafronov/shortener/internal/app/models"
+	"github.com/issafronov/shortener/internal/app/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopy_PreservesRecords(t *testing.T) {
+	ctx := context.Background()
+	src := storage.NewMemoryStorage()
+	for _, url := range []storage.ShortenerURL{
+		{ShortURL: "a", OriginalURL: "https://a.com", UserID: "user1", CorrelationID: "c1"},
+		{ShortURL: "b", OriginalURL: "https://b.com", UserID: "user2"},
+		{ShortURL: "c", OriginalURL: "https://c.com", UserID: "user1"},
+		{ShortURL: "d", OriginalURL: "https://d.com", UserID: "user2"},
+		{ShortURL: "e", OriginalURL: "https://e.com", UserID: "user1"},
+	} {
+		_, err := src.Create(ctx, url)
+		require.NoError(t, err)
+	}
+	require.NoError(t, src.DeleteURLs(ctx, "user1", []string{"c"}))
+
+	dst, _ := newTestBoltStorage(t)
+	defer dst.Close()
+
+	var batches int
+	progress, err := storage.Copy(ctx, src, dst, storage.CopyOptions{
+		BatchSize: 2,
+		OnBatch: func(storage.CopyProgress) error {
+			batches++
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, storage.CopyProgress{Read: 5, Written: 5, Done: true}, progress)
+	assert.Equal(t, 3, batches)
+
+	all, _, err := dst.Scan(ctx, "", 0)
+	require.NoError(t, err)
+	require.Len(t, all, 5)
+	assert.Equal(t, "c1", all[0].CorrelationID)
+	assert.Equal(t, "user1", all[0].UserID)
+	assert.True(t, all[2].IsDeleted)
+
+	_, err = dst.Get(ctx, "c")
+	assert.ErrorIs(t, err, storage.ErrDeleted)
+	count, err := dst.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+}
+
+func TestCopy_Resume(t *testing.T) {
+	ctx := context.Background()
+	src := storage.NewMemoryStorage()
+	for _, key := range []string{"a", "b", "c", "d"} {
+		_, err := src.Create(ctx, storage.ShortenerURL{ShortURL: key, OriginalURL: "https://" + key + ".com", UserID: "user1"})
+		require.NoError(t, err)
+	}
+
+	dst := storage.NewMemoryStorage()
+
+	// первый запуск прерывается после первой пачки
+	var checkpoint storage.CopyProgress
+	stop := assert.AnError
+	_, err := storage.Copy(ctx, src, dst, storage.CopyOptions{
+		BatchSize: 2,
+		OnBatch: func(p storage.CopyProgress) error {
+			checkpoint = p
+			return stop
+		},
+	})
+	require.ErrorIs(t, err, stop)
+	require.False(t, checkpoint.Done)
+	require.NotEmpty(t, checkpoint.Cursor)
+
+	progress, err := storage.Copy(ctx, src, dst, storage.CopyOptions{BatchSize: 2, Cursor: checkpoint.Cursor})
+	require.NoError(t, err)
+	assert.Equal(t, 2, progress.Read)
+	assert.True(t, progress.Done)
+
+	// повторный полный перенос ничего не дублирует
+	progress, err = storage.Copy(ctx, src, dst, storage.CopyOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 4, progress.Read)
+	assert.Equal(t, 0, progress.Written)
+
+	count, err := dst.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+}
+
+func TestCopy_PreservesHistoryClicksAndTimestamps(t *testing.T) {
+	ctx := context.Background()
+	src := storage.NewMemoryStorage()
+	for _, key := range []string{"a", "b"} {
+		_, err := src.Create(ctx, storage.ShortenerURL{ShortURL: key, OriginalURL: "https://" + key + ".com", UserID: "user1"})
+		require.NoError(t, err)
+	}
+	_, err := src.UpdateURL(ctx, "user1", "a", "https://a2.com")
+	require.NoError(t, err)
+	_, err = src.UpdateURL(ctx, "user1", "a", "https://a3.com")
+	require.NoError(t, err)
+	clickedAt := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, src.SaveClicks(ctx, []models.Click{
+		{ShortURL: "a", ClickedAt: clickedAt, IPHash: "ip1"},
+		{ShortURL: "a", ClickedAt: clickedAt.Add(time.Minute), IPHash: "ip2"},
+		{ShortURL: "b", ClickedAt: clickedAt.Add(2 * time.Hour), IPHash: "ip1"},
+	}))
+	require.NoError(t, src.DeleteURLs(ctx, "user1", []string{"b"}))
+
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "storage.json")
+	engines := map[string]func() storage.Storage{
+		storage.EngineBolt: func() storage.Storage {
+			bs, err := storage.NewBoltStorage(filepath.Join(dir, "shortener.db"))
+			require.NoError(t, err)
+			return bs
+		},
+		storage.EngineFile: func() storage.Storage {
+			fs, err := storage.NewFileStorage(&config.Config{FileStoragePath: filePath})
+			require.NoError(t, err)
+			return fs
+		},
+	}
+
+	for engine, open := range engines {
+		t.Run(engine, func(t *testing.T) {
+			dst := open()
+			progress, err := storage.Copy(ctx, src, dst, storage.CopyOptions{BatchSize: 1})
+			require.NoError(t, err)
+			assert.Equal(t, 2, progress.Versions)
+			assert.Equal(t, 3, progress.Clicks)
+
+			// повторный перенос не дублирует историю и переходы
+			progress, err = storage.Copy(ctx, src, dst, storage.CopyOptions{})
+			require.NoError(t, err)
+			assert.Zero(t, progress.Versions)
+			assert.Zero(t, progress.Clicks)
+
+			// данные переживают повторное открытие хранилища
+			require.NoError(t, dst.(interface{ Close() error }).Close())
+			dst = open()
+			defer dst.(interface{ Close() error }).Close()
+
+			for _, key := range []string{"a", "b"} {
+				want, err := src.GetURL(ctx, key)
+				require.NoError(t, err)
+				got, err := dst.GetURL(ctx, key)
+				require.NoError(t, err)
+				assert.Equal(t, want.OriginalURL, got.OriginalURL)
+				assert.True(t, want.CreatedAt.Equal(got.CreatedAt), key)
+				if want.DeletedAt == nil {
+					assert.Nil(t, got.DeletedAt, key)
+				} else {
+					require.NotNil(t, got.DeletedAt, key)
+					assert.True(t, want.DeletedAt.Equal(*got.DeletedAt), key)
+				}
+
+				wantStats, err := src.ClickStats(ctx, key, time.Hour)
+				require.NoError(t, err)
+				gotStats, err := dst.ClickStats(ctx, key, time.Hour)
+				require.NoError(t, err)
+				assert.Equal(t, wantStats, gotStats, key)
+			}
+
+			wantHistory, err := src.URLHistory(ctx, "a")
+			require.NoError(t, err)
+			gotHistory, err := dst.URLHistory(ctx, "a")
+			require.NoError(t, err)
+			require.Len(t, gotHistory, len(wantHistory))
+			for i := range wantHistory {
+				assert.True(t, wantHistory[i].ChangedAt.Equal(gotHistory[i].ChangedAt))
+				wantHistory[i].ChangedAt, gotHistory[i].ChangedAt = time.Time{}, time.Time{}
+			}
+			assert.Equal(t, wantHistory, gotHistory)
+			assert.Equal(t, "https://a.com", gotHistory[0].PreviousURL)
+		})
+	}
+}
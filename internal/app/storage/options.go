@@ -0,0 +1,73 @@
+package storage
+
+import "time"
+
+// Option задаёт необязательный параметр хранилища
+type Option func(*options)
+
+// options — общие параметры хранилищ
+type options struct {
+	uniqueness     string
+	pool           PoolOptions
+	replicas       []string
+	readYourWrites time.Duration
+	autoMigrate    bool
+	clickRetention time.Duration
+}
+
+// PoolOptions — параметры пула соединений PostgreSQL. Нулевые значения оставляют настройки database/sql по умолчанию.
+type PoolOptions struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	// StatementTimeout ограничивает время выполнения одного запроса на стороне сервера
+	StatementTimeout time.Duration
+}
+
+// WithPool задаёт параметры пула соединений PostgreSQL
+func WithPool(pool PoolOptions) Option {
+	return func(o *options) {
+		o.pool = pool
+	}
+}
+
+// WithReplicas задаёт реплики PostgreSQL, на которые направляется чтение ссылок и статистики
+func WithReplicas(dsns ...string) Option {
+	return func(o *options) {
+		o.replicas = dsns
+	}
+}
+
+// WithReadYourWrites задаёт время, в течение которого после записи пользователь читает
+// с основного сервера, чтобы видеть свои изменения независимо от отставания реплик
+func WithReadYourWrites(window time.Duration) Option {
+	return func(o *options) {
+		o.readYourWrites = window
+	}
+}
+
+// WithAutoMigrate задаёт, применять ли миграции схемы PostgreSQL при открытии хранилища.
+// По умолчанию миграции применяются.
+func WithAutoMigrate(enabled bool) Option {
+	return func(o *options) {
+		o.autoMigrate = enabled
+	}
+}
+
+// WithClickRetention задаёт срок хранения переходов в хранилищах memory, file и bolt;
+// более старые переходы удаляются при записи новых. Нулевое значение хранит переходы бессрочно.
+func WithClickRetention(retention time.Duration) Option {
+	return func(o *options) {
+		o.clickRetention = retention
+	}
+}
+
+// newOptions применяет опции поверх значений по умолчанию
+func newOptions(opts []Option) options {
+	o := options{uniqueness: UniquenessGlobal, autoMigrate: true}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakePostgres возвращает PostgresStorage без реплик поверх fakeDriver
+func newFakePostgres(t *testing.T, db *fakeDB) *PostgresStorage {
+	fakeDBs.Store(t.Name(), db)
+	conn, err := sql.Open("storage-fake", t.Name())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+		fakeDBs.Delete(t.Name())
+	})
+	return &PostgresStorage{db: conn, opts: newOptions(nil), reads: newReadRouter(nil, 0)}
+}
+
+// dedupViolation — ошибка вставки из-за ссылки с тем же оригинальным URL
+var dedupViolation = pgx.PgError{Code: pgerrcode.UniqueViolation, ConstraintName: "urls_dedup_scope_original_url_idx"}
+
+func TestPostgresStorage_CreateRetriesWhenConflictingURLDeleted(t *testing.T) {
+	var inserts int
+	db := &fakeDB{exec: func(query string, _ []driver.Value) error {
+		if !strings.Contains(query, "INSERT INTO urls") {
+			return nil
+		}
+		inserts++
+		if inserts == 1 {
+			return dedupViolation
+		}
+		return nil
+	}}
+	s := newFakePostgres(t, db)
+
+	// конфликтующую ссылку удалили: поиск её ключа ничего не находит
+	key, err := s.Create(context.Background(), ShortenerURL{ShortURL: "new", OriginalURL: "https://a.com", UserID: "user1"})
+	require.NoError(t, err)
+	assert.Equal(t, "new", key)
+	assert.Equal(t, 2, inserts)
+}
+
+func TestPostgresStorage_CreateReportsRepeatedRace(t *testing.T) {
+	db := &fakeDB{exec: func(query string, _ []driver.Value) error {
+		return dedupViolation
+	}}
+	s := newFakePostgres(t, db)
+
+	_, err := s.Create(context.Background(), ShortenerURL{ShortURL: "new", OriginalURL: "https://a.com", UserID: "user1"})
+	assert.ErrorIs(t, err, ErrConcurrentChange)
+	assert.True(t, IsExpectedError(err))
+	assert.Equal(t, dedupAttempts, db.count("INSERT INTO urls"))
+}
+
+func TestPostgresStorage_CreateBatchRetriesWhenConflictingURLDeleted(t *testing.T) {
+	var attempts int
+	db := &fakeDB{answer: func(query string, args []driver.Value) [][]driver.Value {
+		switch {
+		case strings.Contains(query, "INSERT INTO urls"):
+			attempts++
+			if attempts == 1 {
+				// строка пакета не вставлена из-за ссылки с тем же оригинальным URL
+				return [][]driver.Value{{"a"}}
+			}
+			return [][]driver.Value{{"a"}, {"b"}}
+		case strings.Contains(query, "SELECT EXISTS"):
+			return [][]driver.Value{{false}}
+		}
+		// ссылку с тем же оригинальным URL удалили до поиска её ключа
+		return nil
+	}}
+	s := newFakePostgres(t, db)
+
+	err := s.CreateBatch(context.Background(), []ShortenerURL{
+		{ShortURL: "a", OriginalURL: "https://a.com", UserID: "user1"},
+		{ShortURL: "b", OriginalURL: "https://b.com", UserID: "user1"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+}
+
+func TestPostgresStorage_CreateBatchKeyTaken(t *testing.T) {
+	db := &fakeDB{answer: func(query string, args []driver.Value) [][]driver.Value {
+		switch {
+		case strings.Contains(query, "INSERT INTO urls"):
+			return [][]driver.Value{{"a"}}
+		case strings.Contains(query, "SELECT EXISTS"):
+			return [][]driver.Value{{true}}
+		}
+		return nil
+	}}
+	s := newFakePostgres(t, db)
+
+	err := s.CreateBatch(context.Background(), []ShortenerURL{
+		{ShortURL: "a", OriginalURL: "https://a.com", UserID: "user1"},
+		{ShortURL: "b", OriginalURL: "https://b.com", UserID: "user1"},
+	})
+	var batchErr *BatchError
+	require.ErrorAs(t, err, &batchErr)
+	assert.Equal(t, 1, batchErr.Index)
+	assert.ErrorIs(t, err, ErrKeyExists)
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// replicaCheckInterval — период проверки доступности реплик
+const replicaCheckInterval = 5 * time.Second
+
+// replica — реплика PostgreSQL, обслуживающая чтение
+type replica struct {
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// readRouter распределяет чтение по доступным репликам по кругу.
+// Пользователь, недавно изменивший свои ссылки, читает с основного сервера,
+// пока реплики не догонят его запись.
+type readRouter struct {
+	replicas []*replica
+	next     atomic.Uint64
+	window   time.Duration
+	now      func() time.Time
+
+	mu   sync.Mutex
+	pins map[string]time.Time
+}
+
+// newReadRouter возвращает маршрутизатор чтения; window — время, на которое чтение пользователя
+// закрепляется за основным сервером после записи, 0 отключает закрепление
+func newReadRouter(replicas []*replica, window time.Duration) *readRouter {
+	return &readRouter{replicas: replicas, window: window, now: time.Now, pins: make(map[string]time.Time)}
+}
+
+// pin закрепляет чтение пользователя за основным сервером на время window
+func (r *readRouter) pin(userIDs ...string) {
+	if r.window <= 0 || len(r.replicas) == 0 {
+		return
+	}
+	until := r.now().Add(r.window)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, userID := range userIDs {
+		if userID != "" {
+			r.pins[userID] = until
+		}
+	}
+}
+
+// pinned сообщает, закреплено ли чтение пользователя за основным сервером
+func (r *readRouter) pinned(userID string) bool {
+	if userID == "" {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	until, ok := r.pins[userID]
+	return ok && r.now().Before(until)
+}
+
+// pick возвращает следующую доступную реплику или nil, если читать нужно с основного сервера
+func (r *readRouter) pick(userID string) *replica {
+	if len(r.replicas) == 0 || r.pinned(userID) {
+		return nil
+	}
+	start := r.next.Add(1)
+	for i := range r.replicas {
+		rep := r.replicas[(start+uint64(i))%uint64(len(r.replicas))]
+		if rep.healthy.Load() {
+			return rep
+		}
+	}
+	return nil
+}
+
+// check проверяет доступность реплик и удаляет истёкшие закрепления
+func (r *readRouter) check(ctx context.Context) {
+	for _, rep := range r.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, replicaCheckInterval)
+		rep.healthy.Store(rep.db.PingContext(pingCtx) == nil)
+		cancel()
+	}
+	r.prune()
+}
+
+// prune удаляет истёкшие закрепления
+func (r *readRouter) prune() {
+	now := r.now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for userID, until := range r.pins {
+		if !now.Before(until) {
+			delete(r.pins, userID)
+		}
+	}
+}
+
+// run периодически проверяет реплики до отмены ctx
+func (r *readRouter) run(ctx context.Context) {
+	ticker := time.NewTicker(replicaCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.check(ctx)
+		}
+	}
+}
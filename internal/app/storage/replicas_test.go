@@ -0,0 +1,328 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/issafronov/shortener/internal/app/contextkeys"
+	"github.com/issafronov/shortener/internal/app/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestReplicas возвращает реплики без соединений с заданной доступностью
+func newTestReplicas(healthy ...bool) []*replica {
+	replicas := make([]*replica, len(healthy))
+	for i, ok := range healthy {
+		replicas[i] = &replica{}
+		replicas[i].healthy.Store(ok)
+	}
+	return replicas
+}
+
+func TestReadRouter_RoundRobin(t *testing.T) {
+	replicas := newTestReplicas(true, true)
+	r := newReadRouter(replicas, 0)
+
+	first, second, third := r.pick(""), r.pick(""), r.pick("")
+	assert.NotSame(t, first, second)
+	assert.Same(t, first, third)
+}
+
+func TestReadRouter_SkipsUnhealthy(t *testing.T) {
+	replicas := newTestReplicas(false, true, false)
+	r := newReadRouter(replicas, 0)
+
+	for range 3 {
+		assert.Same(t, replicas[1], r.pick("user1"))
+	}
+
+	replicas[1].healthy.Store(false)
+	assert.Nil(t, r.pick("user1"), "reads must go to the primary when no replica is healthy")
+}
+
+func TestReadRouter_Pin(t *testing.T) {
+	now := time.Now()
+	r := newReadRouter(newTestReplicas(true), 5*time.Second)
+	r.now = func() time.Time { return now }
+
+	r.pin("user1")
+	assert.Nil(t, r.pick("user1"))
+	assert.NotNil(t, r.pick("user2"))
+
+	now = now.Add(5 * time.Second)
+	assert.NotNil(t, r.pick("user1"), "pin must expire after the window")
+
+	r.pin("user1")
+	r.prune()
+	assert.Len(t, r.pins, 1)
+	now = now.Add(5 * time.Second)
+	r.prune()
+	assert.Empty(t, r.pins)
+}
+
+func TestReadRouter_PinDisabled(t *testing.T) {
+	r := newReadRouter(nil, 5*time.Second)
+	r.pin("user1")
+	assert.Empty(t, r.pins, "without replicas all reads go to the primary anyway")
+
+	r = newReadRouter(newTestReplicas(true), 0)
+	r.pin("user1")
+	assert.NotNil(t, r.pick("user1"))
+}
+
+// fakeDB — база данных для проверки маршрутизации чтения: записывает запросы
+// и отвечает на них строками, которые возвращает answer; exec, если задан,
+// возвращает ошибку выполнения команды
+type fakeDB struct {
+	mu      sync.Mutex
+	queries []string
+	answer  func(query string, args []driver.Value) [][]driver.Value
+	exec    func(query string, args []driver.Value) error
+}
+
+// count возвращает число запросов, содержащих substr
+func (db *fakeDB) count(substr string) int {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	n := 0
+	for _, q := range db.queries {
+		if strings.Contains(q, substr) {
+			n++
+		}
+	}
+	return n
+}
+
+func (db *fakeDB) record(query string) {
+	db.mu.Lock()
+	db.queries = append(db.queries, query)
+	db.mu.Unlock()
+}
+
+// fakeDBs — базы данных fakeDriver по имени источника
+var fakeDBs sync.Map
+
+func init() {
+	sql.Register("storage-fake", fakeDriver{})
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	db, ok := fakeDBs.Load(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown fake database %q", name)
+	}
+	return &fakeConn{db: db.(*fakeDB)}, nil
+}
+
+type fakeConn struct{ db *fakeDB }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{db: c.db, query: query}, nil
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return c, nil }
+func (c *fakeConn) Commit() error             { return nil }
+func (c *fakeConn) Rollback() error           { return nil }
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.record(s.query)
+	if s.db.exec != nil {
+		if err := s.db.exec(s.query, args); err != nil {
+			return nil, err
+		}
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.record(s.query)
+	var rows [][]driver.Value
+	if s.db.answer != nil {
+		rows = s.db.answer(s.query, args)
+	}
+	return &fakeRows{rows: rows}, nil
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+	next int
+}
+
+func (r *fakeRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return make([]string, 7)
+	}
+	return make([]string, len(r.rows[0]))
+}
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.next])
+	r.next++
+	return nil
+}
+
+// newRoutedStorage возвращает PostgresStorage с основным сервером и одной исправной репликой на fakeDriver
+func newRoutedStorage(t *testing.T, primary, replicaDB *fakeDB, window time.Duration) *PostgresStorage {
+	open := func(name string, db *fakeDB) *sql.DB {
+		fakeDBs.Store(name, db)
+		conn, err := sql.Open("storage-fake", name)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			_ = conn.Close()
+			fakeDBs.Delete(name)
+		})
+		return conn
+	}
+	rep := &replica{db: open(t.Name()+"/replica", replicaDB)}
+	rep.healthy.Store(true)
+	return &PostgresStorage{
+		db:    open(t.Name()+"/primary", primary),
+		opts:  newOptions(nil),
+		reads: newReadRouter([]*replica{rep}, window),
+	}
+}
+
+// urlRows отвечает на запросы к urls одной ссылкой с оригинальным URL original, если ключ известен
+func urlRows(original string, keys ...string) func(string, []driver.Value) [][]driver.Value {
+	return func(query string, args []driver.Value) [][]driver.Value {
+		if !strings.Contains(query, "FROM urls") {
+			return nil
+		}
+		if strings.Contains(query, "short_url = $1") && !slices.Contains(keys, args[0].(string)) {
+			return nil
+		}
+		row := []driver.Value{int64(1), "abc", original, "user1", "", false, nil}
+		if strings.Contains(query, "expires_at, created_at") {
+			row = append(row, time.Now())
+		}
+		return [][]driver.Value{row}
+	}
+}
+
+func TestPostgresStorage_ServicePathsReadFromReplica(t *testing.T) {
+	primary := &fakeDB{answer: urlRows("https://primary.com", "abc", "fresh")}
+	replicaDB := &fakeDB{answer: func(query string, args []driver.Value) [][]driver.Value {
+		if strings.Contains(query, "COUNT(DISTINCT ip_hash)") {
+			return [][]driver.Value{{int64(3), int64(2)}}
+		}
+		return urlRows("https://replica.com", "abc")(query, args)
+	}}
+	pg := newRoutedStorage(t, primary, replicaDB, time.Minute)
+	// хранилище в том виде, в каком его получает сервис
+	retrying, err := NewRetryStorage(pg, RetryPolicy{}, PostgresRetryable)
+	require.NoError(t, err)
+	st := NewCachedStorage(retrying, 10, time.Minute, time.Minute)
+	ctx := context.Background()
+	userCtx := context.WithValue(ctx, contextkeys.UserIDKey, "user1")
+
+	got, err := st.Get(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://replica.com", got)
+
+	urls, _, err := st.ListByUser(userCtx, "user1", models.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	assert.Equal(t, "https://replica.com", urls[0].OriginalURL)
+
+	_, err = st.URLHistory(userCtx, "abc")
+	require.NoError(t, err)
+
+	stats, err := st.ClickStats(userCtx, "abc", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), stats.Total)
+
+	assert.Zero(t, primary.count("SELECT"), "reads must not reach the primary")
+
+	got, err = st.Get(ctx, "fresh")
+	require.NoError(t, err)
+	assert.Equal(t, "https://primary.com", got, "key missing on the replica must be looked up on the primary")
+}
+
+func TestPostgresStorage_ReadYourWritesThroughService(t *testing.T) {
+	primary := &fakeDB{answer: urlRows("https://primary.com", "abc")}
+	replicaDB := &fakeDB{answer: urlRows("https://replica.com", "abc")}
+	pg := newRoutedStorage(t, primary, replicaDB, time.Minute)
+	retrying, err := NewRetryStorage(pg, RetryPolicy{}, PostgresRetryable)
+	require.NoError(t, err)
+	st := NewCachedStorage(retrying, 10, time.Minute, time.Minute)
+	ctx := context.Background()
+
+	_, err = st.Create(ctx, ShortenerURL{ShortURL: "new", OriginalURL: "https://new.com", UserID: "user1"})
+	require.NoError(t, err)
+
+	urls, _, err := st.ListByUser(ctx, "user1", models.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	assert.Equal(t, "https://primary.com", urls[0].OriginalURL, "writer must read from the primary")
+
+	urls, _, err = st.ListByUser(ctx, "user2", models.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	assert.Equal(t, "https://replica.com", urls[0].OriginalURL)
+}
+
+// TestPostgresStorage_Replicas проверяет маршрутизацию чтения на двух независимых серверах PostgreSQL,
+// заданных переменными TEST_DATABASE_DSN и TEST_DATABASE_REPLICA_DSN. Второй сервер не реплицируется,
+// поэтому по его данным видно, куда ушёл запрос.
+func TestPostgresStorage_Replicas(t *testing.T) {
+	primaryDSN, replicaDSN := os.Getenv("TEST_DATABASE_DSN"), os.Getenv("TEST_DATABASE_REPLICA_DSN")
+	if primaryDSN == "" || replicaDSN == "" {
+		t.Skip("TEST_DATABASE_DSN and TEST_DATABASE_REPLICA_DSN are not set")
+	}
+	ctx := context.Background()
+	userID := "replica-test-" + time.Now().Format("150405.000000000")
+
+	// схема на второй сервер накатывается отдельно, ссылка существует только на нём
+	onReplica, err := NewPostgresStorage(ctx, replicaDSN)
+	require.NoError(t, err)
+	defer onReplica.Close()
+	_, err = onReplica.Create(ctx, ShortenerURL{ShortURL: userID + "-r", OriginalURL: "https://replica.com/" + userID, UserID: userID})
+	require.NoError(t, err)
+
+	s, err := NewPostgresStorage(ctx, primaryDSN, WithReplicas(replicaDSN), WithReadYourWrites(time.Minute))
+	require.NoError(t, err)
+	defer s.Close()
+
+	userCtx := context.WithValue(ctx, contextkeys.UserIDKey, userID)
+	userCtx = context.WithValue(userCtx, contextkeys.HostKey, "http://localhost")
+
+	got, err := s.Get(userCtx, userID+"-r")
+	require.NoError(t, err)
+	assert.Equal(t, "https://replica.com/"+userID, got, "read must be served by the replica")
+
+	_, err = s.Create(userCtx, ShortenerURL{ShortURL: userID + "-p", OriginalURL: "https://primary.com/" + userID, UserID: userID})
+	require.NoError(t, err)
+
+	urls, err := s.GetByUser(userCtx, userID)
+	require.NoError(t, err)
+	require.Len(t, urls, 1)
+	assert.Equal(t, "https://primary.com/"+userID, urls[0].OriginalURL, "reads after a write must be pinned to the primary")
+
+	got, err = s.Get(ctx, userID+"-p")
+	require.NoError(t, err)
+	assert.Equal(t, "https://primary.com/"+userID, got, "key missing on the replica must be looked up on the primary")
+}
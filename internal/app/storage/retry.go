@@ -0,0 +1,314 @@
+package storage
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/issafronov/shortener/internal/app/models"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx"
+)
+
+var _ Storage = (*RetryStorage)(nil)
+
+// defaultMaxBackoff ограничивает задержку между повторами, если RetryPolicy.MaxBackoff не задан
+const defaultMaxBackoff = time.Minute
+
+// RetryPolicy задаёт повтор операций хранилища при временных ошибках
+type RetryPolicy struct {
+	// Attempts — число повторов после первой неудачной попытки, 0 отключает повторы
+	Attempts int
+	// Backoff — задержка перед первым повтором, каждая следующая вдвое больше
+	Backoff time.Duration
+	// MaxBackoff ограничивает задержку между повторами, 0 — defaultMaxBackoff
+	MaxBackoff time.Duration
+}
+
+// RetryClassifier определяет, можно ли повторить операцию после ошибки err.
+// Для операций, изменяющих данные, write равен true: их можно повторять,
+// только если ошибка гарантирует, что изменение не было применено.
+type RetryClassifier func(err error, write bool) bool
+
+// RetryStorage повторяет операции хранилища, завершившиеся временной ошибкой,
+// с экспоненциально растущей задержкой. Чтение и идемпотентные операции повторяются
+// при любой временной ошибке. Запись повторяется, только если она заведомо не применена:
+// при обрыве соединения после COMMIT повтор создал бы ссылку или переход второй раз.
+type RetryStorage struct {
+	next      Storage
+	policy    RetryPolicy
+	retryable RetryClassifier
+	sleep     func(context.Context, time.Duration) error
+}
+
+// NewRetryStorage возвращает хранилище, повторяющее операции по policy.
+// retryable определяет, после каких ошибок операцию можно повторить.
+func NewRetryStorage(next Storage, policy RetryPolicy, retryable RetryClassifier) (*RetryStorage, error) {
+	if policy.Attempts < 0 || policy.Backoff < 0 || policy.MaxBackoff < 0 {
+		return nil, fmt.Errorf("invalid retry policy: attempts %d, backoff %s, max backoff %s",
+			policy.Attempts, policy.Backoff, policy.MaxBackoff)
+	}
+	if policy.MaxBackoff == 0 {
+		policy.MaxBackoff = defaultMaxBackoff
+	}
+	policy.Backoff = min(policy.Backoff, policy.MaxBackoff)
+	return &RetryStorage{next: next, policy: policy, retryable: retryable, sleep: sleepContext}, nil
+}
+
+// Unwrap возвращает исходное хранилище
+func (s *RetryStorage) Unwrap() Storage {
+	return s.next
+}
+
+// Close закрывает исходное хранилище, если оно это поддерживает
+func (s *RetryStorage) Close() error {
+	if closer, ok := s.next.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// do выполняет op, повторяя её при временных ошибках, пока не исчерпаны попытки или не отменён ctx.
+// write отмечает операции, изменяющие данные.
+func (s *RetryStorage) do(ctx context.Context, write bool, op func() error) error {
+	backoff := s.policy.Backoff
+	for attempt := 0; ; attempt++ {
+		err := op()
+		if err == nil || attempt >= s.policy.Attempts || !s.retryable(err, write) {
+			return err
+		}
+		// равномерный разброс задержки, чтобы повторы разных запросов не совпадали
+		delay := backoff/2 + rand.N(backoff/2+1)
+		if s.sleep(ctx, delay) != nil {
+			return err
+		}
+		// удвоение не переполняется: backoff не превышает MaxBackoff
+		backoff = min(2*backoff, s.policy.MaxBackoff)
+	}
+}
+
+// sleepContext ждёт d или отмены ctx
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// PostgresRetryable — RetryClassifier для PostgreSQL: чтение повторяется при временных ошибках,
+// запись — только при ошибках, после которых она заведомо не применена
+func PostgresRetryable(err error, write bool) bool {
+	if write {
+		return IsUnappliedPostgresError(err)
+	}
+	return IsTransientPostgresError(err)
+}
+
+// IsUnappliedPostgresError сообщает, что операция PostgreSQL завершилась временной ошибкой
+// и гарантированно не изменила данные: сервер откатил транзакцию из-за конфликта сериализации
+// или взаимоблокировки, соединение не было установлено, либо database/sql вернул driver.ErrBadConn,
+// который драйвер возвращает, только если запрос не был отправлен.
+func IsUnappliedPostgresError(err error) bool {
+	var pgErr pgx.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pgerrcode.SerializationFailure, pgerrcode.DeadlockDetected,
+			pgerrcode.TooManyConnections, pgerrcode.CannotConnectNow,
+			pgerrcode.SQLClientUnableToEstablishSQLConnection, pgerrcode.SQLServerRejectedEstablishmentOfSQLConnection:
+			return true
+		}
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// IsTransientPostgresError сообщает, имеет ли смысл повторить операцию PostgreSQL:
+// конфликт сериализации, взаимоблокировка, обрыв или нехватка соединений, перезапуск сервера.
+// Истечение statement_timeout временной ошибкой не считается.
+func IsTransientPostgresError(err error) bool {
+	var pgErr pgx.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pgerrcode.SerializationFailure, pgerrcode.DeadlockDetected,
+			pgerrcode.TooManyConnections, pgerrcode.AdminShutdown, pgerrcode.CannotConnectNow:
+			return true
+		}
+		return pgerrcode.IsConnectionException(pgErr.Code)
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && !netErr.Timeout()
+}
+
+// Create сохраняет ссылку
+func (s *RetryStorage) Create(ctx context.Context, url ShortenerURL) (key string, err error) {
+	err = s.do(ctx, true, func() error {
+		key, err = s.next.Create(ctx, url)
+		return err
+	})
+	return key, err
+}
+
+// CreateBatch атомарно сохраняет пакет записей
+func (s *RetryStorage) CreateBatch(ctx context.Context, urls []ShortenerURL) error {
+	return s.do(ctx, true, func() error {
+		return s.next.CreateBatch(ctx, urls)
+	})
+}
+
+// Get возвращает оригинальный URL по короткому ключу
+func (s *RetryStorage) Get(ctx context.Context, url string) (originalURL string, err error) {
+	err = s.do(ctx, false, func() error {
+		originalURL, err = s.next.Get(ctx, url)
+		return err
+	})
+	return originalURL, err
+}
+
+// GetByUser возвращает ссылки пользователя
+func (s *RetryStorage) GetByUser(ctx context.Context, username string) (urls []models.ShortURLResponse, err error) {
+	err = s.do(ctx, false, func() error {
+		urls, err = s.next.GetByUser(ctx, username)
+		return err
+	})
+	return urls, err
+}
+
+// Ping проверяет доступность хранилища без повторов, чтобы сразу сообщать о сбое
+func (s *RetryStorage) Ping(ctx context.Context) error {
+	return s.next.Ping(ctx)
+}
+
+// DeleteURLs помечает ссылки пользователя удалёнными. Повторная пометка ничего не меняет,
+// поэтому операция повторяется как чтение.
+func (s *RetryStorage) DeleteURLs(ctx context.Context, userID string, urls []string) error {
+	return s.do(ctx, false, func() error {
+		return s.next.DeleteURLs(ctx, userID, urls)
+	})
+}
+
+// DeleteURLsBatch помечает удалёнными ссылки нескольких пользователей
+func (s *RetryStorage) DeleteURLsBatch(ctx context.Context, reqs []models.DeleteRequest) (results []models.DeleteOutcomes, err error) {
+	err = s.do(ctx, true, func() error {
+		results, err = s.next.DeleteURLsBatch(ctx, reqs)
+		return err
+	})
+	return results, err
+}
+
+// CountURLs возвращает число ссылок
+func (s *RetryStorage) CountURLs(ctx context.Context) (count int64, err error) {
+	err = s.do(ctx, false, func() error {
+		count, err = s.next.CountURLs(ctx)
+		return err
+	})
+	return count, err
+}
+
+// CountUsers возвращает число пользователей
+func (s *RetryStorage) CountUsers(ctx context.Context) (count int64, err error) {
+	err = s.do(ctx, false, func() error {
+		count, err = s.next.CountUsers(ctx)
+		return err
+	})
+	return count, err
+}
+
+// Scan возвращает страницу записей для экспорта
+func (s *RetryStorage) Scan(ctx context.Context, cursor string, limit int) (urls []ShortenerURL, next string, err error) {
+	err = s.do(ctx, false, func() error {
+		urls, next, err = s.next.Scan(ctx, cursor, limit)
+		return err
+	})
+	return urls, next, err
+}
+
+// Import сохраняет записи как есть
+func (s *RetryStorage) Import(ctx context.Context, urls []ShortenerURL) (imported int, err error) {
+	err = s.do(ctx, true, func() error {
+		imported, err = s.next.Import(ctx, urls)
+		return err
+	})
+	return imported, err
+}
+
+// PurgeExpired помечает удалёнными ссылки с истёкшим сроком действия
+func (s *RetryStorage) PurgeExpired(ctx context.Context, now time.Time) (purged int64, err error) {
+	err = s.do(ctx, true, func() error {
+		purged, err = s.next.PurgeExpired(ctx, now)
+		return err
+	})
+	return purged, err
+}
+
+// ListByUser возвращает страницу ссылок пользователя
+func (s *RetryStorage) ListByUser(ctx context.Context, userID string, opts models.ListOptions) (urls []ShortenerURL, next string, err error) {
+	err = s.do(ctx, false, func() error {
+		urls, next, err = s.next.ListByUser(ctx, userID, opts)
+		return err
+	})
+	return urls, next, err
+}
+
+// UpdateURL меняет оригинальный URL ссылки
+func (s *RetryStorage) UpdateURL(ctx context.Context, userID, key, originalURL string) (version models.URLVersion, err error) {
+	err = s.do(ctx, true, func() error {
+		version, err = s.next.UpdateURL(ctx, userID, key, originalURL)
+		return err
+	})
+	return version, err
+}
+
+// URLHistory возвращает историю изменений ссылки
+func (s *RetryStorage) URLHistory(ctx context.Context, key string) (history []models.URLVersion, err error) {
+	err = s.do(ctx, false, func() error {
+		history, err = s.next.URLHistory(ctx, key)
+		return err
+	})
+	return history, err
+}
+
+// GetURL возвращает запись по короткому ключу
+func (s *RetryStorage) GetURL(ctx context.Context, key string) (url ShortenerURL, err error) {
+	err = s.do(ctx, false, func() error {
+		url, err = s.next.GetURL(ctx, key)
+		return err
+	})
+	return url, err
+}
+
+// SaveClicks сохраняет пачку переходов
+func (s *RetryStorage) SaveClicks(ctx context.Context, clicks []models.Click) error {
+	return s.do(ctx, true, func() error {
+		return s.next.SaveClicks(ctx, clicks)
+	})
+}
+
+// ClickStats возвращает статистику переходов по ссылке
+func (s *RetryStorage) ClickStats(ctx context.Context, key string, bucket time.Duration) (stats models.LinkStats, err error) {
+	err = s.do(ctx, false, func() error {
+		stats, err = s.next.ClickStats(ctx, key, bucket)
+		return err
+	})
+	return stats, err
+}
@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyStorage возвращает ошибку failures первых вызовов Get и Create
+type flakyStorage struct {
+	*MemoryStorage
+	failures int
+	err      error
+	calls    int
+}
+
+func (s *flakyStorage) Get(ctx context.Context, url string) (string, error) {
+	s.calls++
+	if s.calls <= s.failures {
+		return "", s.err
+	}
+	return s.MemoryStorage.Get(ctx, url)
+}
+
+func (s *flakyStorage) Create(ctx context.Context, url ShortenerURL) (string, error) {
+	s.calls++
+	if s.calls <= s.failures {
+		return "", s.err
+	}
+	return s.MemoryStorage.Create(ctx, url)
+}
+
+func newFlakyRetryStorage(t *testing.T, failures int, err error) (*RetryStorage, *flakyStorage, *[]time.Duration) {
+	next := &flakyStorage{MemoryStorage: NewMemoryStorage(), failures: failures, err: err}
+	_, createErr := next.MemoryStorage.Create(context.Background(), ShortenerURL{ShortURL: "abc", OriginalURL: "https://a.com"})
+	require.NoError(t, createErr)
+
+	var delays []time.Duration
+	s, err := NewRetryStorage(next, RetryPolicy{Attempts: 3, Backoff: 100 * time.Millisecond, MaxBackoff: 150 * time.Millisecond}, PostgresRetryable)
+	require.NoError(t, err)
+	s.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	return s, next, &delays
+}
+
+func TestRetryStorage_RetriesTransientErrors(t *testing.T) {
+	s, next, delays := newFlakyRetryStorage(t, 3, pgx.PgError{Code: pgerrcode.SerializationFailure})
+
+	got, err := s.Get(context.Background(), "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://a.com", got)
+	assert.Equal(t, 4, next.calls)
+
+	// задержка случайна в пределах от половины до полного значения, которое удваивается до MaxBackoff
+	require.Len(t, *delays, 3)
+	for i, backoff := range []time.Duration{100 * time.Millisecond, 150 * time.Millisecond, 150 * time.Millisecond} {
+		assert.GreaterOrEqual(t, (*delays)[i], backoff/2)
+		assert.LessOrEqual(t, (*delays)[i], backoff)
+	}
+}
+
+func TestRetryStorage_GivesUp(t *testing.T) {
+	s, next, _ := newFlakyRetryStorage(t, 10, driver.ErrBadConn)
+
+	_, err := s.Get(context.Background(), "abc")
+	assert.ErrorIs(t, err, driver.ErrBadConn)
+	assert.Equal(t, 4, next.calls)
+}
+
+func TestRetryStorage_DoesNotRetryPermanentErrors(t *testing.T) {
+	s, next, _ := newFlakyRetryStorage(t, 10, ErrNotFound)
+
+	_, err := s.Get(context.Background(), "abc")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, 1, next.calls)
+}
+
+func TestRetryStorage_RetriesWritesOnlyIfUnapplied(t *testing.T) {
+	s, next, _ := newFlakyRetryStorage(t, 1, syscall.ECONNRESET)
+	_, err := s.Create(context.Background(), ShortenerURL{ShortURL: "new", OriginalURL: "https://new.com"})
+	assert.ErrorIs(t, err, syscall.ECONNRESET, "connection may have dropped after COMMIT")
+	assert.Equal(t, 1, next.calls)
+
+	s, next, _ = newFlakyRetryStorage(t, 1, driver.ErrBadConn)
+	key, err := s.Create(context.Background(), ShortenerURL{ShortURL: "new", OriginalURL: "https://new.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "new", key)
+	assert.Equal(t, 2, next.calls)
+
+	s, next, _ = newFlakyRetryStorage(t, 1, syscall.ECONNRESET)
+	_, err = s.Get(context.Background(), "abc")
+	require.NoError(t, err, "reads are retried on any transient error")
+	assert.Equal(t, 2, next.calls)
+}
+
+func TestNewRetryStorage_ValidatesPolicy(t *testing.T) {
+	for _, policy := range []RetryPolicy{
+		{Attempts: -1},
+		{Attempts: 3, Backoff: -time.Millisecond},
+		{Attempts: 3, Backoff: time.Millisecond, MaxBackoff: -time.Second},
+	} {
+		_, err := NewRetryStorage(NewMemoryStorage(), policy, PostgresRetryable)
+		assert.Error(t, err, policy)
+	}
+
+	// нулевая задержка и большое число повторов не приводят к панике и переполнению
+	next := &flakyStorage{MemoryStorage: NewMemoryStorage(), failures: 100, err: driver.ErrBadConn}
+	s, err := NewRetryStorage(next, RetryPolicy{Attempts: 100}, PostgresRetryable)
+	require.NoError(t, err)
+	s.sleep = func(context.Context, time.Duration) error { return nil }
+	_, err = s.Get(context.Background(), "abc")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	s, err = NewRetryStorage(next, RetryPolicy{Attempts: 100, Backoff: time.Hour}, PostgresRetryable)
+	require.NoError(t, err)
+	var delays []time.Duration
+	s.sleep = func(_ context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	next.calls = 0
+	_, _ = s.Get(context.Background(), "abc")
+	require.NotEmpty(t, delays)
+	for _, d := range delays {
+		assert.LessOrEqual(t, d, defaultMaxBackoff)
+		assert.Positive(t, d)
+	}
+}
+
+func TestIsUnappliedPostgresError(t *testing.T) {
+	tests := []struct {
+		err       error
+		unapplied bool
+	}{
+		{pgx.PgError{Code: pgerrcode.SerializationFailure}, true},
+		{pgx.PgError{Code: pgerrcode.CannotConnectNow}, true},
+		{fmt.Errorf("query: %w", driver.ErrBadConn), true},
+		{&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, true},
+		{pgx.PgError{Code: pgerrcode.ConnectionFailure}, false},
+		{pgx.PgError{Code: pgerrcode.AdminShutdown}, false},
+		{&net.OpError{Op: "read", Err: syscall.ECONNRESET}, false},
+		{io.ErrUnexpectedEOF, false},
+		{pgx.PgError{Code: pgerrcode.UniqueViolation}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			assert.Equal(t, tt.unapplied, IsUnappliedPostgresError(tt.err))
+		})
+	}
+}
+
+func TestIsTransientPostgresError(t *testing.T) {
+	tests := []struct {
+		err       error
+		transient bool
+	}{
+		{pgx.PgError{Code: pgerrcode.SerializationFailure}, true},
+		{pgx.PgError{Code: pgerrcode.DeadlockDetected}, true},
+		{pgx.PgError{Code: pgerrcode.ConnectionFailure}, true},
+		{pgx.PgError{Code: pgerrcode.AdminShutdown}, true},
+		{fmt.Errorf("query: %w", driver.ErrBadConn), true},
+		{pgx.PgError{Code: pgerrcode.UniqueViolation}, false},
+		{pgx.PgError{Code: pgerrcode.QueryCanceled}, false},
+		{context.DeadlineExceeded, false},
+		{ErrNotFound, false},
+		{errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			assert.Equal(t, tt.transient, IsTransientPostgresError(tt.err))
+		})
+	}
+}
@@ -1,33 +1,86 @@
 package storage
 
 import (
-	"bufio"
 	"context"
 	"database/sql"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
+	"strconv"
+	"sync"
+	"time"
 
-	"github.com/issafronov/shortener/internal/app/config"
 	"github.com/issafronov/shortener/internal/app/contextkeys"
 	"github.com/issafronov/shortener/internal/app/models"
 	"github.com/issafronov/shortener/internal/middleware/logger"
 	"github.com/issafronov/shortener/internal/scripts"
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx"
+	"github.com/jackc/pgx/pgtype"
+	"github.com/jackc/pgx/stdlib"
 	"go.uber.org/zap"
 )
 
-// Urls хранит все сокращённые URL-адреса в памяти при использовании файлового хранилища.
-var Urls = make(map[string]ShortenerURL)
-
-// UsersUrls сопоставляет пользователя с его URL-ами в памяти при использовании файлового хранилища.
-var UsersUrls = make(map[string][]string)
-
 // ErrConflict возвращается, если URL уже существует в базе.
 var ErrConflict = errors.New("conflict")
 
+// ErrKeyExists возвращается, если короткий ключ уже занят другой ссылкой.
+var ErrKeyExists = errors.New("short key already exists")
+
+// ErrNotFound возвращается, если сокращённая ссылка не найдена.
+var ErrNotFound = errors.New("url not found")
+
+// ErrDeleted возвращается, если сокращённая ссылка была удалена.
+var ErrDeleted = errors.New("url gone")
+
+// ErrInvalidCursor возвращается, если курсор постраничного чтения не удалось разобрать.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrExpired возвращается, если срок действия сокращённой ссылки истёк.
+var ErrExpired = errors.New("url expired")
+
+// ErrConcurrentChange возвращается, если ссылка, с которой конфликтует новая запись,
+// повторно удаляется одновременно с сохранением; операцию можно повторить.
+var ErrConcurrentChange = errors.New("conflicting url changed concurrently")
+
+// BatchError сообщает, какую запись пакета не удалось сохранить и почему
+type BatchError struct {
+	// Index — номер записи в пакете
+	Index int
+	// Existing — короткий ключ существующей ссылки с тем же оригинальным URL при ErrConflict
+	Existing string
+	// Err — причина: ErrConflict или ErrKeyExists
+	Err error
+}
+
+// Error возвращает текст ошибки
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch item %d: %v", e.Index, e.Err)
+}
+
+// Unwrap позволяет сопоставлять ошибку с её причиной
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// IsExpectedError сообщает, описывает ли ошибка штатный результат операции:
+// конфликт, отсутствующую, удалённую или просроченную ссылку, неверный курсор, гонку с удалением.
+// Такие ошибки не считаются сбоями хранилища.
+func IsExpectedError(err error) bool {
+	for _, target := range []error{ErrConflict, ErrKeyExists, ErrNotFound, ErrDeleted, ErrExpired, ErrInvalidCursor, ErrConcurrentChange} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// shortURLConstraint — имя ограничения уникальности короткого ключа в таблице urls
+const shortURLConstraint = "urls_short_url_key"
+
+// dedupAttempts — сколько раз сохранение повторяется, если конфликтующая по оригинальному URL
+// ссылка удалена между неудачной вставкой и поиском её ключа
+const dedupAttempts = 2
+
 // ShortenerURL - объект сокращённой ссылки.
 type ShortenerURL struct {
 	UUID          int    `json:"uuid"`
@@ -36,196 +89,496 @@ type ShortenerURL struct {
 	OriginalURL   string `json:"original_url"`
 	UserID        string `json:"user_id"`
 	IsDeleted     bool   `json:"is_deleted"`
+	// ExpiresAt — момент истечения срока действия ссылки, nil для бессрочных ссылок
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// CreatedAt — момент создания ссылки; пуст у записей, сохранённых до его появления
+	CreatedAt time.Time `json:"created_at"`
+	// DeletedAt — момент удаления ссылки, nil для живых ссылок и записей, удалённых до его появления
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Expired сообщает, истёк ли срок действия ссылки к моменту now
+func (u ShortenerURL) Expired(now time.Time) bool {
+	return u.ExpiresAt != nil && !u.ExpiresAt.After(now)
+}
+
+// stampCreated задаёт ссылке момент создания now, если он ещё не задан.
+// Время хранится с точностью PostgreSQL, чтобы курсоры списков совпадали во всех хранилищах.
+func stampCreated(url ShortenerURL, now time.Time) ShortenerURL {
+	if url.CreatedAt.IsZero() {
+		url.CreatedAt = now.UTC().Truncate(time.Microsecond)
+	}
+	return url
+}
+
+// markDeleted помечает ссылку удалённой; момент удаления сохраняется при первом удалении
+func markDeleted(url ShortenerURL, now time.Time) ShortenerURL {
+	url.IsDeleted = true
+	if url.DeletedAt == nil {
+		deletedAt := now.UTC().Truncate(time.Microsecond)
+		url.DeletedAt = &deletedAt
+	}
+	return url
 }
 
 // Storage описывает интерфейс хранилища URL-ов
 type Storage interface {
 	Create(ctx context.Context, url ShortenerURL) (string, error)
+	// CreateBatch сохраняет пакет новых записей атомарно: либо все, либо ни одной.
+	// Если запись не удалось сохранить, возвращает *BatchError с её номером и причиной:
+	// ErrConflict с ключом существующей ссылки или ErrKeyExists.
+	CreateBatch(ctx context.Context, urls []ShortenerURL) error
 	Get(ctx context.Context, url string) (string, error)
 	GetByUser(ctx context.Context, username string) ([]models.ShortURLResponse, error)
 	Ping(ctx context.Context) error
 	DeleteURLs(ctx context.Context, userID string, urls []string) error
+	// DeleteURLsBatch помечает удалёнными ссылки нескольких пользователей за одну операцию.
+	// Ссылки, не принадлежащие пользователю из запроса, пропускаются.
+	// Возвращает результаты по каждому ключу в порядке запросов.
+	DeleteURLsBatch(ctx context.Context, reqs []models.DeleteRequest) ([]models.DeleteOutcomes, error)
 	CountURLs(ctx context.Context) (int64, error)
 	CountUsers(ctx context.Context) (int64, error)
+	// Scan возвращает до limit записей, следующих за курсором, в стабильном порядке
+	// и курсор для продолжения. Пустой курсор означает начало, пустой следующий курсор — конец данных.
+	Scan(ctx context.Context, cursor string, limit int) ([]ShortenerURL, string, error)
+	// Import сохраняет записи как есть, включая владельца, correlation_id и признак удаления.
+	// Записи, чей короткий ключ уже существует, пропускаются. Возвращает число сохранённых записей.
+	Import(ctx context.Context, urls []ShortenerURL) (int, error)
+	// PurgeExpired помечает удалёнными ссылки, срок действия которых истёк к моменту now.
+	// Возвращает число помеченных ссылок.
+	PurgeExpired(ctx context.Context, now time.Time) (int64, error)
+	// ListByUser возвращает страницу ссылок пользователя с учётом фильтров и порядка
+	// и курсор следующей страницы; пустой курсор означает, что страниц больше нет.
+	ListByUser(ctx context.Context, userID string, opts models.ListOptions) ([]ShortenerURL, string, error)
+	// UpdateURL меняет оригинальный URL ссылки пользователя и добавляет запись в историю.
+	// Возвращает ErrNotFound, если ссылки нет или она принадлежит другому пользователю,
+	// и ErrDeleted для удалённой ссылки.
+	UpdateURL(ctx context.Context, userID, key, originalURL string) (models.URLVersion, error)
+	// URLHistory возвращает историю изменений ссылки по возрастанию версии
+	URLHistory(ctx context.Context, key string) ([]models.URLVersion, error)
+	// GetURL возвращает запись по короткому ключу как есть, включая удалённые
+	GetURL(ctx context.Context, key string) (ShortenerURL, error)
+	// SaveClicks сохраняет пачку переходов по ссылкам
+	SaveClicks(ctx context.Context, clicks []models.Click) error
+	// ClickStats возвращает статистику переходов по ссылке с гистограммой по интервалам bucket
+	ClickStats(ctx context.Context, key string, bucket time.Duration) (models.LinkStats, error)
 }
 
-// FileStorage реализует интерфейс Storage с использованием файлового хранилища
-type FileStorage struct {
-	file   *os.File
-	writer *bufio.Writer
-	reader *bufio.Reader
+// KeyCounter реализуют хранилища, которые сохраняют счётчик генератора коротких ключей между перезапусками
+type KeyCounter interface {
+	// ReserveKeys атомарно резервирует n значений счётчика name, больших floor и всех ранее
+	// зарезервированных, и возвращает последнее из них. Если счётчика ещё нет, он начинается
+	// с общего числа ссылок, включая удалённые.
+	ReserveKeys(ctx context.Context, name string, floor, n uint64) (uint64, error)
 }
 
-// Ping проверяет доступность файлового хранилища
-func (f *FileStorage) Ping(ctx context.Context) error {
-	return nil
+// Find ищет среди обёрток хранилища первое, реализующее интерфейс T
+func Find[T any](st Storage) (T, bool) {
+	for st != nil {
+		if found, ok := st.(T); ok {
+			return found, true
+		}
+		wrapper, ok := st.(interface{ Unwrap() Storage })
+		if !ok {
+			break
+		}
+		st = wrapper.Unwrap()
+	}
+	var zero T
+	return zero, false
 }
 
-// Create сохраняет URL в файл
-func (f *FileStorage) Create(ctx context.Context, url ShortenerURL) (string, error) {
-	if _, exists := Urls[url.ShortURL]; exists {
-		return "", ErrConflict
-	}
+// PostgresStorage реализует интерфейс Storage с использованием базы PostgreSQL.
+// Если заданы реплики, чтение ссылок и статистики распределяется между ними, а запись идёт на основной сервер.
+type PostgresStorage struct {
+	db           *sql.DB
+	opts         options
+	driverConfig *stdlib.DriverConfig
+	reads        *readRouter
+	stopChecks   context.CancelFunc
+	checks       sync.WaitGroup
+}
 
-	url.UUID = len(Urls) + 1
+// NewPostgresStorage создаёт новое подключение к PostgreSQL, настраивает пул соединений и выполняет миграции.
+// Недоступные при запуске реплики не мешают работе и подключаются, когда начинают отвечать.
+func NewPostgresStorage(ctx context.Context, dsn string, opts ...Option) (*PostgresStorage, error) {
+	o := newOptions(opts)
 
-	Urls[url.ShortURL] = url
-	UsersUrls[url.UserID] = append(UsersUrls[url.UserID], url.ShortURL)
+	// statement_timeout передаётся параметром сессии каждого нового соединения
+	driverConfig := &stdlib.DriverConfig{}
+	if o.pool.StatementTimeout > 0 {
+		driverConfig.RuntimeParams = map[string]string{
+			"statement_timeout": strconv.FormatInt(o.pool.StatementTimeout.Milliseconds(), 10),
+		}
+	}
+	stdlib.RegisterDriverConfig(driverConfig)
 
-	data, err := json.Marshal(url)
+	db, err := openPool(driverConfig, dsn, o.pool)
 	if err != nil {
-		logger.Log.Info("Failed to marshal shortener URL", zap.Error(err))
-		return "", err
-	}
-	if _, err := f.writer.Write(data); err != nil {
-		logger.Log.Info("Failed to write URL", zap.String("url", url.ShortURL), zap.Error(err))
-		return "", err
-	}
-	if err := f.writer.WriteByte('\n'); err != nil {
-		logger.Log.Info("Error writing data new line", zap.Error(err))
-		return "", err
+		stdlib.UnregisterDriverConfig(driverConfig)
+		return nil, err
 	}
-	return url.ShortURL, f.writer.Flush()
-}
 
-// Get возвращает оригинальный URL по сокращённому
-func (f *FileStorage) Get(ctx context.Context, url string) (string, error) {
-	link, ok := Urls[url]
-	if !ok {
-		return "", errors.New("url not found")
+	s := &PostgresStorage{db: db, opts: o, driverConfig: driverConfig, stopChecks: func() {}}
+	replicas := make([]*replica, 0, len(o.replicas))
+	for _, replicaDSN := range o.replicas {
+		replicaDB, err := openPool(driverConfig, replicaDSN, o.pool)
+		if err != nil {
+			for _, rep := range replicas {
+				_ = rep.db.Close()
+			}
+			_ = s.Close()
+			return nil, fmt.Errorf("open replica: %w", err)
+		}
+		replicas = append(replicas, &replica{db: replicaDB})
 	}
-	return link.OriginalURL, nil
-}
+	s.reads = newReadRouter(replicas, o.readYourWrites)
 
-// GetByUser возвращает все URL-ы, сохранённые пользователем
-func (f *FileStorage) GetByUser(ctx context.Context, username string) ([]models.ShortURLResponse, error) {
-	var result []models.ShortURLResponse
-	for key, value := range UsersUrls {
-		if key == username {
-			result = append(result, models.ShortURLResponse{
-				ShortURL:    ctx.Value(contextkeys.HostKey).(string) + "/" + value[0],
-				OriginalURL: value[1],
-			})
+	if o.autoMigrate {
+		if err := scripts.RunMigrations(dsn); err != nil {
+			_ = s.Close()
+			return nil, err
 		}
 	}
-	return result, nil
-}
 
-// DeleteURLs помечает переданные ссылки как удалённые
-func (f *FileStorage) DeleteURLs(ctx context.Context, userID string, ids []string) error {
-	for _, id := range ids {
-		shortenerURL, exists := Urls[id]
-		if !exists {
-			continue
-		}
-		if shortenerURL.UserID == userID {
-			shortenerURL.IsDeleted = true
-			Urls[id] = shortenerURL
-		}
+	if err := db.PingContext(ctx); err != nil {
+		_ = s.Close()
+		return nil, err
 	}
-	return nil
-}
 
-// CountURLs возвращает количество всех сохранённых URL в хранилище.
-func (f *FileStorage) CountURLs(ctx context.Context) (int64, error) {
-	return int64(len(Urls)), nil
-}
+	if err := s.syncUniqueness(ctx); err != nil {
+		_ = s.Close()
+		return nil, err
+	}
 
-// CountUsers возвращает количество пользователей в хранилище.
-func (f *FileStorage) CountUsers(ctx context.Context) (int64, error) {
-	return int64(len(UsersUrls)), nil
+	if len(replicas) > 0 {
+		s.reads.check(ctx)
+		checksCtx, stop := context.WithCancel(context.WithoutCancel(ctx))
+		s.stopChecks = stop
+		s.checks.Add(1)
+		go func() {
+			defer s.checks.Done()
+			s.reads.run(checksCtx)
+		}()
+	}
+
+	return s, nil
 }
 
-// NewFileStorage создаёт экземпляр FileStorage с указанием пути до файла
-func NewFileStorage(config *config.Config) (*FileStorage, error) {
-	file, err := os.OpenFile(config.FileStoragePath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+// syncUniqueness пересчитывает области уникальности ссылок, если режим уникальности
+// изменился с прошлого запуска. Если в новом режиме оригинальные URL повторяются,
+// режим не меняется и возвращается ошибка.
+func (s *PostgresStorage) syncUniqueness(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return &FileStorage{
-		file:   file,
-		writer: bufio.NewWriter(file),
-		reader: bufio.NewReader(file),
-	}, nil
-}
+	defer tx.Rollback()
 
-// PostgresStorage реализует интерфейс Storage с использованием базы PostgreSQL
-type PostgresStorage struct {
-	db *sql.DB
-}
+	var current string
+	err = tx.QueryRowContext(ctx,
+		"SELECT value FROM storage_settings WHERE name = $1 FOR UPDATE", uniquenessSetting,
+	).Scan(&current)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if current == s.opts.uniqueness {
+		return nil
+	}
 
-// NewPostgresStorage создаёт новое подключение к PostgreSQL и выполняет миграции
-func NewPostgresStorage(ctx context.Context, dsn string) (*PostgresStorage, error) {
-	db, err := sql.Open("pgx", dsn)
+	_, err = tx.ExecContext(ctx, `
+		UPDATE urls SET dedup_scope = scope
+		FROM (
+			SELECT id, CASE $1 WHEN 'user' THEN user_id WHEN 'none' THEN $2 || short_url ELSE '' END AS scope
+			FROM urls
+		) AS computed
+		WHERE urls.id = computed.id AND urls.dedup_scope <> computed.scope`,
+		s.opts.uniqueness, noneScopePrefix)
 	if err != nil {
-		return nil, err
+		var pgErr pgx.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return errUniquenessChange(current, s.opts.uniqueness)
+		}
+		return err
 	}
 
-	if err := scripts.RunMigrations(dsn); err != nil {
-		return nil, err
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO storage_settings (name, value) VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET value = EXCLUDED.value`,
+		uniquenessSetting, s.opts.uniqueness)
+	if err != nil {
+		return err
 	}
+	return tx.Commit()
+}
 
-	if err := db.Ping(); err != nil {
+// openPool открывает пул соединений с параметрами pool
+func openPool(driverConfig *stdlib.DriverConfig, dsn string, pool PoolOptions) (*sql.DB, error) {
+	db, err := sql.Open("pgx", driverConfig.ConnectionString(dsn))
+	if err != nil {
 		return nil, err
 	}
+	db.SetMaxOpenConns(pool.MaxOpenConns)
+	if pool.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(pool.MaxIdleConns)
+	}
+	db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+	return db, nil
+}
 
-	return &PostgresStorage{db: db}, nil
+// Close останавливает проверку реплик и закрывает соединения с базами данных
+func (s *PostgresStorage) Close() error {
+	defer stdlib.UnregisterDriverConfig(s.driverConfig)
+
+	s.stopChecks()
+	s.checks.Wait()
+	if s.reads != nil {
+		for _, rep := range s.reads.replicas {
+			_ = rep.db.Close()
+		}
+	}
+	return s.db.Close()
 }
 
-// Ping проверяет соединение с базой данных
+// Ping проверяет соединение с основной базой данных
 func (s *PostgresStorage) Ping(ctx context.Context) error {
 	return s.db.PingContext(ctx)
 }
 
-// Create сохраняет новую запись в базу данных
+// read выполняет запрос чтения на реплике, выбранной для пользователя, или на основном сервере.
+// Если реплика недоступна, она исключается до следующей проверки, а запрос повторяется на основном сервере.
+func (s *PostgresStorage) read(userID string, query func(db *sql.DB) error) error {
+	rep := s.reads.pick(userID)
+	if rep == nil {
+		return query(s.db)
+	}
+	err := query(rep.db)
+	switch {
+	case err == nil:
+		return nil
+	case IsTransientPostgresError(err):
+		rep.healthy.Store(false)
+		return query(s.db)
+	case errors.Is(err, sql.ErrNoRows):
+		// запись могла ещё не доехать до реплики
+		return query(s.db)
+	}
+	return err
+}
+
+// PoolStats возвращает состояние пула соединений
+func (s *PostgresStorage) PoolStats() models.PoolStats {
+	stats := s.db.Stats()
+	return models.PoolStats{
+		MaxOpen:        stats.MaxOpenConnections,
+		Open:           stats.OpenConnections,
+		InUse:          stats.InUse,
+		Idle:           stats.Idle,
+		WaitCount:      stats.WaitCount,
+		WaitDurationMS: stats.WaitDuration.Milliseconds(),
+	}
+}
+
+// Create сохраняет новую запись в базу данных. Если оригинальный URL уже сокращён,
+// возвращает существующий короткий ключ и ErrConflict, если занят короткий ключ — ErrKeyExists.
+// Если конфликтующую ссылку удалили до того, как нашёлся её ключ, вставка повторяется.
 func (s *PostgresStorage) Create(ctx context.Context, url ShortenerURL) (string, error) {
 	query := `
 	INSERT INTO urls (
 	    short_url,
 	    original_url,
-		user_id
+		user_id,
+		correlation_id,
+		expires_at,
+		dedup_scope
 	    )
-	VALUES ($1, $2, $3)
+	VALUES ($1, $2, $3, $4, $5, $6)
 	`
-	_, err := s.db.ExecContext(ctx, query, url.ShortURL, url.OriginalURL, url.UserID)
+	scope := s.opts.scope(url.UserID, url.ShortURL)
+	for range dedupAttempts {
+		_, err := s.db.ExecContext(ctx, query, url.ShortURL, url.OriginalURL, url.UserID, url.CorrelationID, url.ExpiresAt, scope)
+		if err == nil {
+			s.reads.pin(url.UserID)
+			return url.ShortURL, nil
+		}
 
-	if err != nil {
 		var pgErr pgx.PgError
-		if errors.As(err, &pgErr) && pgerrcode.IsIntegrityConstraintViolation(pgErr.Code) {
-			var shortKey string
-			err = s.db.QueryRowContext(
-				ctx,
-				"SELECT short_url FROM urls WHERE original_url = $1",
-				url.OriginalURL,
-			).Scan(&shortKey)
-			if err != nil {
-				return "", err
-			}
-			return shortKey, ErrConflict
+		if !errors.As(err, &pgErr) || !pgerrcode.IsIntegrityConstraintViolation(pgErr.Code) {
+			return "", err
 		}
-		return "", err
+		if pgErr.ConstraintName == shortURLConstraint {
+			return "", ErrKeyExists
+		}
+		var shortKey string
+		err = s.db.QueryRowContext(
+			ctx,
+			"SELECT short_url FROM urls WHERE dedup_scope = $1 AND original_url = $2 AND is_deleted = FALSE",
+			scope, url.OriginalURL,
+		).Scan(&shortKey)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return s.opts.conflict(shortKey)
 	}
+	return "", ErrConcurrentChange
+}
 
-	return "", nil
+// CreateBatch сохраняет пакет одним многострочным INSERT в транзакции.
+// Если какая-то строка не вставлена из-за ограничения уникальности, транзакция откатывается,
+// а причина определяется по первой такой строке. Если конфликтующую ссылку удалили
+// до того, как нашёлся её ключ, пакет сохраняется заново.
+func (s *PostgresStorage) CreateBatch(ctx context.Context, urls []ShortenerURL) error {
+	if len(urls) == 0 {
+		return nil
+	}
+	var err error
+	for range dedupAttempts {
+		if err = s.createBatch(ctx, urls); !errors.Is(err, ErrConcurrentChange) {
+			return err
+		}
+	}
+	return err
+}
+
+// createBatch выполняет одну попытку сохранения пакета
+func (s *PostgresStorage) createBatch(ctx context.Context, urls []ShortenerURL) error {
+
+	columns := make([][]string, 5)
+	expiresAt := pgtype.TimestamptzArray{
+		Elements:   make([]pgtype.Timestamptz, len(urls)),
+		Dimensions: []pgtype.ArrayDimension{{Length: int32(len(urls)), LowerBound: 1}},
+		Status:     pgtype.Present,
+	}
+	for i, url := range urls {
+		columns[0] = append(columns[0], url.ShortURL)
+		columns[1] = append(columns[1], url.OriginalURL)
+		columns[2] = append(columns[2], url.UserID)
+		columns[3] = append(columns[3], url.CorrelationID)
+		columns[4] = append(columns[4], s.opts.scope(url.UserID, url.ShortURL))
+		expiresAt.Elements[i].Status = pgtype.Null
+		if url.ExpiresAt != nil {
+			expiresAt.Elements[i] = pgtype.Timestamptz{Time: *url.ExpiresAt, Status: pgtype.Present}
+		}
+	}
+	arrays := make([]pgtype.TextArray, len(columns))
+	for i, column := range columns {
+		if err := arrays[i].Set(column); err != nil {
+			return err
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		INSERT INTO urls (short_url, original_url, user_id, correlation_id, dedup_scope, expires_at)
+		SELECT * FROM unnest($1::text[], $2::text[], $3::text[], $4::text[], $5::text[], $6::timestamptz[])
+		ON CONFLICT DO NOTHING
+		RETURNING short_url`,
+		&arrays[0], &arrays[1], &arrays[2], &arrays[3], &arrays[4], &expiresAt)
+	if err != nil {
+		return err
+	}
+	inserted := make(map[string]bool, len(urls))
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		inserted[key] = true
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(inserted) < len(urls) {
+		return s.batchError(ctx, tx, urls, inserted)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for _, url := range urls {
+		s.reads.pin(url.UserID)
+	}
+	return nil
+}
+
+// batchError определяет, почему первая невставленная строка пакета нарушила ограничение уникальности
+func (s *PostgresStorage) batchError(ctx context.Context, tx *sql.Tx, urls []ShortenerURL, inserted map[string]bool) error {
+	claimed := make(map[string]bool, len(inserted))
+	for i, url := range urls {
+		if inserted[url.ShortURL] && !claimed[url.ShortURL] {
+			claimed[url.ShortURL] = true
+			continue
+		}
+		var existing string
+		err := tx.QueryRowContext(ctx,
+			"SELECT short_url FROM urls WHERE dedup_scope = $1 AND original_url = $2 AND is_deleted = FALSE",
+			s.opts.scope(url.UserID, url.ShortURL), url.OriginalURL,
+		).Scan(&existing)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return s.keyError(ctx, tx, i, url.ShortURL)
+		case err != nil:
+			return err
+		case inserted[existing]:
+			// оригинальный URL повторяется внутри пакета
+			_, err := s.opts.conflict("")
+			return &BatchError{Index: i, Err: err}
+		default:
+			existing, err := s.opts.conflict(existing)
+			return &BatchError{Index: i, Existing: existing, Err: err}
+		}
+	}
+	return &BatchError{Index: len(urls) - 1, Err: ErrKeyExists}
+}
+
+// keyError определяет причину отказа для строки пакета, у которой не нашлось живой ссылки
+// с тем же оригинальным URL: занятый ключ или удаление конфликтующей ссылки после вставки
+func (s *PostgresStorage) keyError(ctx context.Context, tx *sql.Tx, index int, key string) error {
+	var taken bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM urls WHERE short_url = $1)", key).Scan(&taken); err != nil {
+		return err
+	}
+	if taken {
+		return &BatchError{Index: index, Err: ErrKeyExists}
+	}
+	return &BatchError{Index: index, Err: ErrConcurrentChange}
 }
 
 // Get возвращает оригинальный URL по сокращённому из базы данных
 func (s *PostgresStorage) Get(ctx context.Context, url string) (string, error) {
 	var originalURL string
 	var isDeleted bool
-	err := s.db.QueryRowContext(
-		ctx,
-		"SELECT original_url, is_deleted FROM urls WHERE short_url = $1",
-		url,
-	).Scan(&originalURL, &isDeleted)
+	var expiresAt sql.NullTime
+	userID, _ := ctx.Value(contextkeys.UserIDKey).(string)
+	err := s.read(userID, func(db *sql.DB) error {
+		return db.QueryRowContext(
+			ctx,
+			"SELECT original_url, is_deleted, expires_at FROM urls WHERE short_url = $1",
+			url,
+		).Scan(&originalURL, &isDeleted, &expiresAt)
+	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return "", errors.New("url not found")
+			return "", ErrNotFound
 		}
 		return "", err
 	}
 	if isDeleted {
-		return "", errors.New("url gone")
+		return "", ErrDeleted
+	}
+	if expiresAt.Valid && !expiresAt.Time.After(time.Now()) {
+		return "", ErrExpired
 	}
 	return originalURL, nil
 }
@@ -233,22 +586,26 @@ func (s *PostgresStorage) Get(ctx context.Context, url string) (string, error) {
 // GetByUser возвращает сокращенные ссылки для пользователя
 func (s *PostgresStorage) GetByUser(ctx context.Context, username string) ([]models.ShortURLResponse, error) {
 	var result []models.ShortURLResponse
-	rows, err := s.db.Query("SELECT short_url, original_url FROM urls WHERE user_id = $1", username)
-	if err != nil {
-		logger.Log.Info("Failed to get shortener URLs", zap.String("username", username), zap.Error(err))
-		return nil, err
-	}
-	defer rows.Close()
-	for rows.Next() {
-		var shortURL string
-		var originalURL string
-		if err := rows.Scan(&shortURL, &originalURL); err != nil {
-			return nil, err
+	host, _ := ctx.Value(contextkeys.HostKey).(string)
+	err := s.read(username, func(db *sql.DB) error {
+		result = nil
+		rows, err := db.QueryContext(ctx, "SELECT short_url, original_url FROM urls WHERE user_id = $1", username)
+		if err != nil {
+			return err
 		}
-		fmt.Println(ctx.Value(contextkeys.HostKey).(string), shortURL)
-		result = append(result, models.ShortURLResponse{ShortURL: ctx.Value(contextkeys.HostKey).(string) + "/" + shortURL, OriginalURL: originalURL})
-	}
-	if err := rows.Err(); err != nil {
+		defer rows.Close()
+		for rows.Next() {
+			var shortURL string
+			var originalURL string
+			if err := rows.Scan(&shortURL, &originalURL); err != nil {
+				return err
+			}
+			result = append(result, models.ShortURLResponse{ShortURL: host + "/" + shortURL, OriginalURL: originalURL})
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		logger.FromContext(ctx).Info("Failed to get shortener URLs", zap.String("username", username), zap.Error(err))
 		return nil, err
 	}
 	return result, nil
@@ -262,7 +619,7 @@ func (s *PostgresStorage) DeleteURLs(ctx context.Context, userID string, urls []
 	}
 	defer tx.Rollback()
 
-	stmt, err := tx.PrepareContext(ctx, `UPDATE urls SET is_deleted = TRUE WHERE short_url = $1 AND user_id = $2`)
+	stmt, err := tx.PrepareContext(ctx, `UPDATE urls SET is_deleted = TRUE, deleted_at = COALESCE(deleted_at, now()), updated_at = now() WHERE short_url = $1 AND user_id = $2`)
 	if err != nil {
 		return err
 	}
@@ -274,25 +631,524 @@ func (s *PostgresStorage) DeleteURLs(ctx context.Context, userID string, urls []
 		}
 	}
 
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.reads.pin(userID)
+	return nil
+}
+
+// DeleteURLsBatch помечает ссылки удалёнными в одной транзакции,
+// выполняя по одному UPDATE на каждого пользователя из пачки.
+// Ключи, которые не удалось удалить, проверяются отдельным запросом, чтобы отличить чужие ссылки от несуществующих.
+func (s *PostgresStorage) DeleteURLsBatch(ctx context.Context, reqs []models.DeleteRequest) ([]models.DeleteOutcomes, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	update, err := tx.PrepareContext(ctx, `UPDATE urls SET is_deleted = TRUE, deleted_at = COALESCE(deleted_at, now()), updated_at = now() WHERE user_id = $1 AND short_url = ANY($2) RETURNING short_url`)
+	if err != nil {
+		return nil, err
+	}
+	defer update.Close()
+
+	exists, err := tx.PrepareContext(ctx, `SELECT short_url FROM urls WHERE short_url = ANY($1)`)
+	if err != nil {
+		return nil, err
+	}
+	defer exists.Close()
+
+	results := make([]models.DeleteOutcomes, 0, len(reqs))
+	for _, req := range reqs {
+		outcomes := make(models.DeleteOutcomes, len(req.ShortURLs))
+		for _, key := range req.ShortURLs {
+			outcomes[key] = models.DeleteOutcomeNotFound
+		}
+
+		var keys pgtype.TextArray
+		if err := keys.Set(req.ShortURLs); err != nil {
+			return nil, err
+		}
+		deleted, err := queryKeys(ctx, update, req.UserID, &keys)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range deleted {
+			outcomes[key] = models.DeleteOutcomeDeleted
+		}
+
+		if len(deleted) < len(outcomes) {
+			found, err := queryKeys(ctx, exists, &keys)
+			if err != nil {
+				return nil, err
+			}
+			for _, key := range found {
+				if outcomes[key] == models.DeleteOutcomeNotFound {
+					outcomes[key] = models.DeleteOutcomeNotOwned
+				}
+			}
+		}
+		results = append(results, outcomes)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	for _, req := range reqs {
+		s.reads.pin(req.UserID)
+	}
+	return results, nil
+}
+
+// queryKeys выполняет подготовленный запрос, возвращающий короткие ключи
+func queryKeys(ctx context.Context, stmt *sql.Stmt, args ...any) ([]string, error) {
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
 }
 
 // CountURLs возвращает количество всех сохранённых URL в хранилище.
 func (s *PostgresStorage) CountURLs(ctx context.Context) (int64, error) {
 	var count int64
-	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM urls WHERE is_deleted = FALSE").Scan(&count)
+	userID, _ := ctx.Value(contextkeys.UserIDKey).(string)
+	err := s.read(userID, func(db *sql.DB) error {
+		return db.QueryRowContext(ctx, "SELECT COUNT(*) FROM urls WHERE is_deleted = FALSE").Scan(&count)
+	})
 	if err != nil {
 		return 0, err
 	}
 	return count, nil
 }
 
+// ReserveKeys резервирует значения счётчика генератора ключей в таблице key_counters
+func (s *PostgresStorage) ReserveKeys(ctx context.Context, name string, floor, n uint64) (uint64, error) {
+	var end int64
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO key_counters (name, value)
+		VALUES ($1, GREATEST((SELECT COUNT(*) FROM urls), $2) + $3)
+		ON CONFLICT (name) DO UPDATE SET value = GREATEST(key_counters.value, $2) + $3
+		RETURNING value`, name, int64(floor), int64(n)).Scan(&end)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(end), nil
+}
+
 // CountUsers возвращает количество пользователей в хранилище.
 func (s *PostgresStorage) CountUsers(ctx context.Context) (int64, error) {
 	var count int64
-	err := s.db.QueryRowContext(ctx, "SELECT COUNT(DISTINCT user_id) FROM urls").Scan(&count)
+	userID, _ := ctx.Value(contextkeys.UserIDKey).(string)
+	err := s.read(userID, func(db *sql.DB) error {
+		return db.QueryRowContext(ctx, "SELECT COUNT(DISTINCT user_id) FROM urls").Scan(&count)
+	})
 	if err != nil {
 		return 0, err
 	}
 	return count, nil
 }
+
+// Scan возвращает записи по возрастанию id, начиная после курсора
+func (s *PostgresStorage) Scan(ctx context.Context, cursor string, limit int) ([]ShortenerURL, string, error) {
+	afterID, err := parseIDCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, short_url, original_url, user_id, correlation_id, is_deleted, expires_at, deleted_at, created_at
+		FROM urls
+		WHERE id > $1
+		ORDER BY id
+		LIMIT $2`, afterID, limit)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var result []ShortenerURL
+	for rows.Next() {
+		var url ShortenerURL
+		var isDeleted sql.NullBool
+		var expiresAt, deletedAt sql.NullTime
+		if err := rows.Scan(&url.UUID, &url.ShortURL, &url.OriginalURL, &url.UserID, &url.CorrelationID, &isDeleted, &expiresAt, &deletedAt, &url.CreatedAt); err != nil {
+			return nil, "", err
+		}
+		url.IsDeleted = isDeleted.Bool
+		if expiresAt.Valid {
+			url.ExpiresAt = &expiresAt.Time
+		}
+		if deletedAt.Valid {
+			url.DeletedAt = &deletedAt.Time
+		}
+		result = append(result, url)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	if len(result) < limit {
+		return result, "", nil
+	}
+	return result, strconv.Itoa(result[len(result)-1].UUID), nil
+}
+
+// Import сохраняет записи в одной транзакции, пропуская уже существующие
+// короткие ключи и оригинальные URL, которые уже сокращены.
+// Моменты создания и удаления записей сохраняются; если они не заданы, берётся текущее время.
+func (s *PostgresStorage) Import(ctx context.Context, urls []ShortenerURL) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO urls (short_url, original_url, user_id, correlation_id, is_deleted, expires_at, dedup_scope, deleted_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, CASE WHEN $5 THEN COALESCE($8, now()) END, COALESCE($9, now()))
+		ON CONFLICT DO NOTHING`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	imported := 0
+	for _, url := range urls {
+		var createdAt *time.Time
+		if !url.CreatedAt.IsZero() {
+			createdAt = &url.CreatedAt
+		}
+		res, err := stmt.ExecContext(ctx, url.ShortURL, url.OriginalURL, url.UserID, url.CorrelationID, url.IsDeleted, url.ExpiresAt,
+			s.opts.scope(url.UserID, url.ShortURL), url.DeletedAt, createdAt)
+		if err != nil {
+			return 0, err
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			imported += int(n)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return imported, nil
+}
+
+// PurgeExpired помечает удалёнными ссылки с истёкшим сроком действия
+func (s *PostgresStorage) PurgeExpired(ctx context.Context, now time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx,
+		"UPDATE urls SET is_deleted = TRUE, deleted_at = $1, updated_at = $1 WHERE expires_at <= $1 AND is_deleted = FALSE", now)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// GetURL возвращает запись по короткому ключу
+func (s *PostgresStorage) GetURL(ctx context.Context, key string) (ShortenerURL, error) {
+	var url ShortenerURL
+	var isDeleted sql.NullBool
+	var expiresAt sql.NullTime
+	userID, _ := ctx.Value(contextkeys.UserIDKey).(string)
+	err := s.read(userID, func(db *sql.DB) error {
+		return db.QueryRowContext(ctx, `
+			SELECT id, short_url, original_url, user_id, correlation_id, is_deleted, expires_at
+			FROM urls
+			WHERE short_url = $1`, key,
+		).Scan(&url.UUID, &url.ShortURL, &url.OriginalURL, &url.UserID, &url.CorrelationID, &isDeleted, &expiresAt)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ShortenerURL{}, ErrNotFound
+		}
+		return ShortenerURL{}, err
+	}
+	url.IsDeleted = isDeleted.Bool
+	if expiresAt.Valid {
+		url.ExpiresAt = &expiresAt.Time
+	}
+	return url, nil
+}
+
+// SaveClicks сохраняет пачку переходов в одной транзакции
+func (s *PostgresStorage) SaveClicks(ctx context.Context, clicks []models.Click) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO clicks (short_url, clicked_at, referrer, user_agent, ip_hash)
+		VALUES ($1, $2, $3, $4, $5)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, click := range clicks {
+		if _, err := stmt.ExecContext(ctx, click.ShortURL, click.ClickedAt, click.Referrer, click.UserAgent, click.IPHash); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Clicks возвращает все сохранённые переходы по ссылке в порядке времени перехода
+func (s *PostgresStorage) Clicks(ctx context.Context, key string) ([]models.Click, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT clicked_at, referrer, user_agent, ip_hash
+		FROM clicks
+		WHERE short_url = $1
+		ORDER BY clicked_at, id`, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var clicks []models.Click
+	for rows.Next() {
+		click := models.Click{ShortURL: key}
+		if err := rows.Scan(&click.ClickedAt, &click.Referrer, &click.UserAgent, &click.IPHash); err != nil {
+			return nil, err
+		}
+		clicks = append(clicks, click)
+	}
+	return clicks, rows.Err()
+}
+
+// ClickStats считает статистику переходов по ссылке средствами базы данных
+func (s *PostgresStorage) ClickStats(ctx context.Context, key string, bucket time.Duration) (models.LinkStats, error) {
+	var stats models.LinkStats
+	userID, _ := ctx.Value(contextkeys.UserIDKey).(string)
+	err := s.read(userID, func(db *sql.DB) error {
+		stats = models.LinkStats{ShortURL: key, Histogram: []models.StatsBucket{}}
+		err := db.QueryRowContext(ctx,
+			"SELECT COUNT(*), COUNT(DISTINCT ip_hash) FROM clicks WHERE short_url = $1", key,
+		).Scan(&stats.Total, &stats.UniqueVisitors)
+		if err != nil {
+			return err
+		}
+
+		rows, err := db.QueryContext(ctx, `
+			SELECT to_timestamp(floor(extract(epoch FROM clicked_at) / $2) * $2) AS bucket, COUNT(*)
+			FROM clicks
+			WHERE short_url = $1
+			GROUP BY bucket
+			ORDER BY bucket`, key, int64(bucket/time.Second))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var b models.StatsBucket
+			if err := rows.Scan(&b.Start, &b.Count); err != nil {
+				return err
+			}
+			b.Start = b.Start.UTC()
+			stats.Histogram = append(stats.Histogram, b)
+		}
+		return rows.Err()
+	})
+	return stats, err
+}
+
+// ListByUser возвращает страницу ссылок пользователя в порядке (created_at, id), используя эту пару как курсор
+func (s *PostgresStorage) ListByUser(ctx context.Context, userID string, opts models.ListOptions) ([]ShortenerURL, string, error) {
+	cursor, err := parseListCursor(opts.Cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query := `
+		SELECT id, short_url, original_url, user_id, correlation_id, is_deleted, expires_at, created_at
+		FROM urls
+		WHERE user_id = $1`
+	args := []any{userID}
+
+	if !opts.IncludeDeleted {
+		query += " AND is_deleted = FALSE"
+	}
+	if opts.Query != "" {
+		args = append(args, opts.Query)
+		query += fmt.Sprintf(" AND strpos(original_url, $%d) > 0", len(args))
+	}
+	order := "ASC"
+	if opts.Cursor != "" {
+		args = append(args, cursor.createdAt, cursor.id)
+		op := ">"
+		if opts.Descending() {
+			op = "<"
+		}
+		query += fmt.Sprintf(" AND (created_at, id) %s ($%d, $%d)", op, len(args)-1, len(args))
+	}
+	if opts.Descending() {
+		order = "DESC"
+	}
+	query += fmt.Sprintf(" ORDER BY created_at %s, id %s", order, order)
+	if opts.Limit > 0 {
+		// запрашиваем на одну запись больше, чтобы узнать, есть ли следующая страница
+		args = append(args, opts.Limit+1)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	var result []ShortenerURL
+	err = s.read(userID, func(db *sql.DB) error {
+		result = nil
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var url ShortenerURL
+			var isDeleted sql.NullBool
+			var expiresAt sql.NullTime
+			if err := rows.Scan(&url.UUID, &url.ShortURL, &url.OriginalURL, &url.UserID, &url.CorrelationID, &isDeleted, &expiresAt, &url.CreatedAt); err != nil {
+				return err
+			}
+			url.IsDeleted = isDeleted.Bool
+			if expiresAt.Valid {
+				url.ExpiresAt = &expiresAt.Time
+			}
+			url.CreatedAt = url.CreatedAt.UTC()
+			result = append(result, url)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	if opts.Limit > 0 && len(result) > opts.Limit {
+		result = result[:opts.Limit]
+		return result, cursorOf(result[len(result)-1]).String(), nil
+	}
+	return result, "", nil
+}
+
+// UpdateURL меняет оригинальный URL ссылки и записывает изменение в url_history в одной транзакции
+func (s *PostgresStorage) UpdateURL(ctx context.Context, userID, key, originalURL string) (models.URLVersion, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.URLVersion{}, err
+	}
+	defer tx.Rollback()
+
+	var owner, previous string
+	var isDeleted bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT user_id, original_url, is_deleted FROM urls WHERE short_url = $1 FOR UPDATE", key,
+	).Scan(&owner, &previous, &isDeleted)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.URLVersion{}, ErrNotFound
+		}
+		return models.URLVersion{}, err
+	}
+	if owner != userID {
+		return models.URLVersion{}, ErrNotFound
+	}
+	if isDeleted {
+		return models.URLVersion{}, ErrDeleted
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE urls SET original_url = $1, updated_at = now() WHERE short_url = $2", originalURL, key)
+	if err != nil {
+		var pgErr pgx.PgError
+		if errors.As(err, &pgErr) && pgerrcode.IsIntegrityConstraintViolation(pgErr.Code) {
+			return models.URLVersion{}, ErrConflict
+		}
+		return models.URLVersion{}, err
+	}
+
+	version := models.URLVersion{OriginalURL: originalURL, PreviousURL: previous, ChangedBy: userID}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO url_history (short_url, version, original_url, previous_url, changed_by)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4 FROM url_history WHERE short_url = $1
+		RETURNING version, changed_at`,
+		key, originalURL, previous, userID,
+	).Scan(&version.Version, &version.ChangedAt)
+	if err != nil {
+		return models.URLVersion{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.URLVersion{}, err
+	}
+	s.reads.pin(userID)
+	return version, nil
+}
+
+// ImportHistory сохраняет перенесённую историю изменений ссылки в одной транзакции,
+// пропуская уже существующие версии
+func (s *PostgresStorage) ImportHistory(ctx context.Context, key string, history []models.URLVersion) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO url_history (short_url, version, original_url, previous_url, changed_by, changed_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT DO NOTHING`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, v := range history {
+		if _, err := stmt.ExecContext(ctx, key, v.Version, v.OriginalURL, v.PreviousURL, v.ChangedBy, v.ChangedAt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// URLHistory возвращает историю изменений ссылки
+func (s *PostgresStorage) URLHistory(ctx context.Context, key string) ([]models.URLVersion, error) {
+	var history []models.URLVersion
+	userID, _ := ctx.Value(contextkeys.UserIDKey).(string)
+	err := s.read(userID, func(db *sql.DB) error {
+		history = nil
+		rows, err := db.QueryContext(ctx, `
+			SELECT version, original_url, previous_url, changed_by, changed_at
+			FROM url_history
+			WHERE short_url = $1
+			ORDER BY version`, key)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var v models.URLVersion
+			if err := rows.Scan(&v.Version, &v.OriginalURL, &v.PreviousURL, &v.ChangedBy, &v.ChangedAt); err != nil {
+				return err
+			}
+			history = append(history, v)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
+}
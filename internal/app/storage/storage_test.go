@@ -2,6 +2,7 @@ package storage_test
 
 import (
 	"context"
+	"errors"
 	"os"
 	"testing"
 
@@ -37,9 +38,6 @@ func TestFileStorage_CreateAndGet(t *testing.T) {
 	_, err = s.Create(ctx, url)
 	require.NoError(t, err)
 
-	// 🧠 Вручную добавим в Urls (т.к. Create этого не делает)
-	storage.Urls[short] = url
-
 	// Get
 	got, err := s.Get(ctx, short)
 	require.NoError(t, err)
@@ -64,31 +62,24 @@ func TestFileStorage_Ping(t *testing.T) {
 	}
 }
 
-func cleanupGlobals() {
-	for k := range storage.Urls {
-		delete(storage.Urls, k)
-	}
-	for k := range storage.UsersUrls {
-		delete(storage.UsersUrls, k)
-	}
-}
-
 func TestFileStorage_DeleteURLs(t *testing.T) {
-	cleanupGlobals()
+	tmpFile, err := os.CreateTemp("", "storage-test-*.json")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
 
-	// Подготовим URL для удаления
-	storage.Urls["short1"] = storage.ShortenerURL{
-		UserID: "user1",
-	}
+	fs, err := storage.NewFileStorage(&config.Config{FileStoragePath: tmpFile.Name()})
+	require.NoError(t, err)
 
-	fs := &storage.FileStorage{}
+	// Подготовим URL для удаления
+	_, err = fs.Create(context.Background(), storage.ShortenerURL{ShortURL: "short1", UserID: "user1"})
+	require.NoError(t, err)
 
-	err := fs.DeleteURLs(context.Background(), "user1", []string{"short1"})
+	err = fs.DeleteURLs(context.Background(), "user1", []string{"short1"})
 	if err != nil {
 		t.Fatalf("DeleteURLs returned error: %v", err)
 	}
 
-	if !storage.Urls["short1"].IsDeleted {
-		t.Error("Expected IsDeleted to be true after DeleteURLs")
+	if _, err := fs.Get(context.Background(), "short1"); !errors.Is(err, storage.ErrDeleted) {
+		t.Errorf("Expected ErrDeleted after DeleteURLs, got %v", err)
 	}
 }
@@ -0,0 +1,106 @@
+package storage
+
+import "fmt"
+
+// Области уникальности оригинального URL, которые можно выбрать параметром url_uniqueness
+const (
+	// UniquenessGlobal — оригинальный URL сокращается один раз на всё хранилище
+	UniquenessGlobal = "global"
+	// UniquenessUser — каждый пользователь может сократить оригинальный URL один раз
+	UniquenessUser = "user"
+	// UniquenessNone — оригинальный URL можно сокращать сколько угодно раз
+	UniquenessNone = "none"
+)
+
+// noneScopePrefix отличает области режима none, по одной на ссылку, от идентификаторов пользователей
+const noneScopePrefix = "key:"
+
+// WithUniqueness задаёт область уникальности оригинального URL.
+// По умолчанию используется UniquenessGlobal.
+func WithUniqueness(mode string) Option {
+	return func(o *options) {
+		o.uniqueness = mode
+	}
+}
+
+// scope возвращает область уникальности для ссылки key пользователя userID:
+// пустую строку в глобальном режиме, идентификатор пользователя в режиме user
+// и собственную область ссылки в режиме none, где оригинальные URL не пересекаются
+func (o options) scope(userID, key string) string {
+	switch o.uniqueness {
+	case UniquenessUser:
+		return userID
+	case UniquenessNone:
+		return noneScopePrefix + key
+	default:
+		return ""
+	}
+}
+
+// conflict возвращает результат создания ссылки, оригинальный URL которой уже закреплён
+// в её области за ссылкой existing. В режиме none у каждой ссылки своя область,
+// поэтому совпадение означает, что занят сам короткий ключ.
+func (o options) conflict(existing string) (string, error) {
+	if o.uniqueness == UniquenessNone {
+		return "", ErrKeyExists
+	}
+	return existing, ErrConflict
+}
+
+// ValidateUniqueness проверяет, что область уникальности поддерживается
+func ValidateUniqueness(mode string) error {
+	switch mode {
+	case UniquenessGlobal, UniquenessUser, UniquenessNone:
+		return nil
+	default:
+		return fmt.Errorf("unknown url uniqueness %q", mode)
+	}
+}
+
+// uniquenessSetting — имя сохранённого в хранилище режима уникальности, с которым посчитаны области ссылок
+const uniquenessSetting = "url_uniqueness"
+
+// errUniquenessChange сообщает, что сохранённые ссылки нарушают уникальность в новом режиме
+func errUniquenessChange(from, to string) error {
+	if from == "" {
+		from = "unknown"
+	}
+	return fmt.Errorf("cannot switch url uniqueness from %s to %s: stored links repeat original URLs in the new scope, "+
+		"remove the duplicates or keep the previous mode", from, to)
+}
+
+// originalKey формирует ключ индекса оригинальных URL.
+// В глобальной области ключ совпадает с оригинальным URL.
+func originalKey(scope, originalURL string) string {
+	if scope == "" {
+		return originalURL
+	}
+	return scope + "\x00" + originalURL
+}
+
+// checkBatch проверяет, что пакет можно сохранить целиком: короткие ключи и оригинальные URL
+// не заняты в хранилище и не повторяются внутри пакета.
+// keyOf возвращает ключ записи в индексе оригинальных URL, original — владельца такого ключа.
+func (o options) checkBatch(urls []ShortenerURL, keyOf func(ShortenerURL) string, original func(string) (string, bool), keyExists func(string) bool) error {
+	keys := make(map[string]struct{}, len(urls))
+	originals := make(map[string]struct{}, len(urls))
+	for i, url := range urls {
+		if !url.IsDeleted {
+			key := keyOf(url)
+			if existing, taken := original(key); taken {
+				existing, err := o.conflict(existing)
+				return &BatchError{Index: i, Existing: existing, Err: err}
+			}
+			if _, repeated := originals[key]; repeated {
+				_, err := o.conflict("")
+				return &BatchError{Index: i, Err: err}
+			}
+			originals[key] = struct{}{}
+		}
+		if _, repeated := keys[url.ShortURL]; repeated || keyExists(url.ShortURL) {
+			return &BatchError{Index: i, Err: ErrKeyExists}
+		}
+		keys[url.ShortURL] = struct{}{}
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package storage_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/issafronov/shortener/internal/app/config"
+	"github.com/issafronov/shortener/internal/app/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// uniquenessStorages возвращает встраиваемые хранилища с заданной областью уникальности
+func uniquenessStorages(t *testing.T, mode string) map[string]storage.Storage {
+	dir := t.TempDir()
+
+	fs, err := storage.NewFileStorage(&config.Config{FileStoragePath: filepath.Join(dir, "storage.json"), URLUniqueness: mode})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = fs.Close() })
+
+	bs, err := storage.NewBoltStorage(filepath.Join(dir, "shortener.db"), storage.WithUniqueness(mode))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = bs.Close() })
+
+	return map[string]storage.Storage{
+		storage.EngineMemory: storage.NewMemoryStorage(storage.WithUniqueness(mode)),
+		storage.EngineFile:   fs,
+		storage.EngineBolt:   bs,
+	}
+}
+
+func TestStorage_ConflictReturnsExistingKey(t *testing.T) {
+	for engine, s := range uniquenessStorages(t, storage.UniquenessGlobal) {
+		t.Run(engine, func(t *testing.T) {
+			ctx := context.Background()
+
+			_, err := s.Create(ctx, storage.ShortenerURL{ShortURL: "abc123", OriginalURL: "https://example.com", UserID: "user1"})
+			require.NoError(t, err)
+
+			existing, err := s.Create(ctx, storage.ShortenerURL{ShortURL: "xyz789", OriginalURL: "https://example.com", UserID: "user2"})
+			assert.ErrorIs(t, err, storage.ErrConflict)
+			assert.Equal(t, "abc123", existing)
+
+			_, err = s.Create(ctx, storage.ShortenerURL{ShortURL: "other1", OriginalURL: "https://other.com", UserID: "user1"})
+			require.NoError(t, err)
+			_, err = s.UpdateURL(ctx, "user1", "other1", "https://example.com")
+			assert.ErrorIs(t, err, storage.ErrConflict)
+
+			imported, err := s.Import(ctx, []storage.ShortenerURL{{ShortURL: "imp1", OriginalURL: "https://example.com", UserID: "user3"}})
+			require.NoError(t, err)
+			assert.Zero(t, imported)
+
+			require.NoError(t, s.DeleteURLs(ctx, "user1", []string{"abc123"}))
+			key, err := s.Create(ctx, storage.ShortenerURL{ShortURL: "new123", OriginalURL: "https://example.com", UserID: "user2"})
+			require.NoError(t, err)
+			assert.Equal(t, "new123", key)
+		})
+	}
+}
+
+func TestStorage_UserUniqueness(t *testing.T) {
+	for engine, s := range uniquenessStorages(t, storage.UniquenessUser) {
+		t.Run(engine, func(t *testing.T) {
+			ctx := context.Background()
+
+			_, err := s.Create(ctx, storage.ShortenerURL{ShortURL: "abc123", OriginalURL: "https://example.com", UserID: "user1"})
+			require.NoError(t, err)
+
+			_, err = s.Create(ctx, storage.ShortenerURL{ShortURL: "xyz789", OriginalURL: "https://example.com", UserID: "user2"})
+			require.NoError(t, err)
+
+			existing, err := s.Create(ctx, storage.ShortenerURL{ShortURL: "dup123", OriginalURL: "https://example.com", UserID: "user1"})
+			assert.ErrorIs(t, err, storage.ErrConflict)
+			assert.Equal(t, "abc123", existing)
+		})
+	}
+}
+
+func TestStorage_NoUniqueness(t *testing.T) {
+	for engine, s := range uniquenessStorages(t, storage.UniquenessNone) {
+		t.Run(engine, func(t *testing.T) {
+			ctx := context.Background()
+
+			_, err := s.Create(ctx, storage.ShortenerURL{ShortURL: "abc123", OriginalURL: "https://example.com", UserID: "user1"})
+			require.NoError(t, err)
+			_, err = s.Create(ctx, storage.ShortenerURL{ShortURL: "xyz789", OriginalURL: "https://example.com", UserID: "user1"})
+			require.NoError(t, err)
+
+			require.NoError(t, s.CreateBatch(ctx, []storage.ShortenerURL{
+				{ShortURL: "new1", OriginalURL: "https://example.com", UserID: "user2"},
+				{ShortURL: "new2", OriginalURL: "https://example.com", UserID: "user2"},
+			}))
+			_, err = s.Create(ctx, storage.ShortenerURL{ShortURL: "new1", OriginalURL: "https://example.com", UserID: "user2"})
+			assert.ErrorIs(t, err, storage.ErrKeyExists)
+
+			_, err = s.Create(ctx, storage.ShortenerURL{ShortURL: "other1", OriginalURL: "https://other.com", UserID: "user1"})
+			require.NoError(t, err)
+			_, err = s.UpdateURL(ctx, "user1", "other1", "https://example.com")
+			require.NoError(t, err)
+
+			count, err := s.CountURLs(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, int64(5), count)
+		})
+	}
+}
+
+func TestFileStorage_ConflictSurvivesRestart(t *testing.T) {
+	cfg := &config.Config{FileStoragePath: filepath.Join(t.TempDir(), "storage.json")}
+	ctx := context.Background()
+
+	fs, err := storage.NewFileStorage(cfg)
+	require.NoError(t, err)
+	_, err = fs.Create(ctx, storage.ShortenerURL{ShortURL: "abc", OriginalURL: "https://example.com", UserID: "user1"})
+	require.NoError(t, err)
+	require.NoError(t, fs.Close())
+
+	fs, err = storage.NewFileStorage(cfg)
+	require.NoError(t, err)
+	defer fs.Close()
+
+	existing, err := fs.Create(ctx, storage.ShortenerURL{ShortURL: "xyz", OriginalURL: "https://example.com", UserID: "user2"})
+	assert.ErrorIs(t, err, storage.ErrConflict)
+	assert.Equal(t, "abc", existing)
+}
+
+func TestValidateUniqueness(t *testing.T) {
+	assert.NoError(t, storage.ValidateUniqueness(storage.UniquenessGlobal))
+	assert.NoError(t, storage.ValidateUniqueness(storage.UniquenessUser))
+	assert.NoError(t, storage.ValidateUniqueness(storage.UniquenessNone))
+	assert.Error(t, storage.ValidateUniqueness("tenant"))
+}
+
+func TestStorage_CreateBatchIsAtomic(t *testing.T) {
+	for engine, s := range uniquenessStorages(t, storage.UniquenessGlobal) {
+		t.Run(engine, func(t *testing.T) {
+			ctx := context.Background()
+
+			_, err := s.Create(ctx, storage.ShortenerURL{ShortURL: "abc123", OriginalURL: "https://example.com", UserID: "user1"})
+			require.NoError(t, err)
+
+			err = s.CreateBatch(ctx, []storage.ShortenerURL{
+				{ShortURL: "new1", OriginalURL: "https://new.com", UserID: "user1"},
+				{ShortURL: "new2", OriginalURL: "https://example.com", UserID: "user1"},
+			})
+			var batchErr *storage.BatchError
+			require.ErrorAs(t, err, &batchErr)
+			assert.ErrorIs(t, err, storage.ErrConflict)
+			assert.Equal(t, 1, batchErr.Index)
+			assert.Equal(t, "abc123", batchErr.Existing)
+
+			_, err = s.Get(ctx, "new1")
+			assert.ErrorIs(t, err, storage.ErrNotFound)
+
+			err = s.CreateBatch(ctx, []storage.ShortenerURL{
+				{ShortURL: "new1", OriginalURL: "https://new.com", UserID: "user1"},
+				{ShortURL: "new1", OriginalURL: "https://other.com", UserID: "user1"},
+			})
+			require.ErrorAs(t, err, &batchErr)
+			assert.ErrorIs(t, err, storage.ErrKeyExists)
+
+			require.NoError(t, s.CreateBatch(ctx, []storage.ShortenerURL{
+				{ShortURL: "new1", OriginalURL: "https://new.com", UserID: "user1"},
+				{ShortURL: "new2", OriginalURL: "https://other.com", UserID: "user2"},
+			}))
+			got, err := s.Get(ctx, "new2")
+			require.NoError(t, err)
+			assert.Equal(t, "https://other.com", got)
+
+			count, err := s.CountURLs(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, int64(3), count)
+		})
+	}
+}
+
+func TestBoltStorage_UniquenessChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shortener.db")
+	ctx := context.Background()
+
+	s, err := storage.NewBoltStorage(path, storage.WithUniqueness(storage.UniquenessUser))
+	require.NoError(t, err)
+	for _, url := range []storage.ShortenerURL{
+		{ShortURL: "a", OriginalURL: "https://example.com", UserID: "user1"},
+		{ShortURL: "b", OriginalURL: "https://example.com", UserID: "user2"},
+	} {
+		_, err = s.Create(ctx, url)
+		require.NoError(t, err)
+	}
+	require.NoError(t, s.Close())
+
+	_, err = storage.NewBoltStorage(path, storage.WithUniqueness(storage.UniquenessGlobal))
+	assert.ErrorContains(t, err, "cannot switch url uniqueness from user to global")
+
+	// без повторов в новой области индекс перестраивается под новый режим
+	s, err = storage.NewBoltStorage(path, storage.WithUniqueness(storage.UniquenessUser))
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteURLs(ctx, "user2", []string{"b"}))
+	require.NoError(t, s.Close())
+
+	s, err = storage.NewBoltStorage(path, storage.WithUniqueness(storage.UniquenessGlobal))
+	require.NoError(t, err)
+	defer s.Close()
+	existing, err := s.Create(ctx, storage.ShortenerURL{ShortURL: "c", OriginalURL: "https://example.com", UserID: "user3"})
+	assert.ErrorIs(t, err, storage.ErrConflict)
+	assert.Equal(t, "a", existing)
+}
@@ -0,0 +1,98 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// metadataCarrier позволяет читать и записывать контекст трассировки в метаданных gRPC
+type metadataCarrier metadata.MD
+
+// Get возвращает первое значение ключа
+func (c metadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// Set заменяет значение ключа
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+// Keys возвращает все ключи метаданных
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// UnaryServerInterceptor продолжает трассу из метаданных traceparent или начинает новую
+// и оборачивает вызов серверным спаном с полным именем метода
+func (t *Tracing) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, span := t.startServerSpan(ctx, info.FullMethod)
+		defer span.End()
+
+		resp, err := handler(ctx, req)
+		finishServerSpan(span, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor оборачивает потоковый вызов серверным спаном так же, как UnaryServerInterceptor.
+// Спан охватывает весь поток и доступен обработчику через контекст потока.
+func (t *Tracing) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span := t.startServerSpan(ss.Context(), info.FullMethod)
+		defer span.End()
+
+		err := handler(srv, &tracedStream{ServerStream: ss, ctx: ctx})
+		finishServerSpan(span, err)
+		return err
+	}
+}
+
+// tracedStream подменяет контекст потока контекстом со спаном вызова
+type tracedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context возвращает контекст потока со спаном вызова
+func (s *tracedStream) Context() context.Context {
+	return s.ctx
+}
+
+// startServerSpan продолжает трассу из метаданных вызова или начинает новую и открывает серверный спан
+func (t *Tracing) startServerSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		ctx = t.propagator.Extract(ctx, metadataCarrier(md))
+	}
+	return t.tracer.Start(ctx, method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("rpc.system", "grpc"),
+			attribute.String("rpc.method", method),
+		),
+	)
+}
+
+// finishServerSpan записывает в спан код завершения вызова
+func finishServerSpan(span trace.Span, err error) {
+	code := status.Code(err)
+	span.SetAttributes(attribute.Int("rpc.grpc.status_code", int(code)))
+	if err != nil {
+		span.SetStatus(codes.Error, code.String())
+	}
+}
@@ -0,0 +1,128 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"github.com/issafronov/shortener/internal/app/models"
+	"github.com/issafronov/shortener/internal/app/service"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var _ service.Service = (*Service)(nil)
+
+// Service оборачивает сервис и создаёт спан для каждого вызова бизнес-логики.
+// Статус ошибки спанов сервиса не выставляется: его определяет транспорт по коду ответа.
+type Service struct {
+	next    service.Service
+	tracing *Tracing
+}
+
+// NewService возвращает сервис со спанами вызовов
+func NewService(next service.Service, t *Tracing) *Service {
+	return &Service{next: next, tracing: t}
+}
+
+// start начинает спан вызова сервиса
+func (s *Service) start(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return s.tracing.start(ctx, "service."+method, attrs...)
+}
+
+// CreateURL создаёт сокращённый URL
+func (s *Service) CreateURL(ctx context.Context, originalURL, userID string, opts ...service.CreateOption) (shortKey string, err error) {
+	ctx, span := s.start(ctx, "CreateURL")
+	defer func() {
+		span.SetAttributes(attribute.String("shortener.key", shortKey))
+		finish(span, err, false)
+	}()
+	return s.next.CreateURL(ctx, originalURL, userID, opts...)
+}
+
+// CreateURLBatch создаёт сокращённые URL по батч-запросу
+func (s *Service) CreateURLBatch(ctx context.Context, batch []models.BatchURLData, userID string, mode models.BatchMode) (resp []models.BatchURLDataResponse, err error) {
+	ctx, span := s.start(ctx, "CreateURLBatch",
+		attribute.Int("shortener.batch_size", len(batch)),
+		attribute.String("shortener.batch_mode", string(mode)),
+	)
+	defer func() { finish(span, err, false) }()
+	return s.next.CreateURLBatch(ctx, batch, userID, mode)
+}
+
+// GetOriginalURL возвращает оригинальный URL по короткому ключу
+func (s *Service) GetOriginalURL(ctx context.Context, shortKey string) (originalURL string, err error) {
+	ctx, span := s.start(ctx, "GetOriginalURL", attribute.String("shortener.key", shortKey))
+	defer func() { finish(span, err, false) }()
+	return s.next.GetOriginalURL(ctx, shortKey)
+}
+
+// ListUserURLs возвращает страницу сокращённых URL пользователя
+func (s *Service) ListUserURLs(ctx context.Context, userID, host string, opts models.ListOptions) (urls []models.ShortURLResponse, next string, err error) {
+	ctx, span := s.start(ctx, "ListUserURLs")
+	defer func() { finish(span, err, false) }()
+	return s.next.ListUserURLs(ctx, userID, host, opts)
+}
+
+// DeleteUserURLs удаляет сокращённые ссылки пользователя
+func (s *Service) DeleteUserURLs(ctx context.Context, userID string, ids []string) (jobID string, err error) {
+	ctx, span := s.start(ctx, "DeleteUserURLs", attribute.Int("shortener.batch_size", len(ids)))
+	defer func() {
+		span.SetAttributes(attribute.String("shortener.job_id", jobID))
+		finish(span, err, false)
+	}()
+	return s.next.DeleteUserURLs(ctx, userID, ids)
+}
+
+// GetDeleteJob возвращает задачу удаления ссылок пользователя
+func (s *Service) GetDeleteJob(ctx context.Context, userID, jobID string) (job models.DeleteJob, err error) {
+	ctx, span := s.start(ctx, "GetDeleteJob", attribute.String("shortener.job_id", jobID))
+	defer func() { finish(span, err, false) }()
+	return s.next.GetDeleteJob(ctx, userID, jobID)
+}
+
+// GetStats возвращает количество URL и пользователей
+func (s *Service) GetStats(ctx context.Context) (stats models.Stats, err error) {
+	ctx, span := s.start(ctx, "GetStats")
+	defer func() { finish(span, err, false) }()
+	return s.next.GetStats(ctx)
+}
+
+// Ping проверяет доступность сервиса
+func (s *Service) Ping(ctx context.Context) (health models.Health, err error) {
+	ctx, span := s.start(ctx, "Ping")
+	defer func() { finish(span, err, false) }()
+	return s.next.Ping(ctx)
+}
+
+// RecordClick асинхронно записывает переход по ссылке
+func (s *Service) RecordClick(ctx context.Context, click models.Click, clientIP string) {
+	s.next.RecordClick(ctx, click, clientIP)
+}
+
+// GetLinkStats возвращает статистику переходов по ссылке пользователя
+func (s *Service) GetLinkStats(ctx context.Context, userID, shortKey string, bucket time.Duration) (stats models.LinkStats, err error) {
+	ctx, span := s.start(ctx, "GetLinkStats", attribute.String("shortener.key", shortKey))
+	defer func() { finish(span, err, false) }()
+	return s.next.GetLinkStats(ctx, userID, shortKey, bucket)
+}
+
+// UpdateURL меняет оригинальный URL ссылки пользователя
+func (s *Service) UpdateURL(ctx context.Context, userID, shortKey, originalURL string) (version models.URLVersion, err error) {
+	ctx, span := s.start(ctx, "UpdateURL", attribute.String("shortener.key", shortKey))
+	defer func() { finish(span, err, false) }()
+	return s.next.UpdateURL(ctx, userID, shortKey, originalURL)
+}
+
+// GetURLHistory возвращает историю изменений ссылки пользователя
+func (s *Service) GetURLHistory(ctx context.Context, userID, shortKey string) (history []models.URLVersion, err error) {
+	ctx, span := s.start(ctx, "GetURLHistory", attribute.String("shortener.key", shortKey))
+	defer func() { finish(span, err, false) }()
+	return s.next.GetURLHistory(ctx, userID, shortKey)
+}
+
+// RollbackURL возвращает ссылке оригинальный URL одной из прежних версий
+func (s *Service) RollbackURL(ctx context.Context, userID, shortKey string, version int) (v models.URLVersion, err error) {
+	ctx, span := s.start(ctx, "RollbackURL", attribute.String("shortener.key", shortKey), attribute.Int("shortener.version", version))
+	defer func() { finish(span, err, false) }()
+	return s.next.RollbackURL(ctx, userID, shortKey, version)
+}
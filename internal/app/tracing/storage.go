@@ -0,0 +1,177 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"github.com/issafronov/shortener/internal/app/models"
+	"github.com/issafronov/shortener/internal/app/storage"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var _ storage.Storage = (*Storage)(nil)
+
+// Storage оборачивает хранилище и создаёт спан для каждой операции
+type Storage struct {
+	next    storage.Storage
+	system  string
+	tracing *Tracing
+}
+
+// NewStorage возвращает хранилище со спанами операций; backend — имя движка хранилища
+func NewStorage(next storage.Storage, backend string, t *Tracing) *Storage {
+	system := backend
+	if backend == storage.EnginePostgres {
+		system = "postgresql"
+	}
+	return &Storage{next: next, system: system, tracing: t}
+}
+
+// Unwrap возвращает исходное хранилище
+func (s *Storage) Unwrap() storage.Storage {
+	return s.next
+}
+
+// start начинает клиентский спан операции хранилища
+func (s *Storage) start(ctx context.Context, op string) (context.Context, trace.Span) {
+	return s.tracing.tracer.Start(ctx, "storage."+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", s.system),
+			attribute.String("db.operation.name", op),
+		),
+	)
+}
+
+// finish завершает спан; штатные ошибки вроде «не найдено» сбоем не считаются
+func (s *Storage) finish(span trace.Span, err error) {
+	finish(span, err, err != nil && !storage.IsExpectedError(err))
+}
+
+// Create сохраняет ссылку
+func (s *Storage) Create(ctx context.Context, url storage.ShortenerURL) (key string, err error) {
+	ctx, span := s.start(ctx, "create")
+	defer func() { s.finish(span, err) }()
+	return s.next.Create(ctx, url)
+}
+
+// CreateBatch атомарно сохраняет пакет записей
+func (s *Storage) CreateBatch(ctx context.Context, urls []storage.ShortenerURL) (err error) {
+	ctx, span := s.start(ctx, "create_batch")
+	span.SetAttributes(attribute.Int("shortener.batch_size", len(urls)))
+	defer func() { s.finish(span, err) }()
+	return s.next.CreateBatch(ctx, urls)
+}
+
+// Get возвращает оригинальный URL по короткому ключу
+func (s *Storage) Get(ctx context.Context, url string) (originalURL string, err error) {
+	ctx, span := s.start(ctx, "get")
+	defer func() { s.finish(span, err) }()
+	return s.next.Get(ctx, url)
+}
+
+// GetByUser возвращает ссылки пользователя
+func (s *Storage) GetByUser(ctx context.Context, username string) (urls []models.ShortURLResponse, err error) {
+	ctx, span := s.start(ctx, "get_by_user")
+	defer func() { s.finish(span, err) }()
+	return s.next.GetByUser(ctx, username)
+}
+
+// Ping проверяет доступность хранилища
+func (s *Storage) Ping(ctx context.Context) (err error) {
+	ctx, span := s.start(ctx, "ping")
+	defer func() { s.finish(span, err) }()
+	return s.next.Ping(ctx)
+}
+
+// DeleteURLs помечает ссылки пользователя удалёнными
+func (s *Storage) DeleteURLs(ctx context.Context, userID string, urls []string) (err error) {
+	ctx, span := s.start(ctx, "delete_urls")
+	defer func() { s.finish(span, err) }()
+	return s.next.DeleteURLs(ctx, userID, urls)
+}
+
+// DeleteURLsBatch помечает удалёнными ссылки нескольких пользователей
+func (s *Storage) DeleteURLsBatch(ctx context.Context, reqs []models.DeleteRequest) (results []models.DeleteOutcomes, err error) {
+	ctx, span := s.start(ctx, "delete_urls_batch")
+	defer func() { s.finish(span, err) }()
+	return s.next.DeleteURLsBatch(ctx, reqs)
+}
+
+// CountURLs возвращает количество не удалённых ссылок
+func (s *Storage) CountURLs(ctx context.Context) (count int64, err error) {
+	ctx, span := s.start(ctx, "count_urls")
+	defer func() { s.finish(span, err) }()
+	return s.next.CountURLs(ctx)
+}
+
+// CountUsers возвращает количество пользователей
+func (s *Storage) CountUsers(ctx context.Context) (count int64, err error) {
+	ctx, span := s.start(ctx, "count_users")
+	defer func() { s.finish(span, err) }()
+	return s.next.CountUsers(ctx)
+}
+
+// Scan возвращает страницу записей после курсора
+func (s *Storage) Scan(ctx context.Context, cursor string, limit int) (urls []storage.ShortenerURL, next string, err error) {
+	ctx, span := s.start(ctx, "scan")
+	defer func() { s.finish(span, err) }()
+	return s.next.Scan(ctx, cursor, limit)
+}
+
+// Import сохраняет записи как есть
+func (s *Storage) Import(ctx context.Context, urls []storage.ShortenerURL) (imported int, err error) {
+	ctx, span := s.start(ctx, "import")
+	defer func() { s.finish(span, err) }()
+	return s.next.Import(ctx, urls)
+}
+
+// PurgeExpired удаляет ссылки с истёкшим сроком действия
+func (s *Storage) PurgeExpired(ctx context.Context, now time.Time) (purged int64, err error) {
+	ctx, span := s.start(ctx, "purge_expired")
+	defer func() { s.finish(span, err) }()
+	return s.next.PurgeExpired(ctx, now)
+}
+
+// ListByUser возвращает страницу ссылок пользователя
+func (s *Storage) ListByUser(ctx context.Context, userID string, opts models.ListOptions) (urls []storage.ShortenerURL, next string, err error) {
+	ctx, span := s.start(ctx, "list_by_user")
+	defer func() { s.finish(span, err) }()
+	return s.next.ListByUser(ctx, userID, opts)
+}
+
+// UpdateURL меняет оригинальный URL ссылки
+func (s *Storage) UpdateURL(ctx context.Context, userID, key, originalURL string) (version models.URLVersion, err error) {
+	ctx, span := s.start(ctx, "update_url")
+	defer func() { s.finish(span, err) }()
+	return s.next.UpdateURL(ctx, userID, key, originalURL)
+}
+
+// URLHistory возвращает историю изменений ссылки
+func (s *Storage) URLHistory(ctx context.Context, key string) (history []models.URLVersion, err error) {
+	ctx, span := s.start(ctx, "url_history")
+	defer func() { s.finish(span, err) }()
+	return s.next.URLHistory(ctx, key)
+}
+
+// GetURL возвращает запись по короткому ключу
+func (s *Storage) GetURL(ctx context.Context, key string) (url storage.ShortenerURL, err error) {
+	ctx, span := s.start(ctx, "get_url")
+	defer func() { s.finish(span, err) }()
+	return s.next.GetURL(ctx, key)
+}
+
+// SaveClicks сохраняет пачку переходов
+func (s *Storage) SaveClicks(ctx context.Context, clicks []models.Click) (err error) {
+	ctx, span := s.start(ctx, "save_clicks")
+	defer func() { s.finish(span, err) }()
+	return s.next.SaveClicks(ctx, clicks)
+}
+
+// ClickStats возвращает статистику переходов по ссылке
+func (s *Storage) ClickStats(ctx context.Context, key string, bucket time.Duration) (stats models.LinkStats, err error) {
+	ctx, span := s.start(ctx, "click_stats")
+	defer func() { s.finish(span, err) }()
+	return s.next.ClickStats(ctx, key, bucket)
+}
@@ -0,0 +1,173 @@
+// Package tracing содержит трассировку запросов: распространение контекста W3C traceparent,
+// спаны HTTP, gRPC, сервиса и хранилища, а также подключаемые экспортёры спанов.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName — имя, под которым приложение создаёт спаны
+const instrumentationName = "github.com/issafronov/shortener"
+
+// Встроенные экспортёры спанов
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterFile   = "file"
+)
+
+// ExporterFactory создаёт экспортёр спанов.
+// target задаёт назначение экспорта, его смысл зависит от экспортёра: например, путь до файла.
+type ExporterFactory func(target string) (sdktrace.SpanExporter, error)
+
+var (
+	exportersMu sync.RWMutex
+	exporters   = map[string]ExporterFactory{
+		ExporterStdout: newStdoutExporter,
+		ExporterFile:   newFileExporter,
+	}
+)
+
+// RegisterExporter регистрирует экспортёр спанов под именем name
+func RegisterExporter(name string, factory ExporterFactory) {
+	exportersMu.Lock()
+	defer exportersMu.Unlock()
+	exporters[name] = factory
+}
+
+// NewExporter создаёт зарегистрированный экспортёр по имени.
+// Для пустого имени и none возвращает nil: спаны создаются, но никуда не отправляются.
+func NewExporter(name, target string) (sdktrace.SpanExporter, error) {
+	if name == "" || name == ExporterNone {
+		return nil, nil
+	}
+
+	exportersMu.RLock()
+	factory, ok := exporters[name]
+	exportersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown trace exporter %q, available: %v", name, exporterNames())
+	}
+	return factory(target)
+}
+
+// exporterNames возвращает отсортированные имена зарегистрированных экспортёров
+func exporterNames() []string {
+	exportersMu.RLock()
+	defer exportersMu.RUnlock()
+
+	names := []string{ExporterNone}
+	for name := range exporters {
+		names = append(names, name)
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+// newStdoutExporter пишет спаны в стандартный вывод в читаемом виде
+func newStdoutExporter(string) (sdktrace.SpanExporter, error) {
+	return stdouttrace.New(stdouttrace.WithPrettyPrint())
+}
+
+// fileExporter пишет спаны в файл по одному JSON-объекту в строке и закрывает файл при остановке
+type fileExporter struct {
+	*stdouttrace.Exporter
+	file *os.File
+}
+
+// newFileExporter открывает файл target на дозапись
+func newFileExporter(target string) (sdktrace.SpanExporter, error) {
+	if target == "" {
+		return nil, fmt.Errorf("trace file path is empty")
+	}
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	exp, err := stdouttrace.New(stdouttrace.WithWriter(file))
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &fileExporter{Exporter: exp, file: file}, nil
+}
+
+// Shutdown останавливает экспортёр и закрывает файл
+func (e *fileExporter) Shutdown(ctx context.Context) error {
+	err := e.Exporter.Shutdown(ctx)
+	if cerr := e.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Tracing создаёт спаны приложения и распространяет контекст трассировки
+type Tracing struct {
+	provider   *sdktrace.TracerProvider
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// New возвращает трассировку, отправляющую спаны в exporter.
+// Если exporter равен nil, спаны и идентификаторы трасс создаются, но не экспортируются.
+func New(exporter sdktrace.SpanExporter) *Tracing {
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", "shortener"))),
+	}
+	if exporter != nil {
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+	return newTracing(sdktrace.NewTracerProvider(opts...))
+}
+
+func newTracing(provider *sdktrace.TracerProvider) *Tracing {
+	return &Tracing{
+		provider:   provider,
+		tracer:     provider.Tracer(instrumentationName),
+		propagator: propagation.TraceContext{},
+	}
+}
+
+// TracerProvider возвращает провайдер спанов, например для регистрации глобальным
+func (t *Tracing) TracerProvider() trace.TracerProvider {
+	return t.provider
+}
+
+// Propagator возвращает формат распространения контекста трассировки (W3C traceparent)
+func (t *Tracing) Propagator() propagation.TextMapPropagator {
+	return t.propagator
+}
+
+// Shutdown отправляет накопленные спаны и останавливает экспортёр
+func (t *Tracing) Shutdown(ctx context.Context) error {
+	return t.provider.Shutdown(ctx)
+}
+
+// start начинает внутренний спан с указанными атрибутами
+func (t *Tracing) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// finish завершает спан и записывает в него ошибку событием.
+// Статус Error выставляется, только если ошибка означает сбой, а не штатный результат вроде «не найдено».
+func finish(span trace.Span, err error, failure bool) {
+	if err != nil {
+		span.RecordError(err)
+		if failure {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
@@ -0,0 +1,183 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/issafronov/shortener/internal/app/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	parentTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	parentSpanID  = "00f067aa0ba902b7"
+	traceparent   = "00-" + parentTraceID + "-" + parentSpanID + "-01"
+)
+
+func newTestTracing() (*Tracing, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	return newTracing(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))), recorder
+}
+
+// stubStorage возвращает заданную ошибку из Get
+type stubStorage struct {
+	storage.Storage
+	err error
+}
+
+func (s stubStorage) Get(ctx context.Context, url string) (string, error) {
+	return "https://example.com", s.err
+}
+
+func TestHTTPMiddleware_ContinuesTraceparent(t *testing.T) {
+	tr, recorder := newTestTracing()
+	st := NewStorage(stubStorage{}, storage.EnginePostgres, tr)
+
+	r := chi.NewRouter()
+	r.Use(tr.HTTPMiddleware)
+	r.Get("/{key}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = st.Get(r.Context(), chi.URLParam(r, "key"))
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+	req.Header.Set("traceparent", traceparent)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	storageSpan, serverSpan := spans[0], spans[1]
+
+	assert.Equal(t, "GET /{key}", serverSpan.Name())
+	assert.Equal(t, parentTraceID, serverSpan.SpanContext().TraceID().String())
+	assert.Equal(t, parentSpanID, serverSpan.Parent().SpanID().String())
+	assert.True(t, serverSpan.Parent().IsRemote())
+
+	assert.Equal(t, "storage.get", storageSpan.Name())
+	assert.Equal(t, serverSpan.SpanContext().SpanID(), storageSpan.Parent().SpanID())
+	assert.Contains(t, storageSpan.Attributes(), attribute.String("db.system.name", "postgresql"))
+}
+
+func TestHTTPMiddleware_StartsNewTrace(t *testing.T) {
+	tr, recorder := newTestTracing()
+	handler := tr.HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.True(t, spans[0].SpanContext().IsValid())
+	assert.False(t, spans[0].Parent().IsValid())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+}
+
+func TestUnaryServerInterceptor_ContinuesTraceparent(t *testing.T) {
+	tr, recorder := newTestTracing()
+	interceptor := tr.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/shortener.Shortener/GetOriginalURL"}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("traceparent", traceparent))
+
+	_, err := interceptor(ctx, nil, info, func(ctx context.Context, req any) (any, error) {
+		return nil, errors.New("boom")
+	})
+	require.Error(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, info.FullMethod, spans[0].Name())
+	assert.Equal(t, parentTraceID, spans[0].SpanContext().TraceID().String())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+}
+
+// contextStream — серверный поток с заданным контекстом
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s contextStream) Context() context.Context { return s.ctx }
+
+func TestStreamServerInterceptor_WrapsStream(t *testing.T) {
+	tr, recorder := newTestTracing()
+	interceptor := tr.StreamServerInterceptor()
+	info := &grpc.StreamServerInfo{FullMethod: "/shortener.Shortener/ImportURLs", IsClientStream: true}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("traceparent", traceparent))
+
+	var handlerSpan trace.SpanContext
+	err := interceptor(nil, contextStream{ctx: ctx}, info, func(srv any, ss grpc.ServerStream) error {
+		handlerSpan = trace.SpanContextFromContext(ss.Context())
+		return nil
+	})
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, info.FullMethod, spans[0].Name())
+	assert.Equal(t, parentTraceID, spans[0].SpanContext().TraceID().String())
+	assert.Equal(t, spans[0].SpanContext().SpanID(), handlerSpan.SpanID(), "handler must see the stream span")
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+}
+
+func TestStorage_ExpectedErrorIsNotFailure(t *testing.T) {
+	tr, recorder := newTestTracing()
+	ctx := context.Background()
+
+	_, err := NewStorage(stubStorage{err: storage.ErrNotFound}, storage.EngineMemory, tr).Get(ctx, "abc")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	_, err = NewStorage(stubStorage{err: errors.New("connection reset")}, storage.EngineMemory, tr).Get(ctx, "abc")
+	assert.Error(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Len(t, spans[0].Events(), 1)
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+}
+
+func TestNewExporter(t *testing.T) {
+	exp, err := NewExporter(ExporterNone, "")
+	require.NoError(t, err)
+	assert.Nil(t, exp)
+
+	_, err = NewExporter("jaeger", "")
+	assert.ErrorContains(t, err, "unknown trace exporter")
+
+	path := filepath.Join(t.TempDir(), "traces.json")
+	exp, err = NewExporter(ExporterFile, path)
+	require.NoError(t, err)
+
+	tr := New(exp)
+	_, span := tr.start(context.Background(), "test")
+	span.End()
+	require.NoError(t, tr.Shutdown(context.Background()))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), span.SpanContext().TraceID().String())
+}
+
+func TestRegisterExporter(t *testing.T) {
+	RegisterExporter("memory", func(string) (sdktrace.SpanExporter, error) {
+		return tracetest.NewInMemoryExporter(), nil
+	})
+
+	exp, err := NewExporter("memory", "")
+	require.NoError(t, err)
+	assert.IsType(t, &tracetest.InMemoryExporter{}, exp)
+}
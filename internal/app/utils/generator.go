@@ -1,14 +1,7 @@
 package utils
 
-import "math/rand"
-
-// CreateShortKey генерирует сокращенную ссылку
+// CreateShortKey генерирует случайный короткий ключ заданной длины
 func CreateShortKey(keyLength int) string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-
-	shortKey := make([]byte, keyLength)
-	for i := range shortKey {
-		shortKey[i] = charset[rand.Intn(len(charset))]
-	}
-	return string(shortKey)
+	key, _ := NewRandomGenerator(keyLength).Generate("", 0)
+	return key
 }
@@ -0,0 +1,274 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strconv"
+	"sync"
+)
+
+// base62 — алфавит коротких ключей
+const base62 = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// Стратегии генерации коротких ключей
+const (
+	// KeyGeneratorRandom — криптографически случайный ключ
+	KeyGeneratorRandom = "random"
+	// KeyGeneratorSequence — порядковый номер в base62
+	KeyGeneratorSequence = "sequence"
+	// KeyGeneratorHashids — обфусцированный порядковый номер в перемешанном солью алфавите
+	KeyGeneratorHashids = "hashids"
+	// KeyGeneratorHash — детерминированный хэш оригинального URL
+	KeyGeneratorHash = "hash"
+)
+
+// DefaultKeyLength — длина ключа, если она не задана в конфигурации
+const DefaultKeyLength = 8
+
+// KeyGenerator генерирует короткие ключи для ссылок.
+// attempt — номер попытки, начиная с нуля; при коллизии ключа сервис
+// повторяет генерацию с увеличенным номером попытки.
+type KeyGenerator interface {
+	Generate(originalURL string, attempt int) (string, error)
+}
+
+// KeyGeneratorOptions задаёт параметры генератора ключей
+type KeyGeneratorOptions struct {
+	// Length — длина ключа; для генераторов на основе счётчика — минимальная длина
+	Length int
+	// Salt — соль для перемешивания алфавита генератора hashids
+	Salt string
+	// Start — начальное значение счётчика для генераторов sequence и hashids
+	Start uint64
+	// Reserve резервирует значения счётчика в постоянном хранилище, чтобы после
+	// перезапуска генератор не выдавал их повторно. Если не задан, счётчик живёт только в памяти.
+	Reserve ReserveFunc
+}
+
+// ReserveFunc атомарно резервирует n значений счётчика, больших floor и всех ранее
+// зарезервированных, и возвращает последнее из них. Вызывающему принадлежат значения (end-n, end].
+type ReserveFunc func(floor, n uint64) (end uint64, err error)
+
+// counterReserveBlock — число значений счётчика, резервируемых за одно обращение к хранилищу
+const counterReserveBlock = 1000
+
+// NewKeyGenerator создаёт генератор ключей по названию стратегии
+func NewKeyGenerator(kind string, opts KeyGeneratorOptions) (KeyGenerator, error) {
+	if opts.Length < 0 {
+		return nil, fmt.Errorf("invalid key length %d", opts.Length)
+	}
+	if opts.Length == 0 {
+		opts.Length = DefaultKeyLength
+	}
+
+	switch kind {
+	case "", KeyGeneratorRandom:
+		return NewRandomGenerator(opts.Length), nil
+	case KeyGeneratorSequence:
+		g := NewSequenceGenerator(opts.Length, opts.Start)
+		g.counter.reserve = opts.Reserve
+		return g, nil
+	case KeyGeneratorHashids:
+		g := NewHashidsGenerator(opts.Length, opts.Salt, opts.Start)
+		g.counter.reserve = opts.Reserve
+		return g, nil
+	case KeyGeneratorHash:
+		return NewHashGenerator(opts.Length), nil
+	default:
+		return nil, fmt.Errorf("unknown key generator %q", kind)
+	}
+}
+
+// RandomGenerator генерирует ключи из криптографически стойкого источника случайных чисел
+type RandomGenerator struct {
+	length int
+}
+
+// NewRandomGenerator создаёт генератор случайных ключей заданной длины
+func NewRandomGenerator(length int) *RandomGenerator {
+	return &RandomGenerator{length: length}
+}
+
+// Generate возвращает новый случайный ключ
+func (g *RandomGenerator) Generate(originalURL string, attempt int) (string, error) {
+	base := big.NewInt(int64(len(base62)))
+	key := make([]byte, g.length)
+	for i := range key {
+		n, err := rand.Int(rand.Reader, base)
+		if err != nil {
+			return "", err
+		}
+		key[i] = base62[n.Int64()]
+	}
+	return string(key), nil
+}
+
+// counter — потокобезопасный счётчик генераторов sequence и hashids.
+// При коллизии счётчик продвигается скачками, растущими с номером попытки,
+// чтобы быстрее проскочить уже занятый диапазон. Если задан reserve,
+// значения выдаются только из зарезервированных в хранилище блоков.
+type counter struct {
+	mu      sync.Mutex
+	value   uint64
+	limit   uint64
+	reserve ReserveFunc
+}
+
+func (c *counter) next(attempt int) (uint64, error) {
+	step := uint64(1)
+	if attempt > 0 && attempt < 32 {
+		step = 1 << attempt
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	value := c.value + step
+	if c.reserve != nil && value > c.limit {
+		n := max(step, counterReserveBlock)
+		end, err := c.reserve(value-1, n)
+		if err != nil {
+			return 0, fmt.Errorf("failed to reserve key counter: %w", err)
+		}
+		// хранилище могло уже выдать значения дальше текущего, тогда блок начинается после них
+		value = max(value, end-n+1)
+		c.limit = end
+	}
+	c.value = value
+	return value, nil
+}
+
+// SequenceGenerator генерирует ключи из возрастающего счётчика в base62
+type SequenceGenerator struct {
+	length  int
+	counter counter
+}
+
+// NewSequenceGenerator создаёт генератор порядковых ключей.
+// Ключи дополняются слева до минимальной длины.
+func NewSequenceGenerator(length int, start uint64) *SequenceGenerator {
+	g := &SequenceGenerator{length: length}
+	g.counter.value = start
+	return g
+}
+
+// Generate возвращает ключ для следующего значения счётчика
+func (g *SequenceGenerator) Generate(originalURL string, attempt int) (string, error) {
+	n, err := g.counter.next(attempt)
+	if err != nil {
+		return "", err
+	}
+	return encodeBase62(n, base62, g.length), nil
+}
+
+// HashidsGenerator генерирует из счётчика неугадываемые ключи: значение
+// счётчика перемешивается обратимым умножением и кодируется алфавитом,
+// перемешанным солью. Разные значения счётчика дают разные ключи.
+type HashidsGenerator struct {
+	length   int
+	alphabet string
+	counter  counter
+}
+
+// hashidsPrime — множитель, взаимно простой с 62, поэтому умножение по модулю 62^n обратимо
+const hashidsPrime = 1580030173
+
+// NewHashidsGenerator создаёт генератор обфусцированных ключей
+func NewHashidsGenerator(length int, salt string, start uint64) *HashidsGenerator {
+	g := &HashidsGenerator{length: length, alphabet: shuffleAlphabet(base62, salt)}
+	g.counter.value = start
+	return g
+}
+
+// Generate возвращает ключ для следующего значения счётчика
+func (g *HashidsGenerator) Generate(originalURL string, attempt int) (string, error) {
+	n, err := g.counter.next(attempt)
+	if err != nil {
+		return "", err
+	}
+
+	// пространство ключей 62^length, при исчерпании длина ключа растёт
+	length := g.length
+	space := new(big.Int).Exp(big.NewInt(62), big.NewInt(int64(length)), nil)
+	value := new(big.Int).SetUint64(n)
+	for value.Cmp(space) >= 0 {
+		length++
+		space.Mul(space, big.NewInt(62))
+	}
+
+	value.Mul(value, big.NewInt(hashidsPrime)).Mod(value, space)
+	return encodeBigBase62(value, g.alphabet, length), nil
+}
+
+// HashGenerator генерирует детерминированный ключ из SHA-256 оригинального URL.
+// Один и тот же URL всегда получает один и тот же ключ на первой попытке.
+type HashGenerator struct {
+	length int
+}
+
+// NewHashGenerator создаёт генератор ключей на основе хэша
+func NewHashGenerator(length int) *HashGenerator {
+	return &HashGenerator{length: length}
+}
+
+// Generate возвращает ключ из хэша URL; при повторных попытках к URL добавляется номер попытки
+func (g *HashGenerator) Generate(originalURL string, attempt int) (string, error) {
+	data := originalURL
+	if attempt > 0 {
+		data += "#" + strconv.Itoa(attempt)
+	}
+	sum := sha256.Sum256([]byte(data))
+	value := new(big.Int).SetBytes(sum[:])
+	key := encodeBigBase62(value, base62, g.length)
+	return key[len(key)-g.length:], nil
+}
+
+// encodeBase62 кодирует число алфавитом, дополняя результат до минимальной длины
+func encodeBase62(n uint64, alphabet string, minLength int) string {
+	return encodeBigBase62(new(big.Int).SetUint64(n), alphabet, minLength)
+}
+
+// encodeBigBase62 кодирует произвольное неотрицательное число алфавитом,
+// дополняя результат первым символом алфавита до минимальной длины
+func encodeBigBase62(n *big.Int, alphabet string, minLength int) string {
+	base := big.NewInt(int64(len(alphabet)))
+	value := new(big.Int).Set(n)
+	mod := new(big.Int)
+
+	var key []byte
+	for value.Sign() > 0 {
+		value.DivMod(value, base, mod)
+		key = append(key, alphabet[mod.Int64()])
+	}
+	for len(key) < minLength {
+		key = append(key, alphabet[0])
+	}
+
+	for i, j := 0, len(key)-1; i < j; i, j = i+1, j-1 {
+		key[i], key[j] = key[j], key[i]
+	}
+	return string(key)
+}
+
+// shuffleAlphabet детерминированно перемешивает алфавит солью
+func shuffleAlphabet(alphabet, salt string) string {
+	if salt == "" {
+		return alphabet
+	}
+	sum := sha256.Sum256([]byte(salt))
+	seed := binary.BigEndian.Uint64(sum[:8])
+
+	result := []byte(alphabet)
+	for i := len(result) - 1; i > 0; i-- {
+		// xorshift64
+		seed ^= seed << 13
+		seed ^= seed >> 7
+		seed ^= seed << 17
+		j := int(seed % uint64(i+1))
+		result[i], result[j] = result[j], result[i]
+	}
+	return string(result)
+}
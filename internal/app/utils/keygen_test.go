@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewKeyGenerator(t *testing.T) {
+	for _, kind := range []string{"", KeyGeneratorRandom, KeyGeneratorSequence, KeyGeneratorHashids, KeyGeneratorHash} {
+		t.Run(kind, func(t *testing.T) {
+			gen, err := NewKeyGenerator(kind, KeyGeneratorOptions{Length: 6, Salt: "salt"})
+			require.NoError(t, err)
+
+			key, err := gen.Generate("https://example.com", 0)
+			require.NoError(t, err)
+			assert.Len(t, key, 6)
+		})
+	}
+
+	_, err := NewKeyGenerator("uuid", KeyGeneratorOptions{Length: 6})
+	assert.Error(t, err)
+}
+
+func TestCounterGenerators_Unique(t *testing.T) {
+	generators := map[string]KeyGenerator{
+		KeyGeneratorSequence: NewSequenceGenerator(2, 0),
+		KeyGeneratorHashids:  NewHashidsGenerator(2, "salt", 0),
+	}
+	for name, gen := range generators {
+		t.Run(name, func(t *testing.T) {
+			// 62^2 ключей помещаются в минимальную длину, дальше длина растёт
+			seen := make(map[string]struct{})
+			for i := 0; i < 62*62+100; i++ {
+				key, err := gen.Generate("", 0)
+				require.NoError(t, err)
+				_, dup := seen[key]
+				require.False(t, dup, "duplicate key %s", key)
+				seen[key] = struct{}{}
+			}
+		})
+	}
+}
+
+func TestSequenceGenerator(t *testing.T) {
+	gen := NewSequenceGenerator(3, 60)
+
+	key, _ := gen.Generate("", 0)
+	assert.Equal(t, "aa9", key)
+	key, _ = gen.Generate("", 0)
+	assert.Equal(t, "aba", key)
+
+	// при коллизии счётчик перескакивает вперёд
+	key, _ = gen.Generate("", 2)
+	assert.Equal(t, "abe", key)
+}
+
+func TestSequenceGenerator_Reserve(t *testing.T) {
+	var stored uint64
+	calls := 0
+	reserve := func(floor, n uint64) (uint64, error) {
+		calls++
+		stored = max(stored, floor) + n
+		return stored, nil
+	}
+
+	gen, err := NewKeyGenerator(KeyGeneratorSequence, KeyGeneratorOptions{Length: 1, Reserve: reserve})
+	require.NoError(t, err)
+	for range counterReserveBlock {
+		_, err := gen.Generate("", 0)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, calls, "values must be reserved in blocks")
+	assert.Equal(t, uint64(counterReserveBlock), stored)
+
+	// после перезапуска генератор продолжает после зарезервированного блока,
+	// даже если начальное значение меньше
+	restarted, err := NewKeyGenerator(KeyGeneratorSequence, KeyGeneratorOptions{Length: 1, Start: 10, Reserve: reserve})
+	require.NoError(t, err)
+	key, err := restarted.Generate("", 0)
+	require.NoError(t, err)
+	assert.Equal(t, encodeBase62(counterReserveBlock+1, base62, 1), key)
+
+	failing, err := NewKeyGenerator(KeyGeneratorHashids, KeyGeneratorOptions{Reserve: func(floor, n uint64) (uint64, error) {
+		return 0, errors.New("storage is down")
+	}})
+	require.NoError(t, err)
+	_, err = failing.Generate("", 0)
+	assert.Error(t, err)
+}
+
+func TestHashidsGenerator_Salt(t *testing.T) {
+	a, _ := NewHashidsGenerator(8, "one", 0).Generate("", 0)
+	b, _ := NewHashidsGenerator(8, "two", 0).Generate("", 0)
+	assert.NotEqual(t, a, b)
+}
+
+func TestHashGenerator(t *testing.T) {
+	gen := NewHashGenerator(8)
+
+	first, _ := gen.Generate("https://example.com", 0)
+	again, _ := gen.Generate("https://example.com", 0)
+	retry, _ := gen.Generate("https://example.com", 1)
+	other, _ := gen.Generate("https://example.org", 0)
+
+	assert.Equal(t, first, again)
+	assert.NotEqual(t, first, retry)
+	assert.NotEqual(t, first, other)
+}
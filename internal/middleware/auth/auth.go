@@ -4,10 +4,8 @@ import (
 	"context"
 	"errors"
 	"net/http"
-	"os"
 	"time"
 
-	"github.com/golang-jwt/jwt/v4"
 	"github.com/issafronov/shortener/internal/app/contextkeys"
 	"github.com/issafronov/shortener/internal/app/security"
 	"github.com/issafronov/shortener/internal/app/utils"
@@ -20,11 +18,11 @@ func AuthorizationMiddleware(next http.Handler) http.Handler {
 		tokenString, err := r.Cookie("JWT_TOKEN")
 		var userID string
 		if err != nil {
-			logger.Log.Debug("AuthorizationMiddleware: no JWT_TOKEN cookie")
+			logger.FromContext(r.Context()).Debug("AuthorizationMiddleware: no JWT_TOKEN cookie")
 			userID = utils.CreateShortKey(10)
 			signedCookie, err := security.GenerateJWT(userID)
 			if err != nil {
-				logger.Log.Info("AuthorizationMiddleware: error generating JWT token")
+				logger.FromContext(r.Context()).Info("AuthorizationMiddleware: error generating JWT token")
 				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 				return
 			}
@@ -35,29 +33,22 @@ func AuthorizationMiddleware(next http.Handler) http.Handler {
 			}
 			http.SetCookie(w, cookieValue)
 		} else {
-			claims := &security.Claims{}
-			secret := os.Getenv("SECRET_KEY")
-			if secret == "" {
-				secret = "secret"
-			}
-			token, err := jwt.ParseWithClaims(tokenString.Value, claims, func(token *jwt.Token) (interface{}, error) {
-				return []byte(secret), nil
-			})
+			claims, err := security.ParseJWT(tokenString.Value)
 			if err != nil {
-				if errors.Is(err, jwt.ErrTokenExpired) {
-					logger.Log.Debug("AuthorizationMiddleware: token expired")
+				if errors.Is(err, security.ErrTokenExpired) {
+					logger.FromContext(r.Context()).Debug("AuthorizationMiddleware: token expired")
+					http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+					return
+				}
+				if errors.Is(err, security.ErrInvalidToken) {
+					logger.FromContext(r.Context()).Debug("AuthorizationMiddleware: invalid JWT token")
 					http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 					return
 				}
-				logger.Log.Debug("AuthorizationMiddleware: error parsing JWT token")
+				logger.FromContext(r.Context()).Debug("AuthorizationMiddleware: error parsing JWT token")
 				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 				return
 			}
-			if !token.Valid {
-				logger.Log.Debug("AuthorizationMiddleware: invalid JWT token")
-				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
-				return
-			}
 			userID = claims.UserID
 		}
 		ctx := context.WithValue(r.Context(), contextkeys.UserIDKey, userID)
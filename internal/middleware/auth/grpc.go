@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/issafronov/shortener/internal/app/contextkeys"
+	"github.com/issafronov/shortener/internal/app/security"
+	"github.com/issafronov/shortener/internal/app/utils"
+	"github.com/issafronov/shortener/internal/middleware/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// AuthorizationMetadataKey — ключ метаданных gRPC с bearer JWT-токеном.
+// Токен тот же, что HTTP-сервер выдаёт в cookie JWT_TOKEN.
+const AuthorizationMetadataKey = "authorization"
+
+// bearerPrefix — схема авторизации в значении метаданных authorization
+const bearerPrefix = "Bearer "
+
+// UnaryServerInterceptor аутентифицирует unary-вызовы gRPC по bearer JWT-токену.
+// Анонимному вызывающему создаётся новый пользователь, а его токен возвращается в заголовке ответа.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		authCtx, err := authenticate(ctx, func(md metadata.MD) error {
+			return grpc.SetHeader(ctx, md)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return handler(authCtx, req)
+	}
+}
+
+// StreamServerInterceptor аутентифицирует потоковые вызовы gRPC так же, как UnaryServerInterceptor
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), ss.SetHeader)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticatedStream подменяет контекст потока контекстом с идентификатором пользователя
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context возвращает контекст потока с идентификатором пользователя
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// authenticate проверяет токен из метаданных вызова и кладёт идентификатор пользователя в контекст.
+// Если токена нет, выдаёт новый и передаёт его в setHeader.
+func authenticate(ctx context.Context, setHeader func(metadata.MD) error) (context.Context, error) {
+	log := logger.FromContext(ctx)
+
+	var values []string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		values = md.Get(AuthorizationMetadataKey)
+	}
+
+	if len(values) == 0 {
+		log.Debug("gRPC auth: no authorization metadata")
+		userID := utils.CreateShortKey(10)
+		token, err := security.GenerateJWT(userID)
+		if err != nil {
+			log.Info("gRPC auth: error generating JWT token")
+			return nil, status.Error(codes.Internal, "failed to issue token")
+		}
+		if err := setHeader(metadata.Pairs(AuthorizationMetadataKey, bearerPrefix+token)); err != nil {
+			return nil, status.Error(codes.Internal, "failed to issue token")
+		}
+		return context.WithValue(ctx, contextkeys.UserIDKey, userID), nil
+	}
+
+	token, ok := strings.CutPrefix(values[0], bearerPrefix)
+	if !ok || token == "" {
+		log.Debug("gRPC auth: malformed authorization metadata")
+		return nil, status.Error(codes.Unauthenticated, "authorization must be a bearer token")
+	}
+
+	claims, err := security.ParseJWT(token)
+	if err != nil {
+		if errors.Is(err, security.ErrTokenExpired) {
+			log.Debug("gRPC auth: token expired")
+			return nil, status.Error(codes.Unauthenticated, "token expired")
+		}
+		log.Debug("gRPC auth: invalid JWT token")
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+	if claims.UserID == "" {
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+
+	return context.WithValue(ctx, contextkeys.UserIDKey, claims.UserID), nil
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/issafronov/shortener/internal/app/contextkeys"
+	"github.com/issafronov/shortener/internal/app/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// headerStream — серверный поток, запоминающий заголовки ответа
+type headerStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	header metadata.MD
+}
+
+func (s *headerStream) Context() context.Context { return s.ctx }
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+// callStream выполняет потоковый вызов через интерцептор и возвращает пользователя из контекста обработчика
+func callStream(t *testing.T, ctx context.Context) (*headerStream, string, error) {
+	t.Helper()
+	stream := &headerStream{ctx: ctx}
+	var userID string
+	err := StreamServerInterceptor()(nil, stream, &grpc.StreamServerInfo{}, func(srv any, ss grpc.ServerStream) error {
+		userID, _ = ss.Context().Value(contextkeys.UserIDKey).(string)
+		return nil
+	})
+	return stream, userID, err
+}
+
+func TestStreamServerInterceptor_IssuesTokenForAnonymous(t *testing.T) {
+	t.Setenv("SECRET_KEY", "testsecret")
+
+	stream, userID, err := callStream(t, context.Background())
+	require.NoError(t, err)
+	assert.NotEmpty(t, userID)
+
+	values := stream.header.Get(AuthorizationMetadataKey)
+	require.Len(t, values, 1)
+	claims, err := security.ParseJWT(strings.TrimPrefix(values[0], bearerPrefix))
+	require.NoError(t, err)
+	assert.Equal(t, userID, claims.UserID)
+}
+
+func TestStreamServerInterceptor_UsesBearerToken(t *testing.T) {
+	t.Setenv("SECRET_KEY", "testsecret")
+	token, err := security.GenerateJWT("user1")
+	require.NoError(t, err)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(AuthorizationMetadataKey, bearerPrefix+token))
+	stream, userID, err := callStream(t, ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "user1", userID)
+	assert.Empty(t, stream.header)
+}
+
+func TestUnaryServerInterceptor_RejectsInvalidToken(t *testing.T) {
+	t.Setenv("SECRET_KEY", "testsecret")
+	interceptor := UnaryServerInterceptor()
+	handler := func(ctx context.Context, req any) (any, error) {
+		t.Fatal("handler must not be called")
+		return nil, nil
+	}
+
+	for _, value := range []string{"Bearer garbage", "Basic dXNlcjpwYXNz", "Bearer "} {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(AuthorizationMetadataKey, value))
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err), value)
+	}
+}
@@ -67,6 +67,15 @@ func (c *compressWriter) WriteHeader(statusCode int) {
 	c.wroteHeader = true
 }
 
+// Flush отправляет клиенту уже сжатые данные, не завершая поток gzip.
+// Нужен потоковым ответам, которые пишутся частями.
+func (c *compressWriter) Flush() {
+	if c.compress {
+		_ = c.zw.Flush()
+	}
+	_ = http.NewResponseController(c.w).Flush()
+}
+
 // Close закрывает и возвращает в пул
 func (c *compressWriter) Close() error {
 	if !c.compress {
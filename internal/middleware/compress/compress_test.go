@@ -117,3 +117,30 @@ func TestGzipMiddleware_BadGzipInput(t *testing.T) {
 		t.Errorf("expected status 500, got %d", res.StatusCode)
 	}
 }
+
+// Тестирует, что Flush отправляет клиенту уже записанную часть сжатого ответа
+func TestGzipMiddleware_Flush(t *testing.T) {
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first line\n"))
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Fatalf("flush failed: %v", err)
+		}
+
+		if !rr.Flushed {
+			t.Fatal("expected response to be flushed")
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(rr.Body.Bytes()))
+		if err != nil {
+			t.Fatalf("failed to read flushed gzip: %v", err)
+		}
+		line := make([]byte, len("first line\n"))
+		if _, err := io.ReadFull(zr, line); err != nil || string(line) != "first line\n" {
+			t.Errorf("unexpected flushed body %q: %v", line, err)
+		}
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	GzipMiddleware(handler).ServeHTTP(rr, req)
+}
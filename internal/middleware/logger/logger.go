@@ -1,9 +1,11 @@
 package logger
 
 import (
+	"context"
 	"net/http"
 	"time"
 
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
@@ -40,6 +42,12 @@ func (r *loggingResponseWriter) WriteHeader(statusCode int) {
 	r.responseData.status = statusCode // захватываем код статуса
 }
 
+// Unwrap возвращает исходный http.ResponseWriter, чтобы http.ResponseController
+// мог сбрасывать потоковые ответы
+func (r *loggingResponseWriter) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
 // Initialize настраивает глобальный логгер Log в соответствии с уровнем логирования
 func Initialize(level string) error {
 	lvl, err := zap.ParseAtomicLevel(level)
@@ -59,6 +67,19 @@ func Initialize(level string) error {
 	return nil
 }
 
+// FromContext возвращает логгер Log, дополненный идентификаторами трассы и спана из ctx.
+// Если в контексте нет трассы, возвращается Log без изменений.
+func FromContext(ctx context.Context) *zap.Logger {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return Log
+	}
+	return Log.With(
+		zap.String("trace_id", sc.TraceID().String()),
+		zap.String("span_id", sc.SpanID().String()),
+	)
+}
+
 // RequestLogger — middleware, логирующий HTTP-запросы и ответы
 func RequestLogger(next http.Handler) http.Handler {
 	fn := func(w http.ResponseWriter, r *http.Request) {
@@ -76,7 +97,7 @@ func RequestLogger(next http.Handler) http.Handler {
 
 		duration := time.Since(start)
 
-		Log.Debug("got incoming HTTP request",
+		FromContext(r.Context()).Debug("got incoming HTTP request",
 			zap.String("uri", r.RequestURI),
 			zap.String("method", r.Method),
 			zap.Int("status", responseData.status),
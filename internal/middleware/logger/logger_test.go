@@ -2,11 +2,14 @@ package logger
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -137,3 +140,25 @@ func TestLoggingResponseWriter_WriteHeader(t *testing.T) {
 		t.Errorf("responseData.status = %d, want %d", lrw.responseData.status, status)
 	}
 }
+
+func TestFromContext_AddsTraceID(t *testing.T) {
+	var buf bytes.Buffer
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(&buf), zap.DebugLevel)
+	Log = zap.New(core)
+	defer func() { Log = zap.NewNop() }()
+
+	FromContext(context.Background()).Info("no trace")
+	assert.NotContains(t, buf.String(), "trace_id")
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+
+	buf.Reset()
+	FromContext(ctx).Info("with trace")
+	assert.Contains(t, buf.String(), `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`)
+	assert.Contains(t, buf.String(), `"span_id":"00f067aa0ba902b7"`)
+}
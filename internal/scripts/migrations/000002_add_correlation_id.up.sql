@@ -0,0 +1 @@
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS correlation_id TEXT NOT NULL DEFAULT '';
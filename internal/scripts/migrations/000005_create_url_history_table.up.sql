@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS url_history (
+    id BIGSERIAL PRIMARY KEY,
+    short_url TEXT NOT NULL,
+    version INTEGER NOT NULL,
+    original_url TEXT NOT NULL,
+    previous_url TEXT NOT NULL,
+    changed_by TEXT NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (short_url, version)
+);
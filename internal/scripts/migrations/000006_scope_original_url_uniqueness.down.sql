@@ -0,0 +1,9 @@
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM urls GROUP BY original_url HAVING COUNT(*) > 1) THEN
+        RAISE EXCEPTION 'cannot restore UNIQUE (original_url): urls contains duplicate original URLs, delete the duplicates before rolling back';
+    END IF;
+END $$;
+DROP INDEX IF EXISTS urls_dedup_scope_original_url_idx;
+ALTER TABLE urls DROP COLUMN IF EXISTS dedup_scope;
+ALTER TABLE urls ADD CONSTRAINT urls_original_url_key UNIQUE (original_url);
@@ -0,0 +1,3 @@
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS dedup_scope TEXT NOT NULL DEFAULT '';
+ALTER TABLE urls DROP CONSTRAINT IF EXISTS urls_original_url_key;
+CREATE UNIQUE INDEX IF NOT EXISTS urls_dedup_scope_original_url_idx ON urls (dedup_scope, original_url) WHERE is_deleted = FALSE;
@@ -0,0 +1,4 @@
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+UPDATE urls SET deleted_at = now() WHERE is_deleted = TRUE AND deleted_at IS NULL;
@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS key_counters (
+    name TEXT PRIMARY KEY,
+    value BIGINT NOT NULL
+);
@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS storage_settings (
+    name TEXT PRIMARY KEY,
+    value TEXT NOT NULL
+);
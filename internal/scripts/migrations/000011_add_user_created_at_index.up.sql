@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS urls_user_id_created_at_idx ON urls (user_id, created_at, id);
@@ -1,26 +1,45 @@
 package scripts
 
 import (
+	"embed"
 	"errors"
 	"fmt"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 	"github.com/issafronov/shortener/internal/middleware/logger"
 	"go.uber.org/zap"
 )
 
+// migrations содержит SQL-миграции, встроенные в бинарный файл,
+// поэтому приложение не зависит от рабочей директории
+//
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// NewMigrate возвращает мигратор базы данных databaseURI со встроенными миграциями.
+// Вызывающий должен закрыть его методом Close.
+func NewMigrate(databaseURI string) (*migrate.Migrate, error) {
+	source, err := iofs.New(migrations, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+	m, err := migrate.NewWithSourceInstance("iofs", source, databaseURI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init migrate: %w", err)
+	}
+	return m, nil
+}
+
 // RunMigrations запускает миграции
 func RunMigrations(databaseURI string) error {
-	m, err := migrate.New(
-		"file://internal/scripts/migrations",
-		databaseURI,
-	)
+	m, err := NewMigrate(databaseURI)
 	if err != nil {
 		logger.Log.Error("failed to initialize migrate", zap.Error(err))
-		return fmt.Errorf("failed to init migrate: %w", err)
+		return err
 	}
+	defer m.Close()
 
 	err = m.Up()
 	if err != nil {
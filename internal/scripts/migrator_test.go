@@ -0,0 +1,35 @@
+package scripts
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrationsEmbedded(t *testing.T) {
+	files, err := fs.Glob(migrations, "migrations/*.sql")
+	require.NoError(t, err)
+	assert.NotEmpty(t, files)
+	assert.Zero(t, len(files)%2, "every migration must have up and down files")
+
+	source, err := iofs.New(migrations, "migrations")
+	require.NoError(t, err)
+	defer source.Close()
+
+	first, err := source.First()
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), first)
+
+	count := 1
+	for version := first; ; count++ {
+		next, err := source.Next(version)
+		if err != nil {
+			break
+		}
+		version = next
+	}
+	assert.Equal(t, len(files)/2, count)
+}
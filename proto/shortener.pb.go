@@ -9,6 +9,7 @@ package proto
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -22,8 +23,14 @@ const (
 )
 
 type CreateShortURLRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Url           string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Url   string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	// Необязательный пользовательский короткий ключ
+	Alias string `protobuf:"bytes,2,opt,name=alias,proto3" json:"alias,omitempty"`
+	// Необязательный момент истечения срока действия ссылки
+	ExpiresAt *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	// Необязательный срок действия ссылки в секундах
+	TtlSeconds    int64 `protobuf:"varint,4,opt,name=ttl_seconds,json=ttlSeconds,proto3" json:"ttl_seconds,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -65,6 +72,27 @@ func (x *CreateShortURLRequest) GetUrl() string {
 	return ""
 }
 
+func (x *CreateShortURLRequest) GetAlias() string {
+	if x != nil {
+		return x.Alias
+	}
+	return ""
+}
+
+func (x *CreateShortURLRequest) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+func (x *CreateShortURLRequest) GetTtlSeconds() int64 {
+	if x != nil {
+		return x.TtlSeconds
+	}
+	return 0
+}
+
 type ShortURLResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Result        string                 `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
@@ -110,9 +138,14 @@ func (x *ShortURLResponse) GetResult() string {
 }
 
 type CreateShortURLBatchRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Urls          []*BatchURLData        `protobuf:"bytes,1,rep,name=urls,proto3" json:"urls,omitempty"`
-	UserId        string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Urls  []*BatchURLData        `protobuf:"bytes,1,rep,name=urls,proto3" json:"urls,omitempty"`
+	// Устарело: пользователь определяется по JWT из метаданных authorization, значение игнорируется
+	//
+	// Deprecated: Marked as deprecated in proto/shortener.proto.
+	UserId string `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// atomic (по умолчанию) или best_effort
+	Mode          string `protobuf:"bytes,3,opt,name=mode,proto3" json:"mode,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -154,6 +187,7 @@ func (x *CreateShortURLBatchRequest) GetUrls() []*BatchURLData {
 	return nil
 }
 
+// Deprecated: Marked as deprecated in proto/shortener.proto.
 func (x *CreateShortURLBatchRequest) GetUserId() string {
 	if x != nil {
 		return x.UserId
@@ -161,6 +195,13 @@ func (x *CreateShortURLBatchRequest) GetUserId() string {
 	return ""
 }
 
+func (x *CreateShortURLBatchRequest) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
 type CreateShortURLBatchResponse struct {
 	state         protoimpl.MessageState  `protogen:"open.v1"`
 	Urls          []*BatchURLDataResponse `protobuf:"bytes,1,rep,name=urls,proto3" json:"urls,omitempty"`
@@ -209,6 +250,12 @@ type BatchURLData struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	CorrelationId string                 `protobuf:"bytes,1,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
 	OriginalUrl   string                 `protobuf:"bytes,2,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	// Необязательный пользовательский короткий ключ
+	Alias string `protobuf:"bytes,3,opt,name=alias,proto3" json:"alias,omitempty"`
+	// Необязательный момент истечения срока действия ссылки
+	ExpiresAt *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	// Необязательный срок действия ссылки в секундах
+	TtlSeconds    int64 `protobuf:"varint,5,opt,name=ttl_seconds,json=ttlSeconds,proto3" json:"ttl_seconds,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -257,10 +304,35 @@ func (x *BatchURLData) GetOriginalUrl() string {
 	return ""
 }
 
+func (x *BatchURLData) GetAlias() string {
+	if x != nil {
+		return x.Alias
+	}
+	return ""
+}
+
+func (x *BatchURLData) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+func (x *BatchURLData) GetTtlSeconds() int64 {
+	if x != nil {
+		return x.TtlSeconds
+	}
+	return 0
+}
+
 type BatchURLDataResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	CorrelationId string                 `protobuf:"bytes,1,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
 	ShortUrl      string                 `protobuf:"bytes,2,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
+	// created, existing или invalid
+	Status string `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	// Причина, по которой запись не обработана, для статуса invalid
+	Error         string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -309,6 +381,20 @@ func (x *BatchURLDataResponse) GetShortUrl() string {
 	return ""
 }
 
+func (x *BatchURLDataResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *BatchURLDataResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
 type GetOriginalURLRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	ShortUrl      string                 `protobuf:"bytes,1,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
@@ -398,10 +484,22 @@ func (x *OriginalURLResponse) GetOriginalUrl() string {
 }
 
 type UserIDRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Устарело: пользователь определяется по JWT из метаданных authorization, значение игнорируется
+	//
+	// Deprecated: Marked as deprecated in proto/shortener.proto.
+	UserId string `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// Максимальное количество ссылок на странице, не больше 1000; 0 — все ссылки, а вместе с cursor — 100 ссылок
+	Limit int32 `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	// Курсор, полученный вместе с предыдущей страницей
+	Cursor string `protobuf:"bytes,3,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	// Подстрока, которую должен содержать оригинальный URL
+	Query string `protobuf:"bytes,4,opt,name=query,proto3" json:"query,omitempty"`
+	// Порядок по времени создания: created_asc или created_desc
+	Sort           string `protobuf:"bytes,5,opt,name=sort,proto3" json:"sort,omitempty"`
+	IncludeDeleted bool   `protobuf:"varint,6,opt,name=include_deleted,json=includeDeleted,proto3" json:"include_deleted,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *UserIDRequest) Reset() {
@@ -434,6 +532,7 @@ func (*UserIDRequest) Descriptor() ([]byte, []int) {
 	return file_proto_shortener_proto_rawDescGZIP(), []int{8}
 }
 
+// Deprecated: Marked as deprecated in proto/shortener.proto.
 func (x *UserIDRequest) GetUserId() string {
 	if x != nil {
 		return x.UserId
@@ -441,9 +540,46 @@ func (x *UserIDRequest) GetUserId() string {
 	return ""
 }
 
+func (x *UserIDRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *UserIDRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *UserIDRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *UserIDRequest) GetSort() string {
+	if x != nil {
+		return x.Sort
+	}
+	return ""
+}
+
+func (x *UserIDRequest) GetIncludeDeleted() bool {
+	if x != nil {
+		return x.IncludeDeleted
+	}
+	return false
+}
+
 type UserURLsResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Urls          []*UserURL             `protobuf:"bytes,1,rep,name=urls,proto3" json:"urls,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Urls  []*UserURL             `protobuf:"bytes,1,rep,name=urls,proto3" json:"urls,omitempty"`
+	// Курсор следующей страницы, пустой на последней странице
+	NextCursor    string `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -485,10 +621,18 @@ func (x *UserURLsResponse) GetUrls() []*UserURL {
 	return nil
 }
 
+func (x *UserURLsResponse) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
 type UserURL struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	ShortUrl      string                 `protobuf:"bytes,1,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
 	OriginalUrl   string                 `protobuf:"bytes,2,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	IsDeleted     bool                   `protobuf:"varint,3,opt,name=is_deleted,json=isDeleted,proto3" json:"is_deleted,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -537,10 +681,20 @@ func (x *UserURL) GetOriginalUrl() string {
 	return ""
 }
 
+func (x *UserURL) GetIsDeleted() bool {
+	if x != nil {
+		return x.IsDeleted
+	}
+	return false
+}
+
 type DeleteUserURLsRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	ShortUrlIds   []string               `protobuf:"bytes,2,rep,name=short_url_ids,json=shortUrlIds,proto3" json:"short_url_ids,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Устарело: пользователь определяется по JWT из метаданных authorization, значение игнорируется
+	//
+	// Deprecated: Marked as deprecated in proto/shortener.proto.
+	UserId        string   `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	ShortUrlIds   []string `protobuf:"bytes,2,rep,name=short_url_ids,json=shortUrlIds,proto3" json:"short_url_ids,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -575,6 +729,7 @@ func (*DeleteUserURLsRequest) Descriptor() ([]byte, []int) {
 	return file_proto_shortener_proto_rawDescGZIP(), []int{11}
 }
 
+// Deprecated: Marked as deprecated in proto/shortener.proto.
 func (x *DeleteUserURLsRequest) GetUserId() string {
 	if x != nil {
 		return x.UserId
@@ -590,8 +745,10 @@ func (x *DeleteUserURLsRequest) GetShortUrlIds() []string {
 }
 
 type DeleteUserURLsResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Success bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	// Идентификатор задачи удаления для GetDeleteJob
+	JobId         string `protobuf:"bytes,2,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -633,6 +790,195 @@ func (x *DeleteUserURLsResponse) GetSuccess() bool {
 	return false
 }
 
+func (x *DeleteUserURLsResponse) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+type DeleteJobRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteJobRequest) Reset() {
+	*x = DeleteJobRequest{}
+	mi := &file_proto_shortener_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteJobRequest) ProtoMessage() {}
+
+func (x *DeleteJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteJobRequest.ProtoReflect.Descriptor instead.
+func (*DeleteJobRequest) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *DeleteJobRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+type DeleteOutcome struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	ShortUrl string                 `protobuf:"bytes,1,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
+	// deleted, not_found или not_owned
+	Outcome       string `protobuf:"bytes,2,opt,name=outcome,proto3" json:"outcome,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteOutcome) Reset() {
+	*x = DeleteOutcome{}
+	mi := &file_proto_shortener_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteOutcome) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteOutcome) ProtoMessage() {}
+
+func (x *DeleteOutcome) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteOutcome.ProtoReflect.Descriptor instead.
+func (*DeleteOutcome) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *DeleteOutcome) GetShortUrl() string {
+	if x != nil {
+		return x.ShortUrl
+	}
+	return ""
+}
+
+func (x *DeleteOutcome) GetOutcome() string {
+	if x != nil {
+		return x.Outcome
+	}
+	return ""
+}
+
+type DeleteJobResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	JobId string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	// pending, running, done или failed
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	Results       []*DeleteOutcome       `protobuf:"bytes,3,rep,name=results,proto3" json:"results,omitempty"`
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteJobResponse) Reset() {
+	*x = DeleteJobResponse{}
+	mi := &file_proto_shortener_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteJobResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteJobResponse) ProtoMessage() {}
+
+func (x *DeleteJobResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteJobResponse.ProtoReflect.Descriptor instead.
+func (*DeleteJobResponse) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *DeleteJobResponse) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *DeleteJobResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *DeleteJobResponse) GetResults() []*DeleteOutcome {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *DeleteJobResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *DeleteJobResponse) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *DeleteJobResponse) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
 type PingRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
@@ -641,7 +987,7 @@ type PingRequest struct {
 
 func (x *PingRequest) Reset() {
 	*x = PingRequest{}
-	mi := &file_proto_shortener_proto_msgTypes[13]
+	mi := &file_proto_shortener_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -653,7 +999,7 @@ func (x *PingRequest) String() string {
 func (*PingRequest) ProtoMessage() {}
 
 func (x *PingRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_shortener_proto_msgTypes[13]
+	mi := &file_proto_shortener_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -666,7 +1012,7 @@ func (x *PingRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PingRequest.ProtoReflect.Descriptor instead.
 func (*PingRequest) Descriptor() ([]byte, []int) {
-	return file_proto_shortener_proto_rawDescGZIP(), []int{13}
+	return file_proto_shortener_proto_rawDescGZIP(), []int{16}
 }
 
 type PingResponse struct {
@@ -678,7 +1024,7 @@ type PingResponse struct {
 
 func (x *PingResponse) Reset() {
 	*x = PingResponse{}
-	mi := &file_proto_shortener_proto_msgTypes[14]
+	mi := &file_proto_shortener_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -690,7 +1036,7 @@ func (x *PingResponse) String() string {
 func (*PingResponse) ProtoMessage() {}
 
 func (x *PingResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_shortener_proto_msgTypes[14]
+	mi := &file_proto_shortener_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -703,7 +1049,7 @@ func (x *PingResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PingResponse.ProtoReflect.Descriptor instead.
 func (*PingResponse) Descriptor() ([]byte, []int) {
-	return file_proto_shortener_proto_rawDescGZIP(), []int{14}
+	return file_proto_shortener_proto_rawDescGZIP(), []int{17}
 }
 
 func (x *PingResponse) GetStatus() string {
@@ -721,7 +1067,7 @@ type GetStatsRequest struct {
 
 func (x *GetStatsRequest) Reset() {
 	*x = GetStatsRequest{}
-	mi := &file_proto_shortener_proto_msgTypes[15]
+	mi := &file_proto_shortener_proto_msgTypes[18]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -733,7 +1079,7 @@ func (x *GetStatsRequest) String() string {
 func (*GetStatsRequest) ProtoMessage() {}
 
 func (x *GetStatsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_shortener_proto_msgTypes[15]
+	mi := &file_proto_shortener_proto_msgTypes[18]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -746,7 +1092,7 @@ func (x *GetStatsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetStatsRequest.ProtoReflect.Descriptor instead.
 func (*GetStatsRequest) Descriptor() ([]byte, []int) {
-	return file_proto_shortener_proto_rawDescGZIP(), []int{15}
+	return file_proto_shortener_proto_rawDescGZIP(), []int{18}
 }
 
 type GetStatsResponse struct {
@@ -759,7 +1105,7 @@ type GetStatsResponse struct {
 
 func (x *GetStatsResponse) Reset() {
 	*x = GetStatsResponse{}
-	mi := &file_proto_shortener_proto_msgTypes[16]
+	mi := &file_proto_shortener_proto_msgTypes[19]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -771,7 +1117,7 @@ func (x *GetStatsResponse) String() string {
 func (*GetStatsResponse) ProtoMessage() {}
 
 func (x *GetStatsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_shortener_proto_msgTypes[16]
+	mi := &file_proto_shortener_proto_msgTypes[19]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -784,7 +1130,7 @@ func (x *GetStatsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetStatsResponse.ProtoReflect.Descriptor instead.
 func (*GetStatsResponse) Descriptor() ([]byte, []int) {
-	return file_proto_shortener_proto_rawDescGZIP(), []int{16}
+	return file_proto_shortener_proto_rawDescGZIP(), []int{19}
 }
 
 func (x *GetStatsResponse) GetUrls() int64 {
@@ -801,58 +1147,863 @@ func (x *GetStatsResponse) GetUsers() int64 {
 	return 0
 }
 
-var File_proto_shortener_proto protoreflect.FileDescriptor
+type LinkStatsRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	ShortUrl string                 `protobuf:"bytes,1,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
+	// Интервал гистограммы в секундах, по умолчанию один час
+	BucketSeconds int64 `protobuf:"varint,2,opt,name=bucket_seconds,json=bucketSeconds,proto3" json:"bucket_seconds,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-const file_proto_shortener_proto_rawDesc = "" +
-	"\n" +
-	"\x15proto/shortener.proto\x12\tshortener\")\n" +
-	"\x15CreateShortURLRequest\x12\x10\n" +
-	"\x03url\x18\x01 \x01(\tR\x03url\"*\n" +
-	"\x10ShortURLResponse\x12\x16\n" +
-	"\x06result\x18\x01 \x01(\tR\x06result\"b\n" +
-	"\x1aCreateShortURLBatchRequest\x12+\n" +
-	"\x04urls\x18\x01 \x03(\v2\x17.shortener.BatchURLDataR\x04urls\x12\x17\n" +
-	"\auser_id\x18\x02 \x01(\tR\x06userId\"R\n" +
-	"\x1bCreateShortURLBatchResponse\x123\n" +
-	"\x04urls\x18\x01 \x03(\v2\x1f.shortener.BatchURLDataResponseR\x04urls\"X\n" +
-	"\fBatchURLData\x12%\n" +
-	"\x0ecorrelation_id\x18\x01 \x01(\tR\rcorrelationId\x12!\n" +
-	"\foriginal_url\x18\x02 \x01(\tR\voriginalUrl\"Z\n" +
-	"\x14BatchURLDataResponse\x12%\n" +
-	"\x0ecorrelation_id\x18\x01 \x01(\tR\rcorrelationId\x12\x1b\n" +
-	"\tshort_url\x18\x02 \x01(\tR\bshortUrl\"4\n" +
-	"\x15GetOriginalURLRequest\x12\x1b\n" +
-	"\tshort_url\x18\x01 \x01(\tR\bshortUrl\"8\n" +
-	"\x13OriginalURLResponse\x12!\n" +
-	"\foriginal_url\x18\x01 \x01(\tR\voriginalUrl\"(\n" +
-	"\rUserIDRequest\x12\x17\n" +
-	"\auser_id\x18\x01 \x01(\tR\x06userId\":\n" +
-	"\x10UserURLsResponse\x12&\n" +
-	"\x04urls\x18\x01 \x03(\v2\x12.shortener.UserURLR\x04urls\"I\n" +
+func (x *LinkStatsRequest) Reset() {
+	*x = LinkStatsRequest{}
+	mi := &file_proto_shortener_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LinkStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LinkStatsRequest) ProtoMessage() {}
+
+func (x *LinkStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LinkStatsRequest.ProtoReflect.Descriptor instead.
+func (*LinkStatsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *LinkStatsRequest) GetShortUrl() string {
+	if x != nil {
+		return x.ShortUrl
+	}
+	return ""
+}
+
+func (x *LinkStatsRequest) GetBucketSeconds() int64 {
+	if x != nil {
+		return x.BucketSeconds
+	}
+	return 0
+}
+
+type StatsBucket struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Start         *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=start,proto3" json:"start,omitempty"`
+	Count         int64                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatsBucket) Reset() {
+	*x = StatsBucket{}
+	mi := &file_proto_shortener_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatsBucket) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsBucket) ProtoMessage() {}
+
+func (x *StatsBucket) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatsBucket.ProtoReflect.Descriptor instead.
+func (*StatsBucket) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *StatsBucket) GetStart() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Start
+	}
+	return nil
+}
+
+func (x *StatsBucket) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+type LinkStatsResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	ShortUrl       string                 `protobuf:"bytes,1,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
+	Total          int64                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	UniqueVisitors int64                  `protobuf:"varint,3,opt,name=unique_visitors,json=uniqueVisitors,proto3" json:"unique_visitors,omitempty"`
+	Histogram      []*StatsBucket         `protobuf:"bytes,4,rep,name=histogram,proto3" json:"histogram,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *LinkStatsResponse) Reset() {
+	*x = LinkStatsResponse{}
+	mi := &file_proto_shortener_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LinkStatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LinkStatsResponse) ProtoMessage() {}
+
+func (x *LinkStatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LinkStatsResponse.ProtoReflect.Descriptor instead.
+func (*LinkStatsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *LinkStatsResponse) GetShortUrl() string {
+	if x != nil {
+		return x.ShortUrl
+	}
+	return ""
+}
+
+func (x *LinkStatsResponse) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *LinkStatsResponse) GetUniqueVisitors() int64 {
+	if x != nil {
+		return x.UniqueVisitors
+	}
+	return 0
+}
+
+func (x *LinkStatsResponse) GetHistogram() []*StatsBucket {
+	if x != nil {
+		return x.Histogram
+	}
+	return nil
+}
+
+type UpdateURLRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ShortUrl      string                 `protobuf:"bytes,1,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
+	OriginalUrl   string                 `protobuf:"bytes,2,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateURLRequest) Reset() {
+	*x = UpdateURLRequest{}
+	mi := &file_proto_shortener_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateURLRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateURLRequest) ProtoMessage() {}
+
+func (x *UpdateURLRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateURLRequest.ProtoReflect.Descriptor instead.
+func (*UpdateURLRequest) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *UpdateURLRequest) GetShortUrl() string {
+	if x != nil {
+		return x.ShortUrl
+	}
+	return ""
+}
+
+func (x *UpdateURLRequest) GetOriginalUrl() string {
+	if x != nil {
+		return x.OriginalUrl
+	}
+	return ""
+}
+
+type URLHistoryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ShortUrl      string                 `protobuf:"bytes,1,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *URLHistoryRequest) Reset() {
+	*x = URLHistoryRequest{}
+	mi := &file_proto_shortener_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *URLHistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*URLHistoryRequest) ProtoMessage() {}
+
+func (x *URLHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use URLHistoryRequest.ProtoReflect.Descriptor instead.
+func (*URLHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *URLHistoryRequest) GetShortUrl() string {
+	if x != nil {
+		return x.ShortUrl
+	}
+	return ""
+}
+
+type RollbackURLRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	ShortUrl string                 `protobuf:"bytes,1,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
+	// Версия 0 — адрес, с которым ссылка была создана
+	Version       int32 `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RollbackURLRequest) Reset() {
+	*x = RollbackURLRequest{}
+	mi := &file_proto_shortener_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RollbackURLRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RollbackURLRequest) ProtoMessage() {}
+
+func (x *RollbackURLRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RollbackURLRequest.ProtoReflect.Descriptor instead.
+func (*RollbackURLRequest) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *RollbackURLRequest) GetShortUrl() string {
+	if x != nil {
+		return x.ShortUrl
+	}
+	return ""
+}
+
+func (x *RollbackURLRequest) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type URLVersion struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Version       int32                  `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	OriginalUrl   string                 `protobuf:"bytes,2,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	PreviousUrl   string                 `protobuf:"bytes,3,opt,name=previous_url,json=previousUrl,proto3" json:"previous_url,omitempty"`
+	ChangedBy     string                 `protobuf:"bytes,4,opt,name=changed_by,json=changedBy,proto3" json:"changed_by,omitempty"`
+	ChangedAt     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=changed_at,json=changedAt,proto3" json:"changed_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *URLVersion) Reset() {
+	*x = URLVersion{}
+	mi := &file_proto_shortener_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *URLVersion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*URLVersion) ProtoMessage() {}
+
+func (x *URLVersion) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use URLVersion.ProtoReflect.Descriptor instead.
+func (*URLVersion) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *URLVersion) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *URLVersion) GetOriginalUrl() string {
+	if x != nil {
+		return x.OriginalUrl
+	}
+	return ""
+}
+
+func (x *URLVersion) GetPreviousUrl() string {
+	if x != nil {
+		return x.PreviousUrl
+	}
+	return ""
+}
+
+func (x *URLVersion) GetChangedBy() string {
+	if x != nil {
+		return x.ChangedBy
+	}
+	return ""
+}
+
+func (x *URLVersion) GetChangedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ChangedAt
+	}
+	return nil
+}
+
+type URLHistoryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Versions      []*URLVersion          `protobuf:"bytes,1,rep,name=versions,proto3" json:"versions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *URLHistoryResponse) Reset() {
+	*x = URLHistoryResponse{}
+	mi := &file_proto_shortener_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *URLHistoryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*URLHistoryResponse) ProtoMessage() {}
+
+func (x *URLHistoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use URLHistoryResponse.ProtoReflect.Descriptor instead.
+func (*URLHistoryResponse) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *URLHistoryResponse) GetVersions() []*URLVersion {
+	if x != nil {
+		return x.Versions
+	}
+	return nil
+}
+
+type ImportURLsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Url           *BatchURLData          `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportURLsRequest) Reset() {
+	*x = ImportURLsRequest{}
+	mi := &file_proto_shortener_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportURLsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportURLsRequest) ProtoMessage() {}
+
+func (x *ImportURLsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportURLsRequest.ProtoReflect.Descriptor instead.
+func (*ImportURLsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *ImportURLsRequest) GetUrl() *BatchURLData {
+	if x != nil {
+		return x.Url
+	}
+	return nil
+}
+
+type ImportResult struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Порядковый номер записи в потоке, начиная с 1
+	Record        int32  `protobuf:"varint,1,opt,name=record,proto3" json:"record,omitempty"`
+	CorrelationId string `protobuf:"bytes,2,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
+	ShortUrl      string `protobuf:"bytes,3,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
+	// created, existing или invalid
+	Status        string `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`
+	Error         string `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportResult) Reset() {
+	*x = ImportResult{}
+	mi := &file_proto_shortener_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportResult) ProtoMessage() {}
+
+func (x *ImportResult) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportResult.ProtoReflect.Descriptor instead.
+func (*ImportResult) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *ImportResult) GetRecord() int32 {
+	if x != nil {
+		return x.Record
+	}
+	return 0
+}
+
+func (x *ImportResult) GetCorrelationId() string {
+	if x != nil {
+		return x.CorrelationId
+	}
+	return ""
+}
+
+func (x *ImportResult) GetShortUrl() string {
+	if x != nil {
+		return x.ShortUrl
+	}
+	return ""
+}
+
+func (x *ImportResult) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ImportResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type ImportProgress struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Processed     int32                  `protobuf:"varint,1,opt,name=processed,proto3" json:"processed,omitempty"`
+	Created       int32                  `protobuf:"varint,2,opt,name=created,proto3" json:"created,omitempty"`
+	Existing      int32                  `protobuf:"varint,3,opt,name=existing,proto3" json:"existing,omitempty"`
+	Invalid       int32                  `protobuf:"varint,4,opt,name=invalid,proto3" json:"invalid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportProgress) Reset() {
+	*x = ImportProgress{}
+	mi := &file_proto_shortener_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportProgress) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportProgress) ProtoMessage() {}
+
+func (x *ImportProgress) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportProgress.ProtoReflect.Descriptor instead.
+func (*ImportProgress) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *ImportProgress) GetProcessed() int32 {
+	if x != nil {
+		return x.Processed
+	}
+	return 0
+}
+
+func (x *ImportProgress) GetCreated() int32 {
+	if x != nil {
+		return x.Created
+	}
+	return 0
+}
+
+func (x *ImportProgress) GetExisting() int32 {
+	if x != nil {
+		return x.Existing
+	}
+	return 0
+}
+
+func (x *ImportProgress) GetInvalid() int32 {
+	if x != nil {
+		return x.Invalid
+	}
+	return 0
+}
+
+type ImportURLsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Event:
+	//
+	//	*ImportURLsResponse_Result
+	//	*ImportURLsResponse_Progress
+	Event         isImportURLsResponse_Event `protobuf_oneof:"event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportURLsResponse) Reset() {
+	*x = ImportURLsResponse{}
+	mi := &file_proto_shortener_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportURLsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportURLsResponse) ProtoMessage() {}
+
+func (x *ImportURLsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_shortener_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportURLsResponse.ProtoReflect.Descriptor instead.
+func (*ImportURLsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_shortener_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *ImportURLsResponse) GetEvent() isImportURLsResponse_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *ImportURLsResponse) GetResult() *ImportResult {
+	if x != nil {
+		if x, ok := x.Event.(*ImportURLsResponse_Result); ok {
+			return x.Result
+		}
+	}
+	return nil
+}
+
+func (x *ImportURLsResponse) GetProgress() *ImportProgress {
+	if x != nil {
+		if x, ok := x.Event.(*ImportURLsResponse_Progress); ok {
+			return x.Progress
+		}
+	}
+	return nil
+}
+
+type isImportURLsResponse_Event interface {
+	isImportURLsResponse_Event()
+}
+
+type ImportURLsResponse_Result struct {
+	Result *ImportResult `protobuf:"bytes,1,opt,name=result,proto3,oneof"`
+}
+
+type ImportURLsResponse_Progress struct {
+	Progress *ImportProgress `protobuf:"bytes,2,opt,name=progress,proto3,oneof"`
+}
+
+func (*ImportURLsResponse_Result) isImportURLsResponse_Event() {}
+
+func (*ImportURLsResponse_Progress) isImportURLsResponse_Event() {}
+
+var File_proto_shortener_proto protoreflect.FileDescriptor
+
+const file_proto_shortener_proto_rawDesc = "" +
+	"\n" +
+	"\x15proto/shortener.proto\x12\tshortener\x1a\x1fgoogle/protobuf/timestamp.proto\"\x9b\x01\n" +
+	"\x15CreateShortURLRequest\x12\x10\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\x12\x14\n" +
+	"\x05alias\x18\x02 \x01(\tR\x05alias\x129\n" +
+	"\n" +
+	"expires_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\x12\x1f\n" +
+	"\vttl_seconds\x18\x04 \x01(\x03R\n" +
+	"ttlSeconds\"*\n" +
+	"\x10ShortURLResponse\x12\x16\n" +
+	"\x06result\x18\x01 \x01(\tR\x06result\"z\n" +
+	"\x1aCreateShortURLBatchRequest\x12+\n" +
+	"\x04urls\x18\x01 \x03(\v2\x17.shortener.BatchURLDataR\x04urls\x12\x1b\n" +
+	"\auser_id\x18\x02 \x01(\tB\x02\x18\x01R\x06userId\x12\x12\n" +
+	"\x04mode\x18\x03 \x01(\tR\x04mode\"R\n" +
+	"\x1bCreateShortURLBatchResponse\x123\n" +
+	"\x04urls\x18\x01 \x03(\v2\x1f.shortener.BatchURLDataResponseR\x04urls\"\xca\x01\n" +
+	"\fBatchURLData\x12%\n" +
+	"\x0ecorrelation_id\x18\x01 \x01(\tR\rcorrelationId\x12!\n" +
+	"\foriginal_url\x18\x02 \x01(\tR\voriginalUrl\x12\x14\n" +
+	"\x05alias\x18\x03 \x01(\tR\x05alias\x129\n" +
+	"\n" +
+	"expires_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\x12\x1f\n" +
+	"\vttl_seconds\x18\x05 \x01(\x03R\n" +
+	"ttlSeconds\"\x88\x01\n" +
+	"\x14BatchURLDataResponse\x12%\n" +
+	"\x0ecorrelation_id\x18\x01 \x01(\tR\rcorrelationId\x12\x1b\n" +
+	"\tshort_url\x18\x02 \x01(\tR\bshortUrl\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"4\n" +
+	"\x15GetOriginalURLRequest\x12\x1b\n" +
+	"\tshort_url\x18\x01 \x01(\tR\bshortUrl\"8\n" +
+	"\x13OriginalURLResponse\x12!\n" +
+	"\foriginal_url\x18\x01 \x01(\tR\voriginalUrl\"\xad\x01\n" +
+	"\rUserIDRequest\x12\x1b\n" +
+	"\auser_id\x18\x01 \x01(\tB\x02\x18\x01R\x06userId\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06cursor\x18\x03 \x01(\tR\x06cursor\x12\x14\n" +
+	"\x05query\x18\x04 \x01(\tR\x05query\x12\x12\n" +
+	"\x04sort\x18\x05 \x01(\tR\x04sort\x12'\n" +
+	"\x0finclude_deleted\x18\x06 \x01(\bR\x0eincludeDeleted\"[\n" +
+	"\x10UserURLsResponse\x12&\n" +
+	"\x04urls\x18\x01 \x03(\v2\x12.shortener.UserURLR\x04urls\x12\x1f\n" +
+	"\vnext_cursor\x18\x02 \x01(\tR\n" +
+	"nextCursor\"h\n" +
 	"\aUserURL\x12\x1b\n" +
 	"\tshort_url\x18\x01 \x01(\tR\bshortUrl\x12!\n" +
-	"\foriginal_url\x18\x02 \x01(\tR\voriginalUrl\"T\n" +
-	"\x15DeleteUserURLsRequest\x12\x17\n" +
-	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\"\n" +
-	"\rshort_url_ids\x18\x02 \x03(\tR\vshortUrlIds\"2\n" +
+	"\foriginal_url\x18\x02 \x01(\tR\voriginalUrl\x12\x1d\n" +
+	"\n" +
+	"is_deleted\x18\x03 \x01(\bR\tisDeleted\"X\n" +
+	"\x15DeleteUserURLsRequest\x12\x1b\n" +
+	"\auser_id\x18\x01 \x01(\tB\x02\x18\x01R\x06userId\x12\"\n" +
+	"\rshort_url_ids\x18\x02 \x03(\tR\vshortUrlIds\"I\n" +
 	"\x16DeleteUserURLsResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\"\r\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x15\n" +
+	"\x06job_id\x18\x02 \x01(\tR\x05jobId\")\n" +
+	"\x10DeleteJobRequest\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\"F\n" +
+	"\rDeleteOutcome\x12\x1b\n" +
+	"\tshort_url\x18\x01 \x01(\tR\bshortUrl\x12\x18\n" +
+	"\aoutcome\x18\x02 \x01(\tR\aoutcome\"\x82\x02\n" +
+	"\x11DeleteJobResponse\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x122\n" +
+	"\aresults\x18\x03 \x03(\v2\x18.shortener.DeleteOutcomeR\aresults\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\x129\n" +
+	"\n" +
+	"created_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"\r\n" +
 	"\vPingRequest\"&\n" +
 	"\fPingResponse\x12\x16\n" +
 	"\x06status\x18\x01 \x01(\tR\x06status\"\x11\n" +
 	"\x0fGetStatsRequest\"<\n" +
 	"\x10GetStatsResponse\x12\x12\n" +
 	"\x04urls\x18\x01 \x01(\x03R\x04urls\x12\x14\n" +
-	"\x05users\x18\x02 \x01(\x03R\x05users2\x86\x05\n" +
+	"\x05users\x18\x02 \x01(\x03R\x05users\"V\n" +
+	"\x10LinkStatsRequest\x12\x1b\n" +
+	"\tshort_url\x18\x01 \x01(\tR\bshortUrl\x12%\n" +
+	"\x0ebucket_seconds\x18\x02 \x01(\x03R\rbucketSeconds\"U\n" +
+	"\vStatsBucket\x120\n" +
+	"\x05start\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x05start\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x03R\x05count\"\xa5\x01\n" +
+	"\x11LinkStatsResponse\x12\x1b\n" +
+	"\tshort_url\x18\x01 \x01(\tR\bshortUrl\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\x03R\x05total\x12'\n" +
+	"\x0funique_visitors\x18\x03 \x01(\x03R\x0euniqueVisitors\x124\n" +
+	"\thistogram\x18\x04 \x03(\v2\x16.shortener.StatsBucketR\thistogram\"R\n" +
+	"\x10UpdateURLRequest\x12\x1b\n" +
+	"\tshort_url\x18\x01 \x01(\tR\bshortUrl\x12!\n" +
+	"\foriginal_url\x18\x02 \x01(\tR\voriginalUrl\"0\n" +
+	"\x11URLHistoryRequest\x12\x1b\n" +
+	"\tshort_url\x18\x01 \x01(\tR\bshortUrl\"K\n" +
+	"\x12RollbackURLRequest\x12\x1b\n" +
+	"\tshort_url\x18\x01 \x01(\tR\bshortUrl\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\x05R\aversion\"\xc6\x01\n" +
+	"\n" +
+	"URLVersion\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\x05R\aversion\x12!\n" +
+	"\foriginal_url\x18\x02 \x01(\tR\voriginalUrl\x12!\n" +
+	"\fprevious_url\x18\x03 \x01(\tR\vpreviousUrl\x12\x1d\n" +
+	"\n" +
+	"changed_by\x18\x04 \x01(\tR\tchangedBy\x129\n" +
+	"\n" +
+	"changed_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tchangedAt\"G\n" +
+	"\x12URLHistoryResponse\x121\n" +
+	"\bversions\x18\x01 \x03(\v2\x15.shortener.URLVersionR\bversions\">\n" +
+	"\x11ImportURLsRequest\x12)\n" +
+	"\x03url\x18\x01 \x01(\v2\x17.shortener.BatchURLDataR\x03url\"\x98\x01\n" +
+	"\fImportResult\x12\x16\n" +
+	"\x06record\x18\x01 \x01(\x05R\x06record\x12%\n" +
+	"\x0ecorrelation_id\x18\x02 \x01(\tR\rcorrelationId\x12\x1b\n" +
+	"\tshort_url\x18\x03 \x01(\tR\bshortUrl\x12\x16\n" +
+	"\x06status\x18\x04 \x01(\tR\x06status\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\"~\n" +
+	"\x0eImportProgress\x12\x1c\n" +
+	"\tprocessed\x18\x01 \x01(\x05R\tprocessed\x12\x18\n" +
+	"\acreated\x18\x02 \x01(\x05R\acreated\x12\x1a\n" +
+	"\bexisting\x18\x03 \x01(\x05R\bexisting\x12\x18\n" +
+	"\ainvalid\x18\x04 \x01(\x05R\ainvalid\"\x89\x01\n" +
+	"\x12ImportURLsResponse\x121\n" +
+	"\x06result\x18\x01 \x01(\v2\x17.shortener.ImportResultH\x00R\x06result\x127\n" +
+	"\bprogress\x18\x02 \x01(\v2\x19.shortener.ImportProgressH\x00R\bprogressB\a\n" +
+	"\x05event2\xbf\b\n" +
 	"\tShortener\x12O\n" +
 	"\x0eCreateShortURL\x12 .shortener.CreateShortURLRequest\x1a\x1b.shortener.ShortURLResponse\x12S\n" +
 	"\x12CreateShortURLJSON\x12 .shortener.CreateShortURLRequest\x1a\x1b.shortener.ShortURLResponse\x12d\n" +
 	"\x13CreateShortURLBatch\x12%.shortener.CreateShortURLBatchRequest\x1a&.shortener.CreateShortURLBatchResponse\x12R\n" +
 	"\x0eGetOriginalURL\x12 .shortener.GetOriginalURLRequest\x1a\x1e.shortener.OriginalURLResponse\x12D\n" +
 	"\vGetUserURLs\x12\x18.shortener.UserIDRequest\x1a\x1b.shortener.UserURLsResponse\x12U\n" +
-	"\x0eDeleteUserURLs\x12 .shortener.DeleteUserURLsRequest\x1a!.shortener.DeleteUserURLsResponse\x127\n" +
+	"\x0eDeleteUserURLs\x12 .shortener.DeleteUserURLsRequest\x1a!.shortener.DeleteUserURLsResponse\x12I\n" +
+	"\fGetDeleteJob\x12\x1b.shortener.DeleteJobRequest\x1a\x1c.shortener.DeleteJobResponse\x127\n" +
 	"\x04Ping\x12\x16.shortener.PingRequest\x1a\x17.shortener.PingResponse\x12C\n" +
-	"\bGetStats\x12\x1a.shortener.GetStatsRequest\x1a\x1b.shortener.GetStatsResponseB\x0eZ\f/proto;protob\x06proto3"
+	"\bGetStats\x12\x1a.shortener.GetStatsRequest\x1a\x1b.shortener.GetStatsResponse\x12I\n" +
+	"\fGetLinkStats\x12\x1b.shortener.LinkStatsRequest\x1a\x1c.shortener.LinkStatsResponse\x12?\n" +
+	"\tUpdateURL\x12\x1b.shortener.UpdateURLRequest\x1a\x15.shortener.URLVersion\x12L\n" +
+	"\rGetURLHistory\x12\x1c.shortener.URLHistoryRequest\x1a\x1d.shortener.URLHistoryResponse\x12C\n" +
+	"\vRollbackURL\x12\x1d.shortener.RollbackURLRequest\x1a\x15.shortener.URLVersion\x12M\n" +
+	"\n" +
+	"ImportURLs\x12\x1c.shortener.ImportURLsRequest\x1a\x1d.shortener.ImportURLsResponse(\x010\x01B\x0eZ\f/proto;protob\x06proto3"
 
 var (
 	file_proto_shortener_proto_rawDescOnce sync.Once
@@ -866,7 +2017,7 @@ func file_proto_shortener_proto_rawDescGZIP() []byte {
 	return file_proto_shortener_proto_rawDescData
 }
 
-var file_proto_shortener_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
+var file_proto_shortener_proto_msgTypes = make([]protoimpl.MessageInfo, 32)
 var file_proto_shortener_proto_goTypes = []any{
 	(*CreateShortURLRequest)(nil),       // 0: shortener.CreateShortURLRequest
 	(*ShortURLResponse)(nil),            // 1: shortener.ShortURLResponse
@@ -881,36 +2032,76 @@ var file_proto_shortener_proto_goTypes = []any{
 	(*UserURL)(nil),                     // 10: shortener.UserURL
 	(*DeleteUserURLsRequest)(nil),       // 11: shortener.DeleteUserURLsRequest
 	(*DeleteUserURLsResponse)(nil),      // 12: shortener.DeleteUserURLsResponse
-	(*PingRequest)(nil),                 // 13: shortener.PingRequest
-	(*PingResponse)(nil),                // 14: shortener.PingResponse
-	(*GetStatsRequest)(nil),             // 15: shortener.GetStatsRequest
-	(*GetStatsResponse)(nil),            // 16: shortener.GetStatsResponse
+	(*DeleteJobRequest)(nil),            // 13: shortener.DeleteJobRequest
+	(*DeleteOutcome)(nil),               // 14: shortener.DeleteOutcome
+	(*DeleteJobResponse)(nil),           // 15: shortener.DeleteJobResponse
+	(*PingRequest)(nil),                 // 16: shortener.PingRequest
+	(*PingResponse)(nil),                // 17: shortener.PingResponse
+	(*GetStatsRequest)(nil),             // 18: shortener.GetStatsRequest
+	(*GetStatsResponse)(nil),            // 19: shortener.GetStatsResponse
+	(*LinkStatsRequest)(nil),            // 20: shortener.LinkStatsRequest
+	(*StatsBucket)(nil),                 // 21: shortener.StatsBucket
+	(*LinkStatsResponse)(nil),           // 22: shortener.LinkStatsResponse
+	(*UpdateURLRequest)(nil),            // 23: shortener.UpdateURLRequest
+	(*URLHistoryRequest)(nil),           // 24: shortener.URLHistoryRequest
+	(*RollbackURLRequest)(nil),          // 25: shortener.RollbackURLRequest
+	(*URLVersion)(nil),                  // 26: shortener.URLVersion
+	(*URLHistoryResponse)(nil),          // 27: shortener.URLHistoryResponse
+	(*ImportURLsRequest)(nil),           // 28: shortener.ImportURLsRequest
+	(*ImportResult)(nil),                // 29: shortener.ImportResult
+	(*ImportProgress)(nil),              // 30: shortener.ImportProgress
+	(*ImportURLsResponse)(nil),          // 31: shortener.ImportURLsResponse
+	(*timestamppb.Timestamp)(nil),       // 32: google.protobuf.Timestamp
 }
 var file_proto_shortener_proto_depIdxs = []int32{
-	4,  // 0: shortener.CreateShortURLBatchRequest.urls:type_name -> shortener.BatchURLData
-	5,  // 1: shortener.CreateShortURLBatchResponse.urls:type_name -> shortener.BatchURLDataResponse
-	10, // 2: shortener.UserURLsResponse.urls:type_name -> shortener.UserURL
-	0,  // 3: shortener.Shortener.CreateShortURL:input_type -> shortener.CreateShortURLRequest
-	0,  // 4: shortener.Shortener.CreateShortURLJSON:input_type -> shortener.CreateShortURLRequest
-	2,  // 5: shortener.Shortener.CreateShortURLBatch:input_type -> shortener.CreateShortURLBatchRequest
-	6,  // 6: shortener.Shortener.GetOriginalURL:input_type -> shortener.GetOriginalURLRequest
-	8,  // 7: shortener.Shortener.GetUserURLs:input_type -> shortener.UserIDRequest
-	11, // 8: shortener.Shortener.DeleteUserURLs:input_type -> shortener.DeleteUserURLsRequest
-	13, // 9: shortener.Shortener.Ping:input_type -> shortener.PingRequest
-	15, // 10: shortener.Shortener.GetStats:input_type -> shortener.GetStatsRequest
-	1,  // 11: shortener.Shortener.CreateShortURL:output_type -> shortener.ShortURLResponse
-	1,  // 12: shortener.Shortener.CreateShortURLJSON:output_type -> shortener.ShortURLResponse
-	3,  // 13: shortener.Shortener.CreateShortURLBatch:output_type -> shortener.CreateShortURLBatchResponse
-	7,  // 14: shortener.Shortener.GetOriginalURL:output_type -> shortener.OriginalURLResponse
-	9,  // 15: shortener.Shortener.GetUserURLs:output_type -> shortener.UserURLsResponse
-	12, // 16: shortener.Shortener.DeleteUserURLs:output_type -> shortener.DeleteUserURLsResponse
-	14, // 17: shortener.Shortener.Ping:output_type -> shortener.PingResponse
-	16, // 18: shortener.Shortener.GetStats:output_type -> shortener.GetStatsResponse
-	11, // [11:19] is the sub-list for method output_type
-	3,  // [3:11] is the sub-list for method input_type
-	3,  // [3:3] is the sub-list for extension type_name
-	3,  // [3:3] is the sub-list for extension extendee
-	0,  // [0:3] is the sub-list for field type_name
+	32, // 0: shortener.CreateShortURLRequest.expires_at:type_name -> google.protobuf.Timestamp
+	4,  // 1: shortener.CreateShortURLBatchRequest.urls:type_name -> shortener.BatchURLData
+	5,  // 2: shortener.CreateShortURLBatchResponse.urls:type_name -> shortener.BatchURLDataResponse
+	32, // 3: shortener.BatchURLData.expires_at:type_name -> google.protobuf.Timestamp
+	10, // 4: shortener.UserURLsResponse.urls:type_name -> shortener.UserURL
+	14, // 5: shortener.DeleteJobResponse.results:type_name -> shortener.DeleteOutcome
+	32, // 6: shortener.DeleteJobResponse.created_at:type_name -> google.protobuf.Timestamp
+	32, // 7: shortener.DeleteJobResponse.updated_at:type_name -> google.protobuf.Timestamp
+	32, // 8: shortener.StatsBucket.start:type_name -> google.protobuf.Timestamp
+	21, // 9: shortener.LinkStatsResponse.histogram:type_name -> shortener.StatsBucket
+	32, // 10: shortener.URLVersion.changed_at:type_name -> google.protobuf.Timestamp
+	26, // 11: shortener.URLHistoryResponse.versions:type_name -> shortener.URLVersion
+	4,  // 12: shortener.ImportURLsRequest.url:type_name -> shortener.BatchURLData
+	29, // 13: shortener.ImportURLsResponse.result:type_name -> shortener.ImportResult
+	30, // 14: shortener.ImportURLsResponse.progress:type_name -> shortener.ImportProgress
+	0,  // 15: shortener.Shortener.CreateShortURL:input_type -> shortener.CreateShortURLRequest
+	0,  // 16: shortener.Shortener.CreateShortURLJSON:input_type -> shortener.CreateShortURLRequest
+	2,  // 17: shortener.Shortener.CreateShortURLBatch:input_type -> shortener.CreateShortURLBatchRequest
+	6,  // 18: shortener.Shortener.GetOriginalURL:input_type -> shortener.GetOriginalURLRequest
+	8,  // 19: shortener.Shortener.GetUserURLs:input_type -> shortener.UserIDRequest
+	11, // 20: shortener.Shortener.DeleteUserURLs:input_type -> shortener.DeleteUserURLsRequest
+	13, // 21: shortener.Shortener.GetDeleteJob:input_type -> shortener.DeleteJobRequest
+	16, // 22: shortener.Shortener.Ping:input_type -> shortener.PingRequest
+	18, // 23: shortener.Shortener.GetStats:input_type -> shortener.GetStatsRequest
+	20, // 24: shortener.Shortener.GetLinkStats:input_type -> shortener.LinkStatsRequest
+	23, // 25: shortener.Shortener.UpdateURL:input_type -> shortener.UpdateURLRequest
+	24, // 26: shortener.Shortener.GetURLHistory:input_type -> shortener.URLHistoryRequest
+	25, // 27: shortener.Shortener.RollbackURL:input_type -> shortener.RollbackURLRequest
+	28, // 28: shortener.Shortener.ImportURLs:input_type -> shortener.ImportURLsRequest
+	1,  // 29: shortener.Shortener.CreateShortURL:output_type -> shortener.ShortURLResponse
+	1,  // 30: shortener.Shortener.CreateShortURLJSON:output_type -> shortener.ShortURLResponse
+	3,  // 31: shortener.Shortener.CreateShortURLBatch:output_type -> shortener.CreateShortURLBatchResponse
+	7,  // 32: shortener.Shortener.GetOriginalURL:output_type -> shortener.OriginalURLResponse
+	9,  // 33: shortener.Shortener.GetUserURLs:output_type -> shortener.UserURLsResponse
+	12, // 34: shortener.Shortener.DeleteUserURLs:output_type -> shortener.DeleteUserURLsResponse
+	15, // 35: shortener.Shortener.GetDeleteJob:output_type -> shortener.DeleteJobResponse
+	17, // 36: shortener.Shortener.Ping:output_type -> shortener.PingResponse
+	19, // 37: shortener.Shortener.GetStats:output_type -> shortener.GetStatsResponse
+	22, // 38: shortener.Shortener.GetLinkStats:output_type -> shortener.LinkStatsResponse
+	26, // 39: shortener.Shortener.UpdateURL:output_type -> shortener.URLVersion
+	27, // 40: shortener.Shortener.GetURLHistory:output_type -> shortener.URLHistoryResponse
+	26, // 41: shortener.Shortener.RollbackURL:output_type -> shortener.URLVersion
+	31, // 42: shortener.Shortener.ImportURLs:output_type -> shortener.ImportURLsResponse
+	29, // [29:43] is the sub-list for method output_type
+	15, // [15:29] is the sub-list for method input_type
+	15, // [15:15] is the sub-list for extension type_name
+	15, // [15:15] is the sub-list for extension extendee
+	0,  // [0:15] is the sub-list for field type_name
 }
 
 func init() { file_proto_shortener_proto_init() }
@@ -918,13 +2109,17 @@ func file_proto_shortener_proto_init() {
 	if File_proto_shortener_proto != nil {
 		return
 	}
+	file_proto_shortener_proto_msgTypes[31].OneofWrappers = []any{
+		(*ImportURLsResponse_Result)(nil),
+		(*ImportURLsResponse_Progress)(nil),
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_shortener_proto_rawDesc), len(file_proto_shortener_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   17,
+			NumMessages:   32,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -25,8 +25,14 @@ const (
 	Shortener_GetOriginalURL_FullMethodName      = "/shortener.Shortener/GetOriginalURL"
 	Shortener_GetUserURLs_FullMethodName         = "/shortener.Shortener/GetUserURLs"
 	Shortener_DeleteUserURLs_FullMethodName      = "/shortener.Shortener/DeleteUserURLs"
+	Shortener_GetDeleteJob_FullMethodName        = "/shortener.Shortener/GetDeleteJob"
 	Shortener_Ping_FullMethodName                = "/shortener.Shortener/Ping"
 	Shortener_GetStats_FullMethodName            = "/shortener.Shortener/GetStats"
+	Shortener_GetLinkStats_FullMethodName        = "/shortener.Shortener/GetLinkStats"
+	Shortener_UpdateURL_FullMethodName           = "/shortener.Shortener/UpdateURL"
+	Shortener_GetURLHistory_FullMethodName       = "/shortener.Shortener/GetURLHistory"
+	Shortener_RollbackURL_FullMethodName         = "/shortener.Shortener/RollbackURL"
+	Shortener_ImportURLs_FullMethodName          = "/shortener.Shortener/ImportURLs"
 )
 
 // ShortenerClient is the client API for Shortener service.
@@ -39,8 +45,16 @@ type ShortenerClient interface {
 	GetOriginalURL(ctx context.Context, in *GetOriginalURLRequest, opts ...grpc.CallOption) (*OriginalURLResponse, error)
 	GetUserURLs(ctx context.Context, in *UserIDRequest, opts ...grpc.CallOption) (*UserURLsResponse, error)
 	DeleteUserURLs(ctx context.Context, in *DeleteUserURLsRequest, opts ...grpc.CallOption) (*DeleteUserURLsResponse, error)
+	GetDeleteJob(ctx context.Context, in *DeleteJobRequest, opts ...grpc.CallOption) (*DeleteJobResponse, error)
 	Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResponse, error)
 	GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsResponse, error)
+	GetLinkStats(ctx context.Context, in *LinkStatsRequest, opts ...grpc.CallOption) (*LinkStatsResponse, error)
+	UpdateURL(ctx context.Context, in *UpdateURLRequest, opts ...grpc.CallOption) (*URLVersion, error)
+	GetURLHistory(ctx context.Context, in *URLHistoryRequest, opts ...grpc.CallOption) (*URLHistoryResponse, error)
+	RollbackURL(ctx context.Context, in *RollbackURLRequest, opts ...grpc.CallOption) (*URLVersion, error)
+	// Потоковый импорт: клиент передаёт записи по одной, сервер сохраняет их пачками
+	// и возвращает результат по каждой записи и прогресс после каждой пачки
+	ImportURLs(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ImportURLsRequest, ImportURLsResponse], error)
 }
 
 type shortenerClient struct {
@@ -111,6 +125,16 @@ func (c *shortenerClient) DeleteUserURLs(ctx context.Context, in *DeleteUserURLs
 	return out, nil
 }
 
+func (c *shortenerClient) GetDeleteJob(ctx context.Context, in *DeleteJobRequest, opts ...grpc.CallOption) (*DeleteJobResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteJobResponse)
+	err := c.cc.Invoke(ctx, Shortener_GetDeleteJob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *shortenerClient) Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(PingResponse)
@@ -131,6 +155,59 @@ func (c *shortenerClient) GetStats(ctx context.Context, in *GetStatsRequest, opt
 	return out, nil
 }
 
+func (c *shortenerClient) GetLinkStats(ctx context.Context, in *LinkStatsRequest, opts ...grpc.CallOption) (*LinkStatsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LinkStatsResponse)
+	err := c.cc.Invoke(ctx, Shortener_GetLinkStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) UpdateURL(ctx context.Context, in *UpdateURLRequest, opts ...grpc.CallOption) (*URLVersion, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(URLVersion)
+	err := c.cc.Invoke(ctx, Shortener_UpdateURL_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) GetURLHistory(ctx context.Context, in *URLHistoryRequest, opts ...grpc.CallOption) (*URLHistoryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(URLHistoryResponse)
+	err := c.cc.Invoke(ctx, Shortener_GetURLHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) RollbackURL(ctx context.Context, in *RollbackURLRequest, opts ...grpc.CallOption) (*URLVersion, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(URLVersion)
+	err := c.cc.Invoke(ctx, Shortener_RollbackURL_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) ImportURLs(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ImportURLsRequest, ImportURLsResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Shortener_ServiceDesc.Streams[0], Shortener_ImportURLs_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ImportURLsRequest, ImportURLsResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Shortener_ImportURLsClient = grpc.BidiStreamingClient[ImportURLsRequest, ImportURLsResponse]
+
 // ShortenerServer is the server API for Shortener service.
 // All implementations must embed UnimplementedShortenerServer
 // for forward compatibility.
@@ -141,8 +218,16 @@ type ShortenerServer interface {
 	GetOriginalURL(context.Context, *GetOriginalURLRequest) (*OriginalURLResponse, error)
 	GetUserURLs(context.Context, *UserIDRequest) (*UserURLsResponse, error)
 	DeleteUserURLs(context.Context, *DeleteUserURLsRequest) (*DeleteUserURLsResponse, error)
+	GetDeleteJob(context.Context, *DeleteJobRequest) (*DeleteJobResponse, error)
 	Ping(context.Context, *PingRequest) (*PingResponse, error)
 	GetStats(context.Context, *GetStatsRequest) (*GetStatsResponse, error)
+	GetLinkStats(context.Context, *LinkStatsRequest) (*LinkStatsResponse, error)
+	UpdateURL(context.Context, *UpdateURLRequest) (*URLVersion, error)
+	GetURLHistory(context.Context, *URLHistoryRequest) (*URLHistoryResponse, error)
+	RollbackURL(context.Context, *RollbackURLRequest) (*URLVersion, error)
+	// Потоковый импорт: клиент передаёт записи по одной, сервер сохраняет их пачками
+	// и возвращает результат по каждой записи и прогресс после каждой пачки
+	ImportURLs(grpc.BidiStreamingServer[ImportURLsRequest, ImportURLsResponse]) error
 	mustEmbedUnimplementedShortenerServer()
 }
 
@@ -171,12 +256,30 @@ func (UnimplementedShortenerServer) GetUserURLs(context.Context, *UserIDRequest)
 func (UnimplementedShortenerServer) DeleteUserURLs(context.Context, *DeleteUserURLsRequest) (*DeleteUserURLsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteUserURLs not implemented")
 }
+func (UnimplementedShortenerServer) GetDeleteJob(context.Context, *DeleteJobRequest) (*DeleteJobResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDeleteJob not implemented")
+}
 func (UnimplementedShortenerServer) Ping(context.Context, *PingRequest) (*PingResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Ping not implemented")
 }
 func (UnimplementedShortenerServer) GetStats(context.Context, *GetStatsRequest) (*GetStatsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetStats not implemented")
 }
+func (UnimplementedShortenerServer) GetLinkStats(context.Context, *LinkStatsRequest) (*LinkStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLinkStats not implemented")
+}
+func (UnimplementedShortenerServer) UpdateURL(context.Context, *UpdateURLRequest) (*URLVersion, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateURL not implemented")
+}
+func (UnimplementedShortenerServer) GetURLHistory(context.Context, *URLHistoryRequest) (*URLHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetURLHistory not implemented")
+}
+func (UnimplementedShortenerServer) RollbackURL(context.Context, *RollbackURLRequest) (*URLVersion, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RollbackURL not implemented")
+}
+func (UnimplementedShortenerServer) ImportURLs(grpc.BidiStreamingServer[ImportURLsRequest, ImportURLsResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method ImportURLs not implemented")
+}
 func (UnimplementedShortenerServer) mustEmbedUnimplementedShortenerServer() {}
 func (UnimplementedShortenerServer) testEmbeddedByValue()                   {}
 
@@ -306,6 +409,24 @@ func _Shortener_DeleteUserURLs_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Shortener_GetDeleteJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).GetDeleteJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_GetDeleteJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).GetDeleteJob(ctx, req.(*DeleteJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Shortener_Ping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(PingRequest)
 	if err := dec(in); err != nil {
@@ -342,6 +463,85 @@ func _Shortener_GetStats_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Shortener_GetLinkStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LinkStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).GetLinkStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_GetLinkStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).GetLinkStats(ctx, req.(*LinkStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_UpdateURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateURLRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).UpdateURL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_UpdateURL_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).UpdateURL(ctx, req.(*UpdateURLRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_GetURLHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(URLHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).GetURLHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_GetURLHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).GetURLHistory(ctx, req.(*URLHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_RollbackURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RollbackURLRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).RollbackURL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_RollbackURL_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).RollbackURL(ctx, req.(*RollbackURLRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_ImportURLs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ShortenerServer).ImportURLs(&grpc.GenericServerStream[ImportURLsRequest, ImportURLsResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Shortener_ImportURLsServer = grpc.BidiStreamingServer[ImportURLsRequest, ImportURLsResponse]
+
 // Shortener_ServiceDesc is the grpc.ServiceDesc for Shortener service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -373,6 +573,10 @@ var Shortener_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteUserURLs",
 			Handler:    _Shortener_DeleteUserURLs_Handler,
 		},
+		{
+			MethodName: "GetDeleteJob",
+			Handler:    _Shortener_GetDeleteJob_Handler,
+		},
 		{
 			MethodName: "Ping",
 			Handler:    _Shortener_Ping_Handler,
@@ -381,7 +585,30 @@ var Shortener_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetStats",
 			Handler:    _Shortener_GetStats_Handler,
 		},
+		{
+			MethodName: "GetLinkStats",
+			Handler:    _Shortener_GetLinkStats_Handler,
+		},
+		{
+			MethodName: "UpdateURL",
+			Handler:    _Shortener_UpdateURL_Handler,
+		},
+		{
+			MethodName: "GetURLHistory",
+			Handler:    _Shortener_GetURLHistory_Handler,
+		},
+		{
+			MethodName: "RollbackURL",
+			Handler:    _Shortener_RollbackURL_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ImportURLs",
+			Handler:       _Shortener_ImportURLs_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/shortener.proto",
 }